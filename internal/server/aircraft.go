@@ -0,0 +1,185 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Aircraft performance: whether the runway is long enough today.
+//
+// The temperature factor used to stand in for density altitude, on the reasoning that a
+// hot afternoon is what makes a short grass strip short. It charged the same 30C at a
+// 1300m asphalt runway as at a 360m grass one, and nothing at all for a low QNH. With the
+// airfield's elevation and runways in airports.json and the aircraft's book figure here,
+// the question can be asked directly: how much of the runway does the takeoff need?
+//
+// The book figure is the takeoff distance over a 50ft obstacle at maximum weight, ISA sea
+// level, on a paved dry runway with no wind. It is corrected the way the UK CAA's Safety
+// Sense leaflet 7 and every flying school does it by hand: 10% per 1000ft of density
+// altitude, 20% for dry grass, and 10% per 2kn of tailwind. These are rules of thumb, not
+// the POH's tables, and the factor's curve keeps its distance from the runway's end
+// accordingly.
+//
+// The runway is the end in use, as windOnRunways picks it: the long strip across the
+// wind is not the one anybody takes off from, however much of it would be left over.
+// Only an hour without an end in use -- no headings to pick one by -- falls back to the
+// runway that leaves the most to spare.
+
+// aircraftType is one aircraft's performance, as far as the score needs it.
+type aircraftType struct {
+	ID   string // "c172" -- what a profile names
+	Name string // "Cessna 172S"
+
+	// takeoffDistanceM is the book takeoff distance over 50ft: maximum weight, ISA at sea
+	// level, paved dry runway, no wind.
+	takeoffDistanceM float64
+
+	// carburetted is an engine with a carburettor rather than fuel injection, which the
+	// carb icing factor is charged for. See carbicing.go.
+	carburetted bool
+}
+
+// aircraftTypes is the catalogue profiles choose from. The figures are the manufacturers'
+// published ones, rounded to the metre.
+var aircraftTypes = []aircraftType{
+	{ID: "c152", Name: "Cessna 152", takeoffDistanceM: 408, carburetted: true},
+	{ID: "c172", Name: "Cessna 172S", takeoffDistanceM: 497}, // IO-360, injected
+	{ID: "pa28", Name: "Piper PA-28-181 Archer", takeoffDistanceM: 495, carburetted: true},
+	{ID: "c42", Name: "Comco Ikarus C42", takeoffDistanceM: 280, carburetted: true}, // Rotax 912 UL
+}
+
+// lookupAircraft resolves an aircraft ID from a profile.
+func lookupAircraft(id string) (*aircraftType, error) {
+	for i := range aircraftTypes {
+		if aircraftTypes[i].ID == id {
+			return &aircraftTypes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown aircraft %q", id)
+}
+
+// Runway surfaces, as airports.json gives them.
+const (
+	surfaceAsphalt  = "asphalt"
+	surfaceConcrete = "concrete"
+	surfaceGrass    = "grass"
+)
+
+// surfaceFactors multiply the paved takeoff distance. Dry short grass is Safety Sense 7's
+// 20%; a paved runway is what the book figure already assumes.
+var surfaceFactors = map[string]float64{
+	surfaceAsphalt:  1.0,
+	surfaceConcrete: 1.0,
+	surfaceGrass:    1.2,
+}
+
+// standardPressureHPa is ISA sea-level pressure, and what the takeoff factor assumes when
+// the forecast has no pressure for the hour: a QNH off by 10hPa is 270ft of pressure
+// altitude, which is much less than the hour's temperature does to it.
+const standardPressureHPa = 1013.25
+
+// pressureAltitudeFt is the pressure altitude of an airfield at elevationFt under qnh.
+func pressureAltitudeFt(elevationFt, qnh float64) float64 {
+	return elevationFt + 145366.45*(1-math.Pow(qnh/standardPressureHPa, 0.190284))
+}
+
+// densityAltitudeFt corrects a pressure altitude for temperature: ~120ft per degree away
+// from the ISA temperature at that altitude.
+func densityAltitudeFt(pressureAltitude, temperatureC float64) float64 {
+	isa := 15 - 1.98*pressureAltitude/1000
+	return pressureAltitude + 118.8*(temperatureC-isa)
+}
+
+// takeoffCase is what an hour's takeoff is worked out from besides the weather: where,
+// from which runways, and in what. It is configuration rather than forecast, so it is
+// attached to each hour's conditions when they are built or restored, never archived.
+type takeoffCase struct {
+	elevationFt float64
+	runways     []RunwayDetail
+	aircraft    *aircraftType
+	// ends is the runway each end belongs to: "23" is on "05/23".
+	ends map[string]string
+}
+
+// takeoffCaseFor returns the case for an airfield and a profile, or nil where either side
+// is missing: an airfield without an elevation or runway lengths, or a profile without an
+// aircraft. The factor then skips the hour, and temperature stands in as it always did.
+func takeoffCaseFor(airport Airport, profile *scoringProfile) *takeoffCase {
+	if profile == nil || profile.aircraft == nil || airport.ElevationFt == nil || len(airport.RunwayDetails) == 0 {
+		return nil
+	}
+	// A runway the aircraft cannot use is not one it could take off from, however long.
+	runways := slices.DeleteFunc(slices.Clone(airport.RunwayDetails), func(d RunwayDetail) bool {
+		return d.unusable(profile.aircraft) != ""
+	})
+	if len(runways) == 0 {
+		return nil
+	}
+	ends := make(map[string]string)
+	for _, end := range airport.runwayEnds() {
+		ends[end.designator] = end.runway
+	}
+	return &takeoffCase{elevationFt: *airport.ElevationFt, runways: runways, aircraft: profile.aircraft, ends: ends}
+}
+
+// takeoffRun is one hour's takeoff on the runway in use.
+type takeoffRun struct {
+	runway            RunwayDetail
+	densityAltitudeFt float64
+	requiredM         float64
+	availableM        float64
+}
+
+// share is the required distance as a percentage of the available one.
+func (r takeoffRun) share() float64 {
+	return r.requiredM / r.availableM * 100
+}
+
+// tailwindTakeoffFactor is Safety Sense 7's tailwind correction: 10% of the takeoff
+// distance per 2kn.
+const tailwindTakeoffFactor = 0.1 / 2
+
+// takeoffRun works out the hour's takeoff distance on the runway of the end in use, with
+// its tailwind. Without an end in use, it works it out on each runway and returns the one
+// that leaves the most to spare -- the longest runway, unless it is grass and a paved one
+// is nearly as long. false means the hour has no takeoff case.
+func (c conditions) takeoffRun() (takeoffRun, bool) {
+	if c.takeoff == nil {
+		return takeoffRun{}, false
+	}
+	qnh := standardPressureHPa
+	if c.pressureMSL != nil {
+		qnh = *c.pressureMSL
+	}
+	da := densityAltitudeFt(pressureAltitudeFt(c.takeoff.elevationFt, qnh), c.temperature)
+	// 10% per 1000ft, either way: a cold high-pressure morning is a shorter takeoff.
+	paved := c.takeoff.aircraft.takeoffDistanceM * (1 + 0.1*da/1000)
+
+	if runway, ok := c.takeoff.ends[c.runwayInUse]; ok && c.runwayInUse != "" {
+		if i := slices.IndexFunc(c.takeoff.runways, func(d RunwayDetail) bool { return d.Designator == runway }); i >= 0 {
+			rwy := c.takeoff.runways[i]
+			return takeoffRun{
+				runway:            rwy,
+				densityAltitudeFt: da,
+				requiredM:         paved * surfaceFactors[rwy.Surface] * (1 + tailwindTakeoffFactor*c.tailwind),
+				availableM:        rwy.LengthM,
+			}, true
+		}
+	}
+
+	var best takeoffRun
+	found := false
+	for _, rwy := range c.takeoff.runways {
+		run := takeoffRun{
+			runway:            rwy,
+			densityAltitudeFt: da,
+			requiredM:         paved * surfaceFactors[rwy.Surface],
+			availableM:        rwy.LengthM,
+		}
+		if !found || run.share() < best.share() {
+			best, found = run, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"math"
+	"testing"
+)
+
+// shortStrip is a grass strip and a paved runway at 200ft, for a C172.
+func shortStrip(t *testing.T) *takeoffCase {
+	t.Helper()
+
+	c172, err := lookupAircraft("c172")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &takeoffCase{
+		elevationFt: 200,
+		runways: []RunwayDetail{
+			{Designator: "09/27", LengthM: 800, Surface: surfaceGrass},
+			{Designator: "05/23", LengthM: 600, Surface: surfaceAsphalt},
+		},
+		aircraft: c172,
+	}
+}
+
+func TestPressureAndDensityAltitude(t *testing.T) {
+	tests := []struct {
+		elevation, qnh, temperature float64
+		pressure, density           float64
+	}{
+		// ISA at sea level is sea level.
+		{0, 1013.25, 15, 0, 0},
+		// Each hPa below standard is ~27ft of pressure altitude at the bottom, and 15C is
+		// then a little over ISA.
+		{0, 1003, 15, 281, 347},
+		// 1000ft on a 30C day: ISA there is 13C, and 17 degrees over is ~2000ft more.
+		{1000, 1013.25, 30, 1000, 3020},
+	}
+	for _, tc := range tests {
+		pa := pressureAltitudeFt(tc.elevation, tc.qnh)
+		da := densityAltitudeFt(pa, tc.temperature)
+		if math.Abs(pa-tc.pressure) > 5 || math.Abs(da-tc.density) > 15 {
+			t.Errorf("%vft at %vhPa and %vC: PA %.0f, DA %.0f; want ~%v and ~%v", tc.elevation, tc.qnh, tc.temperature, pa, da, tc.pressure, tc.density)
+		}
+	}
+}
+
+func TestConditionsTakeoffRun(t *testing.T) {
+	c := scoringConditions(t)
+	c.takeoff = shortStrip(t)
+
+	// 18C at 200ft under standard pressure: DA ~600ft, so the paved distance is ~6% over
+	// the book's 497m. The grass strip's 20% more is still the smaller share of its runway.
+	run, ok := c.takeoffRun()
+	if !ok || run.runway.Designator != "09/27" || math.Abs(run.requiredM-632) > 3 || run.availableM != 800 {
+		t.Fatalf("takeoffRun() = %+v, want ~632m of the grass strip's 800m", run)
+	}
+
+	// A hot afternoon with a low QNH.
+	c.temperature, c.pressureMSL = 32, ptrFloat(1000)
+	hot, _ := c.takeoffRun()
+	if hot.densityAltitudeFt < 2500 || hot.requiredM <= run.requiredM {
+		t.Errorf("at 32C and 1000hPa: DA %.0f, %.0fm; want it above 2500ft and longer than %.0fm", hot.densityAltitudeFt, hot.requiredM, run.requiredM)
+	}
+
+	c.takeoff = nil
+	if _, ok := c.takeoffRun(); ok {
+		t.Error("an hour without a takeoff case worked one out")
+	}
+}
+
+// The end in use decides the runway, whatever another one would leave to spare, and its
+// tailwind lengthens the run.
+func TestConditionsTakeoffRun_OnTheEndInUse(t *testing.T) {
+	c := scoringConditions(t)
+	c.takeoff = shortStrip(t)
+	c.takeoff.ends = map[string]string{"09": "09/27", "27": "09/27", "05": "05/23", "23": "05/23"}
+
+	// Into wind on 23: the 600m paved runway, at its ~527m, not the grass strip's 800m.
+	c.runwayInUse, c.tailwind = "23", 0
+	run, ok := c.takeoffRun()
+	if !ok || run.runway.Designator != "05/23" || math.Abs(run.requiredM-527) > 3 || run.availableM != 600 {
+		t.Fatalf("takeoffRun() = %+v, want ~527m of the paved runway's 600m", run)
+	}
+
+	// 4kn of tailwind on a one-way strip: 20% more.
+	c.tailwind = 4
+	downwind, _ := c.takeoffRun()
+	if math.Abs(downwind.requiredM-run.requiredM*1.2) > 1 {
+		t.Errorf("with 4kn of tailwind: %.0fm, want %.0fm", downwind.requiredM, run.requiredM*1.2)
+	}
+
+	// An end on a runway the case does not have falls back to the most to spare.
+	c.runwayInUse = "14"
+	if fallback, _ := c.takeoffRun(); fallback.runway.Designator != "09/27" {
+		t.Errorf("unknown end: runway %s, want the grass strip", fallback.runway.Designator)
+	}
+}
+
+func TestScoreVFR_TakeoffDistance(t *testing.T) {
+	c := scoringConditions(t)
+	c.takeoff = shortStrip(t)
+	c.takeoff.runways = c.takeoff.runways[1:] // the 600m paved runway alone
+
+	_, penalties, _ := scoreVFR(c, vfrLimits)
+	if len(penalties) != 1 || penalties[0].Factor != "takeoff distance" {
+		t.Fatalf("penalties = %+v, want the takeoff distance alone", penalties)
+	}
+	if got := penalties[0].Detail; got != "TODR 527m of 600m available" {
+		t.Errorf("detail = %q, want the distances in words", got)
+	}
+
+	// Where the takeoff can be worked out, temperature no longer stands in for it.
+	c.temperature = 33
+	for _, p := range mustScore(t, c) {
+		if p.Factor == "temperature" {
+			t.Errorf("charged temperature %+v beside the takeoff distance", p)
+		}
+	}
+	// 33C at 200ft is ~2400ft DA and ~620m: the runway is no longer enough.
+	if penalties := mustScore(t, c); len(penalties) != 1 || penalties[0].Severity != noGo.String() || penalties[0].Detail == "" {
+		t.Errorf("penalties = %+v, want a no-go that says why", penalties)
+	}
+
+	// Without one, it does.
+	c.takeoff = nil
+	if penalties := mustScore(t, c); len(penalties) == 0 || penalties[0].Factor != "temperature" {
+		t.Errorf("penalties = %+v, want temperature charged without a takeoff case", penalties)
+	}
+}
+
+func mustScore(t *testing.T, c conditions) []VfrPenalty {
+	t.Helper()
+
+	probability, penalties, _ := scoreVFR(c, vfrLimits)
+	if probability < 0 {
+		t.Fatal("hour was not scored")
+	}
+	return penalties
+}
+
+func TestTakeoffCaseFor(t *testing.T) {
+	airport := testAirport
+	if takeoffCaseFor(airport, defaultProfile()) != nil {
+		t.Error("an airfield without runway details has a takeoff case")
+	}
+
+	airport.ElevationFt = ptrFloat(85)
+	airport.RunwayDetails = []RunwayDetail{{Designator: "05/23", LengthM: 900, Surface: surfaceAsphalt}}
+	got := takeoffCaseFor(airport, defaultProfile())
+	if got == nil || got.aircraft.ID != "c172" || got.elevationFt != 85 || got.ends["23"] != "05/23" {
+		t.Errorf("takeoffCaseFor() = %+v, want EDWN's runway and the PPL profile's C172", got)
+	}
+	if takeoffCaseFor(airport, &scoringProfile{}) != nil {
+		t.Error("a profile without an aircraft has a takeoff case")
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"math"
 	"os"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // airportsJSON is the built-in airfield list. Setting FLUGWETTER_AIRPORTS_FILE replaces it
@@ -31,10 +33,16 @@ type Airport struct {
 	Longitude  float64 `json:"longitude"`
 	// Runways holds the published (magnetic, rounded) designators, for display only.
 	Runways []string `json:"runways"`
-	// RunwayHeadings holds TRUE headings in degrees, both ends of every runway.
-	// crosswindComponent takes the most favourable of them, so a multi-runway field
-	// reports the crosswind after picking the best runway.
+	// RunwayHeadings holds TRUE headings in degrees, both ends of every runway, in the
+	// order of Runways: "05/23" is 05's heading and then 23's. The wind is resolved
+	// against each end and the best usable one is taken as in use. See runways.go.
 	RunwayHeadings []float64 `json:"runway_headings"`
+	// ElevationFt is the aerodrome elevation from the AIP, feet above MSL. A pointer
+	// because the coast has airfields at 3ft, and zero must not read as "not given".
+	ElevationFt *float64 `json:"elevation_ft,omitempty"`
+	// RunwayDetails gives each runway's length and surface, for the takeoff distance
+	// factor. An airfield without them is scored without it. See aircraft.go.
+	RunwayDetails []RunwayDetail `json:"runway_details,omitempty"`
 	// Pinned sorts this airfield to the top of the list and makes it the default.
 	Pinned bool `json:"pinned,omitempty"`
 	// OpeningHours is the airfield's published operating times, copied verbatim from the
@@ -53,6 +61,127 @@ type Airport struct {
 	// Website is the airfield's own page, deep-linked to its opening times where it
 	// publishes such a page. It is how a reader checks the line above against the source.
 	Website string `json:"website,omitempty"`
+	// OperatingWindow is the published hours reduced to one daily UTC window, for the
+	// airfields whose hours are that simple -- EDWN's 0800-1800Z, capped at sunset, which
+	// the flight-window finder applies by keeping to daylight anyway. Absent wherever the
+	// published line carries a lunch break, a weekday split or a season with its own dates:
+	// those are what OpeningHours is free text for, and nothing here pretends otherwise.
+	OperatingWindow *DailyWindow `json:"operating_window_utc,omitempty"`
+	// ReportingStation names the METAR station that stands in for this airfield, overriding
+	// the nearest-within-radius match -- for a field whose nearest station sits on the other
+	// side of a ridge, or in a different airmass along a coast. See observations.go.
+	ReportingStation string `json:"reporting_station,omitempty"`
+	// MosmixStation is the DWD station whose MOSMIX point forecast stands in for this
+	// airfield's cloud base and visibility, "10305" for Lingen. Only airfields with a
+	// station close enough to share their weather carry one. See mosmix.go.
+	MosmixStation string `json:"mosmix_station,omitempty"`
+}
+
+// RunwayDetail is one runway's length and surface. The length is the shorter end's TORA,
+// so either direction is covered by the one figure.
+type RunwayDetail struct {
+	Designator string  `json:"designator"` // "05/23", as in Runways
+	LengthM    float64 `json:"length_m"`
+	Surface    string  `json:"surface"` // "asphalt" | "concrete" | "grass"
+	// Closed rules the runway out for everyone, and says why: "closed", "closed for
+	// resurfacing until 30 SEP". See runways.go.
+	Closed string `json:"closed,omitempty"`
+	// ClosedEnds rules out one end of a runway that is only used the other way -- a strip
+	// with a slope or obstacles at one end: ["23"]. The other end stays usable, tailwind
+	// and all.
+	ClosedEnds []string `json:"closed_ends,omitempty"`
+	// TooShortFor rules the runway out for the listed aircraft, by their IDs in
+	// aircraftTypes, wherever the operator knows better than the takeoff factor's rule of
+	// thumb.
+	TooShortFor []string `json:"too_short_for,omitempty"`
+}
+
+// validateRunwayDetails checks each detail names one of the airfield's runways, once, with a
+// length and a surface the takeoff factor can use.
+func validateRunwayDetails(a Airport) error {
+	seen := make(map[string]bool, len(a.RunwayDetails))
+	for _, rwy := range a.RunwayDetails {
+		switch {
+		case !slices.Contains(a.Runways, rwy.Designator):
+			return fmt.Errorf("runway details for %q, which is not one of its runways", rwy.Designator)
+		case seen[rwy.Designator]:
+			return fmt.Errorf("runway %s has two sets of details", rwy.Designator)
+		case rwy.LengthM <= 0:
+			return fmt.Errorf("runway %s has length %v", rwy.Designator, rwy.LengthM)
+		}
+		if _, ok := surfaceFactors[rwy.Surface]; !ok {
+			return fmt.Errorf("runway %s has unknown surface %q", rwy.Designator, rwy.Surface)
+		}
+		ends := strings.Split(rwy.Designator, "/")
+		for _, end := range rwy.ClosedEnds {
+			if !slices.Contains(ends, end) {
+				return fmt.Errorf("runway %s has no end %q to close", rwy.Designator, end)
+			}
+		}
+		if len(rwy.ClosedEnds) >= len(ends) {
+			return fmt.Errorf("runway %s has every end closed; close the runway instead", rwy.Designator)
+		}
+		for _, id := range rwy.TooShortFor {
+			if _, err := lookupAircraft(id); err != nil {
+				return fmt.Errorf("runway %s: %w", rwy.Designator, err)
+			}
+		}
+		seen[rwy.Designator] = true
+	}
+
+	// An airfield with nothing left to land on is one to take off the list, not one to
+	// score every hour of against a runway that is not there.
+	open := func(aircraft *aircraftType) bool {
+		return slices.ContainsFunc(a.Runways, func(runway string) bool {
+			detail, ok := a.runwayDetail(runway)
+			return !ok || detail.unusable(aircraft) == ""
+		})
+	}
+	if len(a.Runways) > 0 && !open(nil) {
+		return fmt.Errorf("every runway is closed")
+	}
+	for i := range aircraftTypes {
+		if len(a.Runways) > 0 && !open(&aircraftTypes[i]) {
+			return fmt.Errorf("every runway is too short for the %s", aircraftTypes[i].Name)
+		}
+	}
+	return nil
+}
+
+// DailyWindow is a window repeated every UTC day, "0800" to "1800".
+type DailyWindow struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// on returns the window on the UTC day containing day.
+func (w DailyWindow) on(day time.Time) (Interval, error) {
+	midnight := day.UTC().Truncate(24 * time.Hour)
+	from, err := hhmm(w.From)
+	if err != nil {
+		return Interval{}, err
+	}
+	to, err := hhmm(w.To)
+	if err != nil {
+		return Interval{}, err
+	}
+	if to <= from {
+		return Interval{}, fmt.Errorf("window %s-%s ends before it starts", w.From, w.To)
+	}
+	return Interval{From: midnight.Add(from), To: midnight.Add(to)}, nil
+}
+
+// hhmm reads the AIP's four-digit UTC times; 2400 is the end of the day.
+func hhmm(s string) (time.Duration, error) {
+	if len(s) != 4 {
+		return 0, fmt.Errorf("invalid time %q, want HHMM", s)
+	}
+	hours, errH := strconv.Atoi(s[:2])
+	minutes, errM := strconv.Atoi(s[2:])
+	if errH != nil || errM != nil || hours > 24 || minutes > 59 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("invalid time %q, want HHMM", s)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
 }
 
 // LatString and LonString format the coordinates for the two consumers that need strings:
@@ -61,18 +190,6 @@ type Airport struct {
 func (a Airport) LatString() string { return strconv.FormatFloat(a.Latitude, 'f', 4, 64) }
 func (a Airport) LonString() string { return strconv.FormatFloat(a.Longitude, 'f', 4, 64) }
 
-// crosswindComponent returns the crosswind in knots for a wind of speedKnots
-// from directionDegrees (meteorological, true north), taking the most
-// favourable of the airport's runway headings.
-func (a Airport) crosswindComponent(speedKnots float64, directionDegrees int) float64 {
-	crosswind := math.Inf(1)
-	for _, heading := range a.RunwayHeadings {
-		angle := (float64(directionDegrees) - heading) * math.Pi / 180
-		crosswind = math.Min(crosswind, math.Abs(speedKnots*math.Sin(angle)))
-	}
-	return crosswind
-}
-
 var (
 	// airports is the loaded list in display order: pinned first, then north to south.
 	airports []Airport
@@ -119,8 +236,8 @@ func loadAirports() error {
 }
 
 // validateAirports rejects a list that would produce wrong weather rather than an obvious
-// error: a duplicate identifier silently shadows an airfield, and a missing runway heading
-// makes crosswindComponent return +Inf.
+// error: a duplicate identifier silently shadows an airfield, and an airfield without runway
+// headings has no end for windOnRunways to resolve the wind on, and would score as calm.
 func validateAirports(list []Airport) error {
 	if len(list) == 0 {
 		return fmt.Errorf("airport list is empty")
@@ -143,6 +260,22 @@ func validateAirports(list []Airport) error {
 		case len(a.RunwayHeadings) == 0:
 			return fmt.Errorf("airport %s has no runway headings", a.Identifier)
 		}
+		if a.ReportingStation != "" {
+			if _, ok := lookupReportingStation(a.ReportingStation); !ok {
+				return fmt.Errorf("airport %s names unknown reporting station %q", a.Identifier, a.ReportingStation)
+			}
+		}
+		if err := validateRunwayDetails(a); err != nil {
+			return fmt.Errorf("airport %s: %w", a.Identifier, err)
+		}
+		if a.MosmixStation != "" && !mosmixStationPattern.MatchString(a.MosmixStation) {
+			return fmt.Errorf("airport %s has malformed MOSMIX station %q", a.Identifier, a.MosmixStation)
+		}
+		if a.OperatingWindow != nil {
+			if _, err := a.OperatingWindow.on(time.Time{}); err != nil {
+				return fmt.Errorf("airport %s has an invalid operating window: %w", a.Identifier, err)
+			}
+		}
 		seen[a.Identifier] = true
 		if a.Pinned {
 			pinned++
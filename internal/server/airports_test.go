@@ -198,6 +198,70 @@ func TestValidateAirports(t *testing.T) {
 			list:    []Airport{{Identifier: "EDWN", Name: "x", Longitude: -181, RunwayHeadings: []float64{50}}},
 			wantErr: true,
 		},
+		{
+			name:    "operating window that ends before it starts",
+			list:    []Airport{{Identifier: "EDWN", Name: "x", RunwayHeadings: []float64{50}, OperatingWindow: &DailyWindow{From: "1800", To: "0800"}}},
+			wantErr: true,
+		},
+		{
+			name:    "operating window in the wrong notation",
+			list:    []Airport{{Identifier: "EDWN", Name: "x", RunwayHeadings: []float64{50}, OperatingWindow: &DailyWindow{From: "8:00", To: "1800"}}},
+			wantErr: true,
+		},
+		{
+			name: "runway details for a runway it does not have",
+			list: []Airport{{Identifier: "EDWN", Name: "x", Runways: []string{"05/23"}, RunwayHeadings: []float64{50},
+				RunwayDetails: []RunwayDetail{{Designator: "06/24", LengthM: 900, Surface: surfaceAsphalt}}}},
+			wantErr: true,
+		},
+		{
+			name: "a runway without a length",
+			list: []Airport{{Identifier: "EDWN", Name: "x", Runways: []string{"05/23"}, RunwayHeadings: []float64{50},
+				RunwayDetails: []RunwayDetail{{Designator: "05/23", Surface: surfaceAsphalt}}}},
+			wantErr: true,
+		},
+		{
+			name: "a runway surface the takeoff factor has no figure for",
+			list: []Airport{{Identifier: "EDWN", Name: "x", Runways: []string{"05/23"}, RunwayHeadings: []float64{50},
+				RunwayDetails: []RunwayDetail{{Designator: "05/23", LengthM: 900, Surface: "gravel"}}}},
+			wantErr: true,
+		},
+		{
+			name: "every runway closed",
+			list: []Airport{{Identifier: "EDWN", Name: "x", Runways: []string{"05/23"}, RunwayHeadings: []float64{50, 230},
+				RunwayDetails: []RunwayDetail{{Designator: "05/23", LengthM: 900, Surface: surfaceAsphalt, Closed: "closed"}}}},
+			wantErr: true,
+		},
+		{
+			name: "every runway too short for one of the aircraft",
+			list: []Airport{{Identifier: "EDWN", Name: "x", Runways: []string{"05/23"}, RunwayHeadings: []float64{50, 230},
+				RunwayDetails: []RunwayDetail{{Designator: "05/23", LengthM: 900, Surface: surfaceAsphalt, TooShortFor: []string{"c172"}}}}},
+			wantErr: true,
+		},
+		{
+			name: "too short for an aircraft nobody flies",
+			list: []Airport{{Identifier: "EDWN", Name: "x", Runways: []string{"05/23"}, RunwayHeadings: []float64{50, 230},
+				RunwayDetails: []RunwayDetail{{Designator: "05/23", LengthM: 900, Surface: surfaceAsphalt, TooShortFor: []string{"a380"}}}}},
+			wantErr: true,
+		},
+		{
+			name: "closing an end the runway does not have",
+			list: []Airport{{Identifier: "EDWN", Name: "x", Runways: []string{"05/23"}, RunwayHeadings: []float64{50, 230},
+				RunwayDetails: []RunwayDetail{{Designator: "05/23", LengthM: 900, Surface: surfaceAsphalt, ClosedEnds: []string{"24"}}}}},
+			wantErr: true,
+		},
+		{
+			name: "closing both ends of a runway",
+			list: []Airport{{Identifier: "EDWN", Name: "x", Runways: []string{"05/23"}, RunwayHeadings: []float64{50, 230},
+				RunwayDetails: []RunwayDetail{{Designator: "05/23", LengthM: 900, Surface: surfaceAsphalt, ClosedEnds: []string{"05", "23"}}}}},
+			wantErr: true,
+		},
+		{
+			// A station ID that cannot be one would build a URL that 404s on every fetch.
+			name:    "malformed MOSMIX station",
+			list:    []Airport{{Identifier: "EDWN", Name: "x", RunwayHeadings: []float64{50}, MosmixStation: "10305/../x"}},
+			wantErr: true,
+		},
 		{
 			name: "two pinned airports",
 			list: []Airport{
@@ -0,0 +1,997 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// The forecast archive: every payload the server builds, kept on disk.
+//
+// The cache in weather.go holds one payload per airport and profile, and a new model run
+// throws it away. That is right for serving and wrong for everything that wants to look
+// back: how the forecast for Saturday moved between runs, or how the score for an hour
+// compared with what was then observed. And a restart began from nothing, refetching every
+// airfield as if it had never been seen.
+//
+// So every forecast fetched from upstream is also written here, in a bbolt file named by
+// FLUGWETTER_ARCHIVE_FILE. One bucket per airport, and in it one record per ModelRun set,
+// so the same run fetched twice (the backstop TTL does that when run detection is down) is
+// one record, not two. Keys sort by the newest run in the set, so the last key in a bucket
+// is the most recent forecast for it.
+//
+// A record carries the scoring inputs as well as the payload. Without them a restored
+// payload could not be nowcast or routed, and could only ever show the score it was given
+// under whatever table was loaded then. With them, a warm start re-scores against the table
+// loaded now -- and against any profile, not only the one that happened to ask first, so
+// one record per run serves them all. Archives written before that kept a bucket per
+// airport and profile; the sweep folds those into the airport's.
+//
+// Without the variable there is no archive, and nothing else changes.
+const (
+	// archiveFileEnv names the env var holding the archive's path.
+	archiveFileEnv = "FLUGWETTER_ARCHIVE_FILE"
+
+	// archiveRetentionEnv is how many days of records are kept; older ones are deleted as
+	// new ones are written, and by the sweep every archiveSweepInterval.
+	archiveRetentionEnv  = "FLUGWETTER_ARCHIVE_RETENTION_DAYS"
+	defaultArchiveDays   = 30
+	archiveMaxDays       = 400
+	archiveOpenTimeout   = 5 * time.Second
+	archiveFileMode      = 0o600
+	archiveUnknownRunSet = "unknown"
+	archiveSweepInterval = 6 * time.Hour
+
+	// archiveMetarBucket holds the observations, beside the forecast buckets. Its name has
+	// no "/" in it, so it can never be mistaken for one.
+	archiveMetarBucket = "metar"
+
+	// archiveMaxRecordsPerKey bounds one airport whatever the retention says:
+	// eight runs a day for the default 30 days, with room to spare. A run poller that
+	// flaps would otherwise fill the file at the rate of the backstop.
+	archiveMaxRecordsPerKey = 300
+
+	// archiveMaxMetarsPerStation does the same for a station's observations: two routine
+	// reports an hour for the longest retention, and room for the SPECIs.
+	archiveMaxMetarsPerStation = 25000
+)
+
+// archivedPayload is one record.
+type archivedPayload struct {
+	Airport     string                `json:"airport"`
+	Profile     string                `json:"profile"`
+	ModelRuns   []ModelRun            `json:"model_runs"`
+	GeneratedAt time.Time             `json:"generated_at"`
+	Payload     *ProcessedWeatherData `json:"payload"`
+	// Conditions are the payload's modelConditions, index-aligned with its VfrData.
+	Conditions []archivedConditions `json:"conditions"`
+	// Daylight is every date's daylight, which the conditions refer to by date.
+	Daylight map[string]*SunriseSunsetResponse `json:"daylight,omitempty"`
+}
+
+// archivedConditions is conditions with its fields exported, as JSON needs them.
+type archivedConditions struct {
+	Time                     time.Time `json:"time"`
+	CloudBaseFL              *int      `json:"cloud_base_fl,omitempty"`
+	FreezingLevelFt          *int      `json:"freezing_level_ft,omitempty"`
+	BaseIcing                int       `json:"base_icing,omitempty"`
+	WindSpeed                float64   `json:"wind_speed"`
+	WindGusts                float64   `json:"wind_gusts,omitempty"`
+	Crosswind                float64   `json:"crosswind"`
+	CrosswindGusts           float64   `json:"crosswind_gusts"`
+	Tailwind                 float64   `json:"tailwind,omitempty"`
+	RunwayInUse              string    `json:"runway_in_use,omitempty"`
+	RunwayHeading            float64   `json:"runway_heading,omitempty"`
+	SunAzimuth               float64   `json:"sun_azimuth,omitempty"`
+	SunElevation             float64   `json:"sun_elevation,omitempty"`
+	CloudCover               *int      `json:"cloud_cover,omitempty"`
+	LowLevelShear            *float64  `json:"low_level_shear,omitempty"`
+	LowLevelShearTopFt       int       `json:"low_level_shear_top_ft,omitempty"`
+	VisibilityKM             *float64  `json:"visibility_km,omitempty"`
+	Temperature              float64   `json:"temperature"`
+	PressureMSL              *float64  `json:"pressure_msl,omitempty"`
+	Precipitation            float64   `json:"precipitation"`
+	PrecipitationProbability int       `json:"precipitation_probability"`
+	DewPoint                 *float64  `json:"dew_point,omitempty"`
+	RelativeHumidity         *int      `json:"relative_humidity,omitempty"`
+	CloudCoverMid            *int      `json:"cloud_cover_mid,omitempty"`
+	CloudCoverHigh           *int      `json:"cloud_cover_high,omitempty"`
+	WeatherCode              *int      `json:"weather_code,omitempty"`
+	CAPE                     *float64  `json:"cape,omitempty"`
+	LiftedIndex              *float64  `json:"lifted_index,omitempty"`
+	ConvectiveBaseFt         *int      `json:"convective_base_ft,omitempty"`
+	CloudBaseSource          string    `json:"cloud_base_source,omitempty"`
+	VisibilitySource         string    `json:"visibility_source,omitempty"`
+
+	// WindDirection is absent from records written before the runway in use was worked
+	// out again on restore; theirs is the one they were archived with.
+	WindDirection *int `json:"wind_direction,omitempty"`
+
+	// Ground is what the last two days had left on the airfield, absent without them.
+	Ground *archivedGround `json:"ground,omitempty"`
+}
+
+// archivedGround is groundState with its fields exported. The runway's surface is not
+// archived: like the takeoff case it is the airfield's as it is now.
+type archivedGround struct {
+	Rain24h  float64  `json:"rain_24h"`
+	Rain48h  float64  `json:"rain_48h"`
+	Standing float64  `json:"standing"`
+	SnowCM   *float64 `json:"snow_cm,omitempty"`
+	Frozen   bool     `json:"frozen,omitempty"`
+}
+
+func archiveConditions(c conditions) archivedConditions {
+	var ground *archivedGround
+	if g := c.ground; g != nil {
+		ground = &archivedGround{Rain24h: g.rain24h, Rain48h: g.rain48h, Standing: g.standing, SnowCM: g.snowCM, Frozen: g.frozen}
+	}
+	return archivedConditions{
+		Time:                     c.time,
+		CloudBaseFL:              c.cloudBaseFL,
+		FreezingLevelFt:          c.freezingLevelFt,
+		BaseIcing:                int(c.baseIcing),
+		WindSpeed:                c.windSpeed,
+		WindGusts:                c.windGusts,
+		WindDirection:            &c.windDirection,
+		Crosswind:                c.crosswind,
+		CrosswindGusts:           c.crosswindGusts,
+		Tailwind:                 c.tailwind,
+		RunwayInUse:              c.runwayInUse,
+		RunwayHeading:            c.runwayHeading,
+		SunAzimuth:               c.sun.azimuth,
+		SunElevation:             c.sun.elevation,
+		CloudCover:               c.cloudCover,
+		LowLevelShear:            c.lowLevelShear,
+		LowLevelShearTopFt:       c.lowLevelShearTopFt,
+		VisibilityKM:             c.visibilityKM,
+		Temperature:              c.temperature,
+		PressureMSL:              c.pressureMSL,
+		Precipitation:            c.precipitation,
+		PrecipitationProbability: c.precipitationProbability,
+		DewPoint:                 c.dewPoint,
+		RelativeHumidity:         c.relativeHumidity,
+		CloudCoverMid:            c.cloudCoverMid,
+		CloudCoverHigh:           c.cloudCoverHigh,
+		WeatherCode:              c.weatherCode,
+		CAPE:                     c.cape,
+		LiftedIndex:              c.liftedIndex,
+		ConvectiveBaseFt:         c.convectiveBaseFt,
+		CloudBaseSource:          c.cloudBaseSource,
+		VisibilitySource:         c.visibilitySource,
+		Ground:                   ground,
+	}
+}
+
+func (a archivedConditions) restore(daylight map[string]*SunriseSunsetResponse) conditions {
+	c := conditions{
+		time:                     a.Time,
+		cloudBaseFL:              a.CloudBaseFL,
+		freezingLevelFt:          a.FreezingLevelFt,
+		baseIcing:                icingRisk(a.BaseIcing),
+		windSpeed:                a.WindSpeed,
+		windGusts:                a.WindGusts,
+		crosswind:                a.Crosswind,
+		crosswindGusts:           a.CrosswindGusts,
+		tailwind:                 a.Tailwind,
+		runwayInUse:              a.RunwayInUse,
+		runwayHeading:            a.RunwayHeading,
+		sun:                      sunSky{azimuth: a.SunAzimuth, elevation: a.SunElevation},
+		cloudCover:               a.CloudCover,
+		lowLevelShear:            a.LowLevelShear,
+		lowLevelShearTopFt:       a.LowLevelShearTopFt,
+		visibilityKM:             a.VisibilityKM,
+		temperature:              a.Temperature,
+		pressureMSL:              a.PressureMSL,
+		precipitation:            a.Precipitation,
+		precipitationProbability: a.PrecipitationProbability,
+		dewPoint:                 a.DewPoint,
+		relativeHumidity:         a.RelativeHumidity,
+		cloudCoverMid:            a.CloudCoverMid,
+		cloudCoverHigh:           a.CloudCoverHigh,
+		weatherCode:              a.WeatherCode,
+		cape:                     a.CAPE,
+		liftedIndex:              a.LiftedIndex,
+		convectiveBaseFt:         a.ConvectiveBaseFt,
+		cloudBaseSource:          a.CloudBaseSource,
+		visibilitySource:         a.VisibilitySource,
+	}
+	if a.WindDirection != nil {
+		c.windDirection = *a.WindDirection
+	}
+	if g := a.Ground; g != nil {
+		c.ground = &groundState{rain24h: g.Rain24h, rain48h: g.Rain48h, standing: g.Standing, snowCM: g.SnowCM, frozen: g.Frozen}
+	}
+	if !a.Time.IsZero() {
+		c.daylight = daylight[a.Time.Format("2006-01-02")]
+	}
+	return c
+}
+
+// newArchivedPayload captures a payload and its scoring inputs.
+func newArchivedPayload(airport Airport, profile *scoringProfile, data *ProcessedWeatherData) archivedPayload {
+	record := archivedPayload{
+		Airport:     airport.Identifier,
+		Profile:     profile.ID,
+		ModelRuns:   data.ModelRuns,
+		GeneratedAt: data.GeneratedAt,
+		Payload:     data,
+		Conditions:  make([]archivedConditions, len(data.modelConditions)),
+		Daylight:    make(map[string]*SunriseSunsetResponse),
+	}
+	for i, c := range data.modelConditions {
+		record.Conditions[i] = archiveConditions(c)
+		if c.daylight != nil && !c.time.IsZero() {
+			record.Daylight[c.time.Format("2006-01-02")] = c.daylight
+		}
+	}
+	return record
+}
+
+// restore returns the record's payload with its scoring inputs back in place, and every
+// hour re-scored against profile -- the table loaded now, which need not be the one the
+// record was scored against. The nowcast fields are the serve-time ones and stay empty.
+//
+// The takeoff case, the carburettor, the runways and what they are open to are
+// configuration rather than forecast, so they are the airfield's and the profile's as they
+// are now, not as they were when the record was written: the end in use is picked again
+// for the profile's aircraft, and the wind chart's runways with it. The ensemble's band is
+// the record's profile's, and is left out for any other.
+func (r archivedPayload) restore(profile *scoringProfile) *ProcessedWeatherData {
+	data := *r.Payload
+	data.VfrData = append([]VfrPoint(nil), r.Payload.VfrData...)
+	data.WindData = append([]WindPoint(nil), r.Payload.WindData...)
+	data.Nowcast = nil
+	data.Stale = false
+	with := r.restoring(profile)
+	data.modelConditions = make([]conditions, len(r.Conditions))
+	for i := range r.Conditions {
+		c, wind := r.restoreConditions(i, with)
+		data.modelConditions[i] = c
+		if wind != nil && i < len(data.WindData) {
+			point := &data.WindData[i]
+			markSunGlare(wind.ends, c.sun, c.cloudCover)
+			point.Crosswind10m, point.CrosswindGusts10m, point.Tailwind10m = wind.crosswind, wind.crosswindGusts, wind.tailwind
+			point.Runways = wind.ends
+		}
+	}
+	if r.Profile != profile.ID {
+		for i := range data.VfrData {
+			data.VfrData[i].Ensemble = nil
+		}
+	}
+
+	if len(data.modelConditions) == len(data.VfrData) {
+		for i, c := range data.modelConditions {
+			if c.time.IsZero() {
+				continue
+			}
+			point := &data.VfrData[i]
+			point.Probability, point.Penalties, point.VisibilityKnown = scoreVFR(c, profile.limits)
+		}
+	}
+	return &data
+}
+
+// restoreHour is restore for the one hour key: its VfrPoint, re-scored against profile,
+// and its conditions, with nothing else of the payload touched. False where the record
+// does not cover the hour.
+func (r archivedPayload) restoreHour(profile *scoringProfile, key string) (VfrPoint, conditions, bool) {
+	i := vfrIndex(r.Payload, key)
+	if i < 0 {
+		return VfrPoint{}, conditions{}, false
+	}
+	point := r.Payload.VfrData[i]
+	if len(r.Conditions) != len(r.Payload.VfrData) {
+		return point, conditions{}, true
+	}
+
+	c, _ := r.restoreConditions(i, r.restoring(profile))
+	if !c.time.IsZero() {
+		point.Probability, point.Penalties, point.VisibilityKnown = scoreVFR(c, profile.limits)
+	}
+	return point, c, true
+}
+
+// restoring is the configuration a record's conditions are restored with: the profile,
+// and the airfield the record was written for as it is configured now -- known false
+// where it no longer is -- with its takeoff case under the profile.
+type restoring struct {
+	profile *scoringProfile
+	airport Airport
+	known   bool
+	takeoff *takeoffCase
+}
+
+func (r archivedPayload) restoring(profile *scoringProfile) restoring {
+	with := restoring{profile: profile}
+	with.airport, with.known = airportsByID[r.Airport]
+	if with.known {
+		with.takeoff = takeoffCaseFor(with.airport, profile)
+	}
+	return with
+}
+
+// restoreConditions restores the record's i-th conditions with today's configuration. The
+// wind on the runways is returned where the end in use was picked again: for a known
+// airfield, from a record that has the wind's direction.
+func (r archivedPayload) restoreConditions(i int, with restoring) (conditions, *runwayWind) {
+	a := r.Conditions[i]
+	c := a.restore(r.Daylight)
+	c.takeoff = with.takeoff
+	c.carburetted = carburettedFor(with.profile)
+	c.runwaySurface = with.airport.runwaySurface(a.RunwayInUse)
+	if !with.known || a.WindDirection == nil || c.time.IsZero() {
+		return c, nil
+	}
+	wind := c.onRunways(with.airport, with.profile.aircraft)
+	return c, &wind
+}
+
+// runSetKey is a record's key within its bucket: the newest initialization time in the
+// set, so keys sort by run, then every model's run, so one set has one key. A payload
+// built while run detection knew no runs at all is keyed by when it was built instead.
+func runSetKey(runs []ModelRun, generatedAt time.Time) string {
+	if len(runs) == 0 {
+		return generatedAt.UTC().Format("20060102T150405Z") + " " + archiveUnknownRunSet
+	}
+
+	sorted := append([]ModelRun(nil), runs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Model < sorted[j].Model })
+
+	var newest time.Time
+	parts := make([]string, len(sorted))
+	for i, run := range sorted {
+		if run.InitializedAt.After(newest) {
+			newest = run.InitializedAt
+		}
+		parts[i] = run.Model + "@" + run.InitializedAt.UTC().Format("20060102T1504Z")
+	}
+	return newest.UTC().Format("20060102T150405Z") + " " + strings.Join(parts, ",")
+}
+
+// forecastArchive is the open archive, or a disabled one. Every method on a disabled
+// archive is a no-op, so callers need not ask.
+type forecastArchive struct {
+	mutex sync.RWMutex
+
+	db        *bolt.DB
+	retention time.Duration
+
+	// spans indexes every airport's records by the hours they cover, so that a read for
+	// one hour decodes only the records that have it. An airport's is built on its first
+	// such read; generation counts the writes, so a build that raced one is not kept.
+	spansMutex sync.Mutex
+	spans      map[string][]archiveSpan
+	generation uint64
+}
+
+// archiveSpan is the hours one record covers, first and last, in the payload's notation,
+// which sorts as time does.
+type archiveSpan struct {
+	key         string
+	first, last string
+}
+
+// spanOf is the span of the record under key holding data, false for one with no hours.
+func spanOf(key string, data *ProcessedWeatherData) (archiveSpan, bool) {
+	if len(data.VfrData) == 0 {
+		return archiveSpan{}, false
+	}
+	return archiveSpan{key: key, first: data.VfrData[0].Time, last: data.VfrData[len(data.VfrData)-1].Time}, true
+}
+
+func (s archiveSpan) covers(hour string) bool {
+	return s.first <= hour && hour <= s.last
+}
+
+var archive = &forecastArchive{}
+
+// open opens the archive at path, creating it if needed. An empty path leaves it disabled.
+func (a *forecastArchive) open(path string, retention time.Duration) error {
+	return a.openWith(path, retention, false)
+}
+
+// openReadOnly opens an existing archive for reading, as the verify subcommand does. bbolt
+// gives a writer the file to itself, so this waits out the open timeout and fails while a
+// server has it open.
+func (a *forecastArchive) openReadOnly(path string) error {
+	if path == "" {
+		return fmt.Errorf("%s is not set", archiveFileEnv)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to open %s=%q: %w", archiveFileEnv, path, err)
+	}
+	return a.openWith(path, 0, true)
+}
+
+func (a *forecastArchive) openWith(path string, retention time.Duration, readOnly bool) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if path == "" {
+		return nil
+	}
+	// The timeout is for a second process holding the file: bbolt locks it, and waiting
+	// forever would be a hang at startup with nothing in the log.
+	db, err := bolt.Open(path, archiveFileMode, &bolt.Options{Timeout: archiveOpenTimeout, ReadOnly: readOnly})
+	if err != nil {
+		return fmt.Errorf("failed to open %s=%q: %w", archiveFileEnv, path, err)
+	}
+	a.db, a.retention = db, retention
+	return nil
+}
+
+// close closes the archive, leaving it disabled.
+func (a *forecastArchive) close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.db == nil {
+		return nil
+	}
+	err := a.db.Close()
+	a.db = nil
+	a.forgetSpans()
+	return err
+}
+
+// enabled reports whether an archive is open.
+func (a *forecastArchive) enabled() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.db != nil
+}
+
+// archiveRetention reads the retention from the environment. An invalid value falls back to
+// the default with a warning, as metarRadiusKM does.
+func archiveRetention() time.Duration {
+	days := defaultArchiveDays
+	if raw := os.Getenv(archiveRetentionEnv); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > archiveMaxDays {
+			slog.Warn("ignoring invalid archive retention", "value", raw, "default", defaultArchiveDays)
+		} else {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// archiveBucket is an airport's bucket: its identifier, which has no "/" in it, as the
+// buckets of old archives had, and is never archiveMetarBucket.
+func archiveBucket(airport Airport) []byte {
+	return []byte(airport.Identifier)
+}
+
+// store writes one payload, as scored against profile, and prunes its bucket. Every
+// profile's payload from one fetch has the same runs and the same key, and the first to
+// arrive is the record: the rest would only have written the same forecast again.
+func (a *forecastArchive) store(airport Airport, profile *scoringProfile, data *ProcessedWeatherData) error {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.db == nil {
+		return nil
+	}
+
+	key := []byte(runSetKey(data.ModelRuns, data.GeneratedAt))
+	var exists bool
+	if err := a.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(archiveBucket(airport))
+		exists = bucket != nil && bucket.Get(key) != nil
+		return nil
+	}); err != nil || exists {
+		return err
+	}
+
+	value, err := encodeArchived(newArchivedPayload(airport, profile, data))
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-a.retention)
+
+	var added bool
+	err = a.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(archiveBucket(airport))
+		if err != nil {
+			return err
+		}
+		if bucket.Get(key) == nil {
+			if err := bucket.Put(key, value); err != nil {
+				return err
+			}
+			added = true
+		}
+		return pruneBucket(bucket, cutoff, archiveMaxRecordsPerKey)
+	})
+	if err == nil && added {
+		a.addSpan(airport, string(key), data)
+	}
+	return err
+}
+
+// addSpan puts a record just written into its airport's index, where there is one yet.
+// What pruning took out of it goes the next time it is read.
+func (a *forecastArchive) addSpan(airport Airport, key string, data *ProcessedWeatherData) {
+	a.spansMutex.Lock()
+	defer a.spansMutex.Unlock()
+
+	a.generation++
+	spans, ok := a.spans[airport.Identifier]
+	span, hasHours := spanOf(key, data)
+	if !ok || !hasHours {
+		return
+	}
+	i, _ := slices.BinarySearchFunc(spans, key, func(s archiveSpan, key string) int { return strings.Compare(s.key, key) })
+	a.spans[airport.Identifier] = slices.Insert(spans, i, span)
+}
+
+// forgetSpans drops every airport's index, for a write that touched more than one record.
+func (a *forecastArchive) forgetSpans() {
+	a.spansMutex.Lock()
+	defer a.spansMutex.Unlock()
+
+	a.generation++
+	a.spans = nil
+}
+
+// pruneBucket deletes records older than cutoff, by the time in their key, and the oldest
+// past maxRecords.
+func pruneBucket(bucket *bolt.Bucket, cutoff time.Time, maxRecords int) error {
+	// Counted by hand: Stats reads the pages, and misses what this transaction just put.
+	count := 0
+	cursor := bucket.Cursor()
+	for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+		count++
+	}
+
+	var expired [][]byte
+	for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+		at, err := time.Parse("20060102T150405Z", strings.SplitN(string(k), " ", 2)[0])
+		if (err == nil && !at.Before(cutoff)) && count-len(expired) <= maxRecords {
+			break
+		}
+		expired = append(expired, append([]byte(nil), k...))
+	}
+	for _, k := range expired {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sweep prunes every bucket, not only the ones being written, and deletes those it leaves
+// empty. A bucket is otherwise pruned only as a record is added to it, and some never get
+// another: an airport or profile taken out of the configuration, a station no longer polled.
+func (a *forecastArchive) sweep() error {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.db == nil {
+		return nil
+	}
+	cutoff := time.Now().Add(-a.retention)
+	// Folded buckets add records to an airport's; pruned ones would only be found missing.
+	defer a.forgetSpans()
+
+	return a.db.Update(func(tx *bolt.Tx) error {
+		if err := foldProfileBuckets(tx); err != nil {
+			return err
+		}
+
+		// Named first and pruned after: bbolt does not allow deleting a bucket while
+		// iterating over its parent.
+		var names [][]byte
+		err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			names = append(names, append([]byte(nil), name...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if string(name) == archiveMetarBucket {
+				if err := sweepMetars(tx.Bucket(name), cutoff); err != nil {
+					return err
+				}
+				continue
+			}
+			bucket := tx.Bucket(name)
+			if err := pruneBucket(bucket, cutoff, archiveMaxRecordsPerKey); err != nil {
+				return err
+			}
+			if k, _ := bucket.Cursor().First(); k == nil {
+				if err := tx.DeleteBucket(name); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// foldProfileBuckets moves the records of an old archive's "airport/profile" buckets into
+// the airport's, and deletes them. Where two profiles have a record for the same runs, the
+// one already there, or the first in bucket order, is kept: restore re-scores either.
+func foldProfileBuckets(tx *bolt.Tx) error {
+	var names [][]byte
+	err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		if bytes.Contains(name, []byte("/")) {
+			names = append(names, append([]byte(nil), name...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		airport, _, _ := bytes.Cut(name, []byte("/"))
+		into, err := tx.CreateBucketIfNotExists(airport)
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(name).ForEach(func(k, v []byte) error {
+			if into.Get(k) != nil {
+				return nil
+			}
+			return into.Put(append([]byte(nil), k...), append([]byte(nil), v...))
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sweepMetars is sweep for the stations inside archiveMetarBucket.
+func sweepMetars(root *bolt.Bucket, cutoff time.Time) error {
+	var stations [][]byte
+	err := root.ForEach(func(k, v []byte) error {
+		if v == nil {
+			stations = append(stations, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, station := range stations {
+		bucket := root.Bucket(station)
+		if err := pruneBucket(bucket, cutoff, archiveMaxMetarsPerStation); err != nil {
+			return err
+		}
+		if k, _ := bucket.Cursor().First(); k == nil {
+			if err := root.DeleteBucket(station); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// watchArchive sweeps the archive until ctx is cancelled.
+func watchArchive(ctx context.Context) {
+	ticker := time.NewTicker(archiveSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := archive.sweep(); err != nil {
+				slog.Warn("failed to sweep the forecast archive", "error", err)
+			}
+		}
+	}
+}
+
+// storeMetars keeps the latest observation of each station, for verification to pair the
+// forecasts with. They live in one bucket per station inside archiveMetarBucket, keyed by
+// observation time, so the poll that sees the same METAR again rewrites it rather than
+// adding it twice. The retention is the forecasts'.
+func (a *forecastArchive) storeMetars(metars map[string]*Metar) error {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.db == nil || len(metars) == 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-a.retention)
+
+	return a.db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists([]byte(archiveMetarBucket))
+		if err != nil {
+			return err
+		}
+		for station, m := range metars {
+			bucket, err := root.CreateBucketIfNotExists([]byte(station))
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(m)
+			if err != nil {
+				return fmt.Errorf("failed to encode METAR: %w", err)
+			}
+			if err := bucket.Put([]byte(m.ObservedAt.UTC().Format("20060102T150405Z")), value); err != nil {
+				return err
+			}
+			if err := pruneBucket(bucket, cutoff, archiveMaxMetarsPerStation); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// metars returns a station's archived observations from from to to, oldest first.
+func (a *forecastArchive) metars(station string, from, to time.Time) ([]*Metar, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.db == nil {
+		return nil, nil
+	}
+
+	var metars []*Metar
+	err := a.db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(archiveMetarBucket))
+		if root == nil {
+			return nil
+		}
+		bucket := root.Bucket([]byte(station))
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		last := []byte(to.UTC().Format("20060102T150405Z"))
+		for k, v := cursor.Seek([]byte(from.UTC().Format("20060102T150405Z"))); k != nil && bytes.Compare(k, last) <= 0; k, v = cursor.Next() {
+			var m Metar
+			if err := json.Unmarshal(v, &m); err != nil {
+				slog.Warn("skipping unreadable archived METAR", "station", station, "key", string(k), "error", err)
+				continue
+			}
+			metars = append(metars, &m)
+		}
+		return nil
+	})
+	return metars, err
+}
+
+// history returns every record for one airport, oldest first. restore scores them for a
+// profile.
+func (a *forecastArchive) history(airport Airport) ([]archivedPayload, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.db == nil {
+		return nil, nil
+	}
+
+	var records []archivedPayload
+	err := a.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(archiveBucket(airport))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			record, err := decodeArchived(v)
+			if err != nil {
+				// One unreadable record is not the whole history.
+				slog.Warn("skipping unreadable archive record", "key", string(k), "error", err)
+				return nil
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	return records, err
+}
+
+// historyCovering is history for the records that cover hour, in the payload's notation:
+// the only ones decoded, but for the airport's first such read, which builds its index.
+func (a *forecastArchive) historyCovering(airport Airport, hour string) ([]archivedPayload, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.db == nil {
+		return nil, nil
+	}
+
+	spans, err := a.spansFor(airport)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []archivedPayload
+	var gone []string
+	err = a.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(archiveBucket(airport))
+		for _, span := range spans {
+			if !span.covers(hour) {
+				continue
+			}
+			var v []byte
+			if bucket != nil {
+				v = bucket.Get([]byte(span.key))
+			}
+			if v == nil {
+				gone = append(gone, span.key)
+				continue
+			}
+			record, err := decodeArchived(v)
+			if err != nil {
+				slog.Warn("skipping unreadable archive record", "key", span.key, "error", err)
+				continue
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	if len(gone) > 0 {
+		a.spansMutex.Lock()
+		if spans, ok := a.spans[airport.Identifier]; ok {
+			a.spans[airport.Identifier] = slices.DeleteFunc(slices.Clone(spans), func(s archiveSpan) bool {
+				return slices.Contains(gone, s.key)
+			})
+		}
+		a.spansMutex.Unlock()
+	}
+	return records, err
+}
+
+// spansFor returns the airport's index, building it from every record where there is none
+// yet. The build decodes with no lock held, and is kept only if nothing was written
+// meanwhile.
+func (a *forecastArchive) spansFor(airport Airport) ([]archiveSpan, error) {
+	a.spansMutex.Lock()
+	spans, ok := a.spans[airport.Identifier]
+	generation := a.generation
+	a.spansMutex.Unlock()
+	if ok {
+		return spans, nil
+	}
+
+	err := a.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(archiveBucket(airport))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			record, err := decodeArchived(v)
+			if err != nil {
+				slog.Warn("skipping unreadable archive record", "key", string(k), "error", err)
+				return nil
+			}
+			if span, ok := spanOf(string(k), record.Payload); ok {
+				spans = append(spans, span)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a.spansMutex.Lock()
+	defer a.spansMutex.Unlock()
+	if a.generation == generation {
+		if a.spans == nil {
+			a.spans = make(map[string][]archiveSpan)
+		}
+		a.spans[airport.Identifier] = spans
+	}
+	return spans, nil
+}
+
+// warm loads the newest record of every airport still configured into the cache, once
+// for every profile, each re-scored against the table loaded now.
+//
+// A record whose runs are still the latest ones is exactly what a refetch would return,
+// and goes in as fresh -- its cache timestamp is now rather than when it was built. Any
+// other goes in under its own age: past the backstop TTL, it is refetched on first use, and
+// served flagged stale if upstream is down -- which is better than a cold start has to
+// offer.
+func (a *forecastArchive) warm() int {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.db == nil {
+		return 0
+	}
+
+	currentRuns := modelRuns.runsFor(primaryModel())
+	current := runSetKey(currentRuns, time.Time{})
+	now := time.Now()
+	profiles := scoring.Load().profiles
+
+	var warmed int
+	err := a.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			airport, ok := airportsByID[string(name)]
+			if !ok {
+				return nil
+			}
+
+			k, v := bucket.Cursor().Last()
+			if k == nil {
+				return nil
+			}
+			record, err := decodeArchived(v)
+			if err != nil {
+				slog.Warn("skipping unreadable archive record", "key", string(k), "error", err)
+				return nil
+			}
+
+			for _, profile := range profiles {
+				data := record.restore(profile)
+				timestamp := data.GeneratedAt
+				if len(currentRuns) > 0 && string(k) == current {
+					timestamp = now
+				}
+
+				cache.mutex.Lock()
+				if entry, ok := cache.entries[cacheKey(airport, profile)]; !ok || entry.timestamp.Before(timestamp) {
+					cache.entries[cacheKey(airport, profile)] = &cacheEntry{data: data, timestamp: timestamp}
+					warmed++
+				}
+				cache.mutex.Unlock()
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		slog.Error("failed to read the forecast archive", "error", err)
+	}
+	return warmed
+}
+
+// encodeArchived and decodeArchived are the record format: gzipped JSON. A week of hourly
+// payload with every cloud layer is a few hundred kilobytes of very repetitive JSON, and
+// compresses by an order of magnitude.
+func encodeArchived(record archivedPayload) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(record); err != nil {
+		return nil, fmt.Errorf("failed to encode archive record: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode archive record: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeArchived(value []byte) (archivedPayload, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return archivedPayload{}, err
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return archivedPayload{}, err
+	}
+
+	var record archivedPayload
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return archivedPayload{}, err
+	}
+	if record.Payload == nil {
+		return archivedPayload{}, errors.New("archive record has no payload")
+	}
+	return record, nil
+}
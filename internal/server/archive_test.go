@@ -0,0 +1,388 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// withArchive opens an archive in a temporary directory for the test and closes it after.
+func withArchive(t *testing.T, retention time.Duration) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "archive.db")
+	if err := archive.open(path, retention); err != nil {
+		t.Fatalf("open() = %v", err)
+	}
+	t.Cleanup(func() { _ = archive.close() })
+	return path
+}
+
+// runsAt is a full ModelRun set with the D2 run at initialized and the others older, as
+// they are.
+func runsAt(initialized time.Time) []ModelRun {
+	return []ModelRun{
+		{Model: "icon_d2", InitializedAt: initialized, AvailableAt: initialized.Add(time.Hour)},
+		{Model: "icon_eu", InitializedAt: initialized.Add(-3 * time.Hour), AvailableAt: initialized.Add(-2 * time.Hour)},
+		{Model: "icon_global", InitializedAt: initialized.Add(-6 * time.Hour), AvailableAt: initialized.Add(-4 * time.Hour)},
+	}
+}
+
+// archivePayload is nowcastPayload stamped with runs.
+func archivePayload(t *testing.T, runs []ModelRun, cloudBaseFL *int) *ProcessedWeatherData {
+	t.Helper()
+
+	data := nowcastPayload(t, cloudBaseFL)
+	data.ModelRuns = runs
+	data.GeneratedAt = runs[0].AvailableAt.Add(10 * time.Minute)
+	return data
+}
+
+// One record serves every profile: the airfield's forecast is archived once, and the end
+// in use is picked again for whichever aircraft it is restored for.
+func TestArchive_OneRecordForEveryProfile(t *testing.T) {
+	withTestAirports(t)
+	withArchive(t, 30*24*time.Hour)
+	stubDayLight(t)
+	airport := wangerooge()
+	airport.RunwayDetails[1].TooShortFor = []string{"c172"}
+	airportsByID[airport.Identifier] = airport
+	ultralight, err := lookupProfile("ultralight")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 15G25 from 190: straight down 19 for the C42, which the C172 may not use.
+	forecast := hourlyFixture([]string{"2026-08-03T12:00"})
+	forecast.hours[0].windSpeed10m, forecast.hours[0].windGusts10m, forecast.hours[0].windDirection10m = ptrFloat(15), ptrFloat(25), ptrTo(190)
+	runs := runsAt(time.Now().UTC().Truncate(time.Hour).Add(-6 * time.Hour))
+	for _, profile := range []*scoringProfile{ultralight, defaultProfile()} {
+		data := processWeatherData(context.Background(), forecast, airport, profile)
+		data.ModelRuns, data.GeneratedAt = runs, runs[0].AvailableAt
+		if err := archive.store(airport, profile, data); err != nil {
+			t.Fatalf("store() = %v", err)
+		}
+	}
+
+	records, err := archive.history(airport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Profile != ultralight.ID {
+		t.Fatalf("got %d records, want the first profile's one", len(records))
+	}
+	for profile, want := range map[*scoringProfile]string{ultralight: "19", defaultProfile(): "27"} {
+		restored := records[0].restore(profile)
+		if got := restored.modelConditions[0].runwayInUse; got != want {
+			t.Errorf("restore(%s) has %q in use, want %q", profile.ID, got, want)
+		}
+		if _, c, _ := records[0].restoreHour(profile, "2026-08-03T12:00"); c.runwayInUse != want {
+			t.Errorf("restoreHour(%s) has %q in use, want %q", profile.ID, c.runwayInUse, want)
+		}
+	}
+}
+
+// An archive written when every profile had its own bucket is folded into the airfield's.
+func TestArchive_FoldsProfileBuckets(t *testing.T) {
+	withTestAirports(t)
+	withArchive(t, 30*24*time.Hour)
+	runs := runsAt(time.Now().UTC().Truncate(time.Hour).Add(-6 * time.Hour))
+	value, err := encodeArchived(newArchivedPayload(testAirport, defaultProfile(), archivePayload(t, runs, ptrTo(20))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := []byte(testAirport.Identifier + "/" + defaultProfile().ID)
+	err = archive.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket(legacy)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(runSetKey(runs, time.Time{})), value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := archive.sweep(); err != nil {
+		t.Fatalf("sweep() = %v", err)
+	}
+	if records, _ := archive.history(testAirport); len(records) != 1 {
+		t.Errorf("got %d records, want the legacy bucket's one", len(records))
+	}
+	_ = archive.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(legacy) != nil {
+			t.Error("the legacy bucket is still there")
+		}
+		return nil
+	})
+}
+
+func TestArchive_KeepsOneRecordPerRunSet(t *testing.T) {
+	withArchive(t, 30*24*time.Hour)
+	first := time.Now().UTC().Truncate(time.Hour).Add(-6 * time.Hour)
+
+	for _, runs := range [][]ModelRun{runsAt(first), runsAt(first), runsAt(first.Add(3 * time.Hour))} {
+		if err := archive.store(testAirport, defaultProfile(), archivePayload(t, runs, ptrTo(20))); err != nil {
+			t.Fatalf("store() = %v", err)
+		}
+	}
+
+	records, err := archive.history(testAirport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want one per run set", len(records))
+	}
+	if !records[0].ModelRuns[0].InitializedAt.Equal(first) || !records[1].ModelRuns[0].InitializedAt.Equal(first.Add(3*time.Hour)) {
+		t.Errorf("records are not oldest first: %v, %v", records[0].ModelRuns[0].InitializedAt, records[1].ModelRuns[0].InitializedAt)
+	}
+}
+
+// A record must come back able to do everything a fresh payload can: be nowcast, be routed,
+// and be scored against the table loaded now rather than the one it was built under.
+func TestArchivedPayload_RestoresTheScoringInputs(t *testing.T) {
+	data := archivePayload(t, runsAt(time.Date(2026, 8, 3, 6, 0, 0, 0, time.UTC)), ptrTo(12))
+	value, err := encodeArchived(newArchivedPayload(testAirport, defaultProfile(), data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := decodeArchived(value)
+	if err != nil {
+		t.Fatalf("decodeArchived() = %v", err)
+	}
+
+	// As if the limits had been retuned between the two: scores nothing against anything.
+	lenient := &scoringProfile{ScoringProfile: ScoringProfile{ID: "lenient"}}
+	restored := record.restore(lenient)
+
+	if len(restored.modelConditions) != len(restored.VfrData) {
+		t.Fatalf("%d conditions for %d hours", len(restored.modelConditions), len(restored.VfrData))
+	}
+	c := restored.modelConditions[2]
+	if !c.time.Equal(data.modelConditions[2].time) || c.cloudBaseFL == nil || *c.cloudBaseFL != 12 || c.daylight == nil {
+		t.Errorf("restored conditions = %+v", c)
+	}
+	if restored.VfrData[2].Probability != 100 || data.VfrData[2].Probability == 100 {
+		t.Errorf("restored score = %d (archived %d), want it re-scored against the lenient table", restored.VfrData[2].Probability, data.VfrData[2].Probability)
+	}
+}
+
+func TestArchive_WarmsTheCacheOnRestart(t *testing.T) {
+	withTestAirports(t)
+	path := withArchive(t, 30*24*time.Hour)
+	stubModelRunMeta(t, nil)
+	stubFetchWeather(t, func(context.Context, Airport, *scoringProfile) (*ProcessedWeatherData, error) {
+		return nil, errors.New("upstream unreachable")
+	})
+
+	initialized := time.Now().UTC().Truncate(time.Hour).Add(-4 * time.Hour)
+	current, previous := runsAt(initialized), runsAt(initialized.Add(-3*time.Hour))
+	if err := archive.store(testAirport, defaultProfile(), archivePayload(t, previous, ptrTo(10))); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.store(testAirport, defaultProfile(), archivePayload(t, current, ptrTo(20))); err != nil {
+		t.Fatal(err)
+	}
+	// An older run of another airfield, and a record for an airfield no longer listed.
+	if err := archive.store(airportsByID["EDWG"], defaultProfile(), archivePayload(t, previous, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.store(Airport{Identifier: "EDXX"}, defaultProfile(), archivePayload(t, current, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	// What a restart does: a new process, the same file, the runs polled before warming.
+	if err := archive.close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.open(path, 30*24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	modelRuns.mutex.Lock()
+	modelRuns.runs = current
+	modelRuns.mutex.Unlock()
+
+	// One record an airfield, restored for every profile.
+	if warmed, want := archive.warm(), 2*len(profileList()); warmed != want {
+		t.Fatalf("warmed %d entries, want EDWN and EDWG for each of %d profiles", warmed, len(profileList()))
+	}
+	if _, ok := cachedEntry(cacheKey(testAirport, scoring.Load().profiles[1])); !ok {
+		t.Error("a profile that never asked was not warmed")
+	}
+
+	// The latest runs are served as fresh, from the newest record.
+	data, err := GetWeatherData(context.Background(), testAirport, defaultProfile())
+	if err != nil || data.Stale {
+		t.Fatalf("EDWN = %v (stale %v), want the archived payload served fresh", err, data != nil && data.Stale)
+	}
+	if c := data.modelConditions[0]; c.cloudBaseFL == nil || *c.cloudBaseFL != 20 {
+		t.Errorf("EDWN cloud base = %v, want the newest record's FL20", c.cloudBaseFL)
+	}
+
+	// An older run is past the TTL and refetched; with upstream down, it is served stale.
+	data, err = GetWeatherData(context.Background(), airportsByID["EDWG"], defaultProfile())
+	if err != nil || !data.Stale {
+		t.Errorf("EDWG = %v, stale %v, want the old record served flagged", err, data != nil && data.Stale)
+	}
+}
+
+func TestArchive_PrunesPastRetention(t *testing.T) {
+	withArchive(t, 24*time.Hour)
+	now := time.Now().UTC().Truncate(time.Hour)
+
+	for _, age := range []time.Duration{72 * time.Hour, 30 * time.Hour, 6 * time.Hour} {
+		if err := archive.store(testAirport, defaultProfile(), archivePayload(t, runsAt(now.Add(-age)), nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := archive.history(testAirport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || !records[0].ModelRuns[0].InitializedAt.Equal(now.Add(-6*time.Hour)) {
+		t.Errorf("kept %d records, want only the one inside a day", len(records))
+	}
+}
+
+// A bucket nothing writes to any more -- an airfield since removed, a station no longer
+// polled -- is pruned by the sweep, and dropped once empty.
+func TestArchive_SweepPrunesBucketsNoLongerWritten(t *testing.T) {
+	withArchive(t, 30*24*time.Hour)
+	now := time.Now().UTC().Truncate(time.Hour)
+
+	old, recent := runsAt(now.Add(-72*time.Hour)), runsAt(now.Add(-6*time.Hour))
+	if err := archive.store(Airport{Identifier: "EDXX"}, defaultProfile(), archivePayload(t, old, nil)); err != nil {
+		t.Fatal(err)
+	}
+	for _, runs := range [][]ModelRun{old, recent} {
+		if err := archive.store(testAirport, defaultProfile(), archivePayload(t, runs, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.storeMetars(map[string]*Metar{
+		"EDXX": {Station: "EDXX", ObservedAt: now.Add(-72 * time.Hour)},
+		"EHTW": {Station: "EHTW", ObservedAt: now.Add(-time.Hour)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart with a shorter retention, and nothing written since.
+	archive.mutex.Lock()
+	archive.retention = 48 * time.Hour
+	archive.mutex.Unlock()
+	if err := archive.sweep(); err != nil {
+		t.Fatalf("sweep() = %v", err)
+	}
+
+	if records, _ := archive.history(Airport{Identifier: "EDXX"}); len(records) != 0 {
+		t.Errorf("kept %d records of a removed airfield, want none", len(records))
+	}
+	if records, _ := archive.history(testAirport); len(records) != 1 {
+		t.Errorf("kept %d records of a listed airfield, want the one inside the retention", len(records))
+	}
+	if metars, _ := archive.metars("EDXX", now.Add(-100*time.Hour), now); len(metars) != 0 {
+		t.Errorf("kept %d METARs of a station no longer polled, want none", len(metars))
+	}
+	if metars, _ := archive.metars("EHTW", now.Add(-100*time.Hour), now); len(metars) != 1 {
+		t.Errorf("kept %d METARs of a polled station, want 1", len(metars))
+	}
+	_ = archive.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(archiveBucket(Airport{Identifier: "EDXX"})) != nil {
+			t.Error("the removed airfield's emptied bucket is still there")
+		}
+		if tx.Bucket([]byte(archiveMetarBucket)).Bucket([]byte("EDXX")) != nil {
+			t.Error("the station's emptied bucket is still there")
+		}
+		return nil
+	})
+}
+
+// A route's samples are fetched under synthetic airfields nothing reads back from the
+// archive.
+func TestArchive_SkipsEnRouteSamples(t *testing.T) {
+	withTestAirports(t)
+	withArchive(t, 24*time.Hour)
+	stubModelRunMeta(t, nil)
+	runs := runsAt(time.Now().UTC().Truncate(time.Hour).Add(-4 * time.Hour))
+	stubFetchWeather(t, func(context.Context, Airport, *scoringProfile) (*ProcessedWeatherData, error) {
+		return archivePayload(t, runs, nil), nil
+	})
+
+	sample := enRouteAirport(53.0, 7.5)
+	for _, airport := range []Airport{testAirport, sample} {
+		if _, err := GetWeatherData(context.Background(), airport, defaultProfile()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if records, _ := archive.history(testAirport); len(records) != 1 {
+		t.Errorf("kept %d records of the airfield, want 1", len(records))
+	}
+	if records, _ := archive.history(sample); len(records) != 0 {
+		t.Errorf("kept %d records of an en-route sample, want none", len(records))
+	}
+}
+
+func TestPruneBucket_CapsTheRecordCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cap.db")
+	db, err := bolt.Open(path, archiveFileMode, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Hour)
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte("EDWN/ppl"))
+		if err != nil {
+			return err
+		}
+		for i := range archiveMaxRecordsPerKey + 5 {
+			runs := runsAt(now.Add(-time.Duration(i) * time.Minute))
+			if err := bucket.Put([]byte(runSetKey(runs, time.Time{})), []byte("x")); err != nil {
+				return err
+			}
+		}
+		return pruneBucket(bucket, now.Add(-24*time.Hour), archiveMaxRecordsPerKey)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("EDWN/ppl"))
+		if n := bucket.Stats().KeyN; n != archiveMaxRecordsPerKey {
+			t.Errorf("kept %d records, want %d", n, archiveMaxRecordsPerKey)
+		}
+		// The newest survive.
+		if k, _ := bucket.Cursor().Last(); string(k) != runSetKey(runsAt(now), time.Time{}) {
+			t.Errorf("newest key = %q", k)
+		}
+		return nil
+	})
+}
+
+func TestRunSetKey(t *testing.T) {
+	at := time.Date(2026, 8, 3, 6, 0, 0, 0, time.UTC)
+	runs := runsAt(at)
+	reversed := []ModelRun{runs[2], runs[1], runs[0]}
+
+	if runSetKey(runs, time.Time{}) != runSetKey(reversed, time.Time{}) {
+		t.Error("the key depends on the order the runs are listed in")
+	}
+	if want := "20260803T060000Z icon_d2@20260803T0600Z,icon_eu@20260803T0300Z,icon_global@20260803T0000Z"; runSetKey(runs, time.Time{}) != want {
+		t.Errorf("key = %q, want %q", runSetKey(runs, time.Time{}), want)
+	}
+	if runSetKey(runsAt(at), time.Time{}) >= runSetKey(runsAt(at.Add(3*time.Hour)), time.Time{}) {
+		t.Error("keys do not sort by run")
+	}
+	if got := runSetKey(nil, at); got != "20260803T060000Z unknown" {
+		t.Errorf("key without runs = %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"fmt"
+	"math"
+)
+
+// Carburettor icing.
+//
+// Most of the club fleet runs carburetted Lycomings and Rotaxes, and carb icing is the
+// autumn hazard every one of them has been briefed on: the venturi and the throttle plate
+// cool the intake air by up to 30C, and on a mild, humid day that is enough to ice the
+// throttle shut with the outside air well above freezing. It is not the cold that does it
+// but the moisture, which is why a 15C October morning is worse than a dry winter day.
+//
+// carbIcingRiskAt grades an hour on the UK CAA's carburettor icing chart (Safety Sense
+// leaflet 14), the one pinned up in every briefing room: temperature against humidity,
+// divided into four bands. The band edges here are read off the chart to the nearest 5C
+// and 10% RH; the chart itself is drawn with no more precision than that.
+//
+// The grade goes on every hour of the temperature chart. The score charges it only for an
+// aircraft that has a carburettor, and then as an advisory: carb heat is the remedy, not
+// staying on the ground, so the factor never goes beyond difficult.
+
+// carbIcingRisk grades an hour on the chart. The JSON carries it as a number: 0 none,
+// 1 light, 2 serious at descent power, 3 moderate at cruise and serious at descent power,
+// 4 serious at any power.
+//
+// The order is the chart's, from dry to saturated: the moderate band lies between the
+// descent-power band and the any-power one, and is worse than the first because it ices
+// at cruise power as well.
+type carbIcingRisk int
+
+const (
+	carbIcingNone carbIcingRisk = iota
+	carbIcingLight
+	carbIcingSeriousDescent
+	carbIcingModerate
+	carbIcingSeriousAnyPower
+)
+
+func (r carbIcingRisk) String() string {
+	switch r {
+	case carbIcingLight:
+		return "light carb icing"
+	case carbIcingSeriousDescent:
+		return "serious carb icing at descent power"
+	case carbIcingModerate:
+		return "moderate carb icing at cruise power, serious at descent power"
+	case carbIcingSeriousAnyPower:
+		return "serious carb icing at any power"
+	}
+	return "no carb icing"
+}
+
+// carbIcingBands are the chart's bands, worst first: the temperature range, C, each covers,
+// and the relative humidity, %, it starts at.
+var carbIcingBands = []struct {
+	risk             carbIcingRisk
+	minTemp, maxTemp float64
+	minRH            float64
+}{
+	{carbIcingSeriousAnyPower, -5, 25, 80},
+	{carbIcingModerate, -10, 30, 60},
+	{carbIcingSeriousDescent, -10, 30, 50},
+	{carbIcingLight, -10, 35, 30},
+}
+
+// relativeHumidityFrom is the relative humidity, %, of air at tempC with dewPointC, from the
+// Magnus formula.
+func relativeHumidityFrom(tempC, dewPointC float64) float64 {
+	saturation := func(t float64) float64 { return math.Exp(17.625 * t / (243.04 + t)) }
+	return min(100, 100*saturation(dewPointC)/saturation(tempC))
+}
+
+// carbIcingRiskAt grades air at tempC and rh percent relative humidity.
+func carbIcingRiskAt(tempC, rh float64) carbIcingRisk {
+	for _, band := range carbIcingBands {
+		if tempC >= band.minTemp && tempC <= band.maxTemp && rh >= band.minRH {
+			return band.risk
+		}
+	}
+	return carbIcingNone
+}
+
+// carbIcingHumidity is the hour's relative humidity, worked out from the dew point -- the
+// chart's own axis, and the one the temperature series carries -- or the model's RH where
+// there is no dew point. false with neither.
+func carbIcingHumidity(c conditions) (float64, bool) {
+	switch {
+	case c.dewPoint != nil:
+		return relativeHumidityFrom(c.temperature, *c.dewPoint), true
+	case c.relativeHumidity != nil:
+		return float64(*c.relativeHumidity), true
+	}
+	return 0, false
+}
+
+// carbIcingOf grades an hour, carbIcingNone where its humidity is unknown.
+func carbIcingOf(c conditions) carbIcingRisk {
+	rh, ok := carbIcingHumidity(c)
+	if !ok {
+		return carbIcingNone
+	}
+	return carbIcingRiskAt(c.temperature, rh)
+}
+
+// carbIcingDetail is the rationale for the penalty: the band and the air that put the hour
+// in it.
+func carbIcingDetail(c conditions) string {
+	rh, _ := carbIcingHumidity(c)
+	detail := fmt.Sprintf("%s: %.0fC", carbIcingOf(c), c.temperature)
+	if c.dewPoint != nil {
+		detail += fmt.Sprintf(", dew point %.0fC", *c.dewPoint)
+	}
+	return detail + fmt.Sprintf(", RH %.0f%%", rh)
+}
+
+// carburettedFor reports whether a profile's aircraft has a carburettor. false for a profile
+// without an aircraft: there is nothing to say it does.
+func carburettedFor(profile *scoringProfile) bool {
+	return profile != nil && profile.aircraft != nil && profile.aircraft.carburetted
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestRelativeHumidityFrom(t *testing.T) {
+	tests := []struct {
+		temp, dewPoint, want float64
+	}{
+		{15, 15, 100},
+		{20, 10, 52.5},
+		{10, 5, 71.1},
+		// A dew point above the temperature is a rounding artefact, not supersaturation.
+		{5, 5.4, 100},
+	}
+	for _, tc := range tests {
+		if got := relativeHumidityFrom(tc.temp, tc.dewPoint); math.Abs(got-tc.want) > 0.5 {
+			t.Errorf("relativeHumidityFrom(%v, %v) = %.1f, want %v", tc.temp, tc.dewPoint, got, tc.want)
+		}
+	}
+}
+
+func TestCarbIcingRiskAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		temp, rh float64
+		want     carbIcingRisk
+	}{
+		{"a humid October morning", 12, 90, carbIcingSeriousAnyPower},
+		{"just below freezing and saturated", -3, 95, carbIcingSeriousAnyPower},
+		{"a muggy summer afternoon", 28, 85, carbIcingModerate},
+		{"a mild, moderately humid day", 15, 65, carbIcingModerate},
+		{"drier air", 15, 55, carbIcingSeriousDescent},
+		{"a dry spring day", 18, 35, carbIcingLight},
+		{"desert air", 25, 20, carbIcingNone},
+		{"too cold to hold the water", -15, 90, carbIcingNone},
+		{"hot", 36, 60, carbIcingNone},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := carbIcingRiskAt(tc.temp, tc.rh); got != tc.want {
+				t.Errorf("carbIcingRiskAt(%v, %v) = %v, want %v", tc.temp, tc.rh, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScoreVFR_CarbIcingOnlyForCarburettedAircraft(t *testing.T) {
+	c := scoringConditions(t)
+	c.temperature, c.dewPoint = 12, ptrFloat(11)
+
+	// The injected C172 of the default profile is not charged, and not told.
+	for _, p := range mustScore(t, c) {
+		if p.Factor == "carb icing" {
+			t.Errorf("charged %+v without a carburettor", p)
+		}
+	}
+
+	// An advisory: as bad as the chart gets, and still only difficult.
+	c.carburetted = true
+	var carb *VfrPenalty
+	for _, p := range mustScore(t, c) {
+		if p.Factor == "carb icing" {
+			carb = &p
+		}
+	}
+	if carb == nil || carb.Severity != difficult.String() {
+		t.Fatalf("carb icing penalty = %+v, want difficult", carb)
+	}
+	if got, want := carb.Detail, "serious carb icing at any power: 12C, dew point 11C, RH 94%"; got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+
+	// Dry air costs nothing and says nothing.
+	c.dewPoint = ptrFloat(-10)
+	for _, p := range mustScore(t, c) {
+		if p.Factor == "carb icing" {
+			t.Errorf("charged %+v in dry air", p)
+		}
+	}
+}
+
+func TestProcessWeatherData_CarbIcing(t *testing.T) {
+	stubDayLight(t)
+
+	ultralight, err := lookupProfile("ultralight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forecast := hourlyFixture([]string{"2026-08-03T12:00"})
+	forecast.hours[0].temperature, forecast.hours[0].dewPoint = ptrFloat(12), ptrFloat(11)
+
+	// The series carries the grade whatever the aircraft; the score only for a carburettor.
+	for _, tc := range []struct {
+		profile *scoringProfile
+		charged bool
+	}{
+		{defaultProfile(), false},
+		{ultralight, true},
+	} {
+		got := processWeatherData(context.Background(), forecast, testAirport, tc.profile)
+		if risk := carbIcingRisk(got.TemperatureData[0].CarbIcing); risk != carbIcingSeriousAnyPower {
+			t.Errorf("%s: carb icing = %v, want serious at any power", tc.profile.ID, risk)
+		}
+		charged := false
+		for _, p := range got.VfrData[0].Penalties {
+			charged = charged || p.Factor == "carb icing"
+		}
+		if charged != tc.charged {
+			t.Errorf("%s: carb icing charged = %v, want %v", tc.profile.ID, charged, tc.charged)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// Model comparison: /api/weather?models=icon_seamless,ecmwf_ifs025,gfs_seamless.
+//
+// Each model is fetched, scored and cached as the primary one is -- its own payload, from
+// its own runs, against the same profile -- and the response carries all of them side by
+// side with a consensus per hour: the mean score, its range, and whether the models are
+// far enough apart that the mean should not be read on its own.
+//
+// The observations are not blended in. The nowcast corrects the forecast on screen towards
+// what the METAR shows; applied to every model, it would pull them towards each other over
+// the next hours and hide the very disagreement a comparison is for.
+
+// consensusDisagreement is the spread, in points, at which the models are said to disagree:
+// two colour bands apart, so one model's green is another's orange.
+const consensusDisagreement = 40
+
+// ModelComparison is the /api/weather response when models= is given.
+type ModelComparison struct {
+	Airport   string           `json:"airport"`
+	Profile   string           `json:"profile"`
+	Models    []ModelForecast  `json:"models"`
+	Consensus []ConsensusPoint `json:"consensus"`
+}
+
+// ModelForecast is one model's payload, or why there is none.
+type ModelForecast struct {
+	Model string                `json:"model"` // "ecmwf_ifs025"
+	Name  string                `json:"name"`  // "ECMWF"
+	Data  *ProcessedWeatherData `json:"data,omitempty"`
+	Error string                `json:"error,omitempty"`
+}
+
+// ConsensusPoint is one hour's score across the models that scored it.
+type ConsensusPoint struct {
+	Time string `json:"time"`
+	// Probability is the mean of the models' scores, and Min and Max their range.
+	Probability int `json:"probability"`
+	Min         int `json:"min"`
+	Max         int `json:"max"`
+	Spread      int `json:"spread"`
+	// Models is how many models scored the hour. Past a short-range model's horizon it is
+	// fewer than were asked for.
+	Models   int  `json:"models"`
+	Disagree bool `json:"disagree"`
+}
+
+// fetchModelForecasts fetches every model's payload at once, through the cache. A model that
+// fails carries its error rather than failing the others.
+func fetchModelForecasts(ctx context.Context, airport Airport, profile *scoringProfile, models []forecastModel) []ModelForecast {
+	forecasts := make([]ModelForecast, len(models))
+
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			forecast := ModelForecast{Model: model.ID, Name: model.Name}
+			data, err := getModelWeatherData(ctx, airport, profile, model)
+			if err != nil {
+				slog.Error("failed to fetch weather data", "airport", airport.Identifier, "profile", profile.ID, "model", model.ID, "error", err)
+				forecast.Error = "Failed to fetch weather data"
+			} else {
+				forecast.Data = data
+			}
+			forecasts[i] = forecast
+		}()
+	}
+	wg.Wait()
+
+	return forecasts
+}
+
+// consensus scores every hour any model scored, in time order. Unscored hours are left out
+// of an hour's statistics rather than counted as zero.
+func consensus(forecasts []ModelForecast) []ConsensusPoint {
+	byHour := make(map[string][]int)
+	for _, forecast := range forecasts {
+		if forecast.Data == nil {
+			continue
+		}
+		for _, point := range forecast.Data.VfrData {
+			if point.Probability >= 0 {
+				byHour[point.Time] = append(byHour[point.Time], point.Probability)
+			}
+		}
+	}
+
+	hours := make([]string, 0, len(byHour))
+	for hour := range byHour {
+		hours = append(hours, hour)
+	}
+	// Open-Meteo's naive-UTC timestamps sort as they read.
+	slices.Sort(hours)
+
+	points := make([]ConsensusPoint, 0, len(hours))
+	for _, hour := range hours {
+		scores := byHour[hour]
+		sum := 0
+		for _, score := range scores {
+			sum += score
+		}
+		lowest, highest := slices.Min(scores), slices.Max(scores)
+		points = append(points, ConsensusPoint{
+			Time:        hour,
+			Probability: int(math.Round(float64(sum) / float64(len(scores)))),
+			Min:         lowest,
+			Max:         highest,
+			Spread:      highest - lowest,
+			Models:      len(scores),
+			Disagree:    highest-lowest >= consensusDisagreement,
+		})
+	}
+	return points
+}
+
+// getModelComparison serves /api/weather?models=. An unknown model is a 400, as an unknown
+// airport is; a model that fails is reported in its entry, and only all of them failing is
+// a 500.
+func getModelComparison(w http.ResponseWriter, r *http.Request, airport Airport, profile *scoringProfile, raw string) {
+	models, err := parseModels(raw)
+	if err != nil {
+		slog.Warn("rejected unknown model", "error", err)
+		http.Error(w, "Unknown model", http.StatusBadRequest)
+		return
+	}
+
+	forecasts := fetchModelForecasts(r.Context(), airport, profile, models)
+	if !slices.ContainsFunc(forecasts, func(f ModelForecast) bool { return f.Data != nil }) {
+		http.Error(w, "Failed to fetch weather data", http.StatusInternalServerError)
+		return
+	}
+
+	// Uncached in the browser: the entries are fetched on different runs' schedules, and
+	// the one that just advanced is the one worth asking again for.
+	w.Header().Set("Cache-Control", "no-cache")
+	response := ModelComparison{
+		Airport:   airport.Identifier,
+		Profile:   profile.ID,
+		Models:    forecasts,
+		Consensus: consensus(forecasts),
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode model comparison", "error", err)
+		http.Error(w, "Failed to encode model comparison", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// withModels configures the model list for the test and restores the default after it.
+func withModels(t *testing.T, raw string) {
+	t.Helper()
+
+	if err := configureModels(raw); err != nil {
+		t.Fatalf("configureModels(%q) = %v", raw, err)
+	}
+	t.Cleanup(func() { _ = configureModels("") })
+}
+
+// stubFetchModelWeather replaces the upstream call for the comparison models.
+func stubFetchModelWeather(t *testing.T, fn func(context.Context, Airport, *scoringProfile, forecastModel) (*ProcessedWeatherData, error)) {
+	t.Helper()
+
+	original := fetchModelWeatherFn
+	fetchModelWeatherFn = fn
+	t.Cleanup(func() { fetchModelWeatherFn = original })
+}
+
+func TestConfigureModels(t *testing.T) {
+	withModels(t, "")
+	if len(forecastModels) != 1 || primaryModel().ID != "icon_seamless" || len(modelRunSources) != 3 {
+		t.Errorf("default models = %+v with %d run sources, want ICON and its three", forecastModels, len(modelRunSources))
+	}
+
+	withModels(t, "ecmwf_ifs025, icon_seamless,gfs_seamless")
+	if primaryModel().ID != "ecmwf_ifs025" || len(modelRunSources) != 6 || modelRunSources[0].name != "ecmwf_ifs025" {
+		t.Errorf("models = %+v, run sources %+v, want ECMWF first and every model's runs", forecastModels, modelRunSources)
+	}
+	if !strings.Contains(buildAPIURL(testAirport), "models=ecmwf_ifs025&") {
+		t.Errorf("primary query = %s, want ECMWF's", buildAPIURL(testAirport))
+	}
+
+	for _, raw := range []string{"ukmo_seamless", "icon_seamless,icon_seamless", "icon_seamless,"} {
+		if err := configureModels(raw); err == nil {
+			t.Errorf("configureModels(%q) accepted it", raw)
+		}
+	}
+}
+
+func TestConsensus(t *testing.T) {
+	withTestAirports(t)
+	clearSky, low := nowcastPayload(t, nil), nowcastPayload(t, ptrTo(8))
+	low.VfrData[5].Probability = -1
+
+	points := consensus([]ModelForecast{
+		{Model: "icon_seamless", Data: clearSky},
+		{Model: "ecmwf_ifs025", Data: low},
+		{Model: "gfs_seamless", Error: "Failed to fetch weather data"},
+	})
+
+	if len(points) != 6 || points[0].Time != "2026-08-03T10:00" {
+		t.Fatalf("got %d points from %v, want the six hours in order", len(points), points[0].Time)
+	}
+	first, high, lowest := points[0], clearSky.VfrData[0].Probability, low.VfrData[0].Probability
+	if first.Models != 2 || first.Min != lowest || first.Max != high || first.Spread != high-lowest {
+		t.Errorf("10:00 = %+v, want the two scores %d and %d", first, lowest, high)
+	}
+	if want := (high + lowest + 1) / 2; first.Probability != want {
+		t.Errorf("10:00 consensus = %d, want the mean %d", first.Probability, want)
+	}
+	if first.Disagree != (high-lowest >= consensusDisagreement) {
+		t.Errorf("10:00 disagree = %v with a spread of %d", first.Disagree, first.Spread)
+	}
+	// An unscored hour is left out, not averaged in as zero.
+	if last := points[5]; last.Models != 1 || last.Probability != clearSky.VfrData[5].Probability || last.Disagree {
+		t.Errorf("15:00 = %+v, want ICON's score alone", last)
+	}
+}
+
+func TestGetWeatherData_ComparesModels(t *testing.T) {
+	withTestAirports(t)
+	withModels(t, "icon_seamless,ecmwf_ifs025,gfs_seamless")
+
+	stubFetchWeather(t, func(context.Context, Airport, *scoringProfile) (*ProcessedWeatherData, error) {
+		return nowcastPayload(t, nil), nil
+	})
+	failing := map[string]bool{"gfs_seamless": true}
+	var (
+		mutex   sync.Mutex
+		fetched []string
+	)
+	stubFetchModelWeather(t, func(_ context.Context, _ Airport, _ *scoringProfile, model forecastModel) (*ProcessedWeatherData, error) {
+		mutex.Lock()
+		fetched = append(fetched, model.ID)
+		mutex.Unlock()
+		if failing[model.ID] {
+			return nil, errors.New("upstream unreachable")
+		}
+		data := nowcastPayload(t, ptrTo(8))
+		data.GeneratedAt = time.Now()
+		return data, nil
+	})
+
+	rec := httptest.NewRecorder()
+	getWeatherData(rec, httptest.NewRequest(http.MethodGet, "/api/weather?airport=EDWN&models=icon_seamless,ecmwf_ifs025,gfs_seamless", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var got ModelComparison
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(got.Models) != 3 || got.Models[1].Name != "ECMWF" {
+		t.Fatalf("models = %+v, want the three in the order asked", got.Models)
+	}
+	if got.Models[0].Data == nil || got.Models[1].Data == nil || got.Models[2].Error == "" {
+		t.Errorf("models = %+v, want ICON and ECMWF with data and GFS with its error", got.Models)
+	}
+	if got.Models[0].Data.VfrData[0].Probability == got.Models[1].Data.VfrData[0].Probability {
+		t.Error("ICON and ECMWF scored the same; each model's payload should be its own")
+	}
+	if len(got.Consensus) != 6 || got.Consensus[0].Models != 2 {
+		t.Errorf("consensus = %+v, want six hours from two models", got.Consensus)
+	}
+
+	// The comparison models are cached under their own keys, beside the primary one.
+	fetched = nil
+	getWeatherData(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/weather?airport=EDWN&models=ecmwf_ifs025", nil))
+	if len(fetched) != 0 {
+		t.Errorf("refetched %v, want ECMWF served from the cache", fetched)
+	}
+	if _, ok := cachedEntry(cacheKey(testAirport, defaultProfile()) + "@ecmwf_ifs025"); !ok {
+		t.Error("ECMWF's payload was not cached under its own key")
+	}
+
+	for _, models := range []string{"ukmo_seamless", "icon_seamless,icon_seamless", "icon_seamless,"} {
+		rec := httptest.NewRecorder()
+		getWeatherData(rec, httptest.NewRequest(http.MethodGet, "/api/weather?airport=EDWN&models="+models, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("models=%s: status = %d, want 400", models, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	getWeatherData(rec, httptest.NewRequest(http.MethodGet, "/api/weather?airport=EDWN&models=gfs_seamless", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d with every model failing, want 500", rec.Code)
+	}
+}
+
+// Each model's runs are tracked and acted on separately: a new GFS run invalidates GFS's
+// payloads and nothing else, and the label on the forecast stays ICON's.
+func TestModelRuns_TrackEachModel(t *testing.T) {
+	withModels(t, "icon_seamless,gfs_seamless")
+	gfsInitialized := int64(1786082400)
+	stubModelRunMeta(t, func(_ context.Context, url string) (*modelRunMeta, error) {
+		if strings.Contains(url, "gfs") {
+			return metaAt(gfsInitialized), nil
+		}
+		return metaAt(1786082400 - 3*3600), nil
+	})
+
+	if changed := modelRuns.poll(context.Background()); !slices.Equal(changed, []string{"icon_seamless", "gfs_seamless"}) {
+		t.Errorf("first poll changed %v, want both models", changed)
+	}
+	gfsInitialized += 6 * 3600
+	if changed := modelRuns.poll(context.Background()); !slices.Equal(changed, []string{"gfs_seamless"}) {
+		t.Errorf("second poll changed %v, want GFS alone", changed)
+	}
+
+	gfs, _ := lookupModel("gfs_seamless")
+	if runs := modelRuns.runsFor(gfs); len(runs) != 2 || runs[0].Model != "gfs013" {
+		t.Errorf("GFS runs = %+v, want its two", runs)
+	}
+	if got := modelRuns.latestInitializedAt().Unix(); got != 1786082400-3*3600 {
+		t.Errorf("latestInitializedAt() = %d, want ICON's run rather than the newer GFS one", got)
+	}
+
+	stubFetchWeather(t, nil)
+	primary, comparison := cacheKey(testAirport, defaultProfile()), modelCacheKey(testAirport, defaultProfile(), gfs)
+	cache.mutex.Lock()
+	cache.entries[primary] = &cacheEntry{}
+	cache.entries[comparison] = &cacheEntry{}
+	cache.mutex.Unlock()
+
+	cache.invalidateModels([]string{"gfs_seamless"})
+	if _, ok := cachedEntry(primary); !ok {
+		t.Error("a GFS run invalidated ICON's payload")
+	}
+	if _, ok := cachedEntry(comparison); ok {
+		t.Error("a GFS run left GFS's payload cached")
+	}
+}
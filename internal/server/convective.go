@@ -0,0 +1,90 @@
+package server
+
+import "fmt"
+
+// Convection.
+//
+// weather_code used to pick an icon and nothing else, and neither CAPE nor the lifted index
+// was asked for. A July afternoon with 2000 J/kg of CAPE and the model's own thunderstorm
+// code scored 95: high cloud base, good visibility, light wind. Each of those was true until
+// the cell went up.
+//
+// Every hour now gets a convective risk from the model's instability and its weather code.
+// It is an ordinal, like daylight, and its bands are a rule of thumb rather than a forecast
+// of cells: CAPE says how much energy a rising parcel would find, the lifted index whether
+// it rises at all, and a thunderstorm code that the model itself resolved convection. The
+// convective cloud base is where the cumulus would start, for the breakdown.
+
+// convectiveRisk grades an hour. The JSON carries it as a number, 0 to 4.
+type convectiveRisk int
+
+const (
+	convectionNone     convectiveRisk = iota
+	convectionShowers                 // unstable enough for showers and towering cumulus
+	convectionModerate                // isolated thunderstorms possible
+	convectionHigh                    // thunderstorms likely once something triggers them
+	convectionThunder                 // the model forecasts thunder for the hour: WMO 95-99
+)
+
+func (r convectiveRisk) String() string {
+	switch r {
+	case convectionShowers:
+		return "showers possible"
+	case convectionModerate:
+		return "isolated thunderstorms possible"
+	case convectionHigh:
+		return "thunderstorms likely"
+	case convectionThunder:
+		return "thunderstorm forecast"
+	}
+	return "stable"
+}
+
+// isThunderCode reports whether a WMO 4677 code, as Open-Meteo uses them, is a thunderstorm:
+// 95 on its own, 96 and 99 with hail.
+func isThunderCode(code int) bool {
+	return code >= 95 && code <= 99
+}
+
+// convectiveRiskOf grades one hour. Missing instability counts as stable -- an hour without
+// CAPE is not unstable for it -- but a thunderstorm code needs nothing else.
+func convectiveRiskOf(c conditions) convectiveRisk {
+	if c.weatherCode != nil && isThunderCode(*c.weatherCode) {
+		return convectionThunder
+	}
+	if c.cape == nil {
+		return convectionNone
+	}
+	cape := *c.cape
+	// A parcel that is not buoyant where it matters does not use the energy above it: a
+	// positive lifted index caps everything but the showers.
+	stable := c.liftedIndex != nil && *c.liftedIndex >= 0
+	switch {
+	case cape >= 2000 && !stable:
+		return convectionHigh
+	case cape >= 1000 && !stable:
+		return convectionModerate
+	case cape >= 300:
+		return convectionShowers
+	}
+	return convectionNone
+}
+
+// convectiveDetail names the hazard for the breakdown: what the model forecasts, and what it
+// was graded from.
+func convectiveDetail(c conditions) string {
+	risk := convectiveRiskOf(c)
+	detail := risk.String()
+	switch {
+	case risk == convectionThunder:
+		detail += fmt.Sprintf(" (WMO %d)", *c.weatherCode)
+	case c.cape != nil && c.liftedIndex != nil:
+		detail += fmt.Sprintf(": CAPE %.0f J/kg, LI %.1f", *c.cape, *c.liftedIndex)
+	case c.cape != nil:
+		detail += fmt.Sprintf(": CAPE %.0f J/kg", *c.cape)
+	}
+	if c.convectiveBaseFt != nil {
+		detail += fmt.Sprintf(", cumulus from %dft", *c.convectiveBaseFt)
+	}
+	return detail
+}
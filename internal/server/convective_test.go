@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"testing"
+)
+
+func TestConvectiveRiskOf(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        *int
+		cape        *float64
+		liftedIndex *float64
+		want        convectiveRisk
+	}{
+		{"nothing known", nil, nil, nil, convectionNone},
+		{"stable", ptrTo(3), ptrFloat(50), ptrFloat(4), convectionNone},
+		{"fair-weather cumulus", ptrTo(2), ptrFloat(450), ptrFloat(-1), convectionShowers},
+		{"energy the parcel never reaches", ptrTo(2), ptrFloat(1500), ptrFloat(2), convectionShowers},
+		{"isolated storms", ptrTo(80), ptrFloat(1200), ptrFloat(-3), convectionModerate},
+		{"July afternoon", ptrTo(3), ptrFloat(2100), ptrFloat(-5), convectionHigh},
+		{"CAPE without a lifted index", nil, ptrFloat(2100), nil, convectionHigh},
+		{"thunder in a stable hour still is", ptrTo(95), ptrFloat(100), ptrFloat(1), convectionThunder},
+		{"thunder with hail", ptrTo(99), nil, nil, convectionThunder},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := conditions{weatherCode: tc.code, cape: tc.cape, liftedIndex: tc.liftedIndex}
+			if got := convectiveRiskOf(c); got != tc.want {
+				t.Errorf("convectiveRiskOf() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// The request's case: everything else about the hour is fine.
+func TestScoreVFR_ThunderstormIsNoGo(t *testing.T) {
+	c := scoringConditions(t)
+	c.cloudBaseFL = ptrTo(45)
+	c.cape, c.liftedIndex, c.convectiveBaseFt = ptrFloat(2000), ptrFloat(-4), ptrTo(4500)
+
+	// Unstable, and the model has not resolved a storm: critical, and named.
+	penalties := mustScore(t, c)
+	if len(penalties) == 0 || penalties[0].Factor != "convection" || penalties[0].Severity != critical.String() {
+		t.Fatalf("penalties = %+v, want convection first and critical", penalties)
+	}
+	if got, want := penalties[0].Detail, "thunderstorms likely: CAPE 2000 J/kg, LI -4.0, cumulus from 4500ft"; got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+
+	// The model forecasts thunder: the hour is over.
+	c.weatherCode = ptrTo(95)
+	probability, penalties, _ := scoreVFR(c, vfrLimits)
+	if probability != 0 || len(penalties) != 1 || penalties[0].Severity != noGo.String() {
+		t.Fatalf("score %d, penalties %+v; want a no-go", probability, penalties)
+	}
+	if got, want := penalties[0].Detail, "thunderstorm forecast (WMO 95), cumulus from 4500ft"; got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+
+	// A stable hour costs nothing and says nothing.
+	c.weatherCode, c.cape = ptrTo(1), ptrFloat(20)
+	for _, p := range mustScore(t, c) {
+		if p.Factor == "convection" {
+			t.Errorf("charged %+v for a stable hour", p)
+		}
+	}
+}
+
+func TestProcessWeatherData_ConvectiveRisk(t *testing.T) {
+	stubDayLight(t)
+
+	forecast := hourlyFixture([]string{"2026-08-03T14:00", "2026-08-03T15:00"})
+	forecast.hours[0].cape, forecast.hours[0].liftedIndex = ptrFloat(1300), ptrFloat(-2)
+	forecast.hours[0].convectiveCloudBase = ptrFloat(1500)
+	forecast.hours[1].weatherCode = ptrTo(96)
+
+	got := processWeatherData(context.Background(), forecast, testAirport, defaultProfile())
+	first := got.CloudData[0]
+	if convectiveRisk(first.ConvectiveRisk) != convectionModerate || first.ConvectiveBaseFeet == nil || *first.ConvectiveBaseFeet != 4921 {
+		t.Errorf("CloudData[0] = risk %v, base %v; want isolated storms from 4921ft", convectiveRisk(first.ConvectiveRisk), deref(first.ConvectiveBaseFeet))
+	}
+	if convectiveRisk(got.CloudData[1].ConvectiveRisk) != convectionThunder || got.VfrData[1].Probability != 0 {
+		t.Errorf("hour with WMO 96: risk %v, score %d; want a forecast thunderstorm scored 0", convectiveRisk(got.CloudData[1].ConvectiveRisk), got.VfrData[1].Probability)
+	}
+}
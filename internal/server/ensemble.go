@@ -0,0 +1,311 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// The ensemble: how sure the score is.
+//
+// The score is one number per hour, scored from one model run. Day 4 at 80 says nothing
+// about whether the run that produced it is one of many that agree, or the lucky one of a
+// set that mostly closes the field. ICON-EPS answers that: forty perturbed runs of the same
+// model, which Open-Meteo's ensemble API serves as one member per variable per hour.
+//
+// Every member is scored through scoreVFR against the same table as the deterministic run,
+// and the hour carries the spread: P10, P50 and P90 of the member scores, and the fraction
+// of members in which some factor reached its no-go. 80 with a P10 of 0 and half the members
+// no-go is a different forecast from 80 with a P10 of 70.
+//
+// A member is scored from the inputs it has. The ensemble carries no visibility or cloud
+// layers for every model and every hour, and an input it lacks is the deterministic run's,
+// so a member never scores better for a variable it was not asked about. Daylight is the
+// hour's own. A member's precipitation is what falls in that member, so it is charged at
+// certainty rather than at the deterministic run's probability.
+//
+// The ensemble is a second request per airfield and a second thing that can fail. It fails
+// quietly: a payload without it is the payload there was before, and the score is still
+// the deterministic one. FLUGWETTER_ENSEMBLE_URL points it at a stand-in serving the same
+// JSON, or "off" turns it off.
+//
+// The members are the primary model's own ensemble, named by its forecastModel. A primary
+// model without one -- every model but ICON, for now -- has no ensemble: another model's
+// members around its hours would be the spread of a forecast it did not make.
+const (
+	ensembleURLEnv     = "FLUGWETTER_ENSEMBLE_URL"
+	defaultEnsembleURL = "https://ensemble-api.open-meteo.com/v1/ensemble"
+)
+
+// ensembleVariables are the hourly variables asked of every member. The pressure levels
+// are the lower half of the deterministic query's, as far as a cloud base for VFR needs.
+var ensembleVariables = []string{
+	"temperature_2m", "precipitation", "visibility",
+	"wind_speed_10m", "wind_direction_10m", "wind_gusts_10m",
+	"cloud_cover_1000hPa", "cloud_cover_975hPa", "cloud_cover_950hPa", "cloud_cover_925hPa",
+	"cloud_cover_900hPa", "cloud_cover_850hPa", "cloud_cover_800hPa", "cloud_cover_700hPa",
+	"geopotential_height_1000hPa", "geopotential_height_975hPa", "geopotential_height_950hPa",
+	"geopotential_height_925hPa", "geopotential_height_900hPa", "geopotential_height_850hPa",
+	"geopotential_height_800hPa", "geopotential_height_700hPa",
+}
+
+// ensembleLevels pairs the cloud cover and height of each level, lowest first.
+var ensembleLevels = []string{"1000hPa", "975hPa", "950hPa", "925hPa", "900hPa", "850hPa", "800hPa", "700hPa"}
+
+// VfrEnsemble is an hour's score across the ensemble members.
+type VfrEnsemble struct {
+	Members int `json:"members"`
+	P10     int `json:"p10"`
+	P50     int `json:"p50"`
+	P90     int `json:"p90"`
+	// NoGoFraction is the share of members, 0-1, in which some factor reached its no-go.
+	NoGoFraction float64 `json:"no_go_fraction"`
+}
+
+// ensembleMember is one member's hourly series, by variable. A value is nil where the
+// member has none for that hour.
+type ensembleMember map[string][]*float64
+
+// ensembleForecast is the decoded ensemble response.
+type ensembleForecast struct {
+	times   []string
+	members []ensembleMember
+}
+
+// decodeEnsemble reads Open-Meteo's ensemble JSON. The control run is the plain variable
+// name and member n is "<name>_member<nn>"; a member is every variable sharing a suffix.
+func decodeEnsemble(body []byte) (*ensembleForecast, error) {
+	var response struct {
+		Hourly map[string]json.RawMessage `json:"hourly"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse ensemble response: %w", err)
+	}
+
+	forecast := &ensembleForecast{}
+	if raw, ok := response.Hourly["time"]; !ok || json.Unmarshal(raw, &forecast.times) != nil {
+		return nil, fmt.Errorf("ensemble response has no hourly times")
+	}
+
+	bySuffix := make(map[string]ensembleMember)
+	for key, raw := range response.Hourly {
+		name, suffix := key, ""
+		if i := strings.Index(key, "_member"); i >= 0 {
+			name, suffix = key[:i], key[i:]
+		}
+		if !slices.Contains(ensembleVariables, name) {
+			continue
+		}
+		var values []*float64
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse ensemble variable %s: %w", key, err)
+		}
+		if bySuffix[suffix] == nil {
+			bySuffix[suffix] = make(ensembleMember)
+		}
+		bySuffix[suffix][name] = values
+	}
+
+	// Control first, then the members in order, so a response decodes the same every time.
+	suffixes := make([]string, 0, len(bySuffix))
+	for suffix := range bySuffix {
+		suffixes = append(suffixes, suffix)
+	}
+	slices.Sort(suffixes)
+	for _, suffix := range suffixes {
+		forecast.members = append(forecast.members, bySuffix[suffix])
+	}
+	if len(forecast.members) == 0 {
+		return nil, fmt.Errorf("ensemble response has no members")
+	}
+	return forecast, nil
+}
+
+// value returns the member's variable at hour i.
+func (m ensembleMember) value(name string, i int) (float64, bool) {
+	series := m[name]
+	if i >= len(series) || series[i] == nil {
+		return 0, false
+	}
+	return *series[i], true
+}
+
+// conditions is the member's version of the deterministic hour, model: every input it has
+// replaces the model's.
+func (m ensembleMember) conditions(model conditions, i int, airport Airport, aircraft *aircraftType) conditions {
+	c := model
+
+	if v, ok := m.value("temperature_2m", i); ok {
+		c.temperature = v
+	}
+	if v, ok := m.value("precipitation", i); ok {
+		c.precipitation, c.precipitationProbability = v, 100
+	}
+	if v, ok := m.value("visibility", i); ok {
+		km := v / 1000
+		c.visibilityKM = &km
+	}
+
+	speed, speedOK := m.value("wind_speed_10m", i)
+	direction, directionOK := m.value("wind_direction_10m", i)
+	if speedOK && directionOK {
+		gusts, ok := m.value("wind_gusts_10m", i)
+		if !ok {
+			gusts = speed
+		}
+		c.windSpeed, c.windGusts, c.windDirection = speed, gusts, int(math.Round(direction))
+		// The member's wind may put another end in use, and another end may face the sun or
+		// be grass.
+		c.onRunways(airport, aircraft)
+	}
+
+	// The member's cloud base, by getCloudBase's rule, when it has the levels to find one.
+	var layers []CloudLayer
+	for _, level := range ensembleLevels {
+		cover, coverOK := m.value("cloud_cover_"+level, i)
+		height, heightOK := m.value("geopotential_height_"+level, i)
+		if coverOK && heightOK && cover > 0 {
+			layers = append(layers, CloudLayer{HeightFeet: int(height * 3.28084), Coverage: int(math.Round(cover))})
+		}
+	}
+	if len(layers) > 0 || m.hasLevels(i) {
+		c.cloudBaseFL = getCloudBase(layers)
+	}
+	return c
+}
+
+// hasLevels reports whether the member has any cloud cover for hour i: a member with every
+// level at zero has a clear sky, not a missing one.
+func (m ensembleMember) hasLevels(i int) bool {
+	for _, level := range ensembleLevels {
+		if _, ok := m.value("cloud_cover_"+level, i); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyEnsemble scores every member of every hour the ensemble and the payload share, and
+// sets VfrData[].Ensemble. Hours the ensemble does not reach, or that are unscored, are
+// left without.
+func applyEnsemble(data *ProcessedWeatherData, forecast *ensembleForecast, airport Airport, profile *scoringProfile) {
+	if len(data.modelConditions) != len(data.VfrData) {
+		return
+	}
+	index := make(map[string]int, len(forecast.times))
+	for i, t := range forecast.times {
+		index[t] = i
+	}
+
+	for i := range data.VfrData {
+		point := &data.VfrData[i]
+		j, ok := index[point.Time]
+		model := data.modelConditions[i]
+		if !ok || point.Probability < 0 || model.time.IsZero() {
+			continue
+		}
+
+		scores := make([]int, 0, len(forecast.members))
+		noGoMembers := 0
+		for _, member := range forecast.members {
+			score, penalties, _ := scoreVFR(member.conditions(model, j, airport, profile.aircraft), profile.limits)
+			if score < 0 {
+				continue
+			}
+			scores = append(scores, score)
+			if len(penalties) == 1 && penalties[0].Severity == noGo.String() {
+				noGoMembers++
+			}
+		}
+		if len(scores) == 0 {
+			continue
+		}
+		slices.Sort(scores)
+		point.Ensemble = &VfrEnsemble{
+			Members:      len(scores),
+			P10:          percentile(scores, 10),
+			P50:          percentile(scores, 50),
+			P90:          percentile(scores, 90),
+			NoGoFraction: math.Round(float64(noGoMembers)/float64(len(scores))*100) / 100,
+		}
+	}
+}
+
+// percentile interpolates the p-th percentile of sorted scores.
+func percentile(sorted []int, p float64) int {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := min(lower+1, len(sorted)-1)
+	frac := rank - float64(lower)
+	return int(math.Round(float64(sorted[lower]) + frac*float64(sorted[upper]-sorted[lower])))
+}
+
+// ensembleURL is the query for one airport, or "" when the ensemble is off or the primary
+// model has none.
+func ensembleURL(airport Airport) string {
+	model := primaryModel().ensemble
+	if model == "" {
+		return ""
+	}
+	base := os.Getenv(ensembleURLEnv)
+	switch base {
+	case "off":
+		return ""
+	case "":
+		base = defaultEnsembleURL
+	}
+	query := url.Values{
+		"latitude":        {airport.LatString()},
+		"longitude":       {airport.LonString()},
+		"hourly":          {strings.Join(ensembleVariables, ",")},
+		"models":          {model},
+		"timezone":        {"GMT"},
+		"wind_speed_unit": {"kn"},
+	}
+	return base + "?" + query.Encode()
+}
+
+// fetchEnsembleFn indirects fetchEnsemble so tests can stub the network call.
+var fetchEnsembleFn = fetchEnsemble
+
+// fetchEnsemble retrieves one airport's ensemble. A nil forecast and nil error mean the
+// ensemble is off, or that the primary model has none.
+func fetchEnsemble(ctx context.Context, airport Airport) (*ensembleForecast, error) {
+	query := ensembleURL(airport)
+	if query == "" {
+		return nil, nil
+	}
+	body, err := getJSON(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ensemble: %w", err)
+	}
+	return decodeEnsemble(body)
+}
+
+// ensembleFor fetches the ensemble for a fresh forecast to be scored with, or logs why it
+// could not and returns nil. Only listed airfields get one: an en-route sample is scored
+// for its worst hour along a route, and a second request for each of forty of them would
+// buy nothing on screen.
+func ensembleFor(ctx context.Context, airport Airport) *ensembleForecast {
+	if _, listed := airportsByID[airport.Identifier]; !listed {
+		return nil
+	}
+	forecast, err := fetchEnsembleFn(ctx, airport)
+	if err != nil {
+		slog.Warn("serving without the ensemble", "airport", airport.Identifier, "error", err)
+		return nil
+	}
+	if forecast != nil {
+		slog.Debug("ensemble fetched", "airport", airport.Identifier, "members", len(forecast.members), "hours", len(forecast.times))
+	}
+	return forecast
+}
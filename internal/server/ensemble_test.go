@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ensembleFixture is a control run and four members for the first two nowcastPayload
+// hours. They agree at 10:00; at 11:00 two members bring the visibility down to 500m.
+const ensembleFixture = `{
+  "latitude": 52.46, "longitude": 7.18,
+  "hourly_units": {"time": "iso8601", "visibility": "m"},
+  "hourly": {
+    "time": ["2026-08-03T10:00", "2026-08-03T11:00"],
+    "visibility": [20000, 20000],
+    "visibility_member01": [20000, 20000],
+    "visibility_member02": [20000, 20000],
+    "visibility_member03": [20000, 500],
+    "visibility_member04": [20000, 500],
+    "wind_speed_10m": [5, 5],
+    "wind_speed_10m_member01": [5, 5],
+    "wind_direction_10m": [55, 55],
+    "wind_direction_10m_member01": [55, 55],
+    "snowfall_member01": [0, 0]
+  }
+}`
+
+func TestDecodeEnsemble(t *testing.T) {
+	forecast, err := decodeEnsemble([]byte(ensembleFixture))
+	if err != nil {
+		t.Fatalf("decodeEnsemble() = %v", err)
+	}
+	if len(forecast.times) != 2 || len(forecast.members) != 5 {
+		t.Fatalf("decoded %d hours and %d members, want 2 and the control plus 4", len(forecast.times), len(forecast.members))
+	}
+	// The control run sorts first, and a variable nobody asked for is not read.
+	if _, ok := forecast.members[0]["wind_speed_10m"]; !ok {
+		t.Error("the control run is not first")
+	}
+	if _, ok := forecast.members[1]["snowfall"]; ok {
+		t.Error("an unrequested variable was decoded")
+	}
+
+	for _, body := range []string{`{"hourly": {}}`, `{"hourly": {"time": ["2026-08-03T10:00"]}}`, `not json`} {
+		if _, err := decodeEnsemble([]byte(body)); err == nil {
+			t.Errorf("decodeEnsemble(%s) accepted it", body)
+		}
+	}
+}
+
+func TestApplyEnsemble(t *testing.T) {
+	withTestAirports(t)
+	forecast, err := decodeEnsemble([]byte(ensembleFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := nowcastPayload(t, nil)
+
+	applyEnsemble(data, forecast, testAirport, defaultProfile())
+
+	agreed := data.VfrData[0].Ensemble
+	if agreed == nil || agreed.Members != 5 || agreed.P10 != agreed.P90 || agreed.NoGoFraction != 0 {
+		t.Errorf("10:00 ensemble = %+v, want five members in agreement", agreed)
+	}
+	split := data.VfrData[1].Ensemble
+	if split == nil || split.NoGoFraction != 0.4 || split.P10 != 0 || split.P50 != agreed.P50 || split.P90 != agreed.P90 {
+		t.Errorf("11:00 ensemble = %+v, want two members of five no-go and the rest as at 10:00", split)
+	}
+	// The deterministic score is untouched, and hours the ensemble does not reach get none.
+	if data.VfrData[1].Probability == 0 {
+		t.Error("the ensemble overwrote the deterministic score")
+	}
+	if data.VfrData[2].Ensemble != nil {
+		t.Errorf("12:00 ensemble = %+v, want none past the ensemble's last hour", data.VfrData[2].Ensemble)
+	}
+}
+
+// A member without an input is scored on the deterministic run's, rather than as if the
+// input were perfect.
+func TestEnsembleMember_FallsBackToTheModel(t *testing.T) {
+	withTestAirports(t)
+	model := scoringConditions(t)
+	model.cloudBaseFL = ptrTo(8)
+
+	c := ensembleMember{"temperature_2m": {ptrFloat(3)}}.conditions(model, 0, testAirport, nil)
+	if c.cloudBaseFL == nil || *c.cloudBaseFL != 8 || c.temperature != 3 {
+		t.Errorf("member conditions = %+v, want the model's FL8 and the member's 3°C", c)
+	}
+
+	clearSky := ensembleMember{"cloud_cover_1000hPa": {ptrFloat(0)}, "geopotential_height_1000hPa": {ptrFloat(100)}}
+	if c := clearSky.conditions(model, 0, testAirport, nil); c.cloudBaseFL != nil {
+		t.Errorf("member with a clear sky has cloud base FL%d", *c.cloudBaseFL)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	scores := []int{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	for p, want := range map[float64]int{10: 10, 50: 50, 90: 90, 0: 0, 100: 100} {
+		if got := percentile(scores, p); got != want {
+			t.Errorf("P%v = %d, want %d", p, got, want)
+		}
+	}
+	if got := percentile([]int{0, 100}, 50); got != 50 {
+		t.Errorf("P50 of 0 and 100 = %d, want it interpolated", got)
+	}
+	if got := percentile([]int{70}, 10); got != 70 {
+		t.Errorf("P10 of one member = %d", got)
+	}
+}
+
+func TestFetchEnsemble(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		_, _ = w.Write([]byte(ensembleFixture))
+	}))
+	defer server.Close()
+
+	t.Setenv(ensembleURLEnv, server.URL)
+	forecast, err := fetchEnsemble(context.Background(), testAirport)
+	if err != nil || forecast == nil || len(forecast.members) != 5 {
+		t.Fatalf("fetchEnsemble() = %v, %v", forecast, err)
+	}
+	if !strings.Contains(query, "models=icon_seamless") || !strings.Contains(query, "wind_speed_unit=kn") {
+		t.Errorf("query = %s", query)
+	}
+
+	t.Setenv(ensembleURLEnv, "off")
+	if forecast, err := fetchEnsemble(context.Background(), testAirport); forecast != nil || err != nil {
+		t.Errorf("fetchEnsemble() with the ensemble off = %v, %v", forecast, err)
+	}
+}
+
+// ICON-EPS is ICON's spread. With another model primary, there is no ensemble to attach.
+func TestFetchEnsemble_NoneForAPrimaryWithoutOne(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(ensembleFixture))
+	}))
+	defer server.Close()
+	t.Setenv(ensembleURLEnv, server.URL)
+	withModels(t, "ecmwf_ifs025,icon_seamless")
+
+	if forecast, err := fetchEnsemble(context.Background(), testAirport); forecast != nil || err != nil || requests != 0 {
+		t.Errorf("fetchEnsemble() with ECMWF primary = %v, %v after %d requests, want none", forecast, err, requests)
+	}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"fmt"
+	"time"
+)
+
+// Radiation fog.
+//
+// Autumn mornings in the Emsland are lost to radiation fog, and the model's visibility is
+// the input least able to see it coming: fog a hundred metres deep over the moor is below
+// what ICON resolves, and it forecasts 20km for an hour the field spends in 300m. What the
+// model does get right are the ingredients, and those are what a forecaster looks at: air
+// close to saturation, a light wind, and a clear sky to let the ground cool.
+//
+// fogRiskOf grades an hour from those ingredients, and the fog factor charges it with the
+// reasons spelled out. It is a rule of thumb, calibrated on nothing but the briefings it
+// imitates; where the model already brings the visibility down the visibility factor has
+// charged the fog, and this stands aside.
+
+// fogRisk grades an hour's radiation fog. The JSON carries it as a number: 0 unlikely, 1
+// possible, 2 likely.
+type fogRisk int
+
+const (
+	fogUnlikely fogRisk = iota
+	fogPossible
+	fogLikely
+)
+
+func (r fogRisk) String() string {
+	switch r {
+	case fogPossible:
+		return "fog possible"
+	case fogLikely:
+		return "fog likely"
+	}
+	return "fog unlikely"
+}
+
+// The ingredients' thresholds.
+const (
+	// fogMorningAfterSunrise is how long after sunrise radiation fog is still counted on:
+	// in October it takes the sun two or three hours to lift it.
+	fogMorningAfterSunrise = 3 * time.Hour
+
+	// fogSpreadLikely and fogSpreadPossible are the dew point spreads, C, at which the air
+	// is as good as saturated, and at which a clear night will get it there.
+	fogSpreadLikely   = 1.0
+	fogSpreadPossible = 2.5
+
+	// fogWindLikely is the wind, kn, that mixes the cooled layer just enough to make it fog
+	// rather than dew; above fogWindMax it mixes it away, and lifts it into stratus at
+	// best -- which the cloud base then charges.
+	fogWindLikely = 5.0
+	fogWindMax    = 8.0
+
+	// fogClearSky is the most mid- and high-level cloud cover, %, that still lets the ground
+	// radiate. Low cloud is left out: on a fog morning the model often carries the fog
+	// itself as low cloud.
+	fogClearSky = 30
+)
+
+// fogVisibilityCharged is the visibility, km, at or below which the model has seen the fog
+// itself, and the visibility factor has already charged for it.
+const fogVisibilityCharged = 1.5
+
+// inFogWindow reports whether the hour is one radiation fog forms or lingers in: from sunset
+// until fogMorningAfterSunrise. false without a daylight window to tell.
+func inFogWindow(c conditions) bool {
+	if c.daylight == nil {
+		return false
+	}
+	return c.time.After(c.daylight.Parsed.Sunset) || c.time.Before(c.daylight.Parsed.Sunrise.Add(fogMorningAfterSunrise))
+}
+
+// clearAbove reports whether the sky above the fog layer lets the ground radiate. Unknown
+// cover counts as clear: the spread and the wind still have to agree.
+func clearAbove(c conditions) bool {
+	for _, cover := range []*int{c.cloudCoverMid, c.cloudCoverHigh} {
+		if cover != nil && *cover > fogClearSky {
+			return false
+		}
+	}
+	return true
+}
+
+// fogRiskOf grades an hour. An hour outside the fog window, without a dew point, or whose
+// visibility already shows the fog is fogUnlikely: there is nothing left for the estimate
+// to add.
+func fogRiskOf(c conditions) fogRisk {
+	if !inFogWindow(c) || c.dewPoint == nil || c.windSpeed > fogWindMax {
+		return fogUnlikely
+	}
+	if c.visibilityKM != nil && *c.visibilityKM <= fogVisibilityCharged {
+		return fogUnlikely
+	}
+	spread := c.temperature - *c.dewPoint
+	clear := clearAbove(c)
+	switch {
+	case spread <= fogSpreadLikely && c.windSpeed <= fogWindLikely && clear:
+		return fogLikely
+	case spread <= fogSpreadLikely:
+		// Saturated, but windy or overcast enough that it may stay mist or stratus.
+		return fogPossible
+	case spread <= fogSpreadPossible && clear:
+		return fogPossible
+	}
+	return fogUnlikely
+}
+
+// fogDetail is the rationale for the penalty: the grade and the ingredients behind it.
+func fogDetail(c conditions) string {
+	detail := fmt.Sprintf("%s: spread %.1fC", fogRiskOf(c), c.temperature-*c.dewPoint)
+	if c.relativeHumidity != nil {
+		detail += fmt.Sprintf(", RH %d%%", *c.relativeHumidity)
+	}
+	detail += fmt.Sprintf(", wind %.0f kn", c.windSpeed)
+	if clearAbove(c) {
+		detail += ", clear above"
+	} else {
+		detail += ", cloud above"
+	}
+	return detail
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// fogMorning is the baseline fog hour: 05:00, ninety minutes after sunrise on the test
+// date, 6C with a 0.5C spread, 3kn, nothing above, and the model's visibility at 20km.
+func fogMorning(t *testing.T) conditions {
+	t.Helper()
+
+	c := scoringConditions(t).at(t, "2026-08-03T05:00")
+	c.temperature, c.dewPoint, c.relativeHumidity = 6, ptrFloat(5.5), ptrTo(97)
+	c.windSpeed = 3
+	c.cloudCoverMid, c.cloudCoverHigh = ptrTo(0), ptrTo(10)
+	c.visibilityKM = ptrFloat(20)
+	return c
+}
+
+func TestFogRiskOf(t *testing.T) {
+	tests := []struct {
+		name string
+		with func(c conditions) conditions
+		want fogRisk
+	}{
+		{
+			name: "a still, saturated, clear dawn",
+			with: func(c conditions) conditions { return c },
+			want: fogLikely,
+		},
+		{
+			name: "late evening is in the window too",
+			with: func(c conditions) conditions { return c.at(t, "2026-08-03T21:00") },
+			want: fogLikely,
+		},
+		{
+			// 07:00 is three and a half hours after sunrise: whatever formed has lifted.
+			name: "mid-morning is out of the window",
+			with: func(c conditions) conditions { return c.at(t, "2026-08-03T07:00") },
+			want: fogUnlikely,
+		},
+		{
+			name: "midday with the same air is not a fog hour",
+			with: func(c conditions) conditions { return c.at(t, midday) },
+			want: fogUnlikely,
+		},
+		{
+			name: "a 2C spread under a clear sky may get there",
+			with: func(c conditions) conditions { c.dewPoint = ptrFloat(4); return c },
+			want: fogPossible,
+		},
+		{
+			name: "a 4C spread will not",
+			with: func(c conditions) conditions { c.dewPoint = ptrFloat(2); return c },
+			want: fogUnlikely,
+		},
+		{
+			// Saturated but stirred: mist or stratus rather than a fog bank.
+			name: "7kn keeps it to possible",
+			with: func(c conditions) conditions { c.windSpeed = 7; return c },
+			want: fogPossible,
+		},
+		{
+			name: "10kn mixes it away",
+			with: func(c conditions) conditions { c.windSpeed = 10; return c },
+			want: fogUnlikely,
+		},
+		{
+			name: "an altostratus deck keeps the ground warm",
+			with: func(c conditions) conditions { c.cloudCoverMid = ptrTo(90); return c },
+			want: fogPossible,
+		},
+		{
+			name: "a 2C spread under cloud stays unlikely",
+			with: func(c conditions) conditions { c.dewPoint, c.cloudCoverHigh = ptrFloat(4), ptrTo(80); return c },
+			want: fogUnlikely,
+		},
+		{
+			// The model has the fog in its visibility, and the visibility factor charged it.
+			name: "the model already sees it",
+			with: func(c conditions) conditions { c.visibilityKM = ptrFloat(0.4); return c },
+			want: fogUnlikely,
+		},
+		{
+			name: "no dew point, no estimate",
+			with: func(c conditions) conditions { c.dewPoint = nil; return c },
+			want: fogUnlikely,
+		},
+		{
+			name: "no daylight window, no estimate",
+			with: func(c conditions) conditions { c.daylight = nil; return c },
+			want: fogUnlikely,
+		},
+		{
+			name: "unknown cloud above counts as clear",
+			with: func(c conditions) conditions { c.cloudCoverMid, c.cloudCoverHigh = nil, nil; return c },
+			want: fogLikely,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := fogRiskOf(tc.with(fogMorning(t))); got != tc.want {
+				t.Errorf("fogRiskOf() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScoreVFR_FogSaysWhy(t *testing.T) {
+	tests := []struct {
+		name     string
+		with     func(c conditions) conditions
+		severity severity
+		detail   string
+	}{
+		{
+			name:     "likely",
+			with:     func(c conditions) conditions { return c },
+			severity: critical,
+			detail:   "fog likely: spread 0.5C, RH 97%, wind 3 kn, clear above",
+		},
+		{
+			name:     "possible",
+			with:     func(c conditions) conditions { c.cloudCoverMid = ptrTo(90); return c },
+			severity: difficult,
+			detail:   "fog possible: spread 0.5C, RH 97%, wind 3 kn, cloud above",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var fog *VfrPenalty
+			for _, p := range mustScore(t, tc.with(fogMorning(t))) {
+				if p.Factor == "fog" {
+					fog = &p
+				}
+			}
+			if fog == nil || fog.Severity != tc.severity.String() || fog.Detail != tc.detail {
+				t.Errorf("fog penalty = %+v, want %v with %q", fog, tc.severity, tc.detail)
+			}
+		})
+	}
+
+	// Fog that is not coming costs nothing and is not mentioned.
+	c := fogMorning(t)
+	c.dewPoint = ptrFloat(-2)
+	for _, p := range mustScore(t, c) {
+		if strings.HasPrefix(p.Detail, "fog") {
+			t.Errorf("charged %+v with a wide spread", p)
+		}
+	}
+}
+
+func TestProcessWeatherData_FlagsFog(t *testing.T) {
+	stubDayLight(t)
+
+	forecast := hourlyFixture([]string{"2026-08-03T05:00", "2026-08-03T12:00"})
+	for i := range forecast.hours {
+		forecast.hours[i].temperature, forecast.hours[i].dewPoint = ptrFloat(6), ptrFloat(5.5)
+		forecast.hours[i].windSpeed10m = ptrFloat(3)
+	}
+
+	got := processWeatherData(context.Background(), forecast, testAirport, defaultProfile())
+	if risk := fogRisk(got.CloudData[0].FogRisk); risk != fogLikely {
+		t.Errorf("05:00 fog risk = %v, want likely", risk)
+	}
+	if risk := fogRisk(got.CloudData[1].FogRisk); risk != fogUnlikely {
+		t.Errorf("12:00 fog risk = %v, want unlikely: the same air at midday is not a fog hour", risk)
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"fmt"
+	"math"
+)
+
+// Sun glare on the approach.
+//
+// Runway 23 at EDWN points at the winter afternoon sun. An hour before sunset in December it
+// sits a few degrees above the horizon and a few degrees off the approach, and the runway,
+// the traffic on final and the windscreen's every scratch vanish into it. The daylight
+// factor charges only the dark, and nothing else in the table knew where the sun was.
+//
+// Every hour now places the sun (see sunSkyAt) and compares it with each runway end's
+// heading: an aircraft landing on an end flies its heading, and takes off on it, into
+// whatever sun is ahead. The wind series flags every end with a low sun inside
+// sunGlareCone of it, and the sun glare factor charges the end in use, by how far off the
+// approach the sun is. The cone the score charges is the factor's curve, and a limits file
+// can narrow it like any other; sunGlareCone is only the widest the payload reports.
+
+const (
+	// sunGlareMaxElevation is the highest the sun, degrees, can be and still glare: above
+	// it the cabin roof and the visor shade it on a normal approach.
+	sunGlareMaxElevation = 15.0
+
+	// sunGlareCone is the angle, degrees, between the sun and an end's heading within which
+	// the payload flags the end.
+	sunGlareCone = 30.0
+
+	// sunGlareOvercast is the total cloud cover, %, that hides the sun's disc: a low sun
+	// behind it lights the cloud, not the windscreen.
+	sunGlareOvercast = 85
+)
+
+// offAxis is the angle, 0 to 180 degrees, between the sun and a heading.
+func offAxis(sun sunSky, heading float64) float64 {
+	return math.Abs(math.Remainder(sun.azimuth-heading, 360))
+}
+
+// sunGlares reports whether a sun is low enough, and out, to glare on any end at all. An
+// unknown cloud cover counts as clear: it is the sun's position that decides.
+func sunGlares(sun sunSky, cloudCover *int) bool {
+	if sun.elevation <= 0 || sun.elevation > sunGlareMaxElevation {
+		return false
+	}
+	return cloudCover == nil || *cloudCover < sunGlareOvercast
+}
+
+// markSunGlare sets SunGlare on every end the sun is low ahead of, within sunGlareCone.
+func markSunGlare(ends []RunwayWind, sun sunSky, cloudCover *int) {
+	if !sunGlares(sun, cloudCover) {
+		return
+	}
+	for i := range ends {
+		if angle := offAxis(sun, ends[i].Heading); angle <= sunGlareCone {
+			ends[i].SunGlare = &angle
+		}
+	}
+}
+
+// sunGlareOf is the angle between the sun and the heading of the end in use, false where
+// there is no end in use or the sun does not glare this hour.
+func sunGlareOf(c conditions) (float64, bool) {
+	if c.runwayInUse == "" || !sunGlares(c.sun, c.cloudCover) {
+		return 0, false
+	}
+	return offAxis(c.sun, c.runwayHeading), true
+}
+
+// sunGlareDetail names the end and where the sun stands against it.
+func sunGlareDetail(c conditions) string {
+	angle, _ := sunGlareOf(c)
+	return fmt.Sprintf("low sun %.0f° off runway %s, %.0f° above the horizon", angle, c.runwayInUse, c.sun.elevation)
+}
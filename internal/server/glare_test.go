@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSunSkyAt(t *testing.T) {
+	tests := []struct {
+		name               string
+		at                 time.Time
+		lat, lon           float64
+		azimuth, elevation float64
+	}{
+		// Due south at 90 - 51.48 + 23.44 degrees.
+		{"Greenwich, midsummer noon", time.Date(2026, 6, 21, 12, 2, 0, 0, time.UTC), 51.4779, -0.0015, 180, 61.96},
+		// The request's case: twenty minutes before sunset, just left of runway 23.
+		{"EDWN, a December afternoon", time.Date(2026, 12, 15, 15, 0, 0, 0, time.UTC), testAirport.Latitude, testAirport.Longitude, 227.5, 1.2},
+		{"EDWN, the same morning", time.Date(2026, 12, 15, 8, 0, 0, 0, time.UTC), testAirport.Latitude, testAirport.Longitude, 133.9, 2.0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := sunSkyAt(tc.at, tc.lat, tc.lon)
+			if math.Abs(got.azimuth-tc.azimuth) > 0.5 || math.Abs(got.elevation-tc.elevation) > 0.5 {
+				t.Errorf("sunSkyAt() = %+v, want azimuth %v, elevation %v", got, tc.azimuth, tc.elevation)
+			}
+		})
+	}
+}
+
+func TestMarkSunGlare(t *testing.T) {
+	ends := func() []RunwayWind {
+		return []RunwayWind{{Runway: "05", Heading: 55}, {Runway: "23", Heading: 235}}
+	}
+	afternoon := sunSky{azimuth: 215, elevation: 7}
+
+	got := ends()
+	markSunGlare(got, afternoon, ptrTo(20))
+	if got[0].SunGlare != nil || got[1].SunGlare == nil || math.Abs(*got[1].SunGlare-20) > 0.01 {
+		t.Errorf("ends = 05 %v, 23 %v; want only 23, 20 degrees off", deref(got[0].SunGlare), deref(got[1].SunGlare))
+	}
+
+	for name, tc := range map[string]struct {
+		sun   sunSky
+		cover *int
+	}{
+		"overcast":          {afternoon, ptrTo(95)},
+		"sun too high":      {sunSky{azimuth: 215, elevation: 25}, nil},
+		"sun below horizon": {sunSky{azimuth: 235, elevation: -2}, nil},
+		"sun abeam":         {sunSky{azimuth: 145, elevation: 7}, nil},
+	} {
+		got := ends()
+		markSunGlare(got, tc.sun, tc.cover)
+		if got[0].SunGlare != nil || got[1].SunGlare != nil {
+			t.Errorf("%s: flagged %+v", name, got)
+		}
+	}
+}
+
+func TestScoreVFR_SunGlare(t *testing.T) {
+	c := scoringConditions(t)
+	c.runwayInUse, c.runwayHeading = "23", 235
+	c.sun = sunSky{azimuth: 227.5, elevation: 4}
+
+	var glare *VfrPenalty
+	for _, p := range mustScore(t, c) {
+		if p.Factor == "sun glare" {
+			glare = &p
+		}
+	}
+	if glare == nil || glare.Severity != difficult.String() {
+		t.Fatalf("sun glare penalty = %+v, want difficult: 7.5 degrees is most of the way to 5", glare)
+	}
+	if got, want := glare.Detail, "low sun 8° off runway 23, 4° above the horizon"; got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+
+	// The sun behind the aircraft, an overcast, or no runway in use: nothing to charge.
+	for name, with := range map[string]func(c *conditions){
+		"landing on 05":  func(c *conditions) { c.runwayInUse, c.runwayHeading = "05", 55 },
+		"overcast":       func(c *conditions) { c.cloudCover = ptrTo(100) },
+		"no runway":      func(c *conditions) { c.runwayInUse = "" },
+		"sun above 15°":  func(c *conditions) { c.sun.elevation = 20 },
+		"sun below zero": func(c *conditions) { c.sun.elevation = -1 },
+	} {
+		c := c
+		with(&c)
+		for _, p := range mustScore(t, c) {
+			if p.Factor == "sun glare" {
+				t.Errorf("%s: charged %+v", name, p)
+			}
+		}
+	}
+}
+
+func TestProcessWeatherData_SunGlare(t *testing.T) {
+	stubDayLightByDate(t)
+
+	forecast := hourlyFixture([]string{"2026-12-15T14:00"})
+	forecast.hours[0].windSpeed10m, forecast.hours[0].windDirection10m = ptrFloat(8), ptrTo(230)
+	forecast.hours[0].cloudCover = ptrTo(10)
+
+	got := processWeatherData(context.Background(), forecast, testAirport, defaultProfile())
+	runways := got.WindData[0].Runways
+	if len(runways) != 2 || runways[0].SunGlare != nil || runways[1].SunGlare == nil || !runways[1].InUse {
+		t.Fatalf("Runways = %+v, want 23 in use and facing the sun", runways)
+	}
+
+	charged := false
+	for _, p := range got.VfrData[0].Penalties {
+		charged = charged || p.Factor == "sun glare"
+	}
+	if !charged {
+		t.Errorf("penalties = %+v, want sun glare on 23", got.VfrData[0].Penalties)
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Airframe icing.
+//
+// From October to April the cloud base is not the whole question: a base at 2500ft is a
+// comfortable VFR day in June and, with the freezing level at 1000ft, a day on which the
+// cloud just above the cruise is supercooled water and any inadvertent entry ices the
+// airframe. The forecast now carries temperature and humidity on the pressure levels, from
+// which every hour gets a freezing level and every cloud layer an icing risk.
+//
+// The risk is a rule of thumb, not a forecast of accretion: supercooled liquid water is most
+// likely between 0 and -8C, thins out towards -15C, and by -20C most cloud at these heights
+// is ice crystals that do not stick. Within those bands the humidity says how wet the cloud
+// is -- the model's cloud cover on a level is already a humidity threshold, so a layer at
+// 85% is only just cloud.
+
+// icingRisk grades one cloud layer. It is an index rather than a probability, and the JSON
+// carries it as a number: 0 none, 1 light, 2 moderate, 3 severe.
+type icingRisk int
+
+const (
+	icingNone icingRisk = iota
+	icingLight
+	icingModerate
+	icingSevere
+)
+
+func (r icingRisk) String() string {
+	switch r {
+	case icingLight:
+		return "light"
+	case icingModerate:
+		return "moderate"
+	case icingSevere:
+		return "severe"
+	}
+	return "none"
+}
+
+// icingRiskAt grades a cloud layer at temperatureC and relativeHumidity percent.
+func icingRiskAt(temperatureC float64, relativeHumidity int) icingRisk {
+	switch {
+	case temperatureC > 0 || temperatureC < -20 || relativeHumidity < 70:
+		return icingNone
+	case temperatureC < -15:
+		return icingLight
+	case temperatureC < -8:
+		if relativeHumidity >= 85 {
+			return icingModerate
+		}
+		return icingLight
+	case relativeHumidity >= 95:
+		return icingSevere
+	case relativeHumidity >= 85:
+		return icingModerate
+	}
+	return icingLight
+}
+
+// freezingLevelFeet is the lowest height, in feet above MSL, at which the hour's temperature
+// reaches 0C: the 2m temperature at groundFt, then the pressure levels upwards, interpolated
+// linearly between the two either side of the crossing. A sub-zero 2m temperature puts it
+// at the ground. nil means it is not known -- a provider without level temperatures, or no
+// crossing below the highest level that has one.
+//
+// The first crossing is the one that matters for flying under the cloud: a warm layer above
+// it, which is what makes freezing rain, does not lift the freezing level the aircraft meets.
+func freezingLevelFeet(hour forecastHour, groundFt float64) *int {
+	type point struct{ feet, temperature float64 }
+	var points []point
+	if hour.temperature != nil {
+		points = append(points, point{groundFt, *hour.temperature})
+	}
+	for _, level := range hour.levels {
+		if level.height == nil || level.temperature == nil {
+			continue
+		}
+		// Open-Meteo extrapolates the levels that lie underground; they say nothing the 2m
+		// temperature does not.
+		if feet := *level.height * 3.28084; feet > groundFt {
+			points = append(points, point{feet, *level.temperature})
+		}
+	}
+	slices.SortFunc(points, func(a, b point) int {
+		switch {
+		case a.feet < b.feet:
+			return -1
+		case a.feet > b.feet:
+			return 1
+		}
+		return 0
+	})
+
+	for i, p := range points {
+		if p.temperature > 0 {
+			continue
+		}
+		if i == 0 {
+			feet := int(p.feet)
+			return &feet
+		}
+		below := points[i-1]
+		feet := int(below.feet + below.temperature/(below.temperature-p.temperature)*(p.feet-below.feet))
+		return &feet
+	}
+	return nil
+}
+
+// baseIcingRisk is the icing risk of the layer getCloudBase takes the base from, or none
+// where there is no base.
+func baseIcingRisk(layers []CloudLayer) icingRisk {
+	for _, layer := range layers {
+		if layer.Coverage >= 40 {
+			return icingRisk(layer.IcingRisk)
+		}
+	}
+	return icingNone
+}
+
+// icingDetail says what the icing factor charged for, in words.
+func icingDetail(c conditions) string {
+	return fmt.Sprintf("freezing level %dft, %s icing in cloud from FL%03d", *c.freezingLevelFt, c.baseIcing, *c.cloudBaseFL)
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIcingRiskAt(t *testing.T) {
+	tests := []struct {
+		temperature float64
+		humidity    int
+		want        icingRisk
+	}{
+		{2, 100, icingNone},   // liquid
+		{-25, 100, icingNone}, // ice crystals
+		{-5, 60, icingNone},   // too dry to be cloud
+		// Just below freezing, graded by how wet the cloud is.
+		{-3, 98, icingSevere},
+		{-3, 88, icingModerate},
+		{-3, 75, icingLight},
+		{-12, 90, icingModerate},
+		{-12, 75, icingLight},
+		{-18, 100, icingLight},
+	}
+	for _, tc := range tests {
+		if got := icingRiskAt(tc.temperature, tc.humidity); got != tc.want {
+			t.Errorf("icingRiskAt(%v, %v) = %v, want %v", tc.temperature, tc.humidity, got, tc.want)
+		}
+	}
+}
+
+// icingHour is a November hour at EDWN: 4C on the ground, 0C between 925 and 900hPa.
+func icingHour() forecastHour {
+	level := func(hPa int, heightM, temperature float64, humidity, cover int) pressureLevel {
+		return pressureLevel{hPa: hPa, height: ptrFloat(heightM), temperature: ptrFloat(temperature), relativeHumidity: ptrTo(humidity), cloudCover: ptrTo(cover)}
+	}
+	return forecastHour{
+		time:        "2026-11-12T12:00",
+		temperature: ptrFloat(4),
+		levels: []pressureLevel{
+			// 1000hPa is underground on a low-pressure day and extrapolated to 6C.
+			level(1000, -20, 6, 80, 0),
+			level(975, 180, 3, 85, 0),
+			level(950, 400, 2, 90, 0),
+			level(925, 610, 1, 92, 0),
+			level(900, 850, -1, 97, 80),
+			level(850, 1300, -4, 96, 90),
+			level(800, 1800, -9, 90, 30),
+		},
+	}
+}
+
+func TestFreezingLevelFeet(t *testing.T) {
+	// Halfway between 925hPa (2001ft) and 900hPa (2789ft).
+	got := freezingLevelFeet(icingHour(), 85)
+	if got == nil || *got < 2390 || *got > 2400 {
+		t.Errorf("freezingLevelFeet() = %v, want ~2395ft", deref(got))
+	}
+
+	// Below zero on the ground puts it there, whatever the levels say.
+	frost := icingHour()
+	frost.temperature = ptrFloat(-2)
+	if got := freezingLevelFeet(frost, 85); got == nil || *got != 85 {
+		t.Errorf("freezingLevelFeet() = %v, want the ground at 85ft", deref(got))
+	}
+
+	// Above everything there is a temperature for, it is not known.
+	warm := icingHour()
+	warm.levels = warm.levels[:4]
+	if got := freezingLevelFeet(warm, 85); got != nil {
+		t.Errorf("freezingLevelFeet() = %v, want nil above the top level", *got)
+	}
+	if got := freezingLevelFeet(forecastHour{}, 85); got != nil {
+		t.Errorf("freezingLevelFeet() = %v for an hour without temperatures", *got)
+	}
+}
+
+func TestProcessCloudLayers_GradesIcing(t *testing.T) {
+	layers := processCloudLayers(icingHour())
+	if len(layers) != 3 {
+		t.Fatalf("layers = %+v, want 900, 850 and 800hPa", layers)
+	}
+	want := []icingRisk{icingSevere, icingSevere, icingModerate}
+	for i, layer := range layers {
+		if icingRisk(layer.IcingRisk) != want[i] {
+			t.Errorf("layer at %dft: icing %v, want %v", layer.HeightFeet, icingRisk(layer.IcingRisk), want[i])
+		}
+	}
+	if got := baseIcingRisk(layers); got != icingSevere {
+		t.Errorf("baseIcingRisk() = %v, want the 900hPa layer's", got)
+	}
+}
+
+func TestScoreVFR_Icing(t *testing.T) {
+	c := scoringConditions(t)
+	c.cloudBaseFL = ptrTo(30)
+	c.baseIcing = icingModerate
+
+	// The freezing level 1000ft under a base at 3000ft.
+	c.freezingLevelFt = ptrTo(2000)
+	penalties := mustScore(t, c)
+	if len(penalties) == 0 || penalties[0].Factor != "icing" || penalties[0].Severity != difficult.String() {
+		t.Fatalf("penalties = %+v, want icing first and difficult", penalties)
+	}
+	if got := penalties[0].Detail; got != "freezing level 2000ft, moderate icing in cloud from FL030" {
+		t.Errorf("detail = %q", got)
+	}
+
+	// Above the base the cloud is liquid.
+	c.freezingLevelFt = ptrTo(4000)
+	for _, p := range mustScore(t, c) {
+		if p.Factor == "icing" {
+			t.Errorf("charged %+v with the freezing level above the base", p)
+		}
+	}
+
+	// Below it, but in cloud too cold or too dry to ice.
+	c.freezingLevelFt, c.baseIcing = ptrTo(1000), icingNone
+	for _, p := range mustScore(t, c) {
+		if p.Factor == "icing" {
+			t.Errorf("charged %+v for cloud without icing", p)
+		}
+	}
+}
+
+func TestProcessWeatherData_FreezingLevel(t *testing.T) {
+	stubDayLight(t)
+
+	forecast := hourlyFixture([]string{"2026-08-03T12:00"})
+	hour := icingHour()
+	forecast.hours[0].temperature, forecast.hours[0].levels = hour.temperature, hour.levels
+
+	got := processWeatherData(context.Background(), forecast, testAirport, defaultProfile())
+	if fl := got.CloudData[0].FreezingLevelFeet; fl == nil || *fl < 2300 || *fl > 2400 {
+		t.Errorf("FreezingLevelFeet = %v, want ~2390ft, testAirport having no elevation", deref(fl))
+	}
+	if c := got.modelConditions[0]; c.freezingLevelFt == nil || c.baseIcing != icingSevere {
+		t.Errorf("conditions: freezing level %v, icing %v; want both carried into the score", deref(c.freezingLevelFt), c.baseIcing)
+	}
+}
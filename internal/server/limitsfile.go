@@ -0,0 +1,318 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The VFR limits as a file.
+//
+// vfrLimits is a data table that happens to be written in Go, and retuning it meant a
+// toolchain, a build and a redeploy. FLUGWETTER_VFR_LIMITS_FILE replaces the numbers in it
+// -- the severity ladder, every factor's curve, weight and wall, the precipitation scale and
+// the profiles -- from JSON or YAML, so whoever tunes the score does not need to be the one
+// who builds the image.
+//
+// What a file cannot do is add a factor. What a factor measures is code (its value
+// function), so the file names factors by their name in vfrLimits and retunes them; an
+// unknown name is an error rather than a factor that silently measures nothing.
+//
+// A file is held to every rule init() holds vfrLimits to. At startup a bad one is fatal,
+// the same as a bad airports file. On a reload it is refused and the running table stays:
+// a typo made while the server is up must not leave it scoring with nothing.
+
+// limitsFileEnv names the env var holding the path to a limits file.
+const limitsFileEnv = "FLUGWETTER_VFR_LIMITS_FILE"
+
+// limitsFilePollInterval is how often the file is checked for changes. There is no file
+// notification API in the standard library; a stat every few seconds costs nothing and
+// SIGHUP is there for anyone who wants the change to land at once.
+const limitsFilePollInterval = 5 * time.Second
+
+// limitsFile is the file's shape. Every section is optional: what is absent keeps its
+// built-in value.
+type limitsFile struct {
+	// SeverityCost replaces severityCost. Given at all, it must name every band.
+	SeverityCost map[string]float64 `json:"severity_cost"`
+	// Factors retunes vfrLimits itself, keyed by factor name. Every profile is resolved
+	// against the result.
+	Factors map[string]factorFile `json:"factors"`
+	// Profiles replaces profileDefinitions wholesale, so a profile can be removed as well as
+	// retuned. It must still define the default.
+	Profiles []profileFile `json:"profiles"`
+}
+
+// factorFile is one factor's tuning. Curve, weight and wall are required together, as in
+// factorTuning; wall is a pointer so that leaving it out is an error rather than a wall
+// quietly removed.
+type factorFile struct {
+	Curve  []anchorFile `json:"curve"`
+	Weight float64      `json:"weight"`
+	Wall   *bool        `json:"wall"`
+	// Scale replaces the points of the factor's scale. Only a factor that has one can be
+	// given one: which quantity modulates a factor is code, like its value.
+	Scale []scalePointFile `json:"scale"`
+}
+
+type anchorFile struct {
+	Severity string  `json:"severity"` // "difficult"
+	At       float64 `json:"at"`
+}
+
+type scalePointFile struct {
+	At     float64 `json:"at"`
+	Weight float64 `json:"weight"`
+}
+
+type profileFile struct {
+	ID          string                `json:"id"`
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Aircraft    string                `json:"aircraft"`
+	Factors     map[string]factorFile `json:"factors"`
+}
+
+// loadLimitsFile reads, resolves and validates a limits file into a table ready to install.
+// Nothing is installed here, so a failure at any point leaves the running table untouched.
+func loadLimitsFile(path string) (*scoringTable, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s=%q: %w", limitsFileEnv, path, err)
+	}
+
+	parsed, err := parseLimitsFile(raw, filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	table, err := parsed.resolve()
+	if err != nil {
+		return nil, fmt.Errorf("invalid limits in %s: %w", path, err)
+	}
+	return table, nil
+}
+
+// parseLimitsFile decodes JSON, or YAML for a .yaml or .yml file. YAML is converted to JSON
+// and decoded from there, so the json tags are the one schema and both formats reject the
+// same unknown keys -- a misspelt "weigth" is an error, not a default.
+func parseLimitsFile(raw []byte, ext string) (*limitsFile, error) {
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		var doc any
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		converted, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert YAML: %w", err)
+		}
+		raw = converted
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+
+	var parsed limitsFile
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// resolve applies the file to the built-in tables and builds the result.
+func (lf *limitsFile) resolve() (*scoringTable, error) {
+	ladder := severityCost
+	if lf.SeverityCost != nil {
+		parsed, err := parseLadder(lf.SeverityCost)
+		if err != nil {
+			return nil, fmt.Errorf("severity_cost: %w", err)
+		}
+		ladder = parsed
+	}
+
+	base := make([]factor, len(vfrLimits))
+	copy(base, vfrLimits)
+	for name, ff := range lf.Factors {
+		i := factorIndex(base, name)
+		if i < 0 {
+			return nil, fmt.Errorf("factors: unknown factor %q", name)
+		}
+		tuning, err := ff.tuning()
+		if err != nil {
+			return nil, fmt.Errorf("factors: %q: %w", name, err)
+		}
+		base[i].curve, base[i].weight, base[i].wall = tuning.curve, tuning.weight, tuning.wall
+
+		if ff.Scale != nil {
+			if base[i].scaledBy == nil {
+				return nil, fmt.Errorf("factors: %q has no scale to retune", name)
+			}
+			// A copy of the scale, not a write through the shared pointer into vfrLimits.
+			retuned := *base[i].scaledBy
+			retuned.points = make([]scalePoint, len(ff.Scale))
+			for j, p := range ff.Scale {
+				retuned.points[j] = scalePoint{at: p.At, weight: p.Weight}
+			}
+			base[i].scaledBy = &retuned
+		}
+	}
+
+	definitions := profileDefinitions
+	if lf.Profiles != nil {
+		definitions = make([]profileDefinition, 0, len(lf.Profiles))
+		for _, pf := range lf.Profiles {
+			def := profileDefinition{
+				ScoringProfile: ScoringProfile{ID: pf.ID, Name: pf.Name, Description: pf.Description, Aircraft: pf.Aircraft},
+				tunings:        make(map[string]factorTuning, len(pf.Factors)),
+			}
+			for name, ff := range pf.Factors {
+				if ff.Scale != nil {
+					return nil, fmt.Errorf("profile %q: %q: a scale is retuned under factors, not per profile", pf.ID, name)
+				}
+				tuning, err := ff.tuning()
+				if err != nil {
+					return nil, fmt.Errorf("profile %q: %q: %w", pf.ID, name, err)
+				}
+				def.tunings[name] = tuning
+			}
+			definitions = append(definitions, def)
+		}
+	}
+
+	// buildScoringTable validates every factor of every profile, including the base
+	// retuned above, since the default profile is that base untouched.
+	return buildScoringTable(base, ladder, definitions)
+}
+
+func (ff factorFile) tuning() (factorTuning, error) {
+	if ff.Wall == nil {
+		return factorTuning{}, fmt.Errorf("wall must be given")
+	}
+	curve := make([]anchor, len(ff.Curve))
+	for i, a := range ff.Curve {
+		sev, err := parseSeverity(a.Severity)
+		if err != nil {
+			return factorTuning{}, fmt.Errorf("anchor %d: %w", i, err)
+		}
+		curve[i] = anchor{severity: sev, at: a.At}
+	}
+	return factorTuning{curve: curve, weight: ff.Weight, wall: *ff.Wall}, nil
+}
+
+func factorIndex(limits []factor, name string) int {
+	for i, f := range limits {
+		if f.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseSeverity reads a severity by the name the breakdown reports it under.
+func parseSeverity(name string) (severity, error) {
+	for s := perfect; s <= noGo; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q", name)
+}
+
+// parseLadder reads and validates a replacement severityCost. Every band must be priced,
+// perfect must be free, and each band must cost more than the one before -- otherwise the
+// severity words stop meaning what the breakdown says they mean.
+func parseLadder(raw map[string]float64) (map[severity]float64, error) {
+	ladder := make(map[severity]float64, len(raw))
+	for name, cost := range raw {
+		sev, err := parseSeverity(name)
+		if err != nil {
+			return nil, err
+		}
+		// A no-go is not subtracted from anything; see noGoPenaltyCost.
+		if sev == noGo {
+			return nil, fmt.Errorf("no-go has no cost; it ends the hour")
+		}
+		ladder[sev] = cost
+	}
+
+	for s := perfect; s < noGo; s++ {
+		cost, ok := ladder[s]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("no cost for %s", s)
+		case s == perfect && cost != 0:
+			return nil, fmt.Errorf("perfect must cost 0, costs %v", cost)
+		case s > perfect && cost <= ladder[s-1]:
+			return nil, fmt.Errorf("%s costs %v, which does not rise above %s", s, cost, s-1)
+		}
+	}
+	return ladder, nil
+}
+
+// reloadLimitsFile loads the file and, only if it is valid, installs it and drops every
+// cached payload and verification report so the next request is scored against the new
+// table.
+func reloadLimitsFile(path string) error {
+	table, err := loadLimitsFile(path)
+	if err != nil {
+		return err
+	}
+
+	scoring.Store(table)
+	cache.invalidateAll()
+	invalidateVerification()
+	slog.Info("loaded VFR limits from file", "path", path, "profiles", len(table.profiles))
+	return nil
+}
+
+// watchLimitsFile reloads the file on SIGHUP, or when its modification time or size
+// changes, until ctx is cancelled. A failed reload is logged and the running table kept.
+func watchLimitsFile(ctx context.Context, path string) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(limitsFilePollInterval)
+	defer ticker.Stop()
+
+	last, _ := os.Stat(path)
+
+	reload := func(reason string) {
+		if err := reloadLimitsFile(path); err != nil {
+			slog.Error("refused VFR limits file, keeping the running table", "reason", reason, "error", err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			last, _ = os.Stat(path)
+			reload("SIGHUP")
+		case <-ticker.C:
+			current, err := os.Stat(path)
+			if err != nil {
+				// Editors replace a file by renaming over it, so a moment without one is
+				// normal. It is the next stat that sees the new file.
+				continue
+			}
+			if last != nil && current.ModTime().Equal(last.ModTime()) && current.Size() == last.Size() {
+				continue
+			}
+			last = current
+			reload("file changed")
+		}
+	}
+}
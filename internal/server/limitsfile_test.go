@@ -0,0 +1,209 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const limitsFixture = "testdata/vfr_limits.yaml"
+
+// withScoringTable restores the table in use when the test ends. It is package-level state
+// written by a reload, so a test that reloads must put it back.
+func withScoringTable(t *testing.T) {
+	t.Helper()
+
+	previous := scoring.Load()
+	t.Cleanup(func() { scoring.Store(previous) })
+}
+
+// writeLimitsFile writes content to a temporary file with the given extension.
+func writeLimitsFile(t *testing.T, ext, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "limits"+ext)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestLoadLimitsFile_YAML(t *testing.T) {
+	table, err := loadLimitsFile(limitsFixture)
+	if err != nil {
+		t.Fatalf("loadLimitsFile() = %v", err)
+	}
+
+	if len(table.profiles) != 2 {
+		t.Fatalf("len(profiles) = %d, want the file's 2 rather than the built-in list", len(table.profiles))
+	}
+
+	crosswind := table.defaultProfile.limits[factorIndex(table.defaultProfile.limits, "crosswind")]
+	// The file's critical anchor is 12kn, priced by the file's own ladder at 60.
+	if cost, sev, isNoGo := crosswind.evaluate(12); cost != 60 || sev != critical || isNoGo {
+		t.Errorf("crosswind at 12kn = %v, %s, noGo=%v, want 60, critical, false", cost, sev, isNoGo)
+	}
+	if _, _, isNoGo := crosswind.evaluate(12.5); !isNoGo {
+		t.Error("crosswind at 12.5kn is not a no-go, want the file's wall at 12kn")
+	}
+
+	precipitation := table.defaultProfile.limits[factorIndex(table.defaultProfile.limits, "precipitation")]
+	if got := precipitation.scaledBy.weight(0); got != 0.2 {
+		t.Errorf("precipitation scale at 0%% = %v, want the file's 0.2", got)
+	}
+
+	// A profile is resolved against the file's base, so the student inherits the file's
+	// crosswind as well as its own wind.
+	student := table.byID["student-solo"]
+	if student == nil {
+		t.Fatal("student-solo profile missing")
+	}
+	if got := student.limits[factorIndex(student.limits, "crosswind")].curve[3].at; got != 12 {
+		t.Errorf("student crosswind critical anchor = %v, want the file's 12", got)
+	}
+	if got := student.limits[factorIndex(student.limits, "wind")].curve[3].at; got != 15 {
+		t.Errorf("student wind critical anchor = %v, want the profile's 15", got)
+	}
+	// The aircraft is the file's to name too, and a profile that names none flies nothing.
+	if table.defaultProfile.aircraft == nil || table.defaultProfile.aircraft.ID != "c172" || student.aircraft != nil {
+		t.Errorf("aircraft = %v and %v, want the PPL's C172 and none for the student", table.defaultProfile.aircraft, student.aircraft)
+	}
+}
+
+// The file retunes copies. vfrLimits and its shared scale must come out as they went in,
+// or a refused reload later would leave a half-applied table behind.
+func TestLoadLimitsFile_DoesNotWriteThroughToTheBuiltInTable(t *testing.T) {
+	precipitation := vfrLimits[factorIndex(vfrLimits, "precipitation")]
+	before := precipitation.scaledBy.points[0].weight
+	crosswindBefore := vfrLimits[factorIndex(vfrLimits, "crosswind")].curve[3].at
+
+	if _, err := loadLimitsFile(limitsFixture); err != nil {
+		t.Fatalf("loadLimitsFile() = %v", err)
+	}
+
+	if got := precipitation.scaledBy.points[0].weight; got != before {
+		t.Errorf("vfrLimits precipitation scale = %v, was %v before the file was loaded", got, before)
+	}
+	if got := vfrLimits[factorIndex(vfrLimits, "crosswind")].curve[3].at; got != crosswindBefore {
+		t.Errorf("vfrLimits crosswind critical anchor = %v, was %v", got, crosswindBefore)
+	}
+	if got := severityCost[critical]; got != 50 {
+		t.Errorf("severityCost[critical] = %v, want the built-in 50", got)
+	}
+}
+
+// Every section is optional: a file that only reprices the ladder keeps the built-in
+// factors and profiles.
+func TestLoadLimitsFile_JSONWithOnlyALadder(t *testing.T) {
+	path := writeLimitsFile(t, ".json", `{"severity_cost": {"perfect": 0, "good": 1, "difficult": 10, "critical": 40}}`)
+
+	table, err := loadLimitsFile(path)
+	if err != nil {
+		t.Fatalf("loadLimitsFile() = %v", err)
+	}
+	if len(table.profiles) != len(profileDefinitions) {
+		t.Errorf("len(profiles) = %d, want the built-in %d", len(table.profiles), len(profileDefinitions))
+	}
+	wind := table.defaultProfile.limits[factorIndex(table.defaultProfile.limits, "wind")]
+	if got := wind.costOf(critical); got != 40 {
+		t.Errorf("wind critical cost = %v, want the file's 40", got)
+	}
+}
+
+func TestLoadLimitsFile_Rejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     string
+		content string
+	}{
+		{"malformed JSON", ".json", `{"factors": `},
+		{"malformed YAML", ".yaml", "factors: [unclosed"},
+		{"an unknown top-level key", ".json", `{"severity_costs": {}}`},
+		{"a misspelt field", ".json", `{"factors": {"crosswind": {"curve": [], "weigth": 1, "wall": true}}}`},
+		{"an unknown factor", ".json", `{"factors": {"cross wind": {"curve": [{"severity": "perfect", "at": 2}, {"severity": "good", "at": 5}], "weight": 1, "wall": true}}}`},
+		{"a factor without its wall", ".json", `{"factors": {"crosswind": {"curve": [{"severity": "perfect", "at": 2}, {"severity": "good", "at": 5}], "weight": 1}}}`},
+		{"an unknown severity", ".json", `{"factors": {"crosswind": {"curve": [{"severity": "perfect", "at": 2}, {"severity": "bad", "at": 5}], "weight": 1, "wall": true}}}`},
+		{"a curve that runs backwards", ".json", `{"factors": {"crosswind": {"curve": [{"severity": "perfect", "at": 5}, {"severity": "good", "at": 2}, {"severity": "difficult", "at": 8}], "weight": 1, "wall": true}}}`},
+		{"a scale on a factor that has none", ".json", `{"factors": {"wind": {"curve": [{"severity": "perfect", "at": 5}, {"severity": "good", "at": 10}], "weight": 1, "wall": true, "scale": [{"at": 0, "weight": 0.5}, {"at": 100, "weight": 1}]}}}`},
+		{"a ladder with a band missing", ".json", `{"severity_cost": {"perfect": 0, "good": 1, "critical": 50}}`},
+		{"a ladder that does not rise", ".json", `{"severity_cost": {"perfect": 0, "good": 15, "difficult": 15, "critical": 50}}`},
+		{"a ladder where perfect costs something", ".json", `{"severity_cost": {"perfect": 1, "good": 2, "difficult": 15, "critical": 50}}`},
+		{"a ladder that prices no-go", ".json", `{"severity_cost": {"perfect": 0, "good": 1, "difficult": 15, "critical": 50, "no-go": 100}}`},
+		{"profiles without the default", ".json", `{"profiles": [{"id": "student-solo", "name": "Student solo"}]}`},
+		{"a profile id with a cache key's separator", ".json", `{"profiles": [{"id": "ppl", "name": "PPL"}, {"id": "ppl@gfs", "name": "PPL on GFS"}]}`},
+		{"a profile id in capitals", ".json", `{"profiles": [{"id": "ppl", "name": "PPL"}, {"id": "IR", "name": "IR"}]}`},
+		{"a profile flying an unknown aircraft", ".json", `{"profiles": [{"id": "ppl", "name": "PPL", "aircraft": "dr400"}]}`},
+		{"a profile tuning that is malformed", ".json", `{"profiles": [{"id": "ppl", "name": "PPL", "factors": {"crosswind": {"curve": [{"severity": "perfect", "at": 2}, {"severity": "good", "at": 5}, {"severity": "difficult", "at": 4}], "weight": 1, "wall": true}}}]}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := loadLimitsFile(writeLimitsFile(t, tc.ext, tc.content)); err == nil {
+				t.Error("loadLimitsFile() = nil error, want one")
+			}
+		})
+	}
+}
+
+// A valid file replaces the table and drops the cache, so the next request re-scores.
+func TestReloadLimitsFile_InstallsAndInvalidates(t *testing.T) {
+	withScoringTable(t)
+	withTestAirports(t)
+	stubFetchWeather(t, nil)
+
+	cache.mutex.Lock()
+	cache.entries[cacheKey(testAirport, defaultProfile())] = &cacheEntry{data: &ProcessedWeatherData{}, timestamp: time.Now()}
+	cache.mutex.Unlock()
+	verificationReports.mutex.Lock()
+	verificationReports.entries["EDWN/7"] = VerificationReport{GeneratedAt: time.Now()}
+	verificationReports.mutex.Unlock()
+
+	if err := reloadLimitsFile(limitsFixture); err != nil {
+		t.Fatalf("reloadLimitsFile() = %v", err)
+	}
+
+	if got := len(profileList()); got != 2 {
+		t.Errorf("len(profileList()) = %d, want the file's 2", got)
+	}
+	if _, err := lookupProfile("ultralight"); err == nil {
+		t.Error("the built-in ultralight profile survived a file that does not define it")
+	}
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	if len(cache.entries) != 0 {
+		t.Errorf("%d cache entries survived a reload, want 0", len(cache.entries))
+	}
+	verificationReports.mutex.Lock()
+	defer verificationReports.mutex.Unlock()
+	if len(verificationReports.entries) != 0 {
+		t.Errorf("%d verification reports survived a reload, want 0", len(verificationReports.entries))
+	}
+}
+
+// The whole point of validating before installing: a typo made while the server is up must
+// not take the score down with it.
+func TestReloadLimitsFile_BadFileKeepsTheRunningTable(t *testing.T) {
+	withScoringTable(t)
+	withTestAirports(t)
+	stubFetchWeather(t, nil)
+
+	running := scoring.Load()
+	cache.mutex.Lock()
+	cache.entries[cacheKey(testAirport, defaultProfile())] = &cacheEntry{data: &ProcessedWeatherData{}, timestamp: time.Now()}
+	cache.mutex.Unlock()
+
+	path := writeLimitsFile(t, ".json", `{"severity_cost": {"perfect": 0, "good": 50, "difficult": 15, "critical": 50}}`)
+	if err := reloadLimitsFile(path); err == nil {
+		t.Fatal("reloadLimitsFile() = nil error, want the bad ladder refused")
+	}
+
+	if scoring.Load() != running {
+		t.Error("a refused file replaced the running table")
+	}
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	if len(cache.entries) != 1 {
+		t.Errorf("%d cache entries after a refused reload, want the 1 that was there", len(cache.entries))
+	}
+}
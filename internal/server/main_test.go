@@ -77,7 +77,7 @@ func TestGetWeatherData(t *testing.T) {
 		TemperatureData: []TemperaturePoint{{Time: "2026-08-04T10:00", Temperature: 18}},
 		GeneratedAt:     time.Now(),
 	}
-	stubFetchWeather(t, func(_ context.Context, airport Airport) (*ProcessedWeatherData, error) {
+	stubFetchWeather(t, func(_ context.Context, airport Airport, _ *scoringProfile) (*ProcessedWeatherData, error) {
 		copied := *payload
 		return &copied, nil
 	})
@@ -126,7 +126,7 @@ func TestGetWeatherData(t *testing.T) {
 // error rather than an empty but successful-looking payload.
 func TestGetWeatherData_UpstreamFailure(t *testing.T) {
 	withTestAirports(t)
-	stubFetchWeather(t, func(context.Context, Airport) (*ProcessedWeatherData, error) {
+	stubFetchWeather(t, func(context.Context, Airport, *scoringProfile) (*ProcessedWeatherData, error) {
 		return nil, errors.New("open-meteo unreachable")
 	})
 
@@ -142,7 +142,7 @@ func TestGetWeatherData_UpstreamFailure(t *testing.T) {
 // what the frontend keys its age banner on.
 func TestGetWeatherData_ServesStaleWithFlag(t *testing.T) {
 	withTestAirports(t)
-	stubFetchWeather(t, func(context.Context, Airport) (*ProcessedWeatherData, error) {
+	stubFetchWeather(t, func(context.Context, Airport, *scoringProfile) (*ProcessedWeatherData, error) {
 		return nil, errors.New("open-meteo unreachable")
 	})
 
@@ -150,7 +150,7 @@ func TestGetWeatherData_ServesStaleWithFlag(t *testing.T) {
 	// stopped being "expired" the moment the backstop TTL grew.
 	generatedAt := time.Now().Add(-2 * cacheDuration)
 	cache.mutex.Lock()
-	cache.entries[testAirport.Identifier] = &cacheEntry{
+	cache.entries[cacheKey(testAirport, defaultProfile())] = &cacheEntry{
 		data:      &ProcessedWeatherData{GeneratedAt: generatedAt},
 		timestamp: generatedAt,
 	}
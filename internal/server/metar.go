@@ -0,0 +1,499 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// METAR and TAF decoding.
+//
+// Both are the WMO's traditional alphanumeric code (TAC): whitespace-separated groups whose
+// meaning is given by their shape, not their position. The decoder below reads the groups a
+// VFR decision depends on -- wind, visibility, CAVOK, cloud layers, present weather,
+// temperature, QNH -- and the TAF's change groups, and skips the rest (runway visual range,
+// recent weather, wind shear, remarks) rather than failing on them. A station that adds a
+// national group this does not know still yields the parts that matter.
+//
+// Heights stay in feet and visibility in metres, the units the codes are written in; the
+// conversion to the scoring's flight levels and kilometres happens where they are scored.
+
+// ReportedConditions is what a METAR, or one group of a TAF, says about the wind and the
+// sky. Absent fields were not reported, which is not the same as reported as nil: a TAF
+// change group only repeats what changes.
+type ReportedConditions struct {
+	Wind *ReportedWind `json:"wind,omitempty"`
+	// VisibilityM is the prevailing visibility. 9999 -- "10 km or more" -- is decoded as
+	// 10000, as is CAVOK.
+	VisibilityM *int `json:"visibility_m,omitempty"`
+	// MinimumVisibilityM is the directional minimum that may follow it, "4000NE", and
+	// MinimumVisibilityDirection the direction it is in. It never stands in for the
+	// prevailing visibility, however much lower it is.
+	MinimumVisibilityM         *int   `json:"minimum_visibility_m,omitempty"`
+	MinimumVisibilityDirection string `json:"minimum_visibility_direction,omitempty"`
+	// CAVOK stands for visibility of 10 km or more, no cloud below 5000 ft or the highest
+	// minimum sector altitude, no CB or TCU and no significant weather.
+	CAVOK  bool            `json:"cavok,omitempty"`
+	Clouds []ReportedCloud `json:"clouds,omitempty"`
+	// NoSignificantCloud is NSC, SKC, CLR or NCD: the report says there is no cloud worth
+	// reporting, which is different from a report that says nothing about cloud.
+	NoSignificantCloud bool `json:"no_significant_cloud,omitempty"`
+	// CeilingFeet is the lowest BKN, OVC or vertical-visibility layer, the definition of a
+	// ceiling. Nil when there is none or no cloud was reported.
+	CeilingFeet *int `json:"ceiling_feet,omitempty"`
+	// Weather is the present-weather groups as written: "-RA", "TSRA", "BR".
+	Weather []string `json:"weather,omitempty"`
+	// NoSignificantWeather is NSW in a TAF change group: the weather of the groups before
+	// it has ended.
+	NoSignificantWeather bool `json:"no_significant_weather,omitempty"`
+}
+
+// ReportedWind is a decoded wind group, in knots whatever unit it was reported in.
+type ReportedWind struct {
+	// DirectionDeg is true degrees. Nil for VRB.
+	DirectionDeg *int     `json:"direction_deg,omitempty"`
+	SpeedKT      float64  `json:"speed_kt"`
+	GustKT       *float64 `json:"gust_kt,omitempty"`
+	// VariableFromDeg and VariableToDeg are the dddVddd group, when present.
+	VariableFromDeg *int `json:"variable_from_deg,omitempty"`
+	VariableToDeg   *int `json:"variable_to_deg,omitempty"`
+}
+
+// ReportedCloud is one cloud group.
+type ReportedCloud struct {
+	Cover string `json:"cover"` // "FEW" | "SCT" | "BKN" | "OVC" | "VV"
+	// BaseFeet is nil when the height was reported as ///, which automatic stations do for
+	// a layer they detect but cannot measure.
+	BaseFeet *int   `json:"base_feet,omitempty"`
+	Type     string `json:"type,omitempty"` // "CB" | "TCU"
+}
+
+// Metar is a decoded METAR or SPECI.
+type Metar struct {
+	Station    string    `json:"station"`
+	Raw        string    `json:"raw"`
+	ObservedAt time.Time `json:"observed_at"`
+	// Auto marks a report produced without a human observer, whose cloud and weather
+	// groups are correspondingly less reliable.
+	Auto bool `json:"auto,omitempty"`
+	ReportedConditions
+	TemperatureC *float64 `json:"temperature_c,omitempty"`
+	DewPointC    *float64 `json:"dew_point_c,omitempty"`
+	QNHHPa       *float64 `json:"qnh_hpa,omitempty"`
+	// Trend is the two-hour trend forecast appended to the report (NOSIG, or a BECMG or
+	// TEMPO group), passed through as written.
+	Trend string `json:"trend,omitempty"`
+}
+
+// Taf is a decoded terminal aerodrome forecast.
+type Taf struct {
+	Station   string    `json:"station"`
+	Raw       string    `json:"raw"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ValidFrom time.Time `json:"valid_from"`
+	ValidTo   time.Time `json:"valid_to"`
+	// Base is the prevailing forecast the change groups modify.
+	Base    ReportedConditions `json:"base"`
+	Changes []TafChange        `json:"changes,omitempty"`
+}
+
+// TafChange is one FM, BECMG, TEMPO or PROB group.
+type TafChange struct {
+	// Kind is "FM", "BECMG", "TEMPO" or "PROB". PROB30 TEMPO is a TEMPO with a
+	// Probability; a bare PROB40 is a PROB.
+	Kind        string    `json:"kind"`
+	Probability int       `json:"probability,omitempty"`
+	From        time.Time `json:"from"`
+	// To is where the group ends: its own period for BECMG, TEMPO and PROB, and the next FM
+	// group or the end of validity for FM, which has none.
+	To time.Time `json:"to"`
+	ReportedConditions
+}
+
+var (
+	tacStationRe    = regexp.MustCompile(`^[A-Z]{4}$`)
+	tacDayTimeRe    = regexp.MustCompile(`^(\d{2})(\d{2})(\d{2})Z$`)
+	tacPeriodRe     = regexp.MustCompile(`^(\d{2})(\d{2})/(\d{2})(\d{2})$`)
+	tacFromRe       = regexp.MustCompile(`^FM(\d{2})(\d{2})(\d{2})$`)
+	tacProbRe       = regexp.MustCompile(`^PROB(\d{2})$`)
+	tacWindRe       = regexp.MustCompile(`^(\d{3}|VRB)(\d{2,3})(?:G(\d{2,3}))?(KT|MPS)$`)
+	tacWindVarRe    = regexp.MustCompile(`^(\d{3})V(\d{3})$`)
+	tacVisibilityRe = regexp.MustCompile(`^(\d{4})(?:NDV)?$`)
+	tacMinimumRe    = regexp.MustCompile(`^(\d{4})(N|NE|E|SE|S|SW|W|NW)$`)
+	tacStatuteRe    = regexp.MustCompile(`^(P)?(\d{1,2})(?:/(\d))?SM$`)
+	tacCloudRe      = regexp.MustCompile(`^(FEW|SCT|BKN|OVC)(\d{3}|///)(CB|TCU|///)?$`)
+	tacVerticalRe   = regexp.MustCompile(`^VV(\d{3}|///)$`)
+	tacWeatherRe    = regexp.MustCompile(`^(\+|-|VC)?(MI|BC|PR|DR|BL|SH|TS|FZ)?((?:DZ|RA|SN|SG|IC|PL|GR|GS|UP|BR|FG|FU|VA|DU|SA|HZ|PO|SQ|FC|SS|DS)*)$`)
+	tacTempRe       = regexp.MustCompile(`^(M?\d{2})/(M?\d{2})?$`)
+	tacQNHRe        = regexp.MustCompile(`^([QA])(\d{4})$`)
+)
+
+// tacFields splits a report into groups, dropping the terminating "=" some sources keep.
+func tacFields(raw string) []string {
+	return strings.Fields(strings.TrimSuffix(strings.TrimSpace(raw), "="))
+}
+
+// parseMETAR decodes one METAR or SPECI. ref is "now": a report carries only day, hour
+// and minute, so the month and year come from the report being recent.
+func parseMETAR(raw string, ref time.Time) (*Metar, error) {
+	fields := tacFields(raw)
+	i := 0
+	if i < len(fields) && (fields[i] == "METAR" || fields[i] == "SPECI") {
+		i++
+	}
+	if i < len(fields) && fields[i] == "COR" {
+		i++
+	}
+	if i >= len(fields) || !tacStationRe.MatchString(fields[i]) {
+		return nil, fmt.Errorf("no station in METAR %q", raw)
+	}
+	m := &Metar{Station: fields[i], Raw: strings.Join(fields, " ")}
+	i++
+
+	if i >= len(fields) {
+		return nil, fmt.Errorf("METAR %s has no observation time", m.Station)
+	}
+	observed, ok := tacDayTime(fields[i], ref)
+	if !ok {
+		return nil, fmt.Errorf("METAR %s has no observation time: %q", m.Station, fields[i])
+	}
+	m.ObservedAt = observed
+	i++
+
+	for ; i < len(fields); i++ {
+		field := fields[i]
+		switch {
+		case field == "NIL":
+			return nil, fmt.Errorf("METAR %s is NIL", m.Station)
+		case field == "AUTO" || field == "COR":
+			m.Auto = m.Auto || field == "AUTO"
+		case field == "RMK":
+			return m, nil
+		case field == "NOSIG" || field == "BECMG" || field == "TEMPO":
+			trend := fields[i:]
+			for j, f := range trend {
+				if f == "RMK" {
+					trend = trend[:j]
+					break
+				}
+			}
+			m.Trend = strings.Join(trend, " ")
+			return m, nil
+		case m.ReportedConditions.parseGroup(field):
+		case tacTempRe.MatchString(field):
+			match := tacTempRe.FindStringSubmatch(field)
+			m.TemperatureC = tacTemperature(match[1])
+			m.DewPointC = tacTemperature(match[2])
+		case tacQNHRe.MatchString(field):
+			match := tacQNHRe.FindStringSubmatch(field)
+			value, _ := strconv.ParseFloat(match[2], 64)
+			if match[1] == "A" {
+				value = value / 100 * hPaPerInHg // A2992 is inches of mercury
+			}
+			m.QNHHPa = &value
+		}
+		// Anything else -- RVR, recent weather, wind shear, runway state -- is skipped.
+	}
+	return m, nil
+}
+
+// hPaPerInHg converts an altimeter setting given in inches of mercury.
+const hPaPerInHg = 33.8639
+
+// parseTAF decodes one TAF. ref plays the same part as for parseMETAR.
+func parseTAF(raw string, ref time.Time) (*Taf, error) {
+	fields := tacFields(raw)
+	i := 0
+	if i < len(fields) && fields[i] == "TAF" {
+		i++
+	}
+	for i < len(fields) && (fields[i] == "AMD" || fields[i] == "COR") {
+		i++
+	}
+	if i >= len(fields) || !tacStationRe.MatchString(fields[i]) {
+		return nil, fmt.Errorf("no station in TAF %q", raw)
+	}
+	taf := &Taf{Station: fields[i], Raw: strings.Join(fields, " ")}
+	i++
+
+	if i < len(fields) {
+		if issued, ok := tacDayTime(fields[i], ref); ok {
+			taf.IssuedAt = issued
+			i++
+		}
+	}
+	if i < len(fields) && fields[i] == "NIL" {
+		return nil, fmt.Errorf("TAF %s is NIL", taf.Station)
+	}
+	if i >= len(fields) {
+		return nil, fmt.Errorf("TAF %s has no validity period", taf.Station)
+	}
+	from, to, ok := tacPeriod(fields[i], ref)
+	if !ok {
+		return nil, fmt.Errorf("TAF %s has no validity period: %q", taf.Station, fields[i])
+	}
+	taf.ValidFrom, taf.ValidTo = from, to
+	i++
+
+	current := &taf.Base
+	for ; i < len(fields); i++ {
+		field := fields[i]
+		switch {
+		case field == "RMK":
+			i = len(fields)
+			continue
+		case field == "CNL":
+			return nil, fmt.Errorf("TAF %s is cancelled", taf.Station)
+		case tacFromRe.MatchString(field):
+			match := tacFromRe.FindStringSubmatch(field)
+			at := tacResolve(ref, atoi(match[1]), atoi(match[2]), atoi(match[3]))
+			taf.Changes = append(taf.Changes, TafChange{Kind: "FM", From: at})
+			current = &taf.Changes[len(taf.Changes)-1].ReportedConditions
+		case field == "BECMG" || field == "TEMPO" || tacProbRe.MatchString(field):
+			change := TafChange{Kind: field}
+			if match := tacProbRe.FindStringSubmatch(field); match != nil {
+				change.Kind = "PROB"
+				change.Probability = atoi(match[1])
+				if i+1 < len(fields) && fields[i+1] == "TEMPO" {
+					change.Kind = "TEMPO"
+					i++
+				}
+			}
+			if i+1 >= len(fields) {
+				return nil, fmt.Errorf("TAF %s: %s group has no period", taf.Station, change.Kind)
+			}
+			from, to, ok := tacPeriod(fields[i+1], ref)
+			if !ok {
+				return nil, fmt.Errorf("TAF %s: %s group has no period: %q", taf.Station, change.Kind, fields[i+1])
+			}
+			i++
+			change.From, change.To = from, to
+			taf.Changes = append(taf.Changes, change)
+			current = &taf.Changes[len(taf.Changes)-1].ReportedConditions
+		default:
+			// Temperature forecasts (TX/TN) and anything unknown fall through here.
+			current.parseGroup(field)
+		}
+	}
+
+	// An FM group runs until the next one, or to the end of the forecast.
+	for j := range taf.Changes {
+		if taf.Changes[j].Kind != "FM" {
+			continue
+		}
+		taf.Changes[j].To = taf.ValidTo
+		for k := j + 1; k < len(taf.Changes); k++ {
+			if taf.Changes[k].Kind == "FM" {
+				taf.Changes[j].To = taf.Changes[k].From
+				break
+			}
+		}
+	}
+
+	return taf, nil
+}
+
+// parseGroup decodes one group into c and reports whether it was one it knows.
+func (c *ReportedConditions) parseGroup(field string) bool {
+	switch {
+	case field == "CAVOK":
+		c.CAVOK = true
+		c.VisibilityM = ptrTo(10000)
+		c.NoSignificantCloud = true
+	case field == "NSC" || field == "SKC" || field == "CLR" || field == "NCD":
+		c.NoSignificantCloud = true
+	case field == "NSW":
+		c.NoSignificantWeather = true
+	case tacWindRe.MatchString(field):
+		match := tacWindRe.FindStringSubmatch(field)
+		wind := &ReportedWind{SpeedKT: float64(atoi(match[2]))}
+		if match[1] != "VRB" {
+			wind.DirectionDeg = ptrTo(atoi(match[1]))
+		}
+		if match[3] != "" {
+			gust := float64(atoi(match[3]))
+			wind.GustKT = &gust
+		}
+		if match[4] == "MPS" {
+			wind.SpeedKT *= knotsPerMPS
+			if wind.GustKT != nil {
+				*wind.GustKT *= knotsPerMPS
+			}
+		}
+		c.Wind = wind
+	case tacWindVarRe.MatchString(field):
+		if c.Wind == nil {
+			return false
+		}
+		match := tacWindVarRe.FindStringSubmatch(field)
+		c.Wind.VariableFromDeg = ptrTo(atoi(match[1]))
+		c.Wind.VariableToDeg = ptrTo(atoi(match[2]))
+	case tacVisibilityRe.MatchString(field):
+		metres := atoi(tacVisibilityRe.FindStringSubmatch(field)[1])
+		if metres == 9999 {
+			metres = 10000
+		}
+		c.VisibilityM = &metres
+	case tacMinimumRe.MatchString(field):
+		match := tacMinimumRe.FindStringSubmatch(field)
+		c.MinimumVisibilityM = ptrTo(atoi(match[1]))
+		c.MinimumVisibilityDirection = match[2]
+	case tacStatuteRe.MatchString(field):
+		match := tacStatuteRe.FindStringSubmatch(field)
+		miles := float64(atoi(match[2]))
+		if match[3] != "" {
+			miles /= float64(atoi(match[3]))
+		}
+		metres := int(miles * metresPerStatuteMile)
+		c.VisibilityM = &metres
+	case tacCloudRe.MatchString(field):
+		match := tacCloudRe.FindStringSubmatch(field)
+		cloud := ReportedCloud{Cover: match[1], BaseFeet: tacHeight(match[2])}
+		if match[3] != "///" {
+			cloud.Type = match[3]
+		}
+		c.addCloud(cloud)
+	case tacVerticalRe.MatchString(field):
+		c.addCloud(ReportedCloud{Cover: "VV", BaseFeet: tacHeight(tacVerticalRe.FindStringSubmatch(field)[1])})
+	case tacWeatherRe.MatchString(field):
+		match := tacWeatherRe.FindStringSubmatch(field)
+		// The pattern matches an empty group and a bare intensity; neither is weather.
+		if match[2] == "" && match[3] == "" {
+			return false
+		}
+		c.Weather = append(c.Weather, field)
+	default:
+		return false
+	}
+	return true
+}
+
+// addCloud records a layer and lowers the ceiling if it is one.
+func (c *ReportedConditions) addCloud(cloud ReportedCloud) {
+	c.Clouds = append(c.Clouds, cloud)
+	if cloud.BaseFeet == nil || !(cloud.Cover == "BKN" || cloud.Cover == "OVC" || cloud.Cover == "VV") {
+		return
+	}
+	if c.CeilingFeet == nil || *cloud.BaseFeet < *c.CeilingFeet {
+		c.CeilingFeet = ptrTo(*cloud.BaseFeet)
+	}
+}
+
+const (
+	knotsPerMPS          = 1.94384
+	metresPerStatuteMile = 1609.344
+)
+
+// tacHeight decodes a three-digit height in hundreds of feet; /// is unknown.
+func tacHeight(s string) *int {
+	if s == "///" {
+		return nil
+	}
+	return ptrTo(atoi(s) * 100)
+}
+
+// tacTemperature decodes "12" or "M03". An empty or missing value is unknown.
+func tacTemperature(s string) *float64 {
+	if s == "" {
+		return nil
+	}
+	negative := strings.HasPrefix(s, "M")
+	v := float64(atoi(strings.TrimPrefix(s, "M")))
+	if negative {
+		v = -v
+	}
+	return &v
+}
+
+// tacDayTime decodes a ddhhmmZ group.
+func tacDayTime(field string, ref time.Time) (time.Time, bool) {
+	match := tacDayTimeRe.FindStringSubmatch(field)
+	if match == nil {
+		return time.Time{}, false
+	}
+	return tacResolve(ref, atoi(match[1]), atoi(match[2]), atoi(match[3])), true
+}
+
+// tacPeriod decodes a ddhh/ddhh group. Hour 24 is allowed at the end and is midnight.
+func tacPeriod(field string, ref time.Time) (from, to time.Time, ok bool) {
+	match := tacPeriodRe.FindStringSubmatch(field)
+	if match == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	from = tacResolve(ref, atoi(match[1]), atoi(match[2]), 0)
+	to = tacResolve(ref, atoi(match[3]), atoi(match[4]), 0)
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// tacResolve places a day-of-month and time in the month that puts it nearest ref. Reports
+// are at most a day or two either side of now, so the 31st read on the 1st is last month's
+// -- or, on the 1st of March, January's: only months that have the day are candidates,
+// where time.Date would have rolled February the 31st over into March the 3rd. Hour 24
+// is midnight at the end of the day, so the day is checked first and the hour added after:
+// 3024 issued on the 30th of September is the 1st of October, not a day September lacks.
+func tacResolve(ref time.Time, day, hour, minute int) time.Time {
+	ref = ref.UTC()
+	endOfDay := hour == 24
+	if endOfDay {
+		hour = 0
+	}
+	var nearest time.Time
+	for offset := -2; offset <= 1; offset++ {
+		first := time.Date(ref.Year(), ref.Month()+time.Month(offset), 1, hour, minute, 0, 0, time.UTC)
+		candidate := first.AddDate(0, 0, day-1)
+		if candidate.Month() != first.Month() {
+			continue
+		}
+		if endOfDay {
+			candidate = candidate.AddDate(0, 0, 1)
+		}
+		if nearest.IsZero() || candidate.Sub(ref).Abs() < nearest.Sub(ref).Abs() {
+			nearest = candidate
+		}
+	}
+	return nearest
+}
+
+// atoi is strconv.Atoi for strings a regexp has already confirmed are digits.
+func atoi(s string) int {
+	v, _ := strconv.Atoi(s)
+	return v
+}
+
+func ptrTo[T any](v T) *T { return &v }
+
+// splitReports breaks a bulletin into reports: one per line, with indented lines
+// continuing the report above them -- the layout a multi-line TAF is usually printed in.
+// Blank lines are separators.
+func splitReports(text string) []string {
+	var reports []string
+	var current strings.Builder
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			reports = append(reports, s)
+		}
+		current.Reset()
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case line[0] == ' ' || line[0] == '\t':
+			current.WriteString(" ")
+			current.WriteString(strings.TrimSpace(line))
+		default:
+			flush()
+			current.WriteString(line)
+		}
+	}
+	flush()
+	return reports
+}
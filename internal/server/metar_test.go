@@ -0,0 +1,296 @@
+package server
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+// metarRef is "now" for the fixtures in testdata/observations: a little after the last of
+// the reports in them was issued.
+var metarRef = time.Date(2026, 10, 17, 14, 40, 0, 0, time.UTC)
+
+func observationFixture(t *testing.T, kind reportKind) []string {
+	t.Helper()
+
+	raw, err := os.ReadFile("testdata/observations/" + string(kind) + ".txt")
+	if err != nil {
+		t.Fatalf("failed to read the %s fixture: %v", kind, err)
+	}
+	return splitReports(string(raw))
+}
+
+func TestParseMETAR_ReadsTheGroupsScoringNeeds(t *testing.T) {
+	m, err := parseMETAR(observationFixture(t, metarReports)[0], metarRef)
+	if err != nil {
+		t.Fatalf("parseMETAR() = %v", err)
+	}
+
+	if m.Station != "EDDG" || !m.ObservedAt.Equal(time.Date(2026, 10, 17, 14, 20, 0, 0, time.UTC)) {
+		t.Errorf("station, time = %s, %v", m.Station, m.ObservedAt)
+	}
+	if m.Wind == nil || *m.Wind.DirectionDeg != 240 || m.Wind.SpeedKT != 12 || *m.Wind.GustKT != 24 {
+		t.Errorf("wind = %+v, want 240/12G24", m.Wind)
+	}
+	if m.Wind.VariableFromDeg == nil || *m.Wind.VariableFromDeg != 210 || *m.Wind.VariableToDeg != 280 {
+		t.Errorf("variable wind = %v-%v, want 210-280", m.Wind.VariableFromDeg, m.Wind.VariableToDeg)
+	}
+	if m.VisibilityM == nil || *m.VisibilityM != 10000 {
+		t.Errorf("visibility = %v, want 9999 read as 10000", m.VisibilityM)
+	}
+	if len(m.Weather) != 1 || m.Weather[0] != "-SHRA" {
+		t.Errorf("weather = %v, want [-SHRA]", m.Weather)
+	}
+	if len(m.Clouds) != 2 || m.Clouds[1].Type != "CB" {
+		t.Errorf("clouds = %+v, want FEW018 and BKN025CB", m.Clouds)
+	}
+	// FEW does not make a ceiling; the BKN above it does.
+	if m.CeilingFeet == nil || *m.CeilingFeet != 2500 {
+		t.Errorf("ceiling = %v, want 2500", m.CeilingFeet)
+	}
+	if *m.TemperatureC != 14 || *m.DewPointC != 9 || *m.QNHHPa != 1008 {
+		t.Errorf("temperature, dew point, QNH = %v, %v, %v", *m.TemperatureC, *m.DewPointC, *m.QNHHPa)
+	}
+	// The trend is passed through, and the remark after it is not part of it.
+	if m.Trend != "TEMPO SHRA BKN012" {
+		t.Errorf("trend = %q, want %q", m.Trend, "TEMPO SHRA BKN012")
+	}
+}
+
+// A trend group reads like the report in front of it. If it were decoded into the report,
+// the TEMPO's BKN012 would become the observed ceiling.
+func TestParseMETAR_TrendDoesNotOverwriteTheObservation(t *testing.T) {
+	m, err := parseMETAR(observationFixture(t, metarReports)[0], metarRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *m.CeilingFeet != 2500 || len(m.Weather) != 1 {
+		t.Errorf("ceiling = %v, weather = %v: the trend leaked into the observation", *m.CeilingFeet, m.Weather)
+	}
+}
+
+func TestParseMETAR_Variants(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		check func(t *testing.T, m *Metar)
+	}{
+		{"CAVOK", "METAR EDDW 171420Z AUTO 18006KT CAVOK 15/07 Q1010", func(t *testing.T, m *Metar) {
+			if !m.Auto || !m.CAVOK || !m.NoSignificantCloud || *m.VisibilityM != 10000 || m.CeilingFeet != nil {
+				t.Errorf("got %+v, want AUTO, CAVOK, 10000m and no ceiling", m)
+			}
+		}},
+		{"fog, vertical visibility and minus temperatures", "METAR EHTW 171425Z VRB02KT 0800 R05/1200N FG VV002 M01/M01 Q1021", func(t *testing.T, m *Metar) {
+			if m.Wind.DirectionDeg != nil || m.Wind.SpeedKT != 2 {
+				t.Errorf("wind = %+v, want VRB02", m.Wind)
+			}
+			if *m.VisibilityM != 800 || *m.CeilingFeet != 200 || *m.TemperatureC != -1 {
+				t.Errorf("visibility, ceiling, temperature = %v, %v, %v, want 800, 200, -1", *m.VisibilityM, *m.CeilingFeet, *m.TemperatureC)
+			}
+			if len(m.Weather) != 1 || m.Weather[0] != "FG" {
+				t.Errorf("weather = %v, want [FG] -- the RVR group is not weather", m.Weather)
+			}
+		}},
+		{"a directional minimum after the prevailing visibility", "METAR EDDG 171420Z 24012KT 8000 4000NE BKN012 14/13 Q1008", func(t *testing.T, m *Metar) {
+			if *m.VisibilityM != 8000 {
+				t.Errorf("visibility = %v, want the prevailing 8000, not the minimum", *m.VisibilityM)
+			}
+			if m.MinimumVisibilityM == nil || *m.MinimumVisibilityM != 4000 || m.MinimumVisibilityDirection != "NE" {
+				t.Errorf("minimum = %v %q, want 4000 NE", m.MinimumVisibilityM, m.MinimumVisibilityDirection)
+			}
+		}},
+		{"statute miles and inches of mercury", "METAR KJFK 171451Z 31015KT 10SM FEW050 12/M02 A2992 RMK AO2", func(t *testing.T, m *Metar) {
+			if *m.VisibilityM != 16093 {
+				t.Errorf("visibility = %v, want 16093", *m.VisibilityM)
+			}
+			if math.Abs(*m.QNHHPa-1013.2) > 0.1 {
+				t.Errorf("QNH = %v, want 1013.2", *m.QNHHPa)
+			}
+		}},
+		{"wind in metres per second", "METAR UUEE 171430Z 27005MPS 9999 SCT030 08/02 Q1015", func(t *testing.T, m *Metar) {
+			if math.Abs(m.Wind.SpeedKT-9.7) > 0.1 {
+				t.Errorf("wind = %v kt, want 9.7", m.Wind.SpeedKT)
+			}
+		}},
+		{"a terminating equals sign", "EDDG 171420Z 24012KT 9999 OVC004 14/13 Q1008=", func(t *testing.T, m *Metar) {
+			if *m.QNHHPa != 1008 || *m.CeilingFeet != 400 {
+				t.Errorf("QNH, ceiling = %v, %v", *m.QNHHPa, *m.CeilingFeet)
+			}
+		}},
+		{"a layer of unknown height", "METAR EDDG 171420Z AUTO 24012KT 9999 BKN/// 14/09 Q1008", func(t *testing.T, m *Metar) {
+			if len(m.Clouds) != 1 || m.Clouds[0].BaseFeet != nil || m.CeilingFeet != nil {
+				t.Errorf("clouds = %+v, ceiling = %v, want a layer without a height and no ceiling", m.Clouds, m.CeilingFeet)
+			}
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := parseMETAR(tc.raw, metarRef)
+			if err != nil {
+				t.Fatalf("parseMETAR() = %v", err)
+			}
+			tc.check(t, m)
+		})
+	}
+}
+
+func TestParseMETAR_Rejects(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"METAR EHGG 171425Z NIL",
+		"METAR 171425Z 24012KT 9999",
+		"METAR EDDG 24012KT 9999",
+	} {
+		if m, err := parseMETAR(raw, metarRef); err == nil {
+			t.Errorf("parseMETAR(%q) = %+v, want an error", raw, m)
+		}
+	}
+}
+
+func TestParseTAF_ReadsTheChangeGroups(t *testing.T) {
+	taf, err := parseTAF(observationFixture(t, tafReports)[0], metarRef)
+	if err != nil {
+		t.Fatalf("parseTAF() = %v", err)
+	}
+
+	if !taf.ValidFrom.Equal(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)) || !taf.ValidTo.Equal(time.Date(2026, 10, 18, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("validity = %v to %v", taf.ValidFrom, taf.ValidTo)
+	}
+	if taf.Base.Wind == nil || taf.Base.Wind.SpeedKT != 12 || taf.Base.CeilingFeet != nil {
+		t.Errorf("base = %+v, want 24012KT with no ceiling", taf.Base)
+	}
+
+	kinds := make([]string, len(taf.Changes))
+	for i, c := range taf.Changes {
+		kinds[i] = c.Kind
+	}
+	want := []string{"TEMPO", "TEMPO", "BECMG", "FM", "FM"}
+	if len(kinds) != len(want) {
+		t.Fatalf("changes = %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("changes = %v, want %v", kinds, want)
+		}
+	}
+
+	if got := taf.Changes[1]; got.Probability != 30 || len(got.Clouds) != 1 || got.Clouds[0].Type != "CB" {
+		t.Errorf("PROB30 TEMPO = %+v, want probability 30 and a CB", got)
+	}
+	if got := taf.Changes[0]; *got.CeilingFeet != 1200 || *got.Wind.GustKT != 30 {
+		t.Errorf("TEMPO = %+v, want BKN012 and gusts of 30", got)
+	}
+
+	// An FM group runs to the next FM, the last to the end of validity.
+	first, last := taf.Changes[3], taf.Changes[4]
+	if !first.To.Equal(last.From) || !last.To.Equal(taf.ValidTo) {
+		t.Errorf("FM periods = %v-%v and %v-%v", first.From, first.To, last.From, last.To)
+	}
+	if !first.CAVOK || *last.CeilingFeet != 800 {
+		t.Errorf("FM groups = %+v and %+v", first, last)
+	}
+}
+
+func TestParseTAF_Variants(t *testing.T) {
+	amended, err := parseTAF("TAF AMD EDDW 171130Z 1712/1818 20008KT 9999 FEW030 NSW", metarRef)
+	if err != nil || amended.Station != "EDDW" || !amended.Base.NoSignificantWeather {
+		t.Errorf("amended TAF = %+v, %v", amended, err)
+	}
+
+	// Hour 24 closes a period at midnight.
+	midnight, err := parseTAF("TAF EDDG 170500Z 1706/1724 24012KT 9999 SCT025", metarRef)
+	if err != nil || !midnight.ValidTo.Equal(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("validity to = %v, %v, want midnight", midnight.ValidTo, err)
+	}
+
+	for _, raw := range []string{
+		"TAF EHTW 171100Z 1712/1812 CNL",
+		"TAF EDDG 171100Z NIL",
+		"TAF EDDG 171100Z 24012KT 9999",
+		"TAF EDDG 171100Z 1712/1818 24012KT TEMPO 4000",
+	} {
+		if taf, err := parseTAF(raw, metarRef); err == nil {
+			t.Errorf("parseTAF(%q) = %+v, want an error", raw, taf)
+		}
+	}
+}
+
+// A report carries only the day of the month; the month is whichever puts it nearest now.
+func TestTACResolve_AcrossAMonthBoundary(t *testing.T) {
+	tests := []struct {
+		ref  time.Time
+		day  int
+		want time.Time
+	}{
+		{time.Date(2026, 11, 1, 0, 10, 0, 0, time.UTC), 31, time.Date(2026, 10, 31, 23, 50, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC), 1, time.Date(2026, 11, 1, 23, 50, 0, 0, time.UTC)},
+		{time.Date(2027, 1, 1, 0, 10, 0, 0, time.UTC), 31, time.Date(2026, 12, 31, 23, 50, 0, 0, time.UTC)},
+		// February has no 31st: the day read on the 1st of March is January's, not the 3rd of
+		// March time.Date would roll it over to.
+		{time.Date(2026, 3, 1, 0, 10, 0, 0, time.UTC), 31, time.Date(2026, 1, 31, 23, 50, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		if got := tacResolve(tc.ref, tc.day, 23, 50); !got.Equal(tc.want) {
+			t.Errorf("tacResolve(%v, %d) = %v, want %v", tc.ref, tc.day, got, tc.want)
+		}
+	}
+}
+
+// A period ending at hour 24 on the last day of a month ends at midnight in the next one.
+func TestParseTAF_PeriodEndingAtTheEndOfAMonth(t *testing.T) {
+	tests := []struct {
+		raw      string
+		ref      time.Time
+		from, to time.Time
+	}{
+		{
+			"TAF EDDH 301100Z 3012/3024 24012KT 9999 SCT025",
+			time.Date(2026, 9, 30, 11, 0, 0, 0, time.UTC),
+			time.Date(2026, 9, 30, 12, 0, 0, 0, time.UTC), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"TAF EDDH 311100Z 3112/3124 24012KT 9999 SCT025",
+			time.Date(2026, 10, 31, 11, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 31, 12, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"TAF EDDH 281100Z 2812/2824 24012KT 9999 SCT025",
+			time.Date(2026, 2, 28, 11, 0, 0, 0, time.UTC),
+			time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"TAF EDDH 311100Z 3112/3124 24012KT 9999 SCT025",
+			time.Date(2026, 12, 31, 11, 0, 0, 0, time.UTC),
+			time.Date(2026, 12, 31, 12, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		// Read just after the year turned, the period is still last year's.
+		{
+			"TAF EDDH 311100Z 3112/3124 24012KT 9999 SCT025",
+			time.Date(2027, 1, 1, 0, 30, 0, 0, time.UTC),
+			time.Date(2026, 12, 31, 12, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tc := range tests {
+		taf, err := parseTAF(tc.raw, tc.ref)
+		if err != nil {
+			t.Errorf("parseTAF(%q) at %v = %v", tc.raw, tc.ref, err)
+			continue
+		}
+		if !taf.ValidFrom.Equal(tc.from) || !taf.ValidTo.Equal(tc.to) {
+			t.Errorf("parseTAF(%q) at %v: validity = %v to %v, want %v to %v", tc.raw, tc.ref, taf.ValidFrom, taf.ValidTo, tc.from, tc.to)
+		}
+	}
+}
+
+func TestSplitReports_JoinsIndentedLines(t *testing.T) {
+	reports := observationFixture(t, tafReports)
+	if len(reports) != 3 {
+		t.Fatalf("got %d reports, want 3", len(reports))
+	}
+	fields := tacFields(reports[0])
+	if got := fields[len(fields)-1]; got != "OVC008" {
+		t.Errorf("the first TAF ends in %q, want its continuation lines joined on up to OVC008", got)
+	}
+}
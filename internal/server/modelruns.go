@@ -25,16 +25,8 @@ import (
 // built to degrade: a failure keeps the last known runs, leaves the cache alone, and lets
 // the backstop TTL in weather.go do what the clock used to do.
 
-// The models behind `models=icon_seamless`, in the order they cover the forecast: D2 for
-// the first ~46 hours, then EU, then global for the tail.
-var modelRunSources = []struct {
-	name string // as reported to the frontend
-	url  string
-}{
-	{"icon_d2", "https://api.open-meteo.com/data/dwd_icon_d2/static/meta.json"},
-	{"icon_eu", "https://api.open-meteo.com/data/dwd_icon_eu/static/meta.json"},
-	{"icon_global", "https://api.open-meteo.com/data/dwd_icon/static/meta.json"},
-}
+// Which metadata documents are polled follows from the configured models: see
+// modelRunSources in models.go.
 
 const (
 	// Long enough to be a rounding error against a three-hour model cycle, short enough
@@ -90,10 +82,26 @@ func (t *modelRunTracker) snapshot() (runs []ModelRun, degraded bool) {
 	return slices.Clone(t.runs), t.consecutiveFails >= modelRunFailuresBeforeDegraded
 }
 
-// latestInitializedAt returns the newest initialization time across the models, which is the one the
-// frontend labels the forecast with.
+// runsFor returns the known runs of one forecast model, which are the runs a payload
+// fetched from that model was built from. Cloned, as snapshot is.
+func (t *modelRunTracker) runsFor(model forecastModel) []ModelRun {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	var runs []ModelRun
+	for _, run := range t.runs {
+		if model.owns(run) {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// latestInitializedAt returns the newest initialization time across the primary model's
+// runs, which is the one the frontend labels the forecast with. A comparison model's runs
+// are not the forecast on screen, however recent.
 //
-// That is the D2 run, and it is the optimistic reading: D2 only covers the first ~46 hours,
+// For ICON that is the D2 run, and it is the optimistic reading: D2 only covers the first ~46 hours,
 // so the tail of the chart comes from EU and global runs that are three and six hours
 // older. It is the right number for the part of the forecast anyone is actually looking at,
 // and the full set is on the wire for anything that wants to be more careful.
@@ -103,6 +111,9 @@ func (t *modelRunTracker) latestInitializedAt() time.Time {
 
 	var latest time.Time
 	for _, run := range t.runs {
+		if !primaryModel().owns(run) {
+			continue
+		}
 		if run.InitializedAt.After(latest) {
 			latest = run.InitializedAt
 		}
@@ -110,13 +121,13 @@ func (t *modelRunTracker) latestInitializedAt() time.Time {
 	return latest
 }
 
-// poll fetches every model's metadata and reports whether any of them announced a run that
-// was not there before.
+// poll fetches every model's metadata and returns the forecast models that announced a
+// run that was not there before, primary first. Nil means nothing changed.
 //
 // A partial failure is still worth acting on: if D2 has a new run and EU is unreachable,
 // the forecast has changed and should be refetched. Only a total failure counts against the
 // degraded threshold, because anything less means the mechanism is working.
-func (t *modelRunTracker) poll(ctx context.Context) (changed bool) {
+func (t *modelRunTracker) poll(ctx context.Context) (changed []string) {
 	fetched := make([]ModelRun, 0, len(modelRunSources))
 	var failures int
 
@@ -140,7 +151,7 @@ func (t *modelRunTracker) poll(ctx context.Context) (changed bool) {
 
 	if failures == len(modelRunSources) {
 		t.consecutiveFails++
-		return false
+		return nil
 	}
 	t.consecutiveFails = 0
 	t.lastSuccess = time.Now()
@@ -150,11 +161,12 @@ func (t *modelRunTracker) poll(ctx context.Context) (changed bool) {
 		previous[run.Model] = run.AvailableAt
 	}
 
+	advanced := make(map[string]bool)
 	for _, run := range fetched {
 		// Inequality rather than After: a run time that appears to move backwards means
 		// something upstream changed, and refetching is the safe response to that.
 		if was, seen := previous[run.Model]; !seen || !was.Equal(run.AvailableAt) {
-			changed = true
+			advanced[run.Model] = true
 			slog.Info("new model run",
 				"model", run.Model,
 				"initialized", run.InitializedAt.Format(time.RFC3339),
@@ -183,6 +195,11 @@ func (t *modelRunTracker) poll(ctx context.Context) (changed bool) {
 	}
 	t.runs = updated
 
+	for _, model := range forecastModels {
+		if slices.ContainsFunc(model.runs, func(source modelRunSource) bool { return advanced[source.name] }) {
+			changed = append(changed, model.ID)
+		}
+	}
 	return changed
 }
 
@@ -210,13 +227,19 @@ func fetchModelRunMeta(ctx context.Context, url string) (*modelRunMeta, error) {
 	return &meta, nil
 }
 
-// watchModelRuns polls until ctx is cancelled, invalidating the weather cache whenever a
-// model announces a new run.
+// watchModelRuns polls until ctx is cancelled, invalidating a model's cached payloads
+// whenever it announces a new run.
+//
+// Invalidation is per model and otherwise wholesale, because model runs are global: a new
+// run makes every airport's entry from that model stale at the same instant. Only the default
+// airport is re-warmed, under the default profile and the primary model, for the same reason
+// it is the only one warmed at startup -- refetching thirteen airfields nobody is looking at
+// is exactly the waste this change exists to remove.
 //
-// Invalidation is wholesale because model runs are global: a new run makes every airport's
-// entry stale at the same instant. Only the default airport is re-warmed, for the same
-// reason it is the only one warmed at startup -- refetching thirteen airfields nobody is
-// looking at is exactly the waste this change exists to remove.
+// Subscriptions are the exception: their windows are re-scored against the new run, which
+// fetches the airfields they name. That is what they are for. They are scored on the
+// primary model, so only its runs trigger them, and by watchSubscriptions rather than
+// here: two hundred fetches and sends would hold up the next poll.
 func watchModelRuns(ctx context.Context) {
 	ticker := time.NewTicker(modelRunPollInterval)
 	defer ticker.Stop()
@@ -226,11 +249,16 @@ func watchModelRuns(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if modelRuns.poll(ctx) {
-				cache.invalidateAll()
+			changed := modelRuns.poll(ctx)
+			if len(changed) == 0 {
+				continue
+			}
+			cache.invalidateModels(changed)
+			if slices.Contains(changed, primaryModel().ID) {
 				slog.Info("model runs advanced, refreshing the default airport",
 					"airport", defaultAirport.Identifier)
-				_, _ = GetWeatherData(ctx, defaultAirport)
+				_, _ = GetWeatherData(ctx, defaultAirport, defaultProfile())
+				subscriptions.requestCheck()
 			}
 		}
 	}
@@ -43,8 +43,8 @@ func TestModelRuns_FirstPollReportsChange(t *testing.T) {
 		return metaAt(1786082400), nil
 	})
 
-	if !modelRuns.poll(context.Background()) {
-		t.Error("poll() = false on the first poll, want true")
+	if len(modelRuns.poll(context.Background())) == 0 {
+		t.Error("poll() reported no change on the first poll")
 	}
 
 	runs, degraded := modelRuns.snapshot()
@@ -64,8 +64,8 @@ func TestModelRuns_UnchangedPollReportsNoChange(t *testing.T) {
 
 	modelRuns.poll(context.Background())
 
-	if modelRuns.poll(context.Background()) {
-		t.Error("poll() = true for an unchanged run, want false")
+	if len(modelRuns.poll(context.Background())) > 0 {
+		t.Error("poll() reported a change for an unchanged run")
 	}
 }
 
@@ -79,8 +79,8 @@ func TestModelRuns_NewRunReportsChange(t *testing.T) {
 
 	// Three hours later, the next D2/EU cycle.
 	initialized += 3 * 3600
-	if !modelRuns.poll(context.Background()) {
-		t.Error("poll() = false after the run advanced, want true")
+	if len(modelRuns.poll(context.Background())) == 0 {
+		t.Error("poll() reported no change after the run advanced")
 	}
 
 	if got, want := modelRuns.latestInitializedAt(), time.Unix(initialized, 0).UTC(); !got.Equal(want) {
@@ -99,8 +99,8 @@ func TestModelRuns_RunMovingBackwardsStillCountsAsChange(t *testing.T) {
 	modelRuns.poll(context.Background())
 
 	initialized -= 3 * 3600
-	if !modelRuns.poll(context.Background()) {
-		t.Error("poll() = false after the run moved backwards, want true")
+	if len(modelRuns.poll(context.Background())) == 0 {
+		t.Error("poll() reported no change after the run moved backwards")
 	}
 }
 
@@ -111,8 +111,8 @@ func TestModelRuns_TotalFailureReportsNoChangeAndDegrades(t *testing.T) {
 		return nil, errors.New("metadata unreachable")
 	})
 
-	if modelRuns.poll(context.Background()) {
-		t.Error("poll() = true when every model failed, want false")
+	if len(modelRuns.poll(context.Background())) > 0 {
+		t.Error("poll() reported a change when every model failed")
 	}
 	if _, degraded := modelRuns.snapshot(); degraded {
 		t.Error("degraded = true after a single failure — one blip is not a pattern")
@@ -163,8 +163,8 @@ func TestModelRuns_PartialFailureKeepsTheLastKnownRun(t *testing.T) {
 
 	failing = modelRunSources[1].url
 	initialized += 3 * 3600
-	if !modelRuns.poll(context.Background()) {
-		t.Error("poll() = false, want true — the models that answered had a new run")
+	if len(modelRuns.poll(context.Background())) == 0 {
+		t.Error("poll() reported no change — the models that answered had a new run")
 	}
 
 	runs, degraded := modelRuns.snapshot()
@@ -320,20 +320,20 @@ func TestGetWeatherData_BackstopRefetchesBeyondTheTTL(t *testing.T) {
 	withTestAirports(t)
 
 	var fetches int
-	stubFetchWeather(t, func(context.Context, Airport) (*ProcessedWeatherData, error) {
+	stubFetchWeather(t, func(context.Context, Airport, *scoringProfile) (*ProcessedWeatherData, error) {
 		fetches++
 		return &ProcessedWeatherData{GeneratedAt: time.Now()}, nil
 	})
 
 	cache.mutex.Lock()
 	stale := time.Now().Add(-2 * cacheDuration)
-	cache.entries[testAirport.Identifier] = &cacheEntry{
+	cache.entries[cacheKey(testAirport, defaultProfile())] = &cacheEntry{
 		data:      &ProcessedWeatherData{GeneratedAt: stale},
 		timestamp: stale,
 	}
 	cache.mutex.Unlock()
 
-	if _, err := GetWeatherData(context.Background(), testAirport); err != nil {
+	if _, err := GetWeatherData(context.Background(), testAirport, defaultProfile()); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if fetches != 1 {
@@ -342,7 +342,7 @@ func TestGetWeatherData_BackstopRefetchesBeyondTheTTL(t *testing.T) {
 
 	// And the freshly stored entry is inside the window again, so the next call is served
 	// from cache rather than refetching on every request.
-	if _, err := GetWeatherData(context.Background(), testAirport); err != nil {
+	if _, err := GetWeatherData(context.Background(), testAirport, defaultProfile()); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if fetches != 1 {
@@ -360,7 +360,7 @@ func TestProcessWeatherData_StampsTheModelRuns(t *testing.T) {
 	})
 	modelRuns.poll(context.Background())
 
-	got := processWeatherData(context.Background(), hourlyFixture([]string{"2026-08-03T12:00"}), testAirport)
+	got := processWeatherData(context.Background(), hourlyFixture([]string{"2026-08-03T12:00"}), testAirport, defaultProfile())
 
 	if len(got.ModelRuns) != len(modelRunSources) {
 		t.Fatalf("got %d model runs, want %d", len(got.ModelRuns), len(modelRunSources))
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Forecast models.
+//
+// The score has always come from one model, ICON, and one model can be confidently wrong.
+// When ECMWF and GFS put the same hour at 85 and ICON puts it at 20, that disagreement is
+// worth more to a pilot than any of the three numbers alone.
+//
+// FLUGWETTER_MODELS lists the models the server knows how to fetch, comma-separated, from
+// forecastModelCatalogue. The first is the primary: it is what /api/weather serves by
+// default, what the nowcast, the ensemble, the archive and the subscriptions are built on.
+// The rest are only fetched when asked for, by /api/weather?models=, and each is cached,
+// run-tracked and invalidated on its own -- a new GFS run says nothing about ICON's.
+const (
+	modelsEnv = "FLUGWETTER_MODELS"
+
+	// defaultModelID is what the query asked for before the list was configurable.
+	defaultModelID = "icon_seamless"
+)
+
+// forecastModel is one model the forecast can be fetched from.
+type forecastModel struct {
+	ID   string // Open-Meteo's models= value, and the name the API uses for it
+	Name string // for the UI: "ICON"
+
+	// ensemble is the models= value of the model's own ensemble on Open-Meteo's ensemble
+	// API, "" where the server scores none for it. See ensemble.go.
+	ensemble string
+
+	// runs are the models whose metadata announces this one's runs, in the order they
+	// cover the forecast. A seamless model is several, and a new run of any of them is a
+	// new forecast.
+	runs []modelRunSource
+}
+
+// modelRunSource is one metadata document: the name the run is reported under and where to
+// poll it.
+type modelRunSource struct {
+	name  string // as reported to the frontend
+	url   string
+	model string // the forecastModel ID the run belongs to
+}
+
+// forecastModelCatalogue is every model FLUGWETTER_MODELS may name.
+var forecastModelCatalogue = []forecastModel{
+	{
+		// D2 for the first ~46 hours, then EU, then global for the tail. The ensemble is
+		// ICON-EPS, seamless across D2-EPS, EU-EPS and global EPS in the same way.
+		ID: "icon_seamless", Name: "ICON", ensemble: "icon_seamless",
+		runs: []modelRunSource{
+			{"icon_d2", "https://api.open-meteo.com/data/dwd_icon_d2/static/meta.json", "icon_seamless"},
+			{"icon_eu", "https://api.open-meteo.com/data/dwd_icon_eu/static/meta.json", "icon_seamless"},
+			{"icon_global", "https://api.open-meteo.com/data/dwd_icon/static/meta.json", "icon_seamless"},
+		},
+	},
+	{
+		ID: "ecmwf_ifs025", Name: "ECMWF",
+		runs: []modelRunSource{
+			{"ecmwf_ifs025", "https://api.open-meteo.com/data/ecmwf_ifs025/static/meta.json", "ecmwf_ifs025"},
+		},
+	},
+	{
+		// The 13km global run for the first ten days, the 25km one past that.
+		ID: "gfs_seamless", Name: "GFS",
+		runs: []modelRunSource{
+			{"gfs013", "https://api.open-meteo.com/data/ncep_gfs013/static/meta.json", "gfs_seamless"},
+			{"gfs025", "https://api.open-meteo.com/data/ncep_gfs025/static/meta.json", "gfs_seamless"},
+		},
+	},
+}
+
+var (
+	// forecastModels are the configured models, primary first. Set once by
+	// configureModels before anything fetches, and read-only after.
+	forecastModels = []forecastModel{forecastModelCatalogue[0]}
+
+	// modelRunSources are the configured models' run sources, flattened in model order,
+	// which is the order the tracker polls and reports them in.
+	modelRunSources = slices.Clone(forecastModelCatalogue[0].runs)
+)
+
+// configureModels sets the model list from FLUGWETTER_MODELS's value. Empty means ICON
+// alone; an unknown or repeated model is an error, because a comparison silently missing
+// one is indistinguishable from one where it agreed.
+func configureModels(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		raw = defaultModelID
+	}
+
+	var models []forecastModel
+	for _, id := range strings.Split(raw, ",") {
+		model, err := catalogueModel(strings.TrimSpace(id))
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(models, func(m forecastModel) bool { return m.ID == model.ID }) {
+			return fmt.Errorf("model %q listed twice", model.ID)
+		}
+		models = append(models, model)
+	}
+
+	var sources []modelRunSource
+	for _, model := range models {
+		sources = append(sources, model.runs...)
+	}
+	forecastModels, modelRunSources = models, sources
+	return nil
+}
+
+// catalogueModel finds a model by ID among every model the server could fetch.
+func catalogueModel(id string) (forecastModel, error) {
+	for _, model := range forecastModelCatalogue {
+		if model.ID == id {
+			return model, nil
+		}
+	}
+	return forecastModel{}, fmt.Errorf("unknown forecast model %q", id)
+}
+
+// primaryModel is the model everything but the comparison is built on.
+func primaryModel() forecastModel {
+	return forecastModels[0]
+}
+
+// lookupModel finds a configured model by ID. Unlike lookupAirport it has no default to
+// fall back to: the caller asked for a specific model, and a different one in its place
+// would be a comparison with itself.
+func lookupModel(id string) (forecastModel, error) {
+	for _, model := range forecastModels {
+		if model.ID == id {
+			return model, nil
+		}
+	}
+	return forecastModel{}, fmt.Errorf("model %q is not configured", id)
+}
+
+// parseModels reads the models= query parameter: a comma-separated list of configured
+// models, each once.
+func parseModels(raw string) ([]forecastModel, error) {
+	var models []forecastModel
+	for _, id := range strings.Split(raw, ",") {
+		model, err := lookupModel(strings.TrimSpace(id))
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(models, func(m forecastModel) bool { return m.ID == model.ID }) {
+			return nil, fmt.Errorf("model %q requested twice", model.ID)
+		}
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+// isPrimary reports whether the model is the one the rest of the server is built on.
+func (m forecastModel) isPrimary() bool {
+	return m.ID == primaryModel().ID
+}
+
+// owns reports whether a tracked run belongs to this model.
+func (m forecastModel) owns(run ModelRun) bool {
+	return slices.ContainsFunc(m.runs, func(source modelRunSource) bool { return source.name == run.Model })
+}
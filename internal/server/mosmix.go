@@ -0,0 +1,406 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MOSMIX: DWD's station forecast, as a second opinion on cloud base and visibility.
+//
+// ICON's cloud base is the lowest pressure level with 40% cover, which at 1000/975/950hPa is
+// a coarse ladder, and its visibility is a model diagnostic that is noticeably optimistic in
+// mist. MOSMIX-L is DWD's statistical post-processing of the same models against years of
+// observations at one synoptic station: it knows Lingen fogs up on autumn mornings in a way
+// the raw model does not. Where an airfield sits close enough to a station to share its
+// weather, airports.json names the station, and its forecast feeds the two factors.
+//
+// FLUGWETTER_MOSMIX_MODE says how:
+//
+//   - blend (the default) takes whichever of the model and MOSMIX is worse for the hour --
+//     the lower cloud base, the lower visibility. Two forecasts that disagree about a
+//     ceiling are a reason to plan for the lower one.
+//   - replace takes MOSMIX wherever it has a value, and the model only where it has none.
+//
+// Either way the penalty says which source it was charged from, so "cloud base FL8 --
+// critical (MOSMIX)" reads differently from the same line from the model.
+//
+// It fails the way the ensemble does: a payload without MOSMIX is the payload there was
+// before. FLUGWETTER_MOSMIX_URL points it at a stand-in serving the same KMZ files, or
+// "off" turns it off.
+const (
+	mosmixURLEnv     = "FLUGWETTER_MOSMIX_URL"
+	mosmixModeEnv    = "FLUGWETTER_MOSMIX_MODE"
+	defaultMosmixURL = "https://opendata.dwd.de/weather/local_forecasts/mos/MOSMIX_L/single_stations"
+
+	// mosmixCacheTTL is how long one station's forecast is reused. MOSMIX-L is issued every
+	// six hours, and the same station is asked for once per profile on every warm.
+	mosmixCacheTTL = time.Hour
+
+	// mosmixCeilingCover is the cover, in percent, that makes a layer a ceiling -- the rule
+	// getCloudBase applies to the model's layers.
+	mosmixCeilingCover = 40
+
+	// mosmixBelow500ft is where N05, cover below 500ft, puts a base that H_BsC does not
+	// place lower, in metres above the station.
+	mosmixBelow500ft = 152.4
+)
+
+// Sources a penalty can be charged from, for the factors MOSMIX feeds. Unset means MOSMIX
+// was not consulted for the hour and there was only ever the one source.
+const (
+	factorSourceModel  = "model"
+	factorSourceMosmix = "mosmix"
+)
+
+// mosmixMode is how MOSMIX meets the model. See the comment at the top of the file.
+type mosmixMode string
+
+const (
+	mosmixBlend   mosmixMode = "blend"
+	mosmixReplace mosmixMode = "replace"
+)
+
+// mosmixSetting is the mode in use, set from the environment in Run.
+var mosmixSetting = mosmixBlend
+
+// mosmixModeFromEnv reads FLUGWETTER_MOSMIX_MODE. Anything but the two modes is an error:
+// a typo quietly falling back to blend would look like the setting had taken.
+func mosmixModeFromEnv() (mosmixMode, error) {
+	switch raw := os.Getenv(mosmixModeEnv); mosmixMode(raw) {
+	case "", mosmixBlend:
+		return mosmixBlend, nil
+	case mosmixReplace:
+		return mosmixReplace, nil
+	default:
+		return "", fmt.Errorf("%s=%q, want %q or %q", mosmixModeEnv, raw, mosmixBlend, mosmixReplace)
+	}
+}
+
+// mosmixStationPattern is what a DWD station ID looks like: five digits for a WMO station,
+// or a letter-and-digit code for DWD's own.
+var mosmixStationPattern = regexp.MustCompile(`^[A-Z0-9]{4,5}$`)
+
+// mosmixForecast is one station's forecast, reduced to what the two factors need.
+type mosmixForecast struct {
+	station   string
+	issued    time.Time
+	elevation float64 // station height above MSL, m
+	hours     map[string]mosmixHour
+}
+
+// mosmixHour is one hour of it, keyed by the payload's naive-UTC "2006-01-02T15:04".
+type mosmixHour struct {
+	// ceilingKnown reports whether MOSMIX has an opinion on the ceiling this hour; with it
+	// set, a nil cloudBaseFL means there is none.
+	ceilingKnown bool
+	cloudBaseFL  *int
+	visibilityKM *float64
+}
+
+// The KML, as DWD writes it. Only the elements read are declared; encoding/xml matches
+// them by local name, so the kml: and dwd: prefixes need not be spelled out.
+type mosmixKML struct {
+	Document struct {
+		Product struct {
+			IssueTime string   `xml:"IssueTime"`
+			TimeSteps []string `xml:"ForecastTimeSteps>TimeStep"`
+		} `xml:"ExtendedData>ProductDefinition"`
+		Placemarks []struct {
+			Name      string `xml:"name"`
+			Forecasts []struct {
+				Element string `xml:"elementName,attr"`
+				Value   string `xml:"value"`
+			} `xml:"ExtendedData>Forecast"`
+			Coordinates string `xml:"Point>coordinates"`
+		} `xml:"Placemark"`
+	} `xml:"Document"`
+}
+
+// latin1Reader decodes ISO-8859-1, which every MOSMIX file declares. encoding/xml refuses
+// any other declared encoding without a reader for it, and Latin-1 is the one charset that
+// maps byte for rune.
+func latin1Reader(charset string, input io.Reader) (io.Reader, error) {
+	if !strings.EqualFold(charset, "ISO-8859-1") {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	raw, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	decoded := make([]byte, 0, len(raw))
+	for _, b := range raw {
+		decoded = utf8.AppendRune(decoded, rune(b))
+	}
+	return bytes.NewReader(decoded), nil
+}
+
+// decodeMosmixKMZ unpacks a MOSMIX KMZ -- a zip holding one KML -- and decodes it.
+func decodeMosmixKMZ(body []byte, station string) (*mosmixForecast, error) {
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MOSMIX archive: %w", err)
+	}
+	for _, f := range archive.File {
+		if !strings.HasSuffix(strings.ToLower(f.Name), ".kml") {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		kml, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		return decodeMosmixKML(kml, station)
+	}
+	return nil, fmt.Errorf("MOSMIX archive holds no KML")
+}
+
+// decodeMosmixKML reads the station's placemark from a MOSMIX KML. A value of "-" is DWD's
+// missing value and leaves that hour without the element.
+func decodeMosmixKML(body []byte, station string) (*mosmixForecast, error) {
+	var doc mosmixKML
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = latin1Reader
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse MOSMIX KML: %w", err)
+	}
+
+	issued, err := time.Parse(time.RFC3339, doc.Document.Product.IssueTime)
+	if err != nil {
+		return nil, fmt.Errorf("MOSMIX KML has no issue time: %w", err)
+	}
+	steps := make([]string, len(doc.Document.Product.TimeSteps))
+	for i, raw := range doc.Document.Product.TimeSteps {
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("MOSMIX KML has a bad time step %q: %w", raw, err)
+		}
+		steps[i] = t.UTC().Format("2006-01-02T15:04")
+	}
+
+	for _, placemark := range doc.Document.Placemarks {
+		if strings.TrimSpace(placemark.Name) != station {
+			continue
+		}
+
+		// "lon,lat,elevation"; without the elevation a base above the station cannot be
+		// put on the model's flight-level scale.
+		coordinates := strings.Split(strings.TrimSpace(placemark.Coordinates), ",")
+		if len(coordinates) != 3 {
+			return nil, fmt.Errorf("MOSMIX station %s has coordinates %q, want lon,lat,elevation", station, placemark.Coordinates)
+		}
+		elevation, err := strconv.ParseFloat(coordinates[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("MOSMIX station %s has a bad elevation: %w", station, err)
+		}
+
+		elements := make(map[string][]*float64)
+		for _, f := range placemark.Forecasts {
+			fields := strings.Fields(f.Value)
+			values := make([]*float64, len(fields))
+			for i, field := range fields {
+				if field == "-" {
+					continue
+				}
+				v, err := strconv.ParseFloat(field, 64)
+				if err != nil {
+					return nil, fmt.Errorf("MOSMIX element %s has a bad value %q: %w", f.Element, field, err)
+				}
+				values[i] = &v
+			}
+			elements[f.Element] = values
+		}
+		at := func(element string, i int) *float64 {
+			if values := elements[element]; i < len(values) {
+				return values[i]
+			}
+			return nil
+		}
+
+		forecast := &mosmixForecast{station: station, issued: issued, elevation: elevation, hours: make(map[string]mosmixHour, len(steps))}
+		for i, step := range steps {
+			forecast.hours[step] = mosmixHourFrom(at("N", i), at("Nl", i), at("N05", i), at("H_BsC", i), at("VV", i), elevation)
+		}
+		return forecast, nil
+	}
+	return nil, fmt.Errorf("MOSMIX KML has no placemark for station %s", station)
+}
+
+// mosmixHourFrom turns one hour's elements into the two inputs.
+//
+// MOSMIX has no layered cloud like the model's pressure levels. What it has is cover --
+// total (N), low (Nl) and below 500ft (N05) -- and the base of the lowest cloud above the
+// station (H_BsC). The ceiling follows getCloudBase's 40% rule: little enough total cover
+// is no ceiling at all; enough low cover puts one at H_BsC, or at 500ft when N05 says it
+// is lower still. Anything between -- enough cover, but not low -- is cloud the model's
+// layers already place better, and MOSMIX is left without an opinion on the ceiling.
+func mosmixHourFrom(total, low, below500ft, base, visibility *float64, elevation float64) mosmixHour {
+	var hour mosmixHour
+
+	if visibility != nil {
+		km := *visibility / 1000
+		hour.visibilityKM = &km
+	}
+
+	switch {
+	case total != nil && *total < mosmixCeilingCover:
+		hour.ceilingKnown = true
+	case low != nil && *low >= mosmixCeilingCover:
+		aboveStation := math.Inf(1)
+		if base != nil {
+			aboveStation = *base
+		}
+		if below500ft != nil && *below500ft >= mosmixCeilingCover {
+			aboveStation = min(aboveStation, mosmixBelow500ft)
+		}
+		if math.IsInf(aboveStation, 1) {
+			break
+		}
+		// The model's bases are above MSL; so is this one, once the station is under it.
+		fl := int((aboveStation + elevation) * 3.28084 / 100)
+		hour.ceilingKnown, hour.cloudBaseFL = true, &fl
+	}
+	return hour
+}
+
+// mosmixURL is the KMZ for one station, or "" when MOSMIX is off.
+func mosmixURL(station string) string {
+	base := os.Getenv(mosmixURLEnv)
+	switch base {
+	case "off":
+		return ""
+	case "":
+		base = defaultMosmixURL
+	}
+	return fmt.Sprintf("%s/%s/kml/MOSMIX_L_LATEST_%s.kmz", strings.TrimSuffix(base, "/"), station, station)
+}
+
+// mosmixCache holds each station's forecast for mosmixCacheTTL.
+var mosmixCache = struct {
+	mutex   sync.Mutex
+	entries map[string]mosmixCacheEntry
+}{entries: make(map[string]mosmixCacheEntry)}
+
+type mosmixCacheEntry struct {
+	forecast  *mosmixForecast
+	fetchedAt time.Time
+}
+
+// fetchMosmixFn indirects fetchMosmix so tests can stub the network call.
+var fetchMosmixFn = fetchMosmix
+
+// fetchMosmix retrieves one station's forecast. A nil forecast and nil error mean MOSMIX
+// is off.
+func fetchMosmix(ctx context.Context, station string) (*mosmixForecast, error) {
+	query := mosmixURL(station)
+	if query == "" {
+		return nil, nil
+	}
+
+	mosmixCache.mutex.Lock()
+	entry, ok := mosmixCache.entries[query]
+	mosmixCache.mutex.Unlock()
+	if ok && time.Since(entry.fetchedAt) < mosmixCacheTTL {
+		return entry.forecast, nil
+	}
+
+	body, err := getJSON(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch MOSMIX: %w", err)
+	}
+	forecast, err := decodeMosmixKMZ(body, station)
+	if err != nil {
+		return nil, err
+	}
+
+	mosmixCache.mutex.Lock()
+	mosmixCache.entries[query] = mosmixCacheEntry{forecast: forecast, fetchedAt: time.Now()}
+	mosmixCache.mutex.Unlock()
+	return forecast, nil
+}
+
+// applyMosmix merges the station forecast into every hour it reaches and rescores those
+// hours. modelConditions carry the merged inputs from here on, so the nowcast, the ensemble
+// and the archive all start from the same cloud base and visibility the score did.
+func applyMosmix(data *ProcessedWeatherData, forecast *mosmixForecast, mode mosmixMode, profile *scoringProfile) {
+	if len(data.modelConditions) != len(data.VfrData) {
+		return
+	}
+	for i := range data.VfrData {
+		c := &data.modelConditions[i]
+		hour, ok := forecast.hours[data.VfrData[i].Time]
+		if !ok || c.time.IsZero() {
+			continue
+		}
+		c.mergeMosmix(hour, mode)
+
+		point := &data.VfrData[i]
+		point.Probability, point.Penalties, point.VisibilityKnown = scoreVFR(*c, profile.limits)
+	}
+}
+
+// mergeMosmix takes MOSMIX's cloud base and visibility where the mode says it wins, and
+// records which source each came from.
+func (c *conditions) mergeMosmix(hour mosmixHour, mode mosmixMode) {
+	if hour.ceilingKnown {
+		c.cloudBaseSource = factorSourceModel
+		if mode == mosmixReplace || flightLevelBelow(hour.cloudBaseFL, c.cloudBaseFL) {
+			c.cloudBaseFL, c.cloudBaseSource = hour.cloudBaseFL, factorSourceMosmix
+		}
+	}
+
+	if hour.visibilityKM != nil {
+		c.visibilitySource = factorSourceModel
+		if mode == mosmixReplace || c.visibilityKM == nil || *hour.visibilityKM < *c.visibilityKM {
+			c.visibilityKM, c.visibilitySource = hour.visibilityKM, factorSourceMosmix
+		}
+	}
+}
+
+// flightLevelBelow reports whether base a is strictly lower than b, nil being no ceiling.
+func flightLevelBelow(a, b *int) bool {
+	switch {
+	case a == nil:
+		return false
+	case b == nil:
+		return true
+	default:
+		return *a < *b
+	}
+}
+
+// mosmixFor fetches the airfield's station forecast for a fresh forecast to be scored
+// with, or logs why it could not and returns nil. Only listed airfields with a station get
+// one.
+func mosmixFor(ctx context.Context, airport Airport) *mosmixForecast {
+	listed, ok := airportsByID[airport.Identifier]
+	if !ok || listed.MosmixStation == "" {
+		return nil
+	}
+	forecast, err := fetchMosmixFn(ctx, listed.MosmixStation)
+	if err != nil {
+		slog.Warn("serving without MOSMIX", "airport", airport.Identifier, "station", listed.MosmixStation, "error", err)
+		return nil
+	}
+	if forecast != nil {
+		slog.Debug("MOSMIX fetched", "airport", airport.Identifier, "station", forecast.station,
+			"issued", forecast.issued, "mode", mosmixSetting)
+	}
+	return forecast
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// mosmixFixture is a MOSMIX-L KML for Lingen as DWD publishes it, trimmed to twelve hours:
+// fog until 08:00, lifting through 11:00, scattered from 13:00, and missing values at the
+// end.
+const mosmixFixture = "testdata/mosmix_10305.kml"
+
+func loadMosmixFixture(t *testing.T) *mosmixForecast {
+	t.Helper()
+
+	body, err := os.ReadFile(mosmixFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forecast, err := decodeMosmixKML(body, "10305")
+	if err != nil {
+		t.Fatalf("decodeMosmixKML() = %v", err)
+	}
+	return forecast
+}
+
+// mosmixKMZ packs the fixture the way the open-data server serves it.
+func mosmixKMZ(t *testing.T) []byte {
+	t.Helper()
+
+	kml, err := os.ReadFile(mosmixFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	w, err := archive.Create("MOSMIX_L_2026080303_10305.kml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(kml); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withMosmixStation lists the test airport with Lingen as its station.
+func withMosmixStation(t *testing.T) Airport {
+	t.Helper()
+
+	withTestAirports(t)
+	airport := testAirport
+	airport.MosmixStation = "10305"
+	airportsByID[airport.Identifier] = airport
+	return airport
+}
+
+func TestDecodeMosmixKML(t *testing.T) {
+	forecast := loadMosmixFixture(t)
+
+	if forecast.issued.Format("2006-01-02T15:04") != "2026-08-03T03:00" || forecast.elevation != 22 || len(forecast.hours) != 12 {
+		t.Fatalf("forecast = issued %v at %vm with %d hours, want 03Z, 22m and twelve", forecast.issued, forecast.elevation, len(forecast.hours))
+	}
+
+	tests := []struct {
+		hour         string
+		ceilingKnown bool
+		cloudBaseFL  *int
+		visibilityKM *float64
+	}{
+		// N05 says the cloud is below 500ft, and H_BsC puts it lower still: 80m + 22m.
+		{"2026-08-03T08:00", true, ptrTo(3), ptrFloat(0.4)},
+		// N05 at 40% caps H_BsC's 120m at 500ft... which is above it; the base stands.
+		{"2026-08-03T09:00", true, ptrTo(4), ptrFloat(2.5)},
+		{"2026-08-03T10:00", true, ptrTo(8), ptrFloat(6)},
+		// Enough cover, but not low: no opinion on the ceiling.
+		{"2026-08-03T12:00", false, nil, ptrFloat(25)},
+		// Little enough cover is no ceiling at all.
+		{"2026-08-03T13:00", true, nil, ptrFloat(35)},
+		{"2026-08-03T14:00", true, nil, nil},
+		// Every element missing.
+		{"2026-08-03T15:00", false, nil, nil},
+	}
+	for _, tc := range tests {
+		got, ok := forecast.hours[tc.hour]
+		if !ok {
+			t.Errorf("%s: missing", tc.hour)
+			continue
+		}
+		if got.ceilingKnown != tc.ceilingKnown || !equalPtr(got.cloudBaseFL, tc.cloudBaseFL) || !equalPtr(got.visibilityKM, tc.visibilityKM) {
+			t.Errorf("%s = known %v, base %v, visibility %v; want %v, %v, %v", tc.hour,
+				got.ceilingKnown, deref(got.cloudBaseFL), deref(got.visibilityKM),
+				tc.ceilingKnown, deref(tc.cloudBaseFL), deref(tc.visibilityKM))
+		}
+	}
+
+	body, _ := os.ReadFile(mosmixFixture)
+	if _, err := decodeMosmixKML(body, "10315"); err == nil {
+		t.Error("a KML without the station decoded")
+	}
+	if _, err := decodeMosmixKML(bytes.Replace(body, []byte("  283.35"), []byte("  warm.35"), 1), "10305"); err == nil {
+		t.Error("a KML with a value that is not a number decoded")
+	}
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func TestFetchMosmix_ReadsTheKMZAndCachesIt(t *testing.T) {
+	kmz := mosmixKMZ(t)
+	var requests atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/10305/kml/MOSMIX_L_LATEST_10305.kmz" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(kmz)
+	}))
+	t.Cleanup(upstream.Close)
+	t.Setenv(mosmixURLEnv, upstream.URL)
+	t.Cleanup(func() { clear(mosmixCache.entries) })
+
+	for range 2 {
+		forecast, err := fetchMosmix(context.Background(), "10305")
+		if err != nil || forecast == nil || len(forecast.hours) != 12 {
+			t.Fatalf("fetchMosmix() = %v, %v, want the fixture", forecast, err)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("fetched %d times, want the second served from the cache", got)
+	}
+	if _, err := fetchMosmix(context.Background(), "10315"); err == nil {
+		t.Error("a station the server does not have was fetched")
+	}
+
+	t.Setenv(mosmixURLEnv, "off")
+	if forecast, err := fetchMosmix(context.Background(), "10305"); forecast != nil || err != nil {
+		t.Errorf("fetchMosmix() = %v, %v with MOSMIX off, want nothing", forecast, err)
+	}
+}
+
+func TestApplyMosmix(t *testing.T) {
+	withTestAirports(t)
+	forecast := loadMosmixFixture(t)
+
+	cloudBase := func(point VfrPoint) (VfrPenalty, bool) {
+		for _, p := range point.Penalties {
+			if p.Factor == "cloud base" {
+				return p, true
+			}
+		}
+		return VfrPenalty{}, false
+	}
+
+	t.Run("blend takes the worse of the two", func(t *testing.T) {
+		// The model has FL12 and 60km all afternoon.
+		data := nowcastPayload(t, ptrTo(12))
+		before := data.VfrData[0].Probability
+		applyMosmix(data, forecast, mosmixBlend, defaultProfile())
+
+		// 10:00: MOSMIX's FL8 and 6km are both worse, and both win.
+		c := data.modelConditions[0]
+		if *c.cloudBaseFL != 8 || c.cloudBaseSource != factorSourceMosmix || *c.visibilityKM != 6 || c.visibilitySource != factorSourceMosmix {
+			t.Errorf("10:00 = FL%d from %s, %vkm from %s; want MOSMIX's FL8 and 6km", *c.cloudBaseFL, c.cloudBaseSource, *c.visibilityKM, c.visibilitySource)
+		}
+		if data.VfrData[0].Probability >= before {
+			t.Errorf("10:00 scored %d, was %d; MOSMIX's lower ceiling should cost something", data.VfrData[0].Probability, before)
+		}
+		if p, ok := cloudBase(data.VfrData[0]); !ok || p.Source != factorSourceMosmix {
+			t.Errorf("10:00 cloud base penalty = %+v, want it charged from MOSMIX", p)
+		}
+
+		// 11:00: MOSMIX's FL15 is above the model's FL12, which stands, and says so.
+		if p, ok := cloudBase(data.VfrData[1]); !ok || p.Value != 12 || p.Source != factorSourceModel {
+			t.Errorf("11:00 cloud base penalty = %+v, want the model's FL12", p)
+		}
+		// 12:00: MOSMIX has no opinion on the ceiling, so there was only the one source.
+		if p, ok := cloudBase(data.VfrData[2]); !ok || p.Source != "" {
+			t.Errorf("12:00 cloud base penalty = %+v, want it unattributed", p)
+		}
+		// 13:00: MOSMIX's clear sky does not lift the model's ceiling.
+		if c := data.modelConditions[3]; c.cloudBaseFL == nil || *c.cloudBaseFL != 12 || c.cloudBaseSource != factorSourceModel {
+			t.Errorf("13:00 = %v from %q, want the model's FL12", deref(c.cloudBaseFL), c.cloudBaseSource)
+		}
+	})
+
+	t.Run("replace takes MOSMIX wherever it has a value", func(t *testing.T) {
+		data := nowcastPayload(t, ptrTo(12))
+		applyMosmix(data, forecast, mosmixReplace, defaultProfile())
+
+		if p, ok := cloudBase(data.VfrData[1]); !ok || p.Value != 15 || p.Source != factorSourceMosmix {
+			t.Errorf("11:00 cloud base penalty = %+v, want MOSMIX's FL15", p)
+		}
+		if c := data.modelConditions[3]; c.cloudBaseFL != nil || c.cloudBaseSource != factorSourceMosmix {
+			t.Errorf("13:00 = %v from %q, want MOSMIX's clear sky", deref(c.cloudBaseFL), c.cloudBaseSource)
+		}
+		// 15:00 has nothing from MOSMIX, and keeps the model's inputs unattributed.
+		if c := data.modelConditions[5]; *c.cloudBaseFL != 12 || *c.visibilityKM != 60 || c.cloudBaseSource != "" || c.visibilitySource != "" {
+			t.Errorf("15:00 = %+v, want the model's inputs untouched", c)
+		}
+	})
+}
+
+func TestFetchWeather_MergesTheStationForecast(t *testing.T) {
+	airport := withMosmixStation(t)
+	stubDayLight(t)
+	t.Setenv(ensembleURLEnv, "off")
+	withWeatherSource(t, stubProvider{hourlyFixture([]string{"2026-08-03T09:00", "2026-08-03T10:00"})})
+	forecast := loadMosmixFixture(t)
+
+	var asked []string
+	original := fetchMosmixFn
+	fetchMosmixFn = func(_ context.Context, station string) (*mosmixForecast, error) {
+		asked = append(asked, station)
+		return forecast, nil
+	}
+	t.Cleanup(func() { fetchMosmixFn = original })
+
+	got, err := fetchWeather(context.Background(), airport, defaultProfile())
+	if err != nil {
+		t.Fatalf("fetchWeather() = %v", err)
+	}
+	// 09:00: MOSMIX's FL4 is below the wall; the hour is a no-go, and says whose ceiling it was.
+	if point := got.VfrData[0]; point.Probability != 0 || len(point.Penalties) != 1 || point.Penalties[0].Source != factorSourceMosmix {
+		t.Errorf("09:00 = %d with %+v, want a no-go on MOSMIX's cloud base", point.Probability, point.Penalties)
+	}
+
+	// An airfield without a station is never asked about.
+	asked = nil
+	if _, err := fetchWeather(context.Background(), airportsByID["EDWG"], defaultProfile()); err != nil {
+		t.Fatalf("fetchWeather(EDWG) = %v", err)
+	}
+	if len(asked) != 0 {
+		t.Errorf("asked MOSMIX for %v for an airfield without a station", asked)
+	}
+}
+
+func TestMosmixModeFromEnv(t *testing.T) {
+	for raw, want := range map[string]mosmixMode{"": mosmixBlend, "blend": mosmixBlend, "replace": mosmixReplace} {
+		t.Setenv(mosmixModeEnv, raw)
+		if got, err := mosmixModeFromEnv(); got != want || err != nil {
+			t.Errorf("%s=%q: got %q, %v, want %q", mosmixModeEnv, raw, got, err, want)
+		}
+	}
+	t.Setenv(mosmixModeEnv, "Replace ")
+	if _, err := mosmixModeFromEnv(); err == nil || !strings.Contains(err.Error(), mosmixModeEnv) {
+		t.Errorf("a mistyped mode = %v, want an error naming the variable", err)
+	}
+}
@@ -191,7 +191,7 @@ func TestProcessWeatherData_NightBandsAgreeWithTheScore(t *testing.T) {
 		}
 	}
 
-	got := processWeatherData(context.Background(), hourlyFixture(times), testAirport)
+	got := processWeatherData(context.Background(), hourlyFixture(times), testAirport, defaultProfile())
 
 	inNight := func(ts time.Time) bool {
 		for _, interval := range got.NightPeriods {
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// The nowcast: the first hours scored from what the nearest station reports rather than
+// from what ICON predicted for them.
+//
+// A model run is hours old by the time it is served, and its first hours are exactly where
+// a pilot checking the weather before walking to the aeroplane will look. The METAR is the
+// better answer there. So the hour the METAR was observed in is scored from its ceiling,
+// visibility and wind, through the same profile table as every other hour, and the hours
+// after it move back to the model with a linearly decaying observation weight -- after
+// nowcastHours the model stands alone.
+//
+// What is blended is the inputs, not the scores. A blended score would have no breakdown
+// to show for itself; blended inputs are scored once and explain themselves like any other
+// hour. Precipitation, temperature and daylight stay the model's: a METAR says whether it
+// is raining, not how much will fall in the hour.
+//
+// This runs when a payload is served, not when it is built. The cached payload belongs to
+// a model run and lives until the next one; a METAR is replaced every half hour. Keeping
+// the model's inputs on the cached entry lets a new observation re-score four hours without
+// refetching seven days of forecast.
+const (
+	// nowcastHours is how many hours, from the observed one, the observation has a say in.
+	nowcastHours = 3
+
+	// metarMaxAge is how old an observation may be and still be used. Past the next two
+	// routine reports, a METAR no longer describes now; the station has stopped reporting
+	// or the feed has, and the model is the better guess either way.
+	metarMaxAge = 90 * time.Minute
+
+	// clearSkyFL is what "no ceiling" counts as when it is blended with a model's cloud
+	// base. CAVOK promises nothing below 5000ft, and FL50 is where the cloud base factor
+	// reaches perfect -- so an observed clear sky pulls a forecast ceiling up to where it
+	// stops costing anything, rather than being ignored.
+	clearSkyFL = 50
+)
+
+// Nowcast says which observation the first hours of a payload were scored from.
+type Nowcast struct {
+	Station    string    `json:"station"`
+	ObservedAt time.Time `json:"observed_at"`
+}
+
+// VfrPoint.Source values.
+const (
+	sourceForecast = "forecast"
+	sourceObserved = "observed"
+	sourceBlended  = "blended"
+)
+
+// observedInputs is what a METAR contributes to an hour's conditions. Each group has its
+// own known flag: a METAR that reports no cloud group at all says nothing about the
+// ceiling, which is different from one that reports NSC.
+type observedInputs struct {
+	ceilingKnown bool
+	cloudBaseFL  *int // nil with ceilingKnown: no ceiling
+
+	windKnown      bool
+	windSpeed      float64
+	crosswind      float64
+	crosswindGusts float64
+	tailwind       float64
+
+	visibilityKM *float64
+}
+
+// usableMetar returns the METAR an airfield's nowcast would use, if there is one recent
+// enough to use.
+func usableMetar(airport Airport, now time.Time) (*Metar, bool) {
+	match, ok := stationFor(airport, metarRadiusKM())
+	if !ok {
+		return nil, false
+	}
+	metar, _, _, _ := observations.snapshot(match.ICAO)
+	if !metarCurrent(metar, now) {
+		return nil, false
+	}
+	return metar, true
+}
+
+// metarCurrent reports whether metar is recent enough to describe now.
+func metarCurrent(metar *Metar, now time.Time) bool {
+	return metar != nil && now.Sub(metar.ObservedAt) <= metarMaxAge
+}
+
+// applyNowcast returns data with its first hours re-scored against the airfield's latest
+// METAR, or data itself when there is none to use. The cached payload is shared between
+// requests and is never written to: the result is a copy with its own VfrData.
+func applyNowcast(data *ProcessedWeatherData, airport Airport, profile *scoringProfile, now time.Time) *ProcessedWeatherData {
+	metar, ok := usableMetar(airport, now)
+	if !ok || len(data.modelConditions) != len(data.VfrData) {
+		return data
+	}
+	observed := observedFrom(metar, airport, profile.aircraft)
+	observedHour := metar.ObservedAt.Truncate(time.Hour)
+
+	nowcast := *data
+	nowcast.VfrData = slices.Clone(data.VfrData)
+	nowcast.Nowcast = &Nowcast{Station: metar.Station, ObservedAt: metar.ObservedAt}
+
+	for i := range nowcast.VfrData {
+		model := data.modelConditions[i]
+		lead := model.time.Sub(observedHour)
+		if model.time.IsZero() || lead < 0 || lead >= nowcastHours*time.Hour {
+			continue
+		}
+		weight := 1 - lead.Hours()/nowcastHours
+
+		probability, penalties, visibilityKnown := scoreVFR(blendConditions(model, observed, weight), profile.limits)
+		point := &nowcast.VfrData[i]
+		point.Probability, point.Penalties, point.VisibilityKnown = probability, penalties, visibilityKnown
+		point.ObservedWeight = weight
+		point.Source = sourceBlended
+		if weight == 1 {
+			point.Source = sourceObserved
+		}
+	}
+	return &nowcast
+}
+
+// observedFrom reads the scoring inputs out of a METAR, with the runway chosen for
+// aircraft. The ceiling is reported above the station and the model's bases are above
+// MSL, so it is raised by the station's elevation before it is compared with them.
+func observedFrom(m *Metar, airport Airport, aircraft *aircraftType) observedInputs {
+	var in observedInputs
+
+	switch {
+	case m.CeilingFeet != nil:
+		in.ceilingKnown = true
+		fl := (*m.CeilingFeet + int(math.Round(metarElevationFt(m, airport)))) / 100
+		in.cloudBaseFL = &fl
+	case m.NoSignificantCloud || len(m.Clouds) > 0:
+		// Cloud reported, none of it a ceiling.
+		in.ceilingKnown = true
+	}
+
+	if m.VisibilityM != nil {
+		km := float64(*m.VisibilityM) / 1000
+		in.visibilityKM = &km
+	}
+
+	if m.Wind != nil {
+		in.windKnown = true
+		in.windSpeed = m.Wind.SpeedKT
+		gusts := m.Wind.SpeedKT
+		if m.Wind.GustKT != nil {
+			gusts = *m.Wind.GustKT
+		}
+		wind := observedWind(m.Wind, gusts, airport, aircraft)
+		in.crosswind, in.crosswindGusts, in.tailwind = wind.crosswind, wind.crosswindGusts, wind.tailwind
+	}
+
+	return in
+}
+
+// metarElevationFt is the elevation a METAR's heights are above: its station's, or,
+// for a station not in reportingStations, the airfield's, which is then where it was
+// matched. Zero where neither is known.
+func metarElevationFt(m *Metar, airport Airport) float64 {
+	if s, ok := lookupReportingStation(m.Station); ok {
+		return s.ElevationFt
+	}
+	if airport.ElevationFt != nil {
+		return *airport.ElevationFt
+	}
+	return 0
+}
+
+// observedWind is a reported wind, gusting gusts, on the runway end in use. A variable
+// wind is scored at its worst: VRB could be straight across, or straight behind whichever
+// end the wind from there leaves in use, and a dddVddd sector at whichever edge of it is
+// worse.
+func observedWind(w *ReportedWind, gusts float64, airport Airport, aircraft *aircraftType) runwayWind {
+	var worst runwayWind
+	var directions []int
+	switch {
+	case w.DirectionDeg == nil:
+		worst.crosswind, worst.crosswindGusts = w.SpeedKT, gusts
+		for _, end := range airport.runwayEnds() {
+			directions = append(directions, int(math.Round(end.heading+180))%360)
+		}
+	case w.VariableFromDeg != nil && w.VariableToDeg != nil:
+		directions = []int{*w.DirectionDeg, *w.VariableFromDeg, *w.VariableToDeg}
+	default:
+		directions = []int{*w.DirectionDeg}
+	}
+
+	for _, direction := range directions {
+		wind := airport.windOnRunways(w.SpeedKT, gusts, direction, aircraft)
+		worst.crosswind = math.Max(worst.crosswind, wind.crosswind)
+		worst.crosswindGusts = math.Max(worst.crosswindGusts, wind.crosswindGusts)
+		worst.tailwind = math.Max(worst.tailwind, wind.tailwind)
+	}
+	return worst
+}
+
+// blendConditions moves the model's inputs towards the observation by weight. Where only
+// one side has a value, that value is used whatever the weight: a model without visibility
+// is no reason to ignore the visibility that was measured.
+func blendConditions(model conditions, observed observedInputs, weight float64) conditions {
+	blended := model
+	mix := func(obs, mod float64) float64 { return weight*obs + (1-weight)*mod }
+
+	// An observed value is no longer the forecast's, whichever forecast MOSMIX made it: the
+	// hour's own source already says how much of it was measured.
+	if observed.ceilingKnown {
+		blended.cloudBaseSource = ""
+		switch {
+		case weight == 1 || (observed.cloudBaseFL == nil && model.cloudBaseFL == nil):
+			blended.cloudBaseFL = observed.cloudBaseFL
+		default:
+			obs, mod := clearSkyFL, clearSkyFL
+			if observed.cloudBaseFL != nil {
+				obs = *observed.cloudBaseFL
+			}
+			if model.cloudBaseFL != nil {
+				mod = *model.cloudBaseFL
+			}
+			fl := int(math.Round(mix(float64(obs), float64(mod))))
+			blended.cloudBaseFL = &fl
+		}
+	}
+
+	if observed.visibilityKM != nil {
+		blended.visibilitySource = ""
+		km := *observed.visibilityKM
+		if model.visibilityKM != nil {
+			km = mix(km, *model.visibilityKM)
+		}
+		blended.visibilityKM = &km
+	}
+
+	if observed.windKnown {
+		blended.windSpeed = mix(observed.windSpeed, model.windSpeed)
+		blended.crosswind = mix(observed.crosswind, model.crosswind)
+		blended.crosswindGusts = mix(observed.crosswindGusts, model.crosswindGusts)
+		blended.tailwind = mix(observed.tailwind, model.tailwind)
+	}
+
+	return blended
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// nowcastNow is half an hour after the METARs below were observed, on the fixture day.
+var nowcastNow = time.Date(2026, 8, 3, 11, 50, 0, 0, time.UTC)
+
+// withMetar puts one decoded METAR in the tracker, as a poll would have.
+func withMetar(t *testing.T, raw string) *Metar {
+	t.Helper()
+
+	stubObservations(t, dirObservationSource{})
+	m, err := parseMETAR(raw, nowcastNow)
+	if err != nil {
+		t.Fatalf("parseMETAR() = %v", err)
+	}
+	observations.mutex.Lock()
+	observations.metars = map[string]*Metar{m.Station: m}
+	observations.mutex.Unlock()
+	return m
+}
+
+// nowcastPayload is a forecast of clear hours from 10:00 to 15:00 at EDWN, scored the way
+// processWeatherData scores them, with the model's own cloud base at cloudBaseFL.
+func nowcastPayload(t *testing.T, cloudBaseFL *int) *ProcessedWeatherData {
+	t.Helper()
+
+	data := &ProcessedWeatherData{}
+	for hour := 10; hour <= 15; hour++ {
+		c := scoringConditions(t)
+		c.time = time.Date(2026, 8, 3, hour, 0, 0, 0, time.UTC)
+		c.cloudBaseFL = cloudBaseFL
+		probability, penalties, known := scoreVFR(c, defaultProfile().limits)
+		data.VfrData = append(data.VfrData, VfrPoint{
+			Time: c.time.Format("2006-01-02T15:04"), Probability: probability, Penalties: penalties,
+			VisibilityKnown: known, Source: sourceForecast,
+		})
+		data.modelConditions = append(data.modelConditions, c)
+	}
+	return data
+}
+
+func penaltyValue(point VfrPoint, factor string) (float64, bool) {
+	for _, p := range point.Penalties {
+		if p.Factor == factor {
+			return p.Value, true
+		}
+	}
+	return 0, false
+}
+
+func TestApplyNowcast_ScoresTheObservedHourAndDecaysToTheModel(t *testing.T) {
+	withTestAirports(t)
+	// 1100ft over Twente's 115ft is FL12 on the model's scale.
+	withMetar(t, "METAR EHTW 031120Z 24006KT 9999 OVC011 18/12 Q1015")
+	data := nowcastPayload(t, nil)
+
+	got := applyNowcast(data, testAirport, defaultProfile(), nowcastNow)
+
+	wantSources := []string{sourceForecast, sourceObserved, sourceBlended, sourceBlended, sourceForecast, sourceForecast}
+	wantWeights := []float64{0, 1, 2.0 / 3, 1.0 / 3, 0, 0}
+	for i, point := range got.VfrData {
+		if point.Source != wantSources[i] || math.Abs(point.ObservedWeight-wantWeights[i]) > 1e-9 {
+			t.Errorf("%s: source %q weight %v, want %q %v", point.Time, point.Source, point.ObservedWeight, wantSources[i], wantWeights[i])
+		}
+	}
+
+	// The observed hour has the METAR's ceiling; the next two move from it towards the
+	// model's clear sky, which blends as FL50.
+	for i, want := range map[int]float64{1: 12, 2: 25, 3: 37} {
+		if v, ok := penaltyValue(got.VfrData[i], "cloud base"); !ok || v != want {
+			t.Errorf("%s: cloud base = %v (%v), want FL%v", got.VfrData[i].Time, v, ok, want)
+		}
+	}
+	if !(got.VfrData[1].Probability < got.VfrData[2].Probability && got.VfrData[2].Probability <= got.VfrData[3].Probability) {
+		t.Errorf("probabilities %d, %d, %d do not recover towards the model", got.VfrData[1].Probability, got.VfrData[2].Probability, got.VfrData[3].Probability)
+	}
+	if got.VfrData[4].Probability != data.VfrData[4].Probability {
+		t.Error("an hour past the nowcast was re-scored")
+	}
+
+	if got.Nowcast == nil || got.Nowcast.Station != "EHTW" {
+		t.Errorf("Nowcast = %+v, want EHTW", got.Nowcast)
+	}
+}
+
+// The cached payload is shared by every request until the next model run. Writing the
+// nowcast into it would leave one request's observation in everyone's forecast.
+func TestApplyNowcast_DoesNotWriteToTheCachedPayload(t *testing.T) {
+	withTestAirports(t)
+	withMetar(t, "METAR EHTW 031120Z 24006KT 9999 OVC012 18/12 Q1015")
+	data := nowcastPayload(t, nil)
+	before := data.VfrData[1]
+
+	applyNowcast(data, testAirport, defaultProfile(), nowcastNow)
+
+	if data.VfrData[1].Source != before.Source || data.VfrData[1].Probability != before.Probability || data.Nowcast != nil {
+		t.Errorf("cached hour = %+v, was %+v", data.VfrData[1], before)
+	}
+}
+
+func TestApplyNowcast_LeavesThePayloadAloneWithoutAUsableMETAR(t *testing.T) {
+	withTestAirports(t)
+
+	t.Run("no observation", func(t *testing.T) {
+		stubObservations(t, dirObservationSource{})
+		data := nowcastPayload(t, nil)
+		if got := applyNowcast(data, testAirport, defaultProfile(), nowcastNow); got != data {
+			t.Error("the payload was copied with nothing to nowcast from")
+		}
+	})
+
+	t.Run("an observation too old to describe now", func(t *testing.T) {
+		withMetar(t, "METAR EHTW 030950Z 24006KT 9999 OVC012 18/12 Q1015")
+		data := nowcastPayload(t, nil)
+		if got := applyNowcast(data, testAirport, defaultProfile(), nowcastNow); got != data {
+			t.Error("a METAR two hours old was used")
+		}
+	})
+}
+
+// A clear observation is information too: it lifts a forecast ceiling rather than being
+// skipped for having no number.
+func TestApplyNowcast_AClearSkyLiftsAForecastCeiling(t *testing.T) {
+	withTestAirports(t)
+	withMetar(t, "METAR EHTW 031120Z 24006KT CAVOK 18/12 Q1015")
+	data := nowcastPayload(t, ptrTo(15))
+
+	got := applyNowcast(data, testAirport, defaultProfile(), nowcastNow)
+
+	if _, ok := penaltyValue(got.VfrData[1], "cloud base"); ok {
+		t.Errorf("the observed CAVOK hour still carries a cloud base penalty: %+v", got.VfrData[1].Penalties)
+	}
+	if got.VfrData[2].Probability <= data.VfrData[2].Probability {
+		t.Errorf("blended hour = %d, model alone %d, want the clear observation to lift it", got.VfrData[2].Probability, data.VfrData[2].Probability)
+	}
+	// 2/3 of FL50 and 1/3 of FL15.
+	blended := blendConditions(data.modelConditions[2], observedFrom(observations.metars["EHTW"], testAirport, nil), 2.0/3)
+	if blended.cloudBaseFL == nil || *blended.cloudBaseFL != 38 {
+		t.Errorf("blended cloud base = %v, want FL38", blended.cloudBaseFL)
+	}
+}
+
+// A METAR's ceiling is above the station; the model's bases, and so the score's, are above
+// MSL. On a raised station the difference is the station's elevation, not a lower ceiling.
+func TestObservedFrom_CeilingAboveMSL(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		raw     string
+		airport Airport
+		want    int
+	}{
+		// Dortmund, at 425ft.
+		{"a listed station", "METAR EDLW 031120Z 24006KT 9999 OVC010 18/12 Q1015", testAirport, 14},
+		// A station not in the list is the airfield reporting for itself.
+		{"the airfield's own", "METAR EDXR 031120Z 24006KT 9999 OVC010 18/12 Q1015", Airport{Identifier: "EDXR", ElevationFt: ptrFloat(1300)}, 23},
+		{"no elevation known", "METAR EDXR 031120Z 24006KT 9999 OVC010 18/12 Q1015", Airport{Identifier: "EDXR"}, 10},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := parseMETAR(tc.raw, nowcastNow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if in := observedFrom(m, tc.airport, nil); in.cloudBaseFL == nil || *in.cloudBaseFL != tc.want {
+				t.Errorf("cloud base = %v, want FL%d", in.cloudBaseFL, tc.want)
+			}
+		})
+	}
+}
+
+// VRB could be straight across the runway, so it is scored as if it were.
+func TestObservedFrom_VariableWindIsAllCrosswind(t *testing.T) {
+	m, err := parseMETAR("METAR EHTW 031120Z VRB08KT 9999 FEW030 18/12 Q1015", nowcastNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := observedFrom(m, testAirport, nil)
+	if in.crosswind != 8 || in.crosswindGusts != 8 {
+		t.Errorf("crosswind = %v, gusts %v, want the full 8kt", in.crosswind, in.crosswindGusts)
+	}
+	// FEW is cloud, reported, and not a ceiling.
+	if !in.ceilingKnown || in.cloudBaseFL != nil {
+		t.Errorf("ceiling = %v (known %v), want known and none", in.cloudBaseFL, in.ceilingKnown)
+	}
+}
+
+func TestGetWeatherData_ServesTheNowcast(t *testing.T) {
+	withTestAirports(t)
+	withMetar(t, "METAR EHTW 031120Z 24006KT 9999 OVC012 18/12 Q1015")
+	observed := time.Now().Add(-20 * time.Minute)
+	observations.mutex.Lock()
+	observations.metars["EHTW"].ObservedAt = observed
+	observations.mutex.Unlock()
+
+	// Hours laid out around the observation's, not the clock's: twenty minutes back is the
+	// previous hour for the first third of every one.
+	payload := nowcastPayload(t, nil)
+	hour := observed.UTC().Truncate(time.Hour)
+	for i := range payload.modelConditions {
+		payload.modelConditions[i].time = hour.Add(time.Duration(i-1) * time.Hour)
+	}
+	stubFetchWeather(t, func(context.Context, Airport, *scoringProfile) (*ProcessedWeatherData, error) {
+		return payload, nil
+	})
+
+	rec := httptest.NewRecorder()
+	getWeatherData(rec, httptest.NewRequest(http.MethodGet, "/api/weather", nil))
+
+	var got struct {
+		Nowcast *Nowcast   `json:"nowcast"`
+		VfrData []VfrPoint `json:"vfr_data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.Nowcast == nil || got.VfrData[1].Source != sourceObserved {
+		t.Errorf("nowcast = %+v, hour 1 source = %q, want the observation applied", got.Nowcast, got.VfrData[1].Source)
+	}
+}
+
+func TestGetStatus_ReportsTheObservationTheNowcastUses(t *testing.T) {
+	withTestAirports(t)
+	m := withMetar(t, "METAR EHTW 031120Z 24006KT 9999 OVC012 18/12 Q1015")
+	observed := time.Now().Add(-20 * time.Minute).UTC().Truncate(time.Second)
+	m.ObservedAt = observed
+
+	for query, want := range map[string]bool{"?airport=EDWN": true, "?airport=EDWG": false} {
+		rec := httptest.NewRecorder()
+		getStatus(rec, httptest.NewRequest(http.MethodGet, "/api/status"+query, nil))
+
+		var status StatusResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if got := status.LatestObservedAt != nil && status.LatestObservedAt.Equal(observed); got != want {
+			t.Errorf("%s: latest_observed_at = %v, want it reported: %v", query, status.LatestObservedAt, want)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// METAR and TAF, the observations and terminal forecasts of the reporting stations around
+// the airfields.
+//
+// Most of the airfields in the list are small ones without a METAR of their own, so each is
+// matched to a reporting station: the one named in its airports.json entry, else its own
+// identifier if that is a station, else the nearest station within
+// FLUGWETTER_METAR_RADIUS_KM. Beyond that radius an observation says more about somewhere
+// else than about the airfield, and none is better than a misleading one.
+//
+// Where the reports come from is pluggable. The default is aviationweather.gov's data API,
+// which answers raw TAC text for a list of stations without a key.
+// FLUGWETTER_OBSERVATIONS_SOURCE points it elsewhere: another URL serving the same API, or a
+// directory holding metar.txt and taf.txt -- for a mirror, a test rig, or a deployment that
+// must not reach the internet.
+const (
+	observationsSourceEnv = "FLUGWETTER_OBSERVATIONS_SOURCE"
+	metarRadiusEnv        = "FLUGWETTER_METAR_RADIUS_KM"
+
+	defaultObservationsURL = "https://aviationweather.gov/api/data"
+
+	// How far from an airfield a station may be and still stand in for it. 40km gives
+	// Nordhorn-Lingen Twente, 28km off across the border, and Wangerooge Wittmund; much
+	// further and the station is reporting a different coast.
+	defaultMetarRadiusKM = 40.0
+
+	// METARs are issued half-hourly and TAFs every three or six hours; ten minutes picks up
+	// a SPECI without hammering a free service.
+	observationsPollInterval = 10 * time.Minute
+
+	// The same threshold, for the same reason, as the other pollers.
+	observationsFailuresBeforeDegraded = 2
+)
+
+// ReportingStation is a station issuing METARs and, mostly, TAFs.
+type ReportingStation struct {
+	ICAO      string  `json:"icao"`
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// ElevationFt is the aerodrome elevation, feet above MSL: what the METAR's cloud
+	// heights are above.
+	ElevationFt float64 `json:"elevation_ft"`
+}
+
+// reportingStations are the stations within reach of the airfield list. A station missing
+// here cannot be matched to anything, so an airports file covering another region needs its
+// stations added alongside.
+var reportingStations = []ReportingStation{
+	{ICAO: "EDDG", Name: "Münster/Osnabrück", Latitude: 52.1346, Longitude: 7.6848, ElevationFt: 160},
+	{ICAO: "EDDW", Name: "Bremen", Latitude: 53.0475, Longitude: 8.7867, ElevationFt: 14},
+	{ICAO: "EDDH", Name: "Hamburg", Latitude: 53.6304, Longitude: 9.9882, ElevationFt: 53},
+	{ICAO: "EDLW", Name: "Dortmund", Latitude: 51.5183, Longitude: 7.6122, ElevationFt: 425},
+	{ICAO: "EHTW", Name: "Twente", Latitude: 52.2758, Longitude: 6.8908, ElevationFt: 115},
+	{ICAO: "EHGG", Name: "Groningen/Eelde", Latitude: 53.1197, Longitude: 6.5794, ElevationFt: 17},
+	{ICAO: "ETNT", Name: "Wittmund", Latitude: 53.5478, Longitude: 7.6673, ElevationFt: 26},
+	{ICAO: "ETMN", Name: "Nordholz", Latitude: 53.7677, Longitude: 8.6585, ElevationFt: 74},
+}
+
+func lookupReportingStation(icao string) (ReportingStation, bool) {
+	i := slices.IndexFunc(reportingStations, func(s ReportingStation) bool { return s.ICAO == icao })
+	if i < 0 {
+		return ReportingStation{}, false
+	}
+	return reportingStations[i], true
+}
+
+// StationMatch is the station standing in for an airfield.
+type StationMatch struct {
+	ReportingStation
+	// DistanceKM is from the airfield to the station; 0 when the airfield is the station.
+	DistanceKM float64 `json:"distance_km"`
+}
+
+// metarRadiusKM reads FLUGWETTER_METAR_RADIUS_KM at call time, like openAIPEnabled. A value
+// that is not a positive number is logged and the default used: a typo here must not stop
+// the server, and the default is a sane answer.
+func metarRadiusKM() float64 {
+	raw := os.Getenv(metarRadiusEnv)
+	if raw == "" {
+		return defaultMetarRadiusKM
+	}
+	radius, err := strconv.ParseFloat(raw, 64)
+	if err != nil || radius <= 0 || math.IsInf(radius, 0) {
+		slog.Warn("ignoring invalid METAR radius", "env", metarRadiusEnv, "value", raw)
+		return defaultMetarRadiusKM
+	}
+	return radius
+}
+
+// stationFor picks the station for an airfield, or reports that none is in range. An
+// explicit station in airports.json wins regardless of distance: whoever wrote it knows the
+// local weather better than a radius does.
+func stationFor(a Airport, radiusKM float64) (StationMatch, bool) {
+	distanceTo := func(s ReportingStation) float64 {
+		return distanceKM(a.Latitude, a.Longitude, s.Latitude, s.Longitude)
+	}
+
+	if a.ReportingStation != "" {
+		if s, ok := lookupReportingStation(a.ReportingStation); ok {
+			return StationMatch{ReportingStation: s, DistanceKM: distanceTo(s)}, true
+		}
+	}
+	if s, ok := lookupReportingStation(a.Identifier); ok {
+		return StationMatch{ReportingStation: s}, true
+	}
+
+	var best StationMatch
+	found := false
+	for _, s := range reportingStations {
+		d := distanceTo(s)
+		if d <= radiusKM && (!found || d < best.DistanceKM) {
+			best, found = StationMatch{ReportingStation: s, DistanceKM: d}, true
+		}
+	}
+	return best, found
+}
+
+// earthRadiusKM is the mean radius, which is as good as an ellipsoid at these distances.
+const earthRadiusKM = 6371.0
+
+// distanceKM is the great-circle distance between two points, by the haversine formula.
+func distanceKM(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKM * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// stationsInUse is every station some airfield is matched to, sorted so the query, and the
+// log line, are stable.
+func stationsInUse() []string {
+	radius := metarRadiusKM()
+	seen := make(map[string]bool)
+	for _, a := range airports {
+		if match, ok := stationFor(a, radius); ok {
+			seen[match.ICAO] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(seen))
+}
+
+// reportKind is the kind of bulletin asked of a source. Its value is the path segment of
+// the aviationweather.gov API and the base name of the file in a directory source.
+type reportKind string
+
+const (
+	metarReports reportKind = "metar"
+	tafReports   reportKind = "taf"
+)
+
+// observationSource fetches raw bulletins: one report per line, indented lines continuing
+// the one above. A source may return reports for stations not asked for; they are dropped.
+type observationSource interface {
+	reports(ctx context.Context, kind reportKind, stations []string) (string, error)
+}
+
+// httpObservationSource speaks the aviationweather.gov data API, or anything serving the
+// same paths.
+type httpObservationSource struct {
+	baseURL string
+}
+
+func (s httpObservationSource) reports(ctx context.Context, kind reportKind, stations []string) (string, error) {
+	query := url.Values{"ids": {strings.Join(stations, ",")}, "format": {"raw"}}
+	body, err := getJSON(ctx, fmt.Sprintf("%s/%s?%s", strings.TrimSuffix(s.baseURL, "/"), kind, query.Encode()))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// dirObservationSource reads <dir>/metar.txt and <dir>/taf.txt, whatever keeps them current.
+type dirObservationSource struct {
+	dir string
+}
+
+func (s dirObservationSource) reports(_ context.Context, kind reportKind, _ []string) (string, error) {
+	raw, err := os.ReadFile(filepath.Join(s.dir, string(kind)+".txt"))
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// observationSourceFromEnv picks the source FLUGWETTER_OBSERVATIONS_SOURCE names: an
+// http(s) URL, or anything else as a directory.
+func observationSourceFromEnv() observationSource {
+	configured := os.Getenv(observationsSourceEnv)
+	switch {
+	case configured == "":
+		return httpObservationSource{baseURL: defaultObservationsURL}
+	case strings.HasPrefix(configured, "http://") || strings.HasPrefix(configured, "https://"):
+		return httpObservationSource{baseURL: configured}
+	default:
+		return dirObservationSource{dir: configured}
+	}
+}
+
+type observationTracker struct {
+	mutex sync.RWMutex
+
+	metars           map[string]*Metar
+	tafs             map[string]*Taf
+	fetchedAt        time.Time
+	consecutiveFails int
+}
+
+var observations = &observationTracker{}
+
+// observationsSource is the source in use, set from the environment in Run. A var so tests
+// can swap it.
+var observationsSource observationSource = httpObservationSource{baseURL: defaultObservationsURL}
+
+// snapshot returns the station's latest METAR and TAF, either of which may be nil. Both are
+// copies: the decoded reports hold slices, and the next poll must not rewrite what a
+// request is encoding -- see restrictionTracker.snapshot.
+func (t *observationTracker) snapshot(station string) (metar *Metar, taf *Taf, fetchedAt time.Time, degraded bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	if m := t.metars[station]; m != nil {
+		cloned := *m
+		cloned.ReportedConditions = m.ReportedConditions.clone()
+		metar = &cloned
+	}
+	if f := t.tafs[station]; f != nil {
+		cloned := *f
+		cloned.Base = f.Base.clone()
+		cloned.Changes = make([]TafChange, len(f.Changes))
+		for i, change := range f.Changes {
+			cloned.Changes[i] = change
+			cloned.Changes[i].ReportedConditions = change.ReportedConditions.clone()
+		}
+		taf = &cloned
+	}
+	return metar, taf, t.fetchedAt, t.consecutiveFails >= observationsFailuresBeforeDegraded
+}
+
+func (c ReportedConditions) clone() ReportedConditions {
+	if c.Wind != nil {
+		wind := *c.Wind
+		c.Wind = &wind
+	}
+	c.Clouds = slices.Clone(c.Clouds)
+	c.Weather = slices.Clone(c.Weather)
+	return c
+}
+
+// poll fetches both bulletins for every station in use. A kind that fails keeps its
+// previous reports, for the reason restrictionTracker.poll keeps its areas; a report that
+// does not decode is logged and skipped, not allowed to cost the others.
+func (t *observationTracker) poll(ctx context.Context) {
+	stations := stationsInUse()
+	if len(stations) == 0 {
+		return
+	}
+	source := observationsSource
+	now := time.Now().UTC()
+	wanted := func(station string) bool { return slices.Contains(stations, station) }
+
+	var metars map[string]*Metar
+	metarText, metarErr := source.reports(ctx, metarReports, stations)
+	if metarErr == nil {
+		metars = make(map[string]*Metar)
+		for _, raw := range splitReports(metarText) {
+			m, err := parseMETAR(raw, now)
+			if err != nil {
+				slog.Warn("skipping undecodable METAR", "error", err)
+				continue
+			}
+			// Bulletins list the newest first, and a SPECI can follow a METAR within the
+			// hour; keep the latest observation per station.
+			if wanted(m.Station) && (metars[m.Station] == nil || m.ObservedAt.After(metars[m.Station].ObservedAt)) {
+				metars[m.Station] = m
+			}
+		}
+	}
+
+	var tafs map[string]*Taf
+	tafText, tafErr := source.reports(ctx, tafReports, stations)
+	if tafErr == nil {
+		tafs = make(map[string]*Taf)
+		for _, raw := range splitReports(tafText) {
+			f, err := parseTAF(raw, now)
+			if err != nil {
+				slog.Warn("skipping undecodable TAF", "error", err)
+				continue
+			}
+			if wanted(f.Station) && (tafs[f.Station] == nil || f.IssuedAt.After(tafs[f.Station].IssuedAt)) {
+				tafs[f.Station] = f
+			}
+		}
+	}
+
+	// Kept for verification; see verification.go. A failed write costs the archive one
+	// poll's reports, not the serving of them.
+	if err := archive.storeMetars(metars); err != nil {
+		slog.Warn("failed to archive METARs", "error", err)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if metarErr != nil || tafErr != nil {
+		t.consecutiveFails++
+		slog.Warn("observations unavailable", "metar_error", metarErr, "taf_error", tafErr, "consecutive", t.consecutiveFails)
+	} else {
+		t.consecutiveFails = 0
+	}
+	if metars != nil {
+		t.metars = metars
+	}
+	if tafs != nil {
+		t.tafs = tafs
+	}
+	if metars != nil || tafs != nil {
+		t.fetchedAt = now
+		slog.Info("observations fetched", "stations", len(stations), "metars", len(t.metars), "tafs", len(t.tafs))
+	}
+}
+
+// watchObservations polls until ctx is cancelled. Like the airspace plan, the reports are
+// served straight from the tracker and nothing downstream needs invalidating.
+func watchObservations(ctx context.Context) {
+	ticker := time.NewTicker(observationsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			observations.poll(ctx)
+		}
+	}
+}
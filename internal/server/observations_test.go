@@ -0,0 +1,256 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type stubObservationSource func(kind reportKind) (string, error)
+
+func (s stubObservationSource) reports(_ context.Context, kind reportKind, _ []string) (string, error) {
+	return s(kind)
+}
+
+// stubObservations swaps the source and resets the tracker, which is package-level state
+// shared between tests.
+func stubObservations(t *testing.T, source observationSource) {
+	t.Helper()
+
+	original := observationsSource
+	observationsSource = source
+	t.Cleanup(func() { observationsSource = original })
+
+	observations.mutex.Lock()
+	observations.metars = nil
+	observations.tafs = nil
+	observations.fetchedAt = time.Time{}
+	observations.consecutiveFails = 0
+	observations.mutex.Unlock()
+}
+
+func TestDistanceKM(t *testing.T) {
+	// Hamburg to Bremen, about 100km.
+	if got := distanceKM(53.6304, 9.9882, 53.0475, 8.7867); math.Abs(got-100) > 3 {
+		t.Errorf("EDDH-EDDW = %.1fkm, want about 100", got)
+	}
+	if got := distanceKM(52.4575, 7.1850, 52.4575, 7.1850); got != 0 {
+		t.Errorf("distance to itself = %v, want 0", got)
+	}
+}
+
+func TestStationFor(t *testing.T) {
+	station := func(icao string) Airport {
+		s, _ := lookupReportingStation(icao)
+		return Airport{Identifier: icao, Latitude: s.Latitude, Longitude: s.Longitude}
+	}
+
+	tests := []struct {
+		name    string
+		airport Airport
+		radius  float64
+		want    string
+	}{
+		{"a field that is a station", station("EDDG"), 40, "EDDG"},
+		{"the nearest station in range", testAirport, 40, "EHTW"},
+		{"nothing in range", testAirport, 20, ""},
+		{"an explicit station, however far", Airport{Identifier: "EDWN", Latitude: 52.4575, Longitude: 7.1850, ReportingStation: "EDDH"}, 40, "EDDH"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			match, ok := stationFor(tc.airport, tc.radius)
+			if tc.want == "" {
+				if ok {
+					t.Errorf("stationFor() = %s, want none", match.ICAO)
+				}
+				return
+			}
+			if !ok || match.ICAO != tc.want {
+				t.Errorf("stationFor() = %q, %v, want %q", match.ICAO, ok, tc.want)
+			}
+		})
+	}
+}
+
+func TestMetarRadiusKM_FallsBackOnABadValue(t *testing.T) {
+	t.Setenv(metarRadiusEnv, "far")
+	if got := metarRadiusKM(); got != defaultMetarRadiusKM {
+		t.Errorf("metarRadiusKM() = %v, want the default", got)
+	}
+	t.Setenv(metarRadiusEnv, "25")
+	if got := metarRadiusKM(); got != 25 {
+		t.Errorf("metarRadiusKM() = %v, want 25", got)
+	}
+}
+
+func TestValidateAirports_RejectsAnUnknownReportingStation(t *testing.T) {
+	airport := testAirport
+	airport.ReportingStation = "EDXX"
+	if err := validateAirports([]Airport{airport}); err == nil {
+		t.Error("validateAirports() = nil error, want the unknown station refused")
+	}
+}
+
+// The directory source is also how the fixtures get in: it reads them as a mirror would
+// have written them.
+func TestObservations_PollDecodesAndKeepsTheStationsInUse(t *testing.T) {
+	withTestAirports(t)
+	stubObservations(t, dirObservationSource{dir: "testdata/observations"})
+
+	observations.poll(context.Background())
+
+	metar, taf, fetchedAt, degraded := observations.snapshot("EHTW")
+	if metar == nil || *metar.VisibilityM != 800 {
+		t.Errorf("EHTW METAR = %+v, want the fog report", metar)
+	}
+	// Twente's TAF in the fixture is a cancellation, which is no forecast.
+	if taf != nil {
+		t.Errorf("EHTW TAF = %+v, want none", taf)
+	}
+	if fetchedAt.IsZero() || degraded {
+		t.Errorf("fetchedAt = %v, degraded = %v after a successful poll", fetchedAt, degraded)
+	}
+
+	// Bremen is in the bulletin, but no test airfield is matched to it.
+	if metar, _, _, _ := observations.snapshot("EDDW"); metar != nil {
+		t.Error("kept a METAR for a station no airfield uses")
+	}
+}
+
+func TestObservations_KeepsTheLatestReportPerStation(t *testing.T) {
+	withTestAirports(t)
+	airports = append(airports, Airport{Identifier: "EDDG", Name: "Münster/Osnabrück", Latitude: 52.1346, Longitude: 7.6848, RunwayHeadings: []float64{70, 250}})
+	stubObservations(t, dirObservationSource{dir: "testdata/observations"})
+
+	observations.poll(context.Background())
+
+	metar, taf, _, _ := observations.snapshot("EDDG")
+	if metar == nil || metar.ObservedAt.Minute() != 20 {
+		t.Errorf("EDDG METAR = %+v, want the 1420Z one rather than the 1350Z", metar)
+	}
+	if taf == nil || len(taf.Changes) != 5 {
+		t.Errorf("EDDG TAF = %+v, want the one with five change groups", taf)
+	}
+}
+
+func TestObservations_FailedPollKeepsTheReportsAndDegrades(t *testing.T) {
+	withTestAirports(t)
+	fail := false
+	dir := dirObservationSource{dir: "testdata/observations"}
+	stubObservations(t, stubObservationSource(func(kind reportKind) (string, error) {
+		if fail {
+			return "", errors.New("unreachable")
+		}
+		return dir.reports(context.Background(), kind, nil)
+	}))
+
+	observations.poll(context.Background())
+	fail = true
+	observations.poll(context.Background())
+
+	if metar, _, _, degraded := observations.snapshot("EHTW"); metar == nil || degraded {
+		t.Errorf("after one failure: METAR = %v, degraded = %v, want the old report and not degraded", metar, degraded)
+	}
+	observations.poll(context.Background())
+	if _, _, _, degraded := observations.snapshot("EHTW"); !degraded {
+		t.Error("degraded = false after two consecutive failures")
+	}
+}
+
+func TestObservations_SnapshotDoesNotShareStorage(t *testing.T) {
+	withTestAirports(t)
+	stubObservations(t, dirObservationSource{dir: "testdata/observations"})
+	observations.poll(context.Background())
+
+	metar, _, _, _ := observations.snapshot("EHTW")
+	metar.Weather[0] = "clobbered"
+	metar.Wind.SpeedKT = 99
+
+	fresh, _, _, _ := observations.snapshot("EHTW")
+	if fresh.Weather[0] == "clobbered" || fresh.Wind.SpeedKT == 99 {
+		t.Error("the tracker handed out its own storage")
+	}
+}
+
+func TestGetObservations(t *testing.T) {
+	withTestAirports(t)
+	stubObservations(t, dirObservationSource{dir: "testdata/observations"})
+	observations.poll(context.Background())
+	// The fixture's reports carry a fixed day and time; the handler judges them by the clock.
+	observations.mutex.Lock()
+	observations.metars["EHTW"].ObservedAt = time.Now().Add(-20 * time.Minute)
+	observations.mutex.Unlock()
+
+	rec := httptest.NewRecorder()
+	getObservations(rec, httptest.NewRequest(http.MethodGet, "/api/observations?airport=EDWN", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var got ObservationsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.Station == nil || got.Station.ICAO != "EHTW" || got.Station.DistanceKM == 0 {
+		t.Fatalf("station = %+v, want Twente at some distance", got.Station)
+	}
+	if got.METAR == nil || got.METAR.Raw == "" {
+		t.Errorf("METAR = %+v, want the raw report alongside the decode", got.METAR)
+	}
+}
+
+// A station that stopped reporting has no current METAR, though the tracker still holds its
+// last one.
+func TestGetObservations_OldMetarIsNotCurrent(t *testing.T) {
+	withTestAirports(t)
+	stubObservations(t, dirObservationSource{dir: "testdata/observations"})
+	observations.poll(context.Background())
+	observations.mutex.Lock()
+	observations.metars["EHTW"].ObservedAt = time.Now().Add(-metarMaxAge - time.Minute)
+	observations.mutex.Unlock()
+
+	rec := httptest.NewRecorder()
+	getObservations(rec, httptest.NewRequest(http.MethodGet, "/api/observations?airport=EDWN", nil))
+
+	var got ObservationsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.Station == nil || got.METAR != nil {
+		t.Errorf("station = %+v, METAR = %+v, want the station and no METAR", got.Station, got.METAR)
+	}
+}
+
+// No station in range is an answer, not an error: the page shows that there is none.
+func TestGetObservations_NoStationInRange(t *testing.T) {
+	withTestAirports(t)
+	t.Setenv(metarRadiusEnv, "5")
+	stubObservations(t, dirObservationSource{dir: "testdata/observations"})
+
+	rec := httptest.NewRecorder()
+	getObservations(rec, httptest.NewRequest(http.MethodGet, "/api/observations?airport=EDWN", nil))
+
+	var got ObservationsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || got.Station != nil || got.METAR != nil {
+		t.Errorf("status = %d, response = %+v, want 200 and no station", rec.Code, got)
+	}
+}
+
+func TestGetObservations_UnknownAirportIsRejected(t *testing.T) {
+	withTestAirports(t)
+
+	rec := httptest.NewRecorder()
+	getObservations(rec, httptest.NewRequest(http.MethodGet, "/api/observations?airport=XXXX", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Open-Meteo's forecast API, as one weatherProvider.
+//
+// The response is a column per variable: "hourly": {"time": [...], "temperature_2m": [...],
+// "cloud_cover_850hPa": [...]}. It used to be decoded into a struct with a field and a tag
+// per column, and read back out by a processor that listed the pressure levels again. Both
+// lists now follow from the two below, which are also what the query asks for, so a variable
+// cannot be requested and never read, or read and never requested.
+
+// openMeteoSurface are the single-level variables asked for.
+//
+// The query previously also asked for these, which nothing ever read -- they inflated the
+// upstream response and the parse for nothing. Kept as a list rather than deleted outright,
+// because re-enabling one is then a matter of adding it back here and reading it in
+// decodeOpenMeteo:
+//
+//	apparent_temperature, rain, showers, snowfall,
+//	surface_pressure, temperature_80m, temperature_120m, temperature_180m,
+//	wind_speed_120m, wind_speed_180m, wind_direction_120m, wind_direction_180m
+var openMeteoSurface = []string{
+	"precipitation_probability", "pressure_msl", "cloud_cover_low", "cloud_cover",
+	"cloud_cover_mid", "cloud_cover_high", "temperature_2m", "relative_humidity_2m",
+	"dew_point_2m", "precipitation", "weather_code", "visibility", "wind_speed_10m",
+	"wind_speed_80m", "wind_direction_10m", "wind_direction_80m", "wind_gusts_10m",
+	"cape", "lifted_index", "convective_cloud_base", "snow_depth", "soil_temperature_0cm",
+}
+
+// openMeteoLevels are the pressure levels asked for, lowest altitude first. Cloud cover and
+// geopotential height come for every one of them, wind only up to openMeteoWindTopHPa: above
+// 600hPa is above anything the wind chart draws. Temperature and humidity, for the freezing
+// level and the icing risk, come up to openMeteoIcingTopHPa: a freezing level above 500hPa,
+// around 18000ft, is no concern of a VFR flight's.
+var openMeteoLevels = []int{1000, 975, 950, 925, 900, 850, 800, 700, 600, 500, 400, 300, 250, 200, 150, 100, 70, 50, 30}
+
+const (
+	openMeteoWindTopHPa  = 600
+	openMeteoIcingTopHPa = 500
+)
+
+// openMeteoHourly is every variable in the query's hourly= list.
+func openMeteoHourly() []string {
+	variables := append([]string(nil), openMeteoSurface...)
+	for _, hPa := range openMeteoLevels {
+		variables = append(variables, fmt.Sprintf("cloud_cover_%dhPa", hPa), fmt.Sprintf("geopotential_height_%dhPa", hPa))
+		if hPa >= openMeteoWindTopHPa {
+			variables = append(variables, fmt.Sprintf("wind_speed_%dhPa", hPa), fmt.Sprintf("wind_direction_%dhPa", hPa))
+		}
+		if hPa >= openMeteoIcingTopHPa {
+			variables = append(variables, fmt.Sprintf("temperature_%dhPa", hPa), fmt.Sprintf("relative_humidity_%dhPa", hPa))
+		}
+	}
+	return variables
+}
+
+// openMeteoPastDays and openMeteoForecastDays are the query's past_days and forecast_days.
+// The past days' hours are history, the rain the soft field factor looks back over; two
+// days is the factor's window. See softfield.go. The forecast days are Open-Meteo's
+// default, spelled out so the query says what it gets.
+const (
+	openMeteoPastDays     = 2
+	openMeteoForecastDays = 7
+)
+
+// apiURLTemplate takes latitude, longitude and the model; everything else about the query
+// is identical for every airport.
+var apiURLTemplate = "https://api.open-meteo.com/v1/forecast?latitude=%s&longitude=%s&hourly=" +
+	strings.Join(openMeteoHourly(), ",") + "&models=%s&timezone=GMT&wind_speed_unit=kn" +
+	fmt.Sprintf("&past_days=%d&forecast_days=%d", openMeteoPastDays, openMeteoForecastDays)
+
+// buildAPIURL returns the Open-Meteo query for one airport from the primary model.
+func buildAPIURL(airport Airport) string {
+	return buildModelAPIURL(airport, primaryModel())
+}
+
+// buildModelAPIURL returns the Open-Meteo query for one airport from the given model.
+func buildModelAPIURL(airport Airport, model forecastModel) string {
+	return fmt.Sprintf(apiURLTemplate, airport.LatString(), airport.LonString(), model.ID)
+}
+
+// openMeteoColumns is the decoded "hourly" object: each column by variable name, with nil
+// where upstream sent null.
+type openMeteoColumns map[string][]*float64
+
+// float returns a variable at hour i, or nil where the column is absent, short or null.
+func (c openMeteoColumns) float(name string, i int) *float64 {
+	column := c[name]
+	if i >= len(column) {
+		return nil
+	}
+	return column[i]
+}
+
+// int is float for the variables Open-Meteo reports as whole numbers: percentages,
+// directions and weather codes.
+func (c openMeteoColumns) int(name string, i int) *int {
+	v := c.float(name, i)
+	if v == nil {
+		return nil
+	}
+	n := int(math.Round(*v))
+	return &n
+}
+
+// decodeOpenMeteo reads a forecast response into the neutral model. A column that is
+// missing or shorter than "time" leaves those hours without the value, as a null does; only
+// a response without times, or with a column that is not numbers, is an error.
+//
+// The first pastDays days of hours are the query's past_days, and go to history: Open-Meteo
+// starts them at midnight, whole days before today's. They are not told apart by counting
+// back from the end, which would take the first hours of any response longer than
+// openMeteoForecastDays for history. A captured response records the past_days it was
+// fetched with beside "hourly", and that is used instead; one that records none -- an old
+// replay -- has none.
+func decodeOpenMeteo(body []byte, pastDays int) (*hourlyForecast, error) {
+	var response struct {
+		PastDays *int                       `json:"past_days"`
+		Hourly   map[string]json.RawMessage `json:"hourly"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	if response.PastDays != nil {
+		pastDays = *response.PastDays
+	}
+
+	var times []string
+	if raw, ok := response.Hourly["time"]; !ok || json.Unmarshal(raw, &times) != nil {
+		return nil, fmt.Errorf("API response has no hourly times")
+	}
+
+	columns := make(openMeteoColumns)
+	for _, name := range openMeteoHourly() {
+		raw, ok := response.Hourly[name]
+		if !ok {
+			continue
+		}
+		var values []*float64
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse API variable %s: %w", name, err)
+		}
+		columns[name] = values
+	}
+
+	hours := make([]forecastHour, len(times))
+	for i, t := range times {
+		hour := forecastHour{
+			time:                     t,
+			temperature:              columns.float("temperature_2m", i),
+			dewPoint:                 columns.float("dew_point_2m", i),
+			relativeHumidity:         columns.int("relative_humidity_2m", i),
+			pressureMSL:              columns.float("pressure_msl", i),
+			precipitation:            columns.float("precipitation", i),
+			precipitationProbability: columns.int("precipitation_probability", i),
+			weatherCode:              columns.int("weather_code", i),
+			cape:                     columns.float("cape", i),
+			liftedIndex:              columns.float("lifted_index", i),
+			convectiveCloudBase:      columns.float("convective_cloud_base", i),
+			visibility:               columns.float("visibility", i),
+			snowDepth:                columns.float("snow_depth", i),
+			soilTemperature:          columns.float("soil_temperature_0cm", i),
+			cloudCover:               columns.int("cloud_cover", i),
+			cloudCoverLow:            columns.int("cloud_cover_low", i),
+			cloudCoverMid:            columns.int("cloud_cover_mid", i),
+			cloudCoverHigh:           columns.int("cloud_cover_high", i),
+			windSpeed10m:             columns.float("wind_speed_10m", i),
+			windGusts10m:             columns.float("wind_gusts_10m", i),
+			windDirection10m:         columns.int("wind_direction_10m", i),
+			windSpeed80m:             columns.float("wind_speed_80m", i),
+			windDirection80m:         columns.int("wind_direction_80m", i),
+			levels:                   make([]pressureLevel, 0, len(openMeteoLevels)),
+		}
+		for _, hPa := range openMeteoLevels {
+			hour.levels = append(hour.levels, pressureLevel{
+				hPa:              hPa,
+				height:           columns.float(fmt.Sprintf("geopotential_height_%dhPa", hPa), i),
+				cloudCover:       columns.int(fmt.Sprintf("cloud_cover_%dhPa", hPa), i),
+				windSpeed:        columns.float(fmt.Sprintf("wind_speed_%dhPa", hPa), i),
+				windDirection:    columns.int(fmt.Sprintf("wind_direction_%dhPa", hPa), i),
+				temperature:      columns.float(fmt.Sprintf("temperature_%dhPa", hPa), i),
+				relativeHumidity: columns.int(fmt.Sprintf("relative_humidity_%dhPa", hPa), i),
+			})
+		}
+		hours[i] = hour
+	}
+
+	past := pastDays * 24
+	if past < 0 || past >= len(hours) {
+		return nil, fmt.Errorf("API response has %d hours, not enough for %d past days and a forecast", len(hours), pastDays)
+	}
+	return &hourlyForecast{hours: hours[past:], history: hours[:past]}, nil
+}
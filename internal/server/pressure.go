@@ -0,0 +1,111 @@
+package server
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Pressure: QNH, its tendency, and the fronts it gives away.
+//
+// pressure_msl has been fetched since the takeoff factor needed a QNH for the pressure
+// altitude, and was then dropped from the payload. A barometer falling on a Friday evening
+// is half a weekend briefing: it is the front on its way, hours before the cloud base says
+// so.
+//
+// The pressure series gives every hour its QNH, the change over the three hours before it
+// -- the tendency a METAR-reading pilot and a synoptic chart both speak in -- and flags a
+// fall fast enough to mean weather. A front is marked where a falling barometer and a shift
+// in the surface wind meet: either alone is an ordinary afternoon, the two together are a
+// trough going through. None of this is scored; the cloud base, the wind and the
+// precipitation already charge what the front brings.
+
+// PressurePoint is one hour of the pressure series.
+type PressurePoint struct {
+	Time string `json:"time"`
+	// QNH is the model's mean-sea-level pressure, hPa, null where it has none. It is the
+	// model's figure, not rounded down to the whole hectopascal an ATIS would give.
+	QNH *float64 `json:"qnh"`
+	// Tendency3h is the change in QNH over the three hours up to this one, hPa, null where
+	// the forecast does not reach that far back.
+	Tendency3h *float64 `json:"tendency_3h"`
+	// RapidFall is a fall of more than rapidFallHPa in those three hours.
+	RapidFall bool `json:"rapid_fall,omitempty"`
+	// Frontal marks the hours a front is likely to pass through. See frontalHour.
+	Frontal bool `json:"frontal,omitempty"`
+}
+
+const (
+	// tendencyWindow is the period the tendency is measured over, as in synoptic reports.
+	tendencyWindow = 3 * time.Hour
+
+	// rapidFallHPa is the three-hour fall, hPa, past which the Met Office calls a barometer
+	// "falling quickly" -- the wording a forecaster keeps for a deepening low.
+	rapidFallHPa = 3.5
+
+	// frontalFallHPa is the three-hour fall that, with a wind shift, marks a front: a
+	// steadily falling barometer, short of rapid.
+	frontalFallHPa = 1.5
+
+	// frontalShiftDegrees is the change in the surface wind's direction across the hour
+	// that marks a front, measured frontalShiftSpan either side of it.
+	frontalShiftDegrees = 45
+	frontalShiftSpan    = 2 * time.Hour
+
+	// frontalMinWindKn is the surface wind, kn, below which a direction is noise: a light
+	// and variable wind swings through 90 degrees on a quiet afternoon.
+	frontalMinWindKn = 6.0
+)
+
+// directionChange is the angle, 0 to 180 degrees, between two wind directions.
+func directionChange(a, b int) int {
+	d := ((a-b)%360 + 360) % 360
+	return min(d, 360-d)
+}
+
+// pressureSeries builds the pressure series from the forecast's hours. Non-nil, so a
+// forecast without hours marshals as [] rather than null.
+func pressureSeries(hours []forecastHour) []PressurePoint {
+	// The tendency and the wind shift are looked up by time rather than by index, so a gap
+	// in the forecast reads as nothing to compare against rather than as the wrong hour.
+	byTime := make(map[time.Time]forecastHour, len(hours))
+	for _, hour := range hours {
+		if t, err := hourTime(hour.time); err == nil {
+			byTime[t] = hour
+		}
+	}
+
+	series := make([]PressurePoint, 0, len(hours))
+	for _, hour := range hours {
+		point := PressurePoint{Time: hour.time, QNH: hour.pressureMSL}
+		t, err := hourTime(hour.time)
+		if err != nil {
+			slog.Error("failed to parse time", "time", hour.time, "error", err)
+			series = append(series, point)
+			continue
+		}
+		if before, ok := byTime[t.Add(-tendencyWindow)]; ok && hour.pressureMSL != nil && before.pressureMSL != nil {
+			tendency := *hour.pressureMSL - *before.pressureMSL
+			point.Tendency3h = &tendency
+			point.RapidFall = tendency < -rapidFallHPa
+		}
+		point.Frontal = frontalHour(point, byTime[t.Add(-frontalShiftSpan)], byTime[t.Add(frontalShiftSpan)])
+		series = append(series, point)
+	}
+	return series
+}
+
+// frontalHour reports whether a front is likely to pass through the hour: the barometer has
+// fallen at least frontalFallHPa in the three hours up to it, and the surface wind turns by
+// frontalShiftDegrees or more between frontalShiftSpan before and after it. Hours too close
+// to either end of the forecast to compare are never frontal.
+func frontalHour(point PressurePoint, before, after forecastHour) bool {
+	if point.Tendency3h == nil || *point.Tendency3h > -frontalFallHPa {
+		return false
+	}
+	for _, hour := range []forecastHour{before, after} {
+		if hour.windDirection10m == nil || hour.windSpeed10m == nil || *hour.windSpeed10m < frontalMinWindKn {
+			return false
+		}
+	}
+	return directionChange(*before.windDirection10m, *after.windDirection10m) >= frontalShiftDegrees
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestDirectionChange(t *testing.T) {
+	tests := []struct{ a, b, want int }{
+		{200, 290, 90},
+		{350, 20, 30},
+		{20, 350, 30},
+		{90, 270, 180},
+		{240, 240, 0},
+	}
+	for _, tc := range tests {
+		if got := directionChange(tc.a, tc.b); got != tc.want {
+			t.Errorf("directionChange(%d, %d) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+// frontFriday is the request's Friday evening: the barometer falls through the afternoon,
+// and between 19:00 and 20:00 the south-westerly veers north-westerly behind a cold front.
+func frontFriday() []forecastHour {
+	pressures := []float64{1012, 1011.2, 1010.1, 1008.9, 1007.5, 1006.0, 1005.1, 1005.4, 1006.6, 1008.0}
+	directions := []int{210, 210, 215, 220, 225, 230, 280, 300, 310, 310}
+	hours := hourlyFixture([]string{
+		"2026-10-16T14:00", "2026-10-16T15:00", "2026-10-16T16:00", "2026-10-16T17:00", "2026-10-16T18:00",
+		"2026-10-16T19:00", "2026-10-16T20:00", "2026-10-16T21:00", "2026-10-16T22:00", "2026-10-16T23:00",
+	}).hours
+	for i := range hours {
+		hours[i].pressureMSL = ptrFloat(pressures[i])
+		hours[i].windSpeed10m, hours[i].windDirection10m = ptrFloat(14), ptrTo(directions[i])
+	}
+	return hours
+}
+
+func TestPressureSeries(t *testing.T) {
+	series := pressureSeries(frontFriday())
+	if len(series) != 10 {
+		t.Fatalf("len(series) = %d, want 10", len(series))
+	}
+
+	// The first three hours have nothing three hours back to compare against.
+	for _, p := range series[:3] {
+		if p.Tendency3h != nil || p.RapidFall || p.Frontal {
+			t.Errorf("%s = %+v, want no tendency", p.Time, p)
+		}
+	}
+	if p := series[3]; p.Tendency3h == nil || math.Abs(*p.Tendency3h+3.1) > 0.01 || p.RapidFall {
+		t.Errorf("17:00 = %+v, want -3.1 hPa, not yet rapid", p)
+	}
+	if p := series[4]; p.Tendency3h == nil || math.Abs(*p.Tendency3h+3.7) > 0.01 || !p.RapidFall {
+		t.Errorf("18:00 = %+v, want -3.7 hPa and a rapid fall", p)
+	}
+
+	// The wind turns between 16:00 and 23:00, and the hours across the turn that still have
+	// the fall behind them are frontal: by 22:00 the barometer is rising again.
+	var frontal []string
+	for _, p := range series {
+		if p.Frontal {
+			frontal = append(frontal, p.Time)
+		}
+	}
+	if len(frontal) != 4 || frontal[0] != "2026-10-16T18:00" || frontal[3] != "2026-10-16T21:00" {
+		t.Errorf("frontal hours = %v, want 18:00 to 21:00", frontal)
+	}
+}
+
+func TestPressureSeries_NoFrontWithoutBoth(t *testing.T) {
+	// A falling barometer and a steady wind: a low going by to the north.
+	steady := frontFriday()
+	for i := range steady {
+		steady[i].windDirection10m = ptrTo(220)
+	}
+	// The same turn in a light and variable wind.
+	light := frontFriday()
+	for i := range light {
+		light[i].windSpeed10m = ptrFloat(3)
+	}
+	// The same turn with a steady barometer: a sea breeze, not a front.
+	flat := frontFriday()
+	for i := range flat {
+		flat[i].pressureMSL = ptrFloat(1015)
+	}
+
+	for name, hours := range map[string][]forecastHour{"steady wind": steady, "light wind": light, "steady barometer": flat} {
+		for _, p := range pressureSeries(hours) {
+			if p.Frontal {
+				t.Errorf("%s: %s marked frontal", name, p.Time)
+			}
+		}
+	}
+}
+
+func TestPressureSeries_Gaps(t *testing.T) {
+	hours := frontFriday()
+	hours[0].pressureMSL = nil
+	// Drop 15:00: 18:00 still finds 15:00 missing rather than taking 16:00 in its place.
+	hours = append(hours[:1], hours[2:]...)
+
+	series := pressureSeries(hours)
+	if series[0].QNH != nil {
+		t.Errorf("14:00 QNH = %v, want null", *series[0].QNH)
+	}
+	if series[2].Tendency3h != nil {
+		t.Errorf("17:00 tendency = %v, want none against a missing 14:00", *series[2].Tendency3h)
+	}
+	if series[3].Tendency3h != nil {
+		t.Errorf("18:00 tendency = %v, want none against a dropped 15:00", *series[3].Tendency3h)
+	}
+	if pressureSeries(nil) == nil {
+		t.Error("pressureSeries(nil) = nil, want an empty slice so it marshals as []")
+	}
+}
+
+func TestProcessWeatherData_PressureData(t *testing.T) {
+	stubDayLight(t)
+
+	forecast := &hourlyForecast{hours: frontFriday()}
+	got := processWeatherData(context.Background(), forecast, testAirport, defaultProfile())
+	if len(got.PressureData) != len(forecast.hours) {
+		t.Fatalf("len(PressureData) = %d, want %d", len(got.PressureData), len(forecast.hours))
+	}
+	if p := got.PressureData[5]; p.QNH == nil || *p.QNH != 1006.0 || !p.Frontal {
+		t.Errorf("19:00 = %+v, want QNH 1006 and frontal", p)
+	}
+}
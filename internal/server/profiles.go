@@ -0,0 +1,291 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+)
+
+// Scoring profiles.
+//
+// vfrLimits is tuned for one pilot: a current PPL holder in a light single. A student on a
+// first solo cross-country and a 200-hour pilot looking at the same 12kn crosswind are not
+// asking the same question, and one table cannot answer both without being wrong for one
+// of them.
+//
+// A profile is vfrLimits with some of its factors retuned. It can move where a factor
+// bites, how heavily it counts and whether it ends the hour -- never what the factor
+// measures. The value extraction stays in vfrLimits, so every profile scores the same
+// inputs and two profiles' breakdowns are always comparable line by line.
+
+// defaultProfileID is the profile served when a request names none. It is vfrLimits
+// untouched, so a client that has never heard of profiles sees the scores it always did.
+const defaultProfileID = "ppl"
+
+// ScoringProfile is what /api/config lists, so the frontend can offer a selector.
+type ScoringProfile struct {
+	ID          string `json:"id"`   // "student-solo" -- the API parameter
+	Name        string `json:"name"` // "Student solo"
+	Description string `json:"description"`
+	// Aircraft is the aircraft the takeoff distance is worked out for, from aircraftTypes.
+	// Empty leaves the profile without the takeoff factor.
+	Aircraft string `json:"aircraft,omitempty"`
+}
+
+// factorTuning replaces one factor's curve, weight and wall. All three are given together
+// rather than field by field: a curve retuned without its wall reconsidered is the mistake
+// this would otherwise invite.
+type factorTuning struct {
+	curve  []anchor
+	weight float64
+	wall   bool
+}
+
+// profileDefinition is a profile as written down: a name and the factors it retunes, keyed
+// by the factor's name in vfrLimits.
+type profileDefinition struct {
+	ScoringProfile
+	tunings map[string]factorTuning
+}
+
+// profileDefinitions is the list of profiles, in the order the frontend offers them. The
+// default comes first and retunes nothing.
+var profileDefinitions = []profileDefinition{
+	{
+		ScoringProfile: ScoringProfile{
+			ID:          defaultProfileID,
+			Name:        "PPL",
+			Description: "A current private pilot in a light single. The built-in table as it stands.",
+			Aircraft:    "c172",
+		},
+	},
+	{
+		// Club minima for a first solo cross-country, not the legal ones: the legal VFR
+		// minima are far below what anyone would send a student out in.
+		ScoringProfile: ScoringProfile{
+			ID:          "student-solo",
+			Name:        "Student solo",
+			Description: "Club minima for solo cross-country flights: higher ceilings, better visibility, much less wind.",
+			Aircraft:    "c152",
+		},
+		tunings: map[string]factorTuning{
+			"cloud base": {
+				curve:  []anchor{{perfect, 50}, {good, 35}, {difficult, 30}, {critical, 20}},
+				weight: 1.0, wall: true,
+			},
+			"visibility": {
+				curve:  []anchor{{perfect, 50}, {good, 30}, {difficult, 15}, {critical, 10}},
+				weight: 1.3, wall: true,
+			},
+			"wind": {
+				curve:  []anchor{{perfect, 5}, {good, 8}, {difficult, 12}, {critical, 18}},
+				weight: 1.0, wall: true,
+			},
+			"crosswind": {
+				curve:  []anchor{{perfect, 2}, {good, 4}, {difficult, 6}, {critical, 10}},
+				weight: 1.0, wall: true,
+			},
+			"crosswind gust spread": {
+				curve:  []anchor{{perfect, 2}, {good, 4}, {difficult, 7}, {critical, 10}},
+				weight: 1.0, wall: true,
+			},
+		},
+	},
+	{
+		// Light, slow and high-winged: it is the wind that grounds an ultralight, long
+		// before the sky does.
+		ScoringProfile: ScoringProfile{
+			ID:          "ultralight",
+			Name:        "Ultralight",
+			Description: "A light aircraft that is sensitive to wind and gusts; cloud and visibility as for a PPL.",
+			Aircraft:    "c42",
+		},
+		tunings: map[string]factorTuning{
+			"wind": {
+				curve:  []anchor{{perfect, 5}, {good, 8}, {difficult, 15}, {critical, 22}},
+				weight: 1.0, wall: true,
+			},
+			"crosswind": {
+				curve:  []anchor{{perfect, 2}, {good, 4}, {difficult, 8}, {critical, 12}},
+				weight: 1.0, wall: true,
+			},
+			"crosswind gust spread": {
+				curve:  []anchor{{perfect, 2}, {good, 4}, {difficult, 7}, {critical, 10}},
+				weight: 1.2, wall: true,
+			},
+		},
+	},
+	{
+		// Still a VFR score -- the question is the same -- but a low ceiling is a clearance
+		// away for an instrument-rated pilot rather than a cancellation, so cloud and
+		// visibility stop dominating the hour as early.
+		ScoringProfile: ScoringProfile{
+			ID:          "ir",
+			Name:        "IR-rated",
+			Description: "An instrument-rated pilot who can pick up a clearance: low cloud and poor visibility weigh less.",
+			Aircraft:    "pa28",
+		},
+		tunings: map[string]factorTuning{
+			"cloud base": {
+				curve:  []anchor{{perfect, 30}, {good, 15}, {difficult, 10}, {critical, 5}},
+				weight: 0.8, wall: true,
+			},
+			"visibility": {
+				curve:  []anchor{{perfect, 30}, {good, 15}, {difficult, 8}, {critical, 3}},
+				weight: 1.0, wall: true,
+			},
+		},
+	},
+}
+
+// scoringProfile is a definition resolved against vfrLimits: a complete table, ready for
+// scoreVFR.
+type scoringProfile struct {
+	ScoringProfile
+	limits []factor
+	// aircraft is ScoringProfile.Aircraft resolved, or nil for a profile without one.
+	aircraft *aircraftType
+}
+
+// scoringTable is every profile the server scores against, resolved and validated as one
+// unit. It is swapped whole -- see limitsfile.go -- so a request never sees one profile
+// from the old table and another from the new.
+type scoringTable struct {
+	// profiles is every profile in display order, default first.
+	profiles []*scoringProfile
+	// byID indexes profiles by ID.
+	byID map[string]*scoringProfile
+	// defaultProfile is the one named by defaultProfileID.
+	defaultProfile *scoringProfile
+}
+
+// scoring is the table in use. Written at init and on a reload, read by every request.
+var scoring atomic.Pointer[scoringTable]
+
+// profileIDRe is what a profile ID may be. The ID is a query parameter, and part of a cache
+// key between an airport's "/" and a model's "@", so it is held to characters that mean
+// nothing in either.
+var profileIDRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// buildScoringTable resolves every definition against base and validates the result with
+// the same rules vfrLimits itself is held to. A tuning that leaves a curve malformed would
+// score every hour of that profile wrong, and nothing else would notice.
+//
+// ladder is the severity ladder the table's factors are scored against; nil means the
+// built-in severityCost.
+func buildScoringTable(base []factor, ladder map[severity]float64, definitions []profileDefinition) (*scoringTable, error) {
+	if len(definitions) == 0 {
+		return nil, fmt.Errorf("no profiles defined")
+	}
+
+	table := &scoringTable{byID: make(map[string]*scoringProfile, len(definitions))}
+	for i, def := range definitions {
+		switch {
+		case def.ID == "":
+			return nil, fmt.Errorf("profile %d has no id", i)
+		case !profileIDRe.MatchString(def.ID):
+			return nil, fmt.Errorf("profile id %q must be lower-case letters, digits and dashes", def.ID)
+		case table.byID[def.ID] != nil:
+			return nil, fmt.Errorf("duplicate profile id %q", def.ID)
+		case def.Name == "":
+			return nil, fmt.Errorf("profile %q has no name", def.ID)
+		}
+
+		profile, err := buildProfile(base, ladder, def)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", def.ID, err)
+		}
+		table.profiles = append(table.profiles, profile)
+		table.byID[def.ID] = profile
+	}
+
+	table.defaultProfile = table.byID[defaultProfileID]
+	if table.defaultProfile == nil {
+		return nil, fmt.Errorf("the default profile %q is not defined", defaultProfileID)
+	}
+	return table, nil
+}
+
+func buildProfile(base []factor, ladder map[severity]float64, def profileDefinition) (*scoringProfile, error) {
+	// A copy, so a tuning never writes through to vfrLimits or another profile's table.
+	// The curves themselves are shared, which is safe because nothing writes to them.
+	limits := make([]factor, len(base))
+	copy(limits, base)
+
+	applied := 0
+	for i := range limits {
+		limits[i].ladder = ladder
+
+		tuning, ok := def.tunings[limits[i].name]
+		if !ok {
+			continue
+		}
+		limits[i].curve = tuning.curve
+		limits[i].weight = tuning.weight
+		limits[i].wall = tuning.wall
+		applied++
+	}
+	// A misspelt factor name would otherwise be a tuning that silently does nothing.
+	if applied != len(def.tunings) {
+		for name := range def.tunings {
+			if !hasFactor(base, name) {
+				return nil, fmt.Errorf("tunes unknown factor %q", name)
+			}
+		}
+	}
+
+	for _, f := range limits {
+		if err := f.validate(); err != nil {
+			return nil, fmt.Errorf("factor %q: %w", f.name, err)
+		}
+	}
+
+	profile := &scoringProfile{ScoringProfile: def.ScoringProfile, limits: limits}
+	if def.Aircraft != "" {
+		aircraft, err := lookupAircraft(def.Aircraft)
+		if err != nil {
+			return nil, err
+		}
+		profile.aircraft = aircraft
+	}
+	return profile, nil
+}
+
+func hasFactor(limits []factor, name string) bool {
+	for _, f := range limits {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultProfile returns the profile served when a request names none.
+func defaultProfile() *scoringProfile {
+	return scoring.Load().defaultProfile
+}
+
+// profileList returns the profiles as /api/config lists them.
+func profileList() []ScoringProfile {
+	table := scoring.Load()
+	list := make([]ScoringProfile, len(table.profiles))
+	for i, p := range table.profiles {
+		list[i] = p.ScoringProfile
+	}
+	return list
+}
+
+// lookupProfile resolves a profile ID from a request, the same way lookupAirport resolves
+// an airfield: empty means the default, unknown is an error. Scoring a student's request
+// against the PPL table without saying so is not a fallback anyone could spot.
+func lookupProfile(id string) (*scoringProfile, error) {
+	table := scoring.Load()
+	if id == "" {
+		return table.defaultProfile, nil
+	}
+	p, ok := table.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", id)
+	}
+	return p, nil
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// The shipped profiles are built in init(), which would already have panicked on a bad one.
+// This names the profile and the factor when one goes wrong.
+func TestProfileDefinitionsAreWellFormed(t *testing.T) {
+	if _, err := buildScoringTable(vfrLimits, nil, profileDefinitions); err != nil {
+		t.Fatal(err)
+	}
+	if defaultProfile() == nil || defaultProfile().ID != defaultProfileID {
+		t.Fatalf("defaultProfile() = %v, want %q", defaultProfile(), defaultProfileID)
+	}
+}
+
+// The default profile is vfrLimits untouched, so a client that never asks for a profile
+// gets the scores it always got.
+func TestDefaultProfileIsTheBuiltInTable(t *testing.T) {
+	if len(defaultProfile().limits) != len(vfrLimits) {
+		t.Fatalf("len(limits) = %d, want %d", len(defaultProfile().limits), len(vfrLimits))
+	}
+	for i, f := range defaultProfile().limits {
+		base := vfrLimits[i]
+		if f.name != base.name || f.weight != base.weight || f.wall != base.wall || len(f.curve) != len(base.curve) {
+			t.Errorf("factor %q differs from vfrLimits", f.name)
+		}
+	}
+}
+
+func TestBuildProfiles_Rejects(t *testing.T) {
+	ppl := profileDefinition{ScoringProfile: ScoringProfile{ID: defaultProfileID, Name: "PPL"}}
+
+	tests := []struct {
+		name        string
+		definitions []profileDefinition
+	}{
+		{name: "no profiles at all", definitions: nil},
+		{name: "no default profile", definitions: []profileDefinition{
+			{ScoringProfile: ScoringProfile{ID: "other", Name: "Other"}},
+		}},
+		{name: "a duplicate id", definitions: []profileDefinition{ppl, ppl}},
+		{name: "a profile without an id", definitions: []profileDefinition{
+			ppl, {ScoringProfile: ScoringProfile{Name: "Nameless"}},
+		}},
+		{name: "a profile without a name", definitions: []profileDefinition{
+			ppl, {ScoringProfile: ScoringProfile{ID: "anonymous"}},
+		}},
+		{
+			// A misspelt factor would otherwise be a tuning that silently does nothing.
+			name: "a tuning for a factor that does not exist",
+			definitions: []profileDefinition{ppl, {
+				ScoringProfile: ScoringProfile{ID: "typo", Name: "Typo"},
+				tunings: map[string]factorTuning{
+					"cross wind": {curve: []anchor{{perfect, 2}, {good, 4}}, weight: 1, wall: true},
+				},
+			}},
+		},
+		{
+			name: "an aircraft that is not in the catalogue",
+			definitions: []profileDefinition{ppl, {
+				ScoringProfile: ScoringProfile{ID: "glider", Name: "Glider", Aircraft: "ask21"},
+			}},
+		},
+		{
+			name: "a tuning that leaves a curve malformed",
+			definitions: []profileDefinition{ppl, {
+				ScoringProfile: ScoringProfile{ID: "backwards", Name: "Backwards"},
+				tunings: map[string]factorTuning{
+					"crosswind": {curve: []anchor{{perfect, 10}, {good, 4}, {difficult, 8}}, weight: 1, wall: true},
+				},
+			}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := buildScoringTable(vfrLimits, nil, tc.definitions); err == nil {
+				t.Error("buildScoringTable() = nil error, want one")
+			}
+		})
+	}
+}
+
+// A tuning replaces the factor in the profile's own table and nowhere else.
+func TestBuildProfiles_TuningDoesNotLeakIntoTheBase(t *testing.T) {
+	before := vfrLimits[3]
+
+	table, err := buildScoringTable(vfrLimits, nil, []profileDefinition{
+		{ScoringProfile: ScoringProfile{ID: defaultProfileID, Name: "PPL"}},
+		{
+			ScoringProfile: ScoringProfile{ID: "strict", Name: "Strict"},
+			tunings: map[string]factorTuning{
+				before.name: {curve: []anchor{{perfect, 1}, {good, 2}}, weight: 2, wall: true},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if after := vfrLimits[3]; after.weight != before.weight || len(after.curve) != len(before.curve) {
+		t.Errorf("vfrLimits[%q] was rewritten by a profile's tuning", before.name)
+	}
+	if got := table.profiles[1].limits[3]; got.weight != 2 || len(got.curve) != 2 {
+		t.Errorf("strict profile's %q = weight %v, %d anchors, want the tuning", got.name, got.weight, len(got.curve))
+	}
+}
+
+// The point of the feature: an hour a PPL holder would take is a hard one for a student.
+// 8kn straight across the runway is difficult in the PPL table and past the student's wall.
+func TestProfiles_StudentScoresStricterThanPPL(t *testing.T) {
+	student, err := lookupProfile("student-solo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := scoringConditions(t)
+	c.windSpeed = 11
+	c.crosswind = 11
+	c.crosswindGusts = 11
+
+	ppl, _, _ := scoreVFR(c, defaultProfile().limits)
+	solo, penalties, _ := scoreVFR(c, student.limits)
+
+	if ppl == 0 {
+		t.Fatalf("PPL score = 0, want the hour flyable for the test to mean anything")
+	}
+	if solo != 0 {
+		t.Errorf("student score = %d, want 0 -- 11kn of crosswind is past the student's wall", solo)
+	}
+	if len(penalties) != 1 || penalties[0].Severity != noGo.String() {
+		t.Errorf("penalties = %+v, want exactly the no-go reason", penalties)
+	}
+}
+
+func TestLookupProfile(t *testing.T) {
+	if p, err := lookupProfile(""); err != nil || p != defaultProfile() {
+		t.Errorf("lookupProfile(\"\") = %v, %v, want the default", p, err)
+	}
+	if p, err := lookupProfile("ultralight"); err != nil || p.ID != "ultralight" {
+		t.Errorf("lookupProfile(ultralight) = %v, %v", p, err)
+	}
+	if _, err := lookupProfile("glider"); err == nil {
+		t.Error("lookupProfile(glider) = nil error, want one")
+	}
+}
+
+func TestGetConfig_ListsTheProfiles(t *testing.T) {
+	withTestAirports(t)
+
+	rec := httptest.NewRecorder()
+	getConfig(rec, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+
+	var config ConfigResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &config); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(config.Profiles) != len(profileDefinitions) {
+		t.Errorf("len(Profiles) = %d, want %d", len(config.Profiles), len(profileDefinitions))
+	}
+	if config.DefaultProfile != defaultProfileID {
+		t.Errorf("DefaultProfile = %q, want %q", config.DefaultProfile, defaultProfileID)
+	}
+	if config.Profiles[0].ID != defaultProfileID {
+		t.Errorf("Profiles[0] = %q, want the default first", config.Profiles[0].ID)
+	}
+}
+
+// Each profile is its own cache entry: a student's request must not be answered with the
+// payload scored for a PPL, and vice versa.
+func TestGetWeatherData_CachesPerProfile(t *testing.T) {
+	withTestAirports(t)
+
+	var scoredFor []string
+	stubFetchWeather(t, func(_ context.Context, _ Airport, profile *scoringProfile) (*ProcessedWeatherData, error) {
+		scoredFor = append(scoredFor, profile.ID)
+		return &ProcessedWeatherData{GeneratedAt: time.Now()}, nil
+	})
+
+	for _, query := range []string{"", "?profile=student-solo", "?profile=student-solo", "?profile=ppl"} {
+		rec := httptest.NewRecorder()
+		getWeatherData(rec, httptest.NewRequest(http.MethodGet, "/api/weather"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", query, rec.Code, http.StatusOK)
+		}
+	}
+
+	if len(scoredFor) != 2 || scoredFor[0] != defaultProfileID || scoredFor[1] != "student-solo" {
+		t.Errorf("fetched for %v, want one fetch per profile: [ppl student-solo]", scoredFor)
+	}
+}
+
+func TestGetWeatherData_UnknownProfileIsRejected(t *testing.T) {
+	withTestAirports(t)
+
+	rec := httptest.NewRecorder()
+	getWeatherData(rec, httptest.NewRequest(http.MethodGet, "/api/weather?profile=glider", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Weather providers.
+//
+// Scoring and charting used to read Open-Meteo's JSON directly: a struct with a field per
+// variable per pressure level, nineteen of them for cloud cover alone, and a processor that
+// listed them all again by hand. Anything else that might feed the dashboard -- DWD's own
+// open data, a replay of a captured day, a stub in a test -- would have had to dress up as
+// an Open-Meteo response first.
+//
+// A provider now hands over an hourlyForecast instead: one forecastHour per hour, with the
+// pressure levels as a slice, in units the rest of the server already uses. What a provider
+// does not have is nil, and processWeatherData treats a nil exactly as it treated a missing
+// array before: no temperature row, no visibility, no layer at that level.
+
+// forecastReplayDirEnv names a directory of captured responses to serve instead of
+// fetching. See replayProvider.
+const forecastReplayDirEnv = "FLUGWETTER_FORECAST_REPLAY_DIR"
+
+// weatherProvider fetches one airport's hourly forecast from one model.
+type weatherProvider interface {
+	forecast(ctx context.Context, airport Airport, model forecastModel) (*hourlyForecast, error)
+}
+
+// hourlyForecast is a forecast in no provider's shape, oldest hour first.
+type hourlyForecast struct {
+	hours []forecastHour
+
+	// history is the hours before the forecast's first, oldest first, where the provider
+	// has them: what has already fallen on the airfield, for the soft field factor. Never
+	// scored or charted. See softfield.go.
+	history []forecastHour
+}
+
+// forecastHour is one hour of a forecast. Every value is a pointer because a provider may
+// lack any of them for any hour, and zero is a real temperature, wind and cloud cover.
+type forecastHour struct {
+	// time is the hour in naive UTC, "2006-01-02T15:04", as the payload carries it.
+	time string
+
+	temperature              *float64 // °C at 2m
+	dewPoint                 *float64 // °C at 2m
+	relativeHumidity         *int     // % at 2m
+	pressureMSL              *float64 // hPa
+	precipitation            *float64 // mm over the hour
+	precipitationProbability *int     // %
+	weatherCode              *int     // WMO 4677
+	cape                     *float64 // J/kg
+	liftedIndex              *float64 // K
+	convectiveCloudBase      *float64 // m above ground
+	visibility               *float64 // m
+	snowDepth                *float64 // m lying on the ground
+	soilTemperature          *float64 // °C at the surface
+
+	cloudCover     *int // %, total
+	cloudCoverLow  *int
+	cloudCoverMid  *int
+	cloudCoverHigh *int
+
+	windSpeed10m     *float64 // kn
+	windGusts10m     *float64 // kn
+	windDirection10m *int     // degrees true, where the wind is from
+	windSpeed80m     *float64
+	windDirection80m *int
+
+	// levels are the pressure levels the provider has, highest pressure -- lowest
+	// altitude -- first.
+	levels []pressureLevel
+}
+
+// pressureLevel is one pressure level of one hour.
+type pressureLevel struct {
+	hPa              int
+	height           *float64 // geopotential height, m
+	cloudCover       *int     // %
+	windSpeed        *float64 // kn
+	windDirection    *int     // degrees true
+	temperature      *float64 // °C
+	relativeHumidity *int     // %
+}
+
+// level returns the hour's data at the given pressure, or false when the provider has none.
+func (h forecastHour) level(hPa int) (pressureLevel, bool) {
+	for _, l := range h.levels {
+		if l.hPa == hPa {
+			return l, true
+		}
+	}
+	return pressureLevel{}, false
+}
+
+// times returns the forecast's hours, for the lookups that need them before the hours
+// themselves are processed.
+func (f *hourlyForecast) times() []string {
+	times := make([]string, len(f.hours))
+	for i, h := range f.hours {
+		times[i] = h.time
+	}
+	return times
+}
+
+// openMeteoProvider fetches from Open-Meteo's forecast API. See openmeteo.go.
+type openMeteoProvider struct{}
+
+func (openMeteoProvider) forecast(ctx context.Context, airport Airport, model forecastModel) (*hourlyForecast, error) {
+	body, err := getJSON(ctx, buildModelAPIURL(airport, model))
+	if err != nil {
+		return nil, err
+	}
+	return decodeOpenMeteo(body, openMeteoPastDays)
+}
+
+// replayProvider serves captured Open-Meteo responses from a directory: <ICAO>_<model>.json
+// where there is one, else <ICAO>.json for every model. It is for reproducing a day, and
+// for running the server with no network at all. A capture has history only where it
+// records its past_days; see decodeOpenMeteo.
+type replayProvider struct {
+	dir string
+}
+
+func (p replayProvider) forecast(_ context.Context, airport Airport, model forecastModel) (*hourlyForecast, error) {
+	for _, name := range []string{airport.Identifier + "_" + model.ID + ".json", airport.Identifier + ".json"} {
+		body, err := os.ReadFile(filepath.Join(p.dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return decodeOpenMeteo(body, 0)
+	}
+	return nil, fmt.Errorf("no replay for %s in %s", airport.Identifier, p.dir)
+}
+
+// weatherProviderFromEnv picks the provider FLUGWETTER_FORECAST_REPLAY_DIR implies:
+// Open-Meteo unless a replay directory is set.
+func weatherProviderFromEnv() weatherProvider {
+	if dir := os.Getenv(forecastReplayDirEnv); dir != "" {
+		return replayProvider{dir: dir}
+	}
+	return openMeteoProvider{}
+}
+
+// weatherSource is the provider in use, set from the environment in Run. A var so tests can
+// swap it.
+var weatherSource weatherProvider = openMeteoProvider{}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// stubProvider serves a fixed forecast, whatever it is asked for.
+type stubProvider struct {
+	data *hourlyForecast
+}
+
+func (p stubProvider) forecast(context.Context, Airport, forecastModel) (*hourlyForecast, error) {
+	return p.data, nil
+}
+
+func withWeatherSource(t *testing.T, provider weatherProvider) {
+	t.Helper()
+
+	original := weatherSource
+	weatherSource = provider
+	t.Cleanup(func() {
+		weatherSource = original
+		cache.mutex.Lock()
+		cache.forecasts = make(map[string]*airportForecast)
+		cache.mutex.Unlock()
+	})
+}
+
+func TestDecodeOpenMeteo(t *testing.T) {
+	forecast, err := decodeOpenMeteo([]byte(`{"hourly": {
+		"time": ["2026-08-03T10:00", "2026-08-03T11:00"],
+		"temperature_2m": [18.5, null],
+		"visibility": [24000],
+		"cloud_cover_850hPa": [55, 70],
+		"geopotential_height_850hPa": [1500, 1510],
+		"snowfall": ["not", "read"]
+	}}`), 0)
+	if err != nil {
+		t.Fatalf("decodeOpenMeteo() = %v", err)
+	}
+	if len(forecast.hours) != 2 {
+		t.Fatalf("decoded %d hours, want 2", len(forecast.hours))
+	}
+
+	first, second := forecast.hours[0], forecast.hours[1]
+	if first.temperature == nil || *first.temperature != 18.5 || second.temperature != nil {
+		t.Errorf("temperature = %v, %v, want 18.5 and nil for the null", first.temperature, second.temperature)
+	}
+	// A column shorter than the times leaves the hours it does not reach without.
+	if first.visibility == nil || second.visibility != nil {
+		t.Errorf("visibility = %v, %v, want a value and then nil past the column's end", first.visibility, second.visibility)
+	}
+	level, ok := second.level(850)
+	if !ok || level.cloudCover == nil || *level.cloudCover != 70 || level.height == nil || level.windSpeed != nil {
+		t.Errorf("850hPa = %+v, want cover and height and no wind", level)
+	}
+	if first.windSpeed10m != nil {
+		t.Error("an absent column decoded as a value")
+	}
+
+	for _, body := range []string{`not json`, `{"hourly": {}}`, `{"hourly": {"time": ["2026-08-03T10:00"], "temperature_2m": ["warm"]}}`} {
+		if _, err := decodeOpenMeteo([]byte(body), 0); err == nil {
+			t.Errorf("decodeOpenMeteo(%s) accepted it", body)
+		}
+	}
+}
+
+func TestReplayProvider(t *testing.T) {
+	fixture, err := os.ReadFile(goldenFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "EDWN.json"), fixture, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "EDWN_gfs_seamless.json"), []byte(`{"hourly": {"time": ["2026-08-04T00:00"]}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider := replayProvider{dir: dir}
+	gfs, _ := catalogueModel("gfs_seamless")
+
+	if forecast, err := provider.forecast(context.Background(), testAirport, primaryModel()); err != nil || len(forecast.hours) != goldenFixtureHours {
+		t.Errorf("ICON replay = %v, %v, want the airport's file", forecast, err)
+	}
+	if forecast, err := provider.forecast(context.Background(), testAirport, gfs); err != nil || len(forecast.hours) != 1 {
+		t.Errorf("GFS replay = %v, %v, want the model's own file", forecast, err)
+	}
+	if _, err := provider.forecast(context.Background(), Airport{Identifier: "EDWG"}, primaryModel()); err == nil {
+		t.Error("an airport with no file replayed something")
+	}
+}
+
+// Whatever the provider, the forecast is scored and charted the same way.
+func TestFetchWeather_ScoresAnyProvider(t *testing.T) {
+	withTestAirports(t)
+	stubDayLight(t)
+	t.Setenv(ensembleURLEnv, "off")
+	withWeatherSource(t, stubProvider{hourlyFixture([]string{"2026-08-03T12:00", "2026-08-03T13:00"})})
+
+	got, err := fetchWeather(context.Background(), testAirport, defaultProfile())
+	if err != nil {
+		t.Fatalf("fetchWeather() = %v", err)
+	}
+	if len(got.VfrData) != 2 || got.VfrData[0].Probability < 0 || len(got.TemperatureData) != 2 {
+		t.Errorf("payload = %+v, want two scored hours", got.VfrData)
+	}
+}
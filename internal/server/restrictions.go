@@ -112,6 +112,35 @@ func (t *restrictionTracker) windowsFor(name string) []RestrictionWindow {
 	return nil
 }
 
+// activeOver returns the restricted areas -- ED-R, not ED-D -- whose boundary contains the
+// point, and whether the plan they come from is degraded, as snapshot reports it. An area
+// the plan gave no polygon for cannot be placed and is not returned.
+func (t *restrictionTracker) activeOver(lat, lon float64) (areas []RestrictedArea, degraded bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	for _, area := range t.areas {
+		if strings.HasPrefix(area.Name, "ED-R") && polygonContains(area.Polygon, lat, lon) {
+			areas = append(areas, RestrictedArea{Name: area.Name, Windows: slices.Clone(area.Windows)})
+		}
+	}
+	return areas, t.consecutiveFails >= restrictionsFailuresBeforeDegraded
+}
+
+// polygonContains is the even-odd ray test on [latitude, longitude] pairs. Treating degrees
+// as plane coordinates is fine at the size of an ED-R; none of them straddle the antimeridian.
+func polygonContains(polygon [][2]float64, lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		yi, xi := polygon[i][0], polygon[i][1]
+		yj, xj := polygon[j][0], polygon[j][1]
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
 // poll fetches the plan and replaces the set. A failure leaves the previous set in place:
 // stale activity times are useful, an empty list would read as "nothing is active".
 func (t *restrictionTracker) poll(ctx context.Context) {
@@ -0,0 +1,371 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Route scoring: "can I fly EDWN to EDWY and back", rather than "can I fly from EDWN".
+//
+// A route is an ordered list of points -- airfields from the list, or bare coordinates as
+// turning points. Each great-circle leg between them is sampled every
+// routeSampleSpacingKM, every sample gets a forecast of its own, and for each departure
+// hour every sample is scored with scoreVFR at the time the aeroplane would pass it, given
+// a true airspeed. The route's score for that departure is its worst sample, and the answer
+// names which leg and which factor that was.
+//
+// What is scored where follows what happens there. An airfield on the route is scored as
+// at /api/weather, crosswind included, because the aeroplane lands or takes off there. En
+// route there is no runway: crosswind and its gust spread do not apply, and everything else
+// does -- a cloud base of FL8 halfway is a cloud base of FL8.
+//
+// Wind is not applied to the ETA. The groundspeed would need the winds at the altitude
+// flown, which is a choice of altitude this endpoint does not make; at the distances a
+// day VFR route covers, the hour is what matters, and the hour is rounded anyway.
+const (
+	// routeSampleSpacingKM is the distance between en-route samples. ICON-D2's grid is
+	// 2.2km, but what limits a VFR route is weather systems, not grid cells: 25km keeps a
+	// shower line from slipping between two samples and a 100km leg to four fetches.
+	routeSampleSpacingKM = 25.0
+
+	// routeMaxSamples caps the forecasts one request can ask for. Each is an Open-Meteo
+	// call on a cold cache; 40 is a 1000km route.
+	routeMaxSamples = 40
+
+	// routeGridDegrees is what an en-route sample's position is snapped to before it is
+	// fetched. Two routes passing the same place then share a cache entry rather than each
+	// fetching a forecast a few hundred metres from the other's.
+	routeGridDegrees = 0.1
+
+	// routeFetchConcurrency bounds the simultaneous upstream calls for one route.
+	routeFetchConcurrency = 4
+
+	// The true airspeed assumed when none is given, and the range accepted: a slow
+	// ultralight to a fast single.
+	defaultRouteTASKnots = 90.0
+	minRouteTASKnots     = 40.0
+	maxRouteTASKnots     = 250.0
+
+	kmPerNauticalMile = 1.852
+)
+
+// RouteWaypoint is one point of a route as given.
+type RouteWaypoint struct {
+	// Identifier is the airfield's, or empty for a bare coordinate.
+	Identifier string  `json:"identifier,omitempty"`
+	Name       string  `json:"name"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+}
+
+// RouteLeg is the great-circle segment between two consecutive waypoints.
+type RouteLeg struct {
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	DistanceKM float64 `json:"distance_km"`
+	// CourseDeg is the initial true course.
+	CourseDeg       float64 `json:"course_deg"`
+	DurationMinutes float64 `json:"duration_minutes"`
+}
+
+// RouteSample is one point a forecast is scored at.
+type RouteSample struct {
+	Leg       int     `json:"leg"`
+	Kind      string  `json:"kind"` // "departure" | "en route" | "waypoint" | "destination"
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// DistanceKM is from the departure, along the route.
+	DistanceKM float64 `json:"distance_km"`
+}
+
+// RouteHour is the route's score for one departure time.
+type RouteHour struct {
+	Departure string    `json:"departure"` // "2026-08-03T09:00", as VfrPoint.Time
+	Arrival   time.Time `json:"arrival"`
+	// Probability is the worst sample's score; the three below are the worst of each kind
+	// of sample, so a reader can see whether it is the airfields or the way between them.
+	// Turning points are on the way: they count en route, not as the destination.
+	Probability            int `json:"probability"`
+	DepartureProbability   int `json:"departure_probability"`
+	EnRouteProbability     int `json:"en_route_probability"`
+	DestinationProbability int `json:"destination_probability"`
+	// LimitingLeg and LimitingSample index Legs and Samples: where the score was decided.
+	LimitingLeg    int `json:"limiting_leg"`
+	LimitingSample int `json:"limiting_sample"`
+	// LimitingFactor is the limiting sample's worst penalty, empty when nothing cost
+	// anything anywhere on the route.
+	LimitingFactor string `json:"limiting_factor,omitempty"`
+	// Penalties is the limiting sample's full breakdown.
+	Penalties []VfrPenalty `json:"penalties,omitempty"`
+}
+
+// Sample kinds.
+const (
+	sampleDeparture   = "departure"
+	sampleEnRoute     = "en route"
+	sampleWaypoint    = "waypoint"
+	sampleDestination = "destination"
+)
+
+// routePoint is a sample as fetched: the airfield behind it, which is a synthetic one en
+// route, and whether it has a runway the score should use.
+type routePoint struct {
+	RouteSample
+	airport   Airport
+	hasRunway bool
+}
+
+// parseRouteWaypoint reads one via= value: an airfield identifier, or "lat,lon".
+func parseRouteWaypoint(raw string) (RouteWaypoint, bool, error) {
+	raw = strings.TrimSpace(raw)
+	if lat, lon, ok := strings.Cut(raw, ","); ok {
+		latitude, errLat := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		longitude, errLon := strconv.ParseFloat(strings.TrimSpace(lon), 64)
+		// NaN parses, and compares false with every bound.
+		if errLat != nil || errLon != nil || math.IsNaN(latitude) || math.IsNaN(longitude) ||
+			latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+			return RouteWaypoint{}, false, fmt.Errorf("invalid coordinate %q", raw)
+		}
+		return RouteWaypoint{Name: raw, Latitude: latitude, Longitude: longitude}, false, nil
+	}
+
+	a, err := lookupAirport(raw)
+	if err != nil || raw == "" {
+		return RouteWaypoint{}, false, fmt.Errorf("unknown airport %q", raw)
+	}
+	return RouteWaypoint{Identifier: a.Identifier, Name: a.Name, Latitude: a.Latitude, Longitude: a.Longitude}, true, nil
+}
+
+// planRoute lays the samples out along the legs. The first waypoint must be an airfield,
+// and so must the last: a route starts and ends on a runway.
+func planRoute(waypoints []RouteWaypoint, tasKnots float64) ([]RouteLeg, []routePoint, error) {
+	if len(waypoints) < 2 {
+		return nil, nil, fmt.Errorf("a route needs at least two points")
+	}
+	if waypoints[0].Identifier == "" || waypoints[len(waypoints)-1].Identifier == "" {
+		return nil, nil, fmt.Errorf("a route must start and end at an airfield")
+	}
+
+	speedKMH := tasKnots * kmPerNauticalMile
+	pointAt := func(wp RouteWaypoint, leg int, kind string, distance float64) routePoint {
+		p := routePoint{RouteSample: RouteSample{Leg: leg, Kind: kind, Latitude: wp.Latitude, Longitude: wp.Longitude, DistanceKM: distance}}
+		if wp.Identifier != "" {
+			p.airport, p.hasRunway = airportsByID[wp.Identifier], true
+		} else {
+			p.airport = enRouteAirport(wp.Latitude, wp.Longitude)
+		}
+		return p
+	}
+
+	legs := make([]RouteLeg, 0, len(waypoints)-1)
+	points := []routePoint{pointAt(waypoints[0], 0, sampleDeparture, 0)}
+	covered := 0.0
+
+	for i := 0; i+1 < len(waypoints); i++ {
+		from, to := waypoints[i], waypoints[i+1]
+		distance := distanceKM(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+		legs = append(legs, RouteLeg{
+			From:            from.Name,
+			To:              to.Name,
+			DistanceKM:      distance,
+			CourseDeg:       initialCourse(from.Latitude, from.Longitude, to.Latitude, to.Longitude),
+			DurationMinutes: distance / speedKMH * 60,
+		})
+
+		steps := int(math.Ceil(distance / routeSampleSpacingKM))
+		for s := 1; s < steps; s++ {
+			f := float64(s) / float64(steps)
+			lat, lon := intermediatePoint(from.Latitude, from.Longitude, to.Latitude, to.Longitude, f)
+			points = append(points, pointAt(RouteWaypoint{Latitude: lat, Longitude: lon}, i, sampleEnRoute, covered+f*distance))
+		}
+		covered += distance
+
+		kind := sampleWaypoint
+		if i+2 == len(waypoints) {
+			kind = sampleDestination
+		}
+		points = append(points, pointAt(to, i, kind, covered))
+
+		if len(points) > routeMaxSamples {
+			return nil, nil, fmt.Errorf("route is too long: more than %d samples", routeMaxSamples)
+		}
+	}
+	return legs, points, nil
+}
+
+// enRouteAirport is the synthetic airfield a sample's forecast is fetched and cached under.
+// It has no runway, so windOnRunways reports no crosswind or tailwind on it.
+func enRouteAirport(lat, lon float64) Airport {
+	snap := func(v float64) float64 { return math.Round(v/routeGridDegrees) * routeGridDegrees }
+	lat, lon = snap(lat), snap(lon)
+	id := fmt.Sprintf("%.1f,%.1f", lat, lon)
+	return Airport{Identifier: id, Name: "en route " + id, Latitude: lat, Longitude: lon}
+}
+
+// initialCourse is the true course at the start of a great-circle leg, in degrees.
+func initialCourse(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	phi1, phi2, dLon := lat1*rad, lat2*rad, (lon2-lon1)*rad
+	y := math.Sin(dLon) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)/rad+360, 360)
+}
+
+// intermediatePoint is the point a fraction f of the way along the great circle between two
+// points.
+func intermediatePoint(lat1, lon1, lat2, lon2, f float64) (lat, lon float64) {
+	const rad = math.Pi / 180
+	phi1, lambda1, phi2, lambda2 := lat1*rad, lon1*rad, lat2*rad, lon2*rad
+	// The angular distance between the two.
+	delta := distanceKM(lat1, lon1, lat2, lon2) / earthRadiusKM
+	if delta == 0 {
+		return lat1, lon1
+	}
+	a := math.Sin((1-f)*delta) / math.Sin(delta)
+	b := math.Sin(f*delta) / math.Sin(delta)
+	x := a*math.Cos(phi1)*math.Cos(lambda1) + b*math.Cos(phi2)*math.Cos(lambda2)
+	y := a*math.Cos(phi1)*math.Sin(lambda1) + b*math.Cos(phi2)*math.Sin(lambda2)
+	z := a*math.Sin(phi1) + b*math.Sin(phi2)
+	return math.Atan2(z, math.Hypot(x, y)) / rad, math.Atan2(y, x) / rad
+}
+
+// fetchRouteForecasts fetches every distinct location on the route once, through the same
+// cache /api/weather uses.
+func fetchRouteForecasts(ctx context.Context, points []routePoint, profile *scoringProfile) (map[string]*ProcessedWeatherData, error) {
+	unique := make(map[string]Airport)
+	for _, p := range points {
+		unique[p.airport.Identifier] = p.airport
+	}
+
+	var (
+		mutex     sync.Mutex
+		wg        sync.WaitGroup
+		firstErr  error
+		forecasts = make(map[string]*ProcessedWeatherData, len(unique))
+		slots     = make(chan struct{}, routeFetchConcurrency)
+	)
+	for id, airport := range unique {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			data, err := GetWeatherData(ctx, airport, profile)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("forecast for %s: %w", id, err)
+				}
+				return
+			}
+			forecasts[id] = data
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return forecasts, nil
+}
+
+// scoreRoute scores every departure hour of the departure airfield's forecast for which
+// the whole route still falls within the forecast.
+func scoreRoute(points []routePoint, forecasts map[string]*ProcessedWeatherData, profile *scoringProfile, tasKnots float64) []RouteHour {
+	// The model's conditions per sample, by hour.
+	byHour := make([]map[time.Time]conditions, len(points))
+	for i, p := range points {
+		data := forecasts[p.airport.Identifier]
+		byHour[i] = make(map[time.Time]conditions, len(data.modelConditions))
+		for _, c := range data.modelConditions {
+			if !c.time.IsZero() {
+				byHour[i][c.time] = c
+			}
+		}
+	}
+
+	departure := forecasts[points[0].airport.Identifier]
+	speedKMH := tasKnots * kmPerNauticalMile
+	hours := make([]RouteHour, 0, len(departure.modelConditions))
+
+	for _, dep := range departure.modelConditions {
+		if dep.time.IsZero() {
+			continue
+		}
+
+		hour := RouteHour{
+			Departure:   dep.time.Format("2006-01-02T15:04"),
+			Probability: 101, DepartureProbability: 101, EnRouteProbability: 101, DestinationProbability: 101,
+		}
+		complete := true
+		for i, p := range points {
+			eta := dep.time.Add(time.Duration(p.DistanceKM / speedKMH * float64(time.Hour)))
+			c, ok := byHour[i][eta.Round(time.Hour)]
+			if !ok {
+				complete = false
+				break
+			}
+			if !p.hasRunway {
+				c.crosswind, c.crosswindGusts, c.tailwind = 0, 0, 0
+				c.runwayInUse, c.runwaySurface = "", ""
+			}
+
+			probability, penalties, _ := scoreVFR(c, profile.limits)
+			if probability < 0 {
+				// An hour that cannot be scored anywhere on the route cannot be scored.
+				complete = false
+				break
+			}
+
+			switch p.Kind {
+			case sampleDeparture:
+				hour.DepartureProbability = min(hour.DepartureProbability, probability)
+			case sampleEnRoute, sampleWaypoint:
+				hour.EnRouteProbability = min(hour.EnRouteProbability, probability)
+			default: // sampleDestination
+				hour.DestinationProbability = min(hour.DestinationProbability, probability)
+			}
+			if probability < hour.Probability {
+				hour.Probability = probability
+				hour.LimitingLeg, hour.LimitingSample = p.Leg, i
+				hour.Penalties = penalties
+				hour.LimitingFactor = ""
+				if len(penalties) > 0 {
+					hour.LimitingFactor = penalties[0].Factor
+				}
+			}
+			hour.Arrival = eta
+		}
+		if !complete {
+			continue
+		}
+
+		// A route of two airfields and no sample between them has no en-route score of its
+		// own; it is as good as the worse end.
+		if hour.EnRouteProbability > 100 {
+			hour.EnRouteProbability = min(hour.DepartureProbability, hour.DestinationProbability)
+		}
+		hours = append(hours, hour)
+	}
+	return hours
+}
+
+// parseRouteTAS reads tas= in knots, defaulting when absent.
+func parseRouteTAS(raw string) (float64, error) {
+	if raw == "" {
+		return defaultRouteTASKnots, nil
+	}
+	tas, err := strconv.ParseFloat(raw, 64)
+	if err != nil || tas < minRouteTASKnots || tas > maxRouteTASKnots {
+		return 0, fmt.Errorf("tas must be between %v and %v kn, is %q", minRouteTASKnots, maxRouteTASKnots, raw)
+	}
+	return tas, nil
+}
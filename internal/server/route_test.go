@@ -0,0 +1,337 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// routeWaypoints parses a route the way the handler does.
+func routeWaypoints(t *testing.T, raw ...string) []RouteWaypoint {
+	t.Helper()
+
+	waypoints := make([]RouteWaypoint, len(raw))
+	for i, r := range raw {
+		wp, _, err := parseRouteWaypoint(r)
+		if err != nil {
+			t.Fatalf("parseRouteWaypoint(%q) = %v", r, err)
+		}
+		waypoints[i] = wp
+	}
+	return waypoints
+}
+
+// routeHours is a forecast of clear hours from 08:00 to 15:00 on the fixture day, with
+// edit applied to each hour's conditions.
+func routeHours(t *testing.T, edit func(c *conditions)) *ProcessedWeatherData {
+	t.Helper()
+
+	data := &ProcessedWeatherData{}
+	for hour := 8; hour <= 15; hour++ {
+		c := scoringConditions(t)
+		c.time = time.Date(2026, 8, 3, hour, 0, 0, 0, time.UTC)
+		if edit != nil {
+			edit(&c)
+		}
+		data.modelConditions = append(data.modelConditions, c)
+	}
+	return data
+}
+
+// routeForecasts gives every sample of points the clear forecast, and the ones whose index
+// bad accepts the forecast edit makes.
+func routeForecasts(t *testing.T, points []routePoint, bad func(i int) bool, edit func(c *conditions)) map[string]*ProcessedWeatherData {
+	t.Helper()
+
+	forecasts := make(map[string]*ProcessedWeatherData)
+	for i, p := range points {
+		if bad != nil && bad(i) {
+			forecasts[p.airport.Identifier] = routeHours(t, edit)
+		} else if _, ok := forecasts[p.airport.Identifier]; !ok {
+			forecasts[p.airport.Identifier] = routeHours(t, nil)
+		}
+	}
+	return forecasts
+}
+
+func TestPlanRoute_SamplesTheLegs(t *testing.T) {
+	withTestAirports(t)
+
+	legs, points, err := planRoute(routeWaypoints(t, "EDWN", "EDWG"), defaultRouteTASKnots)
+	if err != nil {
+		t.Fatalf("planRoute() = %v", err)
+	}
+
+	if len(legs) != 1 || math.Abs(legs[0].DistanceKM-153) > 3 {
+		t.Fatalf("legs = %+v, want one of about 153km", legs)
+	}
+	// 153km at 90kt is a little under an hour.
+	if d := legs[0].DurationMinutes; d < 55 || d > 62 {
+		t.Errorf("duration = %v minutes, want about 60", d)
+	}
+	if c := legs[0].CourseDeg; c < 15 || c > 25 {
+		t.Errorf("course = %v, want about 020", c)
+	}
+
+	// One sample every 25km at most, both ends included.
+	if want := int(math.Ceil(legs[0].DistanceKM/routeSampleSpacingKM)) + 1; len(points) != want {
+		t.Fatalf("got %d samples, want %d", len(points), want)
+	}
+	if points[0].Kind != sampleDeparture || points[len(points)-1].Kind != sampleDestination {
+		t.Errorf("ends are %q and %q", points[0].Kind, points[len(points)-1].Kind)
+	}
+	for i, p := range points[1 : len(points)-1] {
+		if p.Kind != sampleEnRoute || p.hasRunway || len(p.airport.RunwayHeadings) != 0 {
+			t.Errorf("sample %d = %+v, want an en-route point without a runway", i+1, p)
+		}
+		if gap := p.DistanceKM - points[i].DistanceKM; gap <= 0 || gap > routeSampleSpacingKM {
+			t.Errorf("sample %d is %vkm after the one before it", i+1, gap)
+		}
+	}
+}
+
+func TestPlanRoute_TurningPointsSplitTheLegs(t *testing.T) {
+	withTestAirports(t)
+
+	legs, points, err := planRoute(routeWaypoints(t, "EDWN", "53.0,7.5", "EDWG"), defaultRouteTASKnots)
+	if err != nil {
+		t.Fatalf("planRoute() = %v", err)
+	}
+	if len(legs) != 2 || legs[0].To != "53.0,7.5" || legs[1].From != "53.0,7.5" {
+		t.Fatalf("legs = %+v", legs)
+	}
+
+	waypoints := 0
+	for _, p := range points {
+		if p.Kind == sampleWaypoint {
+			waypoints++
+			if p.Leg != 0 || p.hasRunway {
+				t.Errorf("turning point = %+v, want it to close leg 0 without a runway", p)
+			}
+		}
+	}
+	if waypoints != 1 {
+		t.Errorf("got %d turning points, want 1", waypoints)
+	}
+	if last := points[len(points)-1]; last.Leg != 1 || math.Abs(last.DistanceKM-(legs[0].DistanceKM+legs[1].DistanceKM)) > 1e-6 {
+		t.Errorf("destination = %+v, want it at the end of leg 1", last)
+	}
+}
+
+func TestPlanRoute_Rejects(t *testing.T) {
+	withTestAirports(t)
+
+	tests := map[string][]string{
+		"a single point":                {"EDWN"},
+		"a coordinate for a departure":  {"52.5,7.2", "EDWG"},
+		"a coordinate for a landing":    {"EDWN", "53.8,7.9"},
+		"more samples than are fetched": {"EDWN", "60,7", "EDWG"},
+	}
+	for name, raw := range tests {
+		t.Run(name, func(t *testing.T) {
+			if legs, _, err := planRoute(routeWaypoints(t, raw...), defaultRouteTASKnots); err == nil {
+				t.Errorf("planRoute(%v) = %+v, want an error", raw, legs)
+			}
+		})
+	}
+}
+
+// En-route samples share a cache entry when they are within a grid cell of each other.
+func TestEnRouteAirport_SnapsToTheGrid(t *testing.T) {
+	a, b := enRouteAirport(52.96, 7.53), enRouteAirport(53.04, 7.46)
+	if a.Identifier != "53.0,7.5" || a.Identifier != b.Identifier || a.Latitude != 53 {
+		t.Errorf("got %q at %v and %q, want both at 53.0,7.5", a.Identifier, a.Latitude, b.Identifier)
+	}
+	if a.crosswindComponent(20, 90) != 0 {
+		t.Error("a point without a runway reported a crosswind")
+	}
+}
+
+func TestIntermediatePoint_StaysOnTheGreatCircle(t *testing.T) {
+	from, to := testAirport, Airport{Latitude: 53.78256, Longitude: 7.91957}
+	total := distanceKM(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+
+	for _, f := range []float64{0, 0.25, 0.5, 1} {
+		lat, lon := intermediatePoint(from.Latitude, from.Longitude, to.Latitude, to.Longitude, f)
+		fromStart := distanceKM(from.Latitude, from.Longitude, lat, lon)
+		toEnd := distanceKM(lat, lon, to.Latitude, to.Longitude)
+		if math.Abs(fromStart-f*total) > 0.01 || math.Abs(fromStart+toEnd-total) > 0.01 {
+			t.Errorf("f=%v: %vkm in and %vkm to go, of %vkm", f, fromStart, toEnd, total)
+		}
+	}
+
+	for _, tc := range []struct {
+		lat2, lon2, want float64
+	}{{1, 0, 0}, {0, 1, 90}, {-1, 0, 180}, {0, -1, 270}} {
+		if got := initialCourse(0, 0, tc.lat2, tc.lon2); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("initialCourse to %v,%v = %v, want %v", tc.lat2, tc.lon2, got, tc.want)
+		}
+	}
+}
+
+func TestParseRouteWaypoint(t *testing.T) {
+	withTestAirports(t)
+
+	if wp, isAirport, err := parseRouteWaypoint("EDWG"); err != nil || !isAirport || wp.Name != "Wangerooge" {
+		t.Errorf("EDWG = %+v, %v, %v", wp, isAirport, err)
+	}
+	if wp, isAirport, err := parseRouteWaypoint(" 53.1, 7.4 "); err != nil || isAirport || wp.Latitude != 53.1 || wp.Longitude != 7.4 {
+		t.Errorf("53.1,7.4 = %+v, %v, %v", wp, isAirport, err)
+	}
+	for _, raw := range []string{"", "EDXX", "91,7", "53,x", "NaN,7", "53,nan"} {
+		if wp, _, err := parseRouteWaypoint(raw); err == nil {
+			t.Errorf("parseRouteWaypoint(%q) = %+v, want an error", raw, wp)
+		}
+	}
+}
+
+func TestParseRouteTAS(t *testing.T) {
+	if tas, err := parseRouteTAS(""); err != nil || tas != defaultRouteTASKnots {
+		t.Errorf("default = %v, %v", tas, err)
+	}
+	if tas, err := parseRouteTAS("105"); err != nil || tas != 105 {
+		t.Errorf("105 = %v, %v", tas, err)
+	}
+	for _, raw := range []string{"fast", "20", "400"} {
+		if tas, err := parseRouteTAS(raw); err == nil {
+			t.Errorf("parseRouteTAS(%q) = %v, want an error", raw, tas)
+		}
+	}
+}
+
+func TestScoreRoute_NamesTheLimitingLegAndFactor(t *testing.T) {
+	withTestAirports(t)
+	_, points, err := planRoute(routeWaypoints(t, "EDWN", "53.0,7.5", "EDWG"), defaultRouteTASKnots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Low cloud over the second leg's en-route samples, all day.
+	forecasts := routeForecasts(t, points,
+		func(i int) bool { return points[i].Leg == 1 && points[i].Kind == sampleEnRoute },
+		func(c *conditions) { c.cloudBaseFL = ptrTo(8) })
+
+	hours := scoreRoute(points, forecasts, defaultProfile(), defaultRouteTASKnots)
+	if len(hours) == 0 {
+		t.Fatal("no hours scored")
+	}
+	got := hours[0]
+	if got.Departure != "2026-08-03T08:00" {
+		t.Errorf("first departure = %s", got.Departure)
+	}
+	if got.LimitingLeg != 1 || got.LimitingFactor != "cloud base" || points[got.LimitingSample].Kind != sampleEnRoute {
+		t.Errorf("limited by leg %d sample %d factor %q, want leg 1 en route by the cloud base", got.LimitingLeg, got.LimitingSample, got.LimitingFactor)
+	}
+	if !(got.EnRouteProbability < got.DepartureProbability && got.Probability == got.EnRouteProbability) {
+		t.Errorf("probabilities: route %d, departure %d, en route %d, destination %d", got.Probability, got.DepartureProbability, got.EnRouteProbability, got.DestinationProbability)
+	}
+}
+
+// A turning point is on the way. Bad weather over one costs the route en route, not at
+// the destination.
+func TestScoreRoute_TurningPointsCountEnRoute(t *testing.T) {
+	withTestAirports(t)
+	_, points, err := planRoute(routeWaypoints(t, "EDWN", "53.0,7.5", "53.4,7.9", "EDWG"), defaultRouteTASKnots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forecasts := routeForecasts(t, points,
+		func(i int) bool { return points[i].Kind == sampleWaypoint },
+		func(c *conditions) { c.cloudBaseFL = ptrTo(8) })
+
+	hours := scoreRoute(points, forecasts, defaultProfile(), defaultRouteTASKnots)
+	if len(hours) == 0 {
+		t.Fatal("no hours scored")
+	}
+	for _, hour := range hours {
+		if hour.DestinationProbability != 100 || hour.EnRouteProbability >= 100 || points[hour.LimitingSample].Kind != sampleWaypoint {
+			t.Errorf("%s: en route %d, destination %d, limited by a %q sample, want the turning points en route",
+				hour.Departure, hour.EnRouteProbability, hour.DestinationProbability, points[hour.LimitingSample].Kind)
+		}
+	}
+}
+
+// Crosswind is about runways. A strong wind across the track halfway costs nothing a
+// wind of that strength does not cost anyway.
+func TestScoreRoute_EnRouteSamplesHaveNoCrosswind(t *testing.T) {
+	withTestAirports(t)
+	_, points, err := planRoute(routeWaypoints(t, "EDWN", "EDWG"), defaultRouteTASKnots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forecasts := routeForecasts(t, points,
+		func(i int) bool { return points[i].Kind == sampleEnRoute },
+		func(c *conditions) { c.crosswind, c.crosswindGusts = 40, 45 })
+
+	for _, hour := range scoreRoute(points, forecasts, defaultProfile(), defaultRouteTASKnots) {
+		if hour.EnRouteProbability != 100 {
+			t.Errorf("%s: en route = %d, %+v, want the crosswind ignored", hour.Departure, hour.EnRouteProbability, hour.Penalties)
+		}
+	}
+}
+
+// A departure is only scored if the forecast reaches the landing.
+func TestScoreRoute_DropsDeparturesThatLandPastTheForecast(t *testing.T) {
+	withTestAirports(t)
+	legs, points, err := planRoute(routeWaypoints(t, "EDWN", "EDWG"), defaultRouteTASKnots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hours := scoreRoute(points, routeForecasts(t, points, nil, nil), defaultProfile(), defaultRouteTASKnots)
+
+	// The forecast runs 08:00-15:00 and the flight takes about an hour, which rounds to one.
+	if len(hours) != 7 || hours[len(hours)-1].Departure != "2026-08-03T14:00" {
+		t.Fatalf("got %d hours, the last %+v, want 08:00 to 14:00", len(hours), hours[len(hours)-1])
+	}
+	want := time.Date(2026, 8, 3, 14, 0, 0, 0, time.UTC).Add(time.Duration(legs[0].DurationMinutes * float64(time.Minute)))
+	if got := hours[len(hours)-1].Arrival; got.Sub(want).Abs() > time.Second {
+		t.Errorf("arrival = %v, want %v", got, want)
+	}
+}
+
+func TestGetRoute(t *testing.T) {
+	withTestAirports(t)
+	stubFetchWeather(t, func(_ context.Context, a Airport, _ *scoringProfile) (*ProcessedWeatherData, error) {
+		if a.Identifier == "EDWG" {
+			return routeHours(t, func(c *conditions) { c.visibilityKM = ptrFloat(2) }), nil
+		}
+		return routeHours(t, nil), nil
+	})
+
+	rec := httptest.NewRecorder()
+	getRoute(rec, httptest.NewRequest(http.MethodGet, "/api/route?via=EDWN&via=EDWG&tas=110", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var got RouteResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.TASKnots != 110 || got.Profile != defaultProfile().ID || len(got.Legs) != 1 || len(got.Samples) < 3 {
+		t.Errorf("route = %+v", got)
+	}
+	if len(got.Hours) == 0 || got.Hours[0].LimitingFactor != "visibility" || got.Samples[got.Hours[0].LimitingSample].Kind != sampleDestination {
+		t.Errorf("hours = %+v, want the destination's visibility limiting", got.Hours)
+	}
+
+	for _, query := range []string{
+		"?via=EDWN",
+		"?via=EDWN&via=EDXX",
+		"?via=EDWN&via=EDWG&tas=10",
+		"?via=EDWN&via=EDWG&profile=nope",
+	} {
+		rec := httptest.NewRecorder()
+		getRoute(rec, httptest.NewRequest(http.MethodGet, "/api/route"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
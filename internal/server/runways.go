@@ -0,0 +1,174 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Runway ends and the wind on them.
+//
+// crosswindComponent used to take the smallest crosswind over every heading the airfield
+// has, and report that one number. It never said which runway that was, it could not tell
+// a headwind from a tailwind -- both ends of a runway have the same crosswind -- and it
+// would happily pick a runway that was closed. Wangerooge's two runways came out as one
+// anonymous figure.
+//
+// Every hour now gets the wind on every runway end: the head- or tailwind, the crosswind
+// and the gust crosswind, and which end is in use. The end in use is the usable one with
+// the least crosswind, counting a knot of tailwind as two of crosswind -- a tailwind costs
+// runway on top of control, and nobody lands downwind with the reciprocal available. Its
+// crosswind and tailwind are what the score charges.
+//
+// An operator rules a runway out in airports.json: closed for everyone, or too short for
+// particular aircraft. One end of a one-way strip can be ruled out on its own, and that is
+// when the end in use can have a tailwind: the only way to avoid it is a runway further
+// across the wind, or none. An airfield without runways -- a route's en-route sample -- has no
+// ends and reports no wind on any.
+
+// tailwindAsCrosswind is what a knot of tailwind counts for when choosing the end in use.
+const tailwindAsCrosswind = 2
+
+// RunwayWind is the wind on one runway end, for the wind chart's tooltip.
+type RunwayWind struct {
+	Runway  string  `json:"runway"`  // "23"
+	Heading float64 `json:"heading"` // true degrees
+	// Headwind is the component along the runway, negative for a tailwind.
+	Headwind       float64 `json:"headwind"`
+	Crosswind      float64 `json:"crosswind"`
+	CrosswindGusts float64 `json:"crosswind_gusts"`
+	InUse          bool    `json:"in_use,omitempty"`
+	// Unusable says why the end is ruled out: "closed", "too short for the Comco Ikarus
+	// C42", or "not used in this direction".
+	Unusable string `json:"unusable,omitempty"`
+	// SunGlare is the angle, degrees, between a low sun and the end's heading, where the
+	// sun is within sunGlareCone of it. See glare.go.
+	SunGlare *float64 `json:"sun_glare,omitempty"`
+}
+
+// runwayEnd is one end of one runway.
+type runwayEnd struct {
+	designator string  // "23"
+	heading    float64 // true degrees
+	runway     string  // "05/23", as in Runways
+}
+
+// runwayEnds pairs RunwayHeadings with the designators in Runways: two headings per
+// runway, in the same order. A list where they do not pair up names each end after its
+// heading instead, so the breakdown still has something to call it.
+func (a Airport) runwayEnds() []runwayEnd {
+	ends := make([]runwayEnd, 0, len(a.RunwayHeadings))
+	paired := len(a.RunwayHeadings) == 2*len(a.Runways)
+	for i, heading := range a.RunwayHeadings {
+		end := runwayEnd{heading: heading}
+		if paired {
+			end.runway = a.Runways[i/2]
+			if names := strings.Split(end.runway, "/"); len(names) == 2 {
+				end.designator = names[i%2]
+			}
+		}
+		if end.designator == "" {
+			end.designator = fmt.Sprintf("%02.0f", math.Round(heading/10))
+		}
+		ends = append(ends, end)
+	}
+	return ends
+}
+
+// runwayDetail returns the details given for a runway, if any.
+func (a Airport) runwayDetail(runway string) (RunwayDetail, bool) {
+	i := slices.IndexFunc(a.RunwayDetails, func(d RunwayDetail) bool { return d.Designator == runway })
+	if i < 0 {
+		return RunwayDetail{}, false
+	}
+	return a.RunwayDetails[i], true
+}
+
+// unusable says why a runway cannot be used by aircraft, or "" when it can. A nil
+// aircraft is ruled out by closures alone.
+func (d RunwayDetail) unusable(aircraft *aircraftType) string {
+	if d.Closed != "" {
+		return d.Closed
+	}
+	if aircraft != nil && slices.Contains(d.TooShortFor, aircraft.ID) {
+		return "too short for the " + aircraft.Name
+	}
+	return ""
+}
+
+// runwayWind is one hour's wind against an airfield's runways.
+type runwayWind struct {
+	ends []RunwayWind
+	// crosswind, crosswindGusts and tailwind are on the end in use; all zero where there
+	// is none.
+	crosswind      float64
+	crosswindGusts float64
+	tailwind       float64
+}
+
+// windOnRunways resolves a wind of speed, gusting gusts, from direction against every
+// runway end, and picks the end in use for aircraft.
+func (a Airport) windOnRunways(speed, gusts float64, direction int, aircraft *aircraftType) runwayWind {
+	var result runwayWind
+	inUse, best := -1, math.Inf(1)
+	for i, end := range a.runwayEnds() {
+		angle := (float64(direction) - end.heading) * math.Pi / 180
+		wind := RunwayWind{
+			Runway:         end.designator,
+			Heading:        end.heading,
+			Headwind:       speed * math.Cos(angle),
+			Crosswind:      math.Abs(speed * math.Sin(angle)),
+			CrosswindGusts: math.Abs(gusts * math.Sin(angle)),
+		}
+		if detail, ok := a.runwayDetail(end.runway); ok {
+			wind.Unusable = detail.unusable(aircraft)
+			if wind.Unusable == "" && slices.Contains(detail.ClosedEnds, end.designator) {
+				wind.Unusable = "not used in this direction"
+			}
+		}
+		result.ends = append(result.ends, wind)
+
+		if wind.Unusable != "" {
+			continue
+		}
+		if cost := wind.Crosswind + tailwindAsCrosswind*max(0, -wind.Headwind); cost < best {
+			inUse, best = i, cost
+		}
+	}
+
+	if inUse >= 0 {
+		end := &result.ends[inUse]
+		end.InUse = true
+		result.crosswind, result.crosswindGusts, result.tailwind = end.Crosswind, end.CrosswindGusts, max(0, -end.Headwind)
+	}
+	return result
+}
+
+// onRunways puts the hour's wind on the airfield's runways for aircraft, and sets the end
+// in use and the components on it. The wind is returned for the ends' own figures.
+func (c *conditions) onRunways(airport Airport, aircraft *aircraftType) runwayWind {
+	wind := airport.windOnRunways(c.windSpeed, c.windGusts, c.windDirection, aircraft)
+	c.crosswind, c.crosswindGusts, c.tailwind = wind.crosswind, wind.crosswindGusts, wind.tailwind
+	inUse, _ := wind.endInUse()
+	c.runwayInUse, c.runwayHeading = inUse.Runway, inUse.Heading
+	c.runwaySurface = airport.runwaySurface(inUse.Runway)
+	return wind
+}
+
+// endInUse returns the end in use, false where there is none.
+func (w runwayWind) endInUse() (RunwayWind, bool) {
+	i := slices.IndexFunc(w.ends, func(end RunwayWind) bool { return end.InUse })
+	if i < 0 {
+		return RunwayWind{}, false
+	}
+	return w.ends[i], true
+}
+
+// crosswindComponent returns the crosswind in knots for a wind of speedKnots from
+// directionDegrees (meteorological, true north) on the runway end that would be in use,
+// with only closed runways ruled out. A point without runways -- a route's en-route
+// sample -- has no crosswind to speak of and reports none.
+func (a Airport) crosswindComponent(speedKnots float64, directionDegrees int) float64 {
+	return a.windOnRunways(speedKnots, speedKnots, directionDegrees, nil).crosswind
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"math"
+	"testing"
+)
+
+// wangerooge is EDWG with both its runways: 09/27 asphalt and the short 01/19 grass strip.
+func wangerooge() Airport {
+	return Airport{
+		Identifier:     "EDWG",
+		Name:           "Wangerooge",
+		Runways:        []string{"09/27", "01/19"},
+		RunwayHeadings: []float64{94.1, 274.1, 15.5, 195.5},
+		RunwayDetails: []RunwayDetail{
+			{Designator: "09/27", LengthM: 850, Surface: surfaceAsphalt},
+			{Designator: "01/19", LengthM: 545, Surface: surfaceGrass},
+		},
+	}
+}
+
+func TestRunwayEnds(t *testing.T) {
+	var got []string
+	for _, end := range wangerooge().runwayEnds() {
+		got = append(got, end.designator+" of "+end.runway)
+	}
+	want := []string{"09 of 09/27", "27 of 09/27", "01 of 01/19", "19 of 01/19"}
+	if len(got) != len(want) {
+		t.Fatalf("runwayEnds() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("runwayEnds()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// Headings that do not pair up with the designators are named after themselves.
+	unpaired := Airport{RunwayHeadings: []float64{94.1, 274.1}}
+	if ends := unpaired.runwayEnds(); len(ends) != 2 || ends[0].designator != "09" || ends[1].designator != "27" {
+		t.Errorf("runwayEnds() = %+v, want 09 and 27 from the headings", ends)
+	}
+}
+
+func TestWindOnRunways(t *testing.T) {
+	c42, err := lookupAircraft("c42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c172, err := lookupAircraft("c172")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 15kt gusting 25 from 190: nearly down the grass strip towards 19, and nearly across
+	// the asphalt.
+	airport := wangerooge()
+	wind := airport.windOnRunways(15, 25, 190, c42)
+	if len(wind.ends) != 4 {
+		t.Fatalf("ends = %+v, want all four", wind.ends)
+	}
+	nineteen := wind.ends[3]
+	if !nineteen.InUse || nineteen.Runway != "19" || math.Abs(nineteen.Headwind-14.9) > 0.1 {
+		t.Errorf("19 = %+v, want it in use with ~15kt of headwind", nineteen)
+	}
+	if one := wind.ends[2]; one.InUse || math.Abs(one.Headwind+14.9) > 0.1 || math.Abs(one.Crosswind-nineteen.Crosswind) > 0.01 {
+		t.Errorf("01 = %+v, want the same crosswind as 19 and the headwind as a tailwind", one)
+	}
+	if wind.crosswind != nineteen.Crosswind || wind.crosswindGusts != nineteen.CrosswindGusts || wind.tailwind != 0 {
+		t.Errorf("in use: %+v, want 19's components and no tailwind", wind)
+	}
+
+	// The operator says 545m of grass is not for a C172: the asphalt is in use, crosswind
+	// and all, and 27 rather than 09, which would be downwind.
+	airport.RunwayDetails[1].TooShortFor = []string{"c172"}
+	wind = airport.windOnRunways(15, 25, 190, c172)
+	if !wind.ends[1].InUse || wind.ends[3].Unusable != "too short for the Cessna 172S" || wind.crosswind < 14 || wind.tailwind != 0 {
+		t.Errorf("ends = %+v, want 27 in use and 19 too short", wind.ends)
+	}
+	// It still is for the C42.
+	if wind = airport.windOnRunways(15, 25, 190, c42); !wind.ends[3].InUse {
+		t.Errorf("ends = %+v, want 19 in use for the C42", wind.ends)
+	}
+
+	// Closed is closed for everyone, and says why.
+	airport.RunwayDetails[1].Closed = "closed for resurfacing"
+	if wind = airport.windOnRunways(15, 25, 190, c42); wind.ends[3].Unusable != "closed for resurfacing" || !wind.ends[1].InUse {
+		t.Errorf("ends = %+v, want 19 closed and 27 in use", wind.ends)
+	}
+}
+
+func TestWindOnRunways_OneWayStripHasTailwind(t *testing.T) {
+	airport := testAirport
+	airport.RunwayDetails = []RunwayDetail{{Designator: "05/23", LengthM: 900, Surface: surfaceAsphalt, ClosedEnds: []string{"23"}}}
+
+	// 8kt from 235 is straight down 23, and straight behind 05.
+	wind := airport.windOnRunways(8, 8, 235, nil)
+	if !wind.ends[0].InUse || wind.ends[1].Unusable == "" || math.Abs(wind.tailwind-8) > 0.01 || wind.crosswind > 0.01 {
+		t.Fatalf("wind = %+v, want 05 in use with 8kt of tailwind", wind)
+	}
+
+	c := scoringConditions(t)
+	c.tailwind = wind.tailwind
+	penalties := mustScore(t, c)
+	if len(penalties) != 1 || penalties[0].Factor != "tailwind" {
+		t.Errorf("penalties = %+v, want the tailwind alone", penalties)
+	}
+
+	// A runway usable both ways is never used downwind.
+	if wind := testAirport.windOnRunways(8, 8, 235, nil); wind.tailwind != 0 {
+		t.Errorf("tailwind = %v on a runway usable both ways", wind.tailwind)
+	}
+}
+
+func TestObservedFrom_VariableWindBehindOneWayStrip(t *testing.T) {
+	airport := testAirport
+	airport.RunwayDetails = []RunwayDetail{{Designator: "05/23", LengthM: 900, Surface: surfaceAsphalt, ClosedEnds: []string{"23"}}}
+
+	m, err := parseMETAR("METAR EHTW 031120Z VRB06KT 9999 FEW030 18/12 Q1015", nowcastNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// VRB could be from 235, and 05 is the only way to go.
+	if in := observedFrom(m, airport, nil); in.crosswind != 6 || math.Abs(in.tailwind-6) > 0.01 {
+		t.Errorf("crosswind %v, tailwind %v; want the full 6kt of both", in.crosswind, in.tailwind)
+	}
+	if in := observedFrom(m, testAirport, nil); in.tailwind != 0 {
+		t.Errorf("tailwind = %v with both ends usable", in.tailwind)
+	}
+}
@@ -1,5 +1,5 @@
-// Package server is the whole application: the HTTP surface, the Open-Meteo and
-// sunrise-sunset clients, the caches and the VFR scoring.
+// Package server is the whole application: the HTTP surface, the Open-Meteo client, the
+// caches and the VFR scoring.
 package server
 
 import (
@@ -9,7 +9,9 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -22,6 +24,8 @@ type ProcessedWeatherData struct {
 	CloudData       []CloudPoint       `json:"cloud_data"`
 	WindData        []WindPoint        `json:"wind_data"`
 	VfrData         []VfrPoint         `json:"vfr_data"`
+	// PressureData is QNH, its tendency and the likely frontal hours. See pressure.go.
+	PressureData []PressurePoint `json:"pressure_data"`
 	// GeneratedAt is when this payload was built from an upstream response. It doubles as
 	// the cache entry's timestamp, so the two can never disagree.
 	GeneratedAt time.Time `json:"generated_at"`
@@ -40,6 +44,14 @@ type ProcessedWeatherData struct {
 	// civil dusk, civil dawn to sunrise. The same boundaries the score charges the daylight
 	// penalty for, drawn a lighter grey than the night they lead into.
 	TwilightPeriods []Interval `json:"twilight_periods,omitempty"`
+	// Nowcast is the observation the first hours were scored from, when there was one
+	// recent enough. See nowcast.go.
+	Nowcast *Nowcast `json:"nowcast,omitempty"`
+
+	// modelConditions is what each VfrData hour was scored from, kept so a nowcast can
+	// re-score the first hours against an observation that arrives after the payload was
+	// built. Index-aligned with VfrData.
+	modelConditions []conditions
 }
 
 // Interval is a half-open stretch of time [From, To).
@@ -78,6 +90,46 @@ type RestrictionsResponse struct {
 	Degraded bool `json:"degraded"`
 }
 
+// ObservationsResponse is what /api/observations serves. Station is nil when no reporting
+// station is within range of the airfield; METAR and TAF are nil when the station has none
+// current, which for a TAF is normal outside its hours.
+type ObservationsResponse struct {
+	Airport   string        `json:"airport"`
+	Station   *StationMatch `json:"station"`
+	METAR     *Metar        `json:"metar"`
+	TAF       *Taf          `json:"taf"`
+	FetchedAt time.Time     `json:"fetched_at"`
+	// Degraded reports two consecutive failed polls, as for the restrictions.
+	Degraded bool `json:"degraded"`
+}
+
+// RouteResponse is what /api/route serves: the route as planned, and its score per
+// departure hour.
+type RouteResponse struct {
+	Waypoints []RouteWaypoint `json:"waypoints"`
+	Legs      []RouteLeg      `json:"legs"`
+	Samples   []RouteSample   `json:"samples"`
+	TASKnots  float64         `json:"tas_knots"`
+	Profile   string          `json:"profile"`
+	Hours     []RouteHour     `json:"hours"`
+}
+
+// WindowsResponse is what /api/windows serves.
+type WindowsResponse struct {
+	Airport         string  `json:"airport"`
+	Profile         string  `json:"profile"`
+	MinScore        int     `json:"min_score"`
+	DurationMinutes float64 `json:"duration_minutes"`
+	// OperatingHours reports whether the airfield's operating window was applied.
+	OperatingHours bool `json:"operating_hours"`
+	// RestrictedAreas names the areas over the airfield whose activations were kept clear
+	// of, and RestrictionsDegraded says the plan they came from is the last known one.
+	RestrictedAreas      []string       `json:"restricted_areas"`
+	RestrictionsDegraded bool           `json:"restrictions_degraded"`
+	Stale                bool           `json:"stale"`
+	Windows              []FlightWindow `json:"windows"`
+}
+
 // weatherBrowserCache is how long a browser may reuse a forecast payload without asking.
 // Model runs are hours apart, so this only ever collapses an accidental double-fetch.
 const weatherBrowserCache = time.Minute
@@ -99,6 +151,15 @@ type TemperaturePoint struct {
 	DewPoint                 float64 `json:"dew_point"`
 	Precipitation            float64 `json:"precipitation"`
 	PrecipitationProbability int     `json:"precipitation_probability"`
+	// CarbIcing is the hour's band on the carburettor icing chart, whatever the aircraft:
+	// 0 none up to 4 serious at any power. See carbicing.go.
+	CarbIcing int `json:"carb_icing,omitempty"`
+	// Precipitation24h and Precipitation48h are mm fallen over the day and two days up to
+	// the hour, and GroundWetness what of it is still on the ground, with any snow, as mm
+	// of rain. Null where the forecast does not reach that far back. See softfield.go.
+	Precipitation24h *float64 `json:"precipitation_24h"`
+	Precipitation48h *float64 `json:"precipitation_48h"`
+	GroundWetness    *float64 `json:"ground_wetness"`
 }
 
 type CloudPoint struct {
@@ -106,20 +167,43 @@ type CloudPoint struct {
 	CloudLayers []CloudLayer `json:"cloud_layers"`
 	Visibility  *float64     `json:"visibility"`
 	Base        *int         `json:"base"`
+	// FreezingLevelFeet is feet above MSL, null where it is not known. See icing.go.
+	FreezingLevelFeet *int `json:"freezing_level_feet"`
+	// ConvectiveRisk is 0 stable, 1 showers possible, 2 isolated thunderstorms possible,
+	// 3 thunderstorms likely, 4 thunderstorm forecast; absent for stable. The convective
+	// cloud base is feet above ground. See convective.go.
+	ConvectiveRisk     int  `json:"convective_risk,omitempty"`
+	ConvectiveBaseFeet *int `json:"convective_base_feet,omitempty"`
+	// FogRisk is 0 unlikely, 1 possible, 2 likely; absent for unlikely. See fog.go.
+	FogRisk int `json:"fog_risk,omitempty"`
 }
 
 type CloudLayer struct {
 	HeightFeet int `json:"height_feet"`
 	Coverage   int `json:"coverage"`
+	// IcingRisk is 0 none, 1 light, 2 moderate, 3 severe; absent for none. See icing.go.
+	IcingRisk int `json:"icing_risk,omitempty"`
 }
 
 type WindPoint struct {
-	Time              string      `json:"time"`
-	WindSpeed10m      float64     `json:"wind_speed_10m"`
-	WindGusts10m      float64     `json:"wind_gusts_10m"`
-	Crosswind10m      float64     `json:"crosswind_10m"`
-	CrosswindGusts10m float64     `json:"crosswind_gusts_10m"`
-	WindLayers        []WindLayer `json:"wind_layers"`
+	Time              string  `json:"time"`
+	WindSpeed10m      float64 `json:"wind_speed_10m"`
+	WindGusts10m      float64 `json:"wind_gusts_10m"`
+	Crosswind10m      float64 `json:"crosswind_10m"`
+	CrosswindGusts10m float64 `json:"crosswind_gusts_10m"`
+	// Crosswind10m, CrosswindGusts10m and Tailwind10m are on the runway end in use, which
+	// Runways marks among every end's breakdown. Runways is absent where there are none.
+	Tailwind10m float64      `json:"tailwind_10m"`
+	Runways     []RunwayWind `json:"runways,omitempty"`
+	WindLayers  []WindLayer  `json:"wind_layers"`
+	// Shear is between every pair of adjacent levels the hour has wind for, lowest first,
+	// at heights above the ground. LowLevelShear is the worst change from the 10m wind up
+	// to 3000ft above the ground, in knots, null
+	// without levels to compare; Turbulence is the mechanical-turbulence estimate for the
+	// same layer, 0 none to 3 severe. See shear.go.
+	Shear         []WindShear `json:"shear"`
+	LowLevelShear *float64    `json:"low_level_shear"`
+	Turbulence    int         `json:"turbulence,omitempty"`
 }
 
 type WindLayer struct {
@@ -144,6 +228,15 @@ type VfrPoint struct {
 	// first. An hour with nothing against it carries none, so a clear forecast adds
 	// nothing to the payload. A no-go hour carries exactly one -- the reason.
 	Penalties []VfrPenalty `json:"penalties,omitempty"`
+	// Source is what the hour was scored from: "forecast", "observed" for the hour of the
+	// latest METAR, or "blended" for the hours after it, which move back to the model.
+	Source string `json:"source"`
+	// ObservedWeight is the observation's share of a blended hour's inputs, 1 for an
+	// observed one.
+	ObservedWeight float64 `json:"observed_weight,omitempty"`
+	// Ensemble is the score's spread across the ICON-EPS members, where the ensemble
+	// reaches the hour. See ensemble.go.
+	Ensemble *VfrEnsemble `json:"ensemble,omitempty"`
 }
 
 // VfrPenalty is one factor's contribution to an hour's score, as scored against vfrLimits.
@@ -157,6 +250,12 @@ type VfrPenalty struct {
 	// precipitation is charged for what would fall, times how likely it is to fall. Cost
 	// is the scaled figure; this is what scaled it.
 	Scale *VfrScale `json:"scale,omitempty"`
+	// Source is the forecast the value was taken from, "model" or "mosmix", on the factors
+	// MOSMIX feeds at the airfields that have a station. Absent everywhere else.
+	Source string `json:"source,omitempty"`
+	// Detail says what the value is made of where the number alone would not: "TODR 520m
+	// of 600m available" for the takeoff distance's 86.7%.
+	Detail string `json:"detail,omitempty"`
 }
 
 // VfrScale is the quantity that modulated a penalty.
@@ -225,11 +324,56 @@ func Run() error {
 		return fmt.Errorf("failed to load airports: %w", err)
 	}
 
+	// So is an unknown model: a comparison quietly missing one looks like agreement.
+	if err := configureModels(os.Getenv(modelsEnv)); err != nil {
+		return fmt.Errorf("failed to configure forecast models: %w", err)
+	}
+	// The forecast comes from Open-Meteo unless a replay directory stands in for it.
+	weatherSource = weatherProviderFromEnv()
+	// A mistyped MOSMIX mode is fatal for the same reason: it would look like it had taken.
+	mode, err := mosmixModeFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to configure MOSMIX: %w", err)
+	}
+	mosmixSetting = mode
+	// And so is a mistyped cross-check switch, which would look like the check was running.
+	crossCheck, err := sunriseCrossCheckFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to configure the sunrise cross-check: %w", err)
+	}
+	sunriseCrossCheck = crossCheck
+
 	// Signal-driven shutdown, so `make restart` drains in-flight requests rather than
 	// cutting them mid-response.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// A bad limits file is fatal here for the reason a bad airport list is: the server
+	// would come up scoring against a table nobody chose. Once running, a bad one is only
+	// refused -- see limitsfile.go.
+	if path := os.Getenv(limitsFileEnv); path != "" {
+		if err := reloadLimitsFile(path); err != nil {
+			return fmt.Errorf("failed to load VFR limits: %w", err)
+		}
+		go watchLimitsFile(ctx, path)
+	}
+
+	// The archive is opened before anything can fetch, so the first payload is kept too.
+	if err := archive.open(os.Getenv(archiveFileEnv), archiveRetention()); err != nil {
+		return fmt.Errorf("failed to open the forecast archive: %w", err)
+	}
+	defer func() {
+		if err := archive.close(); err != nil {
+			slog.Error("failed to close the forecast archive", "error", err)
+		}
+	}()
+	// Swept now and then on a ticker: what the last run wrote under an airport or profile
+	// since removed is never written again, and would otherwise never be pruned.
+	if err := archive.sweep(); err != nil {
+		slog.Warn("failed to sweep the forecast archive", "error", err)
+	}
+	go watchArchive(ctx)
+
 	mux := http.NewServeMux()
 
 	// Learn the current model runs before warming, so the first payload carries them and
@@ -244,9 +388,31 @@ func Run() error {
 	restrictions.poll(ctx)
 	go watchRestrictions(ctx)
 
+	// METAR and TAF for the stations the airfields are matched to, on a ten-minute cadence.
+	observationsSource = observationSourceFromEnv()
+	observations.poll(ctx)
+	go watchObservations(ctx)
+
+	// Subscriptions from the last run. A file that cannot be read is fatal, as a limits file
+	// is: starting without them would silently stop every alert anyone was relying on.
+	if err := subscriptions.load(os.Getenv(subscriptionsFileEnv)); err != nil {
+		return fmt.Errorf("failed to load subscriptions: %w", err)
+	}
+	if os.Getenv(subscriptionsFileEnv) == "" {
+		slog.Warn("subscriptions are kept in memory only; set " + subscriptionsFileEnv + " to keep them across restarts")
+	}
+	go watchSubscriptions(ctx)
+
+	// Whatever the archive holds goes back in the cache first: after the run poll, so a
+	// record of the latest runs is known to be current, and before the default airport is
+	// warmed, which is then usually a cache hit.
+	if warmed := archive.warm(); warmed > 0 {
+		slog.Info("cache warmed from the forecast archive", "entries", warmed)
+	}
+
 	// pre cache weather data for the default airport only. Warming all of them would fire
 	// one very large Open-Meteo request per airfield before the first user arrives.
-	_, _ = GetWeatherData(ctx, defaultAirport)
+	_, _ = GetWeatherData(ctx, defaultAirport, defaultProfile())
 
 	// Serve static files from the embedded frontend (or from disk under FLUGWETTER_DEV).
 	assets, err := web.New()
@@ -260,6 +426,14 @@ func Run() error {
 	mux.HandleFunc("GET /api/weather", getWeatherData)
 	mux.HandleFunc("GET /api/status", getStatus)
 	mux.HandleFunc("GET /api/restrictions", getRestrictions)
+	mux.HandleFunc("GET /api/observations", getObservations)
+	mux.HandleFunc("GET /api/route", getRoute)
+	mux.HandleFunc("GET /api/windows", getWindows)
+	mux.HandleFunc("GET /api/trend", getTrend)
+	mux.HandleFunc("GET /api/verification", getVerification)
+	mux.HandleFunc("POST /api/subscriptions", createSubscription)
+	mux.HandleFunc("GET /api/subscriptions/{id}", getSubscription)
+	mux.HandleFunc("DELETE /api/subscriptions/{id}", deleteSubscription)
 	if openAIPEnabled() {
 		mux.HandleFunc(tileRoute, serveOpenAIPTile)
 		slog.Info("openAIP overlay enabled")
@@ -307,11 +481,14 @@ func Run() error {
 }
 
 // ConfigResponse is everything the frontend needs to boot: which airfields exist, which one
-// to show first, and whether the openAIP overlay is available.
+// to show first, which scoring profiles it can offer, and whether the openAIP overlay is
+// available.
 type ConfigResponse struct {
-	Airports       []Airport `json:"airports"`
-	DefaultAirport string    `json:"default_airport"`
-	OpenAIPOverlay bool      `json:"openaip_overlay"`
+	Airports       []Airport        `json:"airports"`
+	DefaultAirport string           `json:"default_airport"`
+	Profiles       []ScoringProfile `json:"profiles"`
+	DefaultProfile string           `json:"default_profile"`
+	OpenAIPOverlay bool             `json:"openaip_overlay"`
 	// Build identifies the running binary, so a deployment can be checked against what was
 	// pushed without guessing from the image tag.
 	Build BuildInfo `json:"build"`
@@ -326,6 +503,8 @@ func getConfig(w http.ResponseWriter, r *http.Request) {
 	config := ConfigResponse{
 		Airports:       airports,
 		DefaultAirport: defaultAirport.Identifier,
+		Profiles:       profileList(),
+		DefaultProfile: defaultProfile().ID,
 		OpenAIPOverlay: openAIPEnabled(),
 		Build:          buildInfo(),
 	}
@@ -343,9 +522,14 @@ type StatusResponse struct {
 	// it rendered and reloads only when this differs, so it is the whole point of the
 	// endpoint. Zero when no poll has succeeded yet.
 	LatestInitializedAt time.Time `json:"latest_initialized_at"`
-	// GeneratedAt is when the default airport's cached payload was built. Informational:
+	// GeneratedAt is when the default airport's cached payload was built, as scored against
+	// the default profile. Informational:
 	// the reload decision keys off LatestRun.
 	GeneratedAt time.Time `json:"generated_at"`
+	// LatestObservedAt is when the METAR the requested airport's nowcast would use was
+	// observed; absent when there is none recent enough. A change here re-scores the first
+	// hours without a new model run, so the frontend reloads on it too.
+	LatestObservedAt *time.Time `json:"latest_observed_at,omitempty"`
 	// ModelRunsDegraded reports that run detection has stopped working, so refreshes have
 	// fallen back to the backstop TTL. Surfaced in the page rather than only logged: a
 	// silent fallback is one that runs for months before anyone notices.
@@ -368,9 +552,16 @@ func getStatus(w http.ResponseWriter, r *http.Request) {
 		ModelRunsDegraded:   degraded,
 		Commit:              buildInfo().Commit,
 	}
-	if entry, ok := cachedEntry(defaultAirport.Identifier); ok {
+	if entry, ok := cachedEntry(cacheKey(defaultAirport, defaultProfile())); ok {
 		status.GeneratedAt = entry.data.GeneratedAt
 	}
+	// An unknown airport is not an error here: the rest of the answer is still right, and
+	// the page asking has its own request for the forecast to fail on.
+	if airport, err := lookupAirport(r.URL.Query().Get("airport")); err == nil {
+		if metar, ok := usableMetar(airport, time.Now()); ok {
+			status.LatestObservedAt = &metar.ObservedAt
+		}
+	}
 
 	if err := json.NewEncoder(w).Encode(status); err != nil {
 		slog.Error("failed to encode status", "error", err)
@@ -407,12 +598,26 @@ func getWeatherData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := GetWeatherData(r.Context(), airport)
+	// Rejected for the same reason: a score against the wrong profile looks like any other.
+	profile, err := lookupProfile(r.URL.Query().Get("profile"))
 	if err != nil {
-		slog.Error("failed to fetch weather data", "airport", airport.Identifier, "error", err)
+		slog.Warn("rejected unknown profile", "error", err)
+		http.Error(w, "Unknown profile", http.StatusBadRequest)
+		return
+	}
+
+	if raw := r.URL.Query().Get("models"); raw != "" {
+		getModelComparison(w, r, airport, profile, raw)
+		return
+	}
+
+	data, err := GetWeatherData(r.Context(), airport, profile)
+	if err != nil {
+		slog.Error("failed to fetch weather data", "airport", airport.Identifier, "profile", profile.ID, "error", err)
 		http.Error(w, "Failed to fetch weather data", http.StatusInternalServerError)
 		return
 	}
+	data = applyNowcast(data, airport, profile, time.Now())
 
 	// Tell the browser this payload is good for a short window only -- long enough to
 	// absorb a double-fetch, far short of the backstop TTL.
@@ -436,3 +641,347 @@ func getWeatherData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func getObservations(w http.ResponseWriter, r *http.Request) {
+	airport, err := lookupAirport(r.URL.Query().Get("airport"))
+	if err != nil {
+		slog.Warn("rejected unknown airport", "error", err)
+		http.Error(w, "Unknown airport", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Polled every ten minutes; a minute in the browser collapses double-fetches and no more.
+	w.Header().Set("Cache-Control", "private, max-age=60")
+
+	response := ObservationsResponse{Airport: airport.Identifier}
+	if match, ok := stationFor(airport, metarRadiusKM()); ok {
+		response.Station = &match
+		response.METAR, response.TAF, response.FetchedAt, response.Degraded = observations.snapshot(match.ICAO)
+		// The tracker keeps the last report a station sent, however long ago; one the
+		// nowcast would no longer use is not shown as current either.
+		now := time.Now()
+		if !metarCurrent(response.METAR, now) {
+			response.METAR = nil
+		}
+		if response.TAF != nil && !now.Before(response.TAF.ValidTo) {
+			response.TAF = nil
+		}
+	} else {
+		_, _, response.FetchedAt, response.Degraded = observations.snapshot("")
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode observations", "error", err)
+	}
+}
+
+func getRoute(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	waypoints := make([]RouteWaypoint, 0, len(query["via"]))
+	for _, raw := range query["via"] {
+		wp, _, err := parseRouteWaypoint(raw)
+		if err != nil {
+			slog.Warn("rejected route", "error", err)
+			http.Error(w, "Invalid route: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		waypoints = append(waypoints, wp)
+	}
+
+	tas, err := parseRouteTAS(query.Get("tas"))
+	if err != nil {
+		http.Error(w, "Invalid route: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	profile, err := lookupProfile(query.Get("profile"))
+	if err != nil {
+		slog.Warn("rejected unknown profile", "error", err)
+		http.Error(w, "Unknown profile", http.StatusBadRequest)
+		return
+	}
+
+	legs, points, err := planRoute(waypoints, tas)
+	if err != nil {
+		slog.Warn("rejected route", "error", err)
+		http.Error(w, "Invalid route: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	forecasts, err := fetchRouteForecasts(r.Context(), points, profile)
+	if err != nil {
+		slog.Error("failed to fetch route forecasts", "error", err)
+		http.Error(w, "Failed to fetch weather data", http.StatusInternalServerError)
+		return
+	}
+
+	response := RouteResponse{
+		Waypoints: waypoints,
+		Legs:      legs,
+		Samples:   make([]RouteSample, len(points)),
+		TASKnots:  tas,
+		Profile:   profile.ID,
+		Hours:     scoreRoute(points, forecasts, profile, tas),
+	}
+	for i, p := range points {
+		response.Samples[i] = p.RouteSample
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode route", "error", err)
+	}
+}
+
+func getWindows(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	airport, err := lookupAirport(query.Get("airport"))
+	if err != nil {
+		slog.Warn("rejected unknown airport", "error", err)
+		http.Error(w, "Unknown airport", http.StatusBadRequest)
+		return
+	}
+	profile, err := lookupProfile(query.Get("profile"))
+	if err != nil {
+		slog.Warn("rejected unknown profile", "error", err)
+		http.Error(w, "Unknown profile", http.StatusBadRequest)
+		return
+	}
+	minScore, duration, err := parseWindowQuery(query.Get("min_score"), query.Get("duration"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := windowOptions{minScore: minScore, duration: duration}
+	if raw := query.Get("operating_hours"); raw != "" {
+		apply, err := strconv.ParseBool(raw)
+		if err != nil {
+			http.Error(w, "operating_hours must be true or false", http.StatusBadRequest)
+			return
+		}
+		// Asked for and not available is an error rather than a silent no-op: a bot
+		// relaying "open and flyable" must not be handed windows that were never checked.
+		if apply && airport.OperatingWindow == nil {
+			http.Error(w, "No machine-readable operating hours for "+airport.Identifier, http.StatusBadRequest)
+			return
+		}
+		if apply {
+			opts.operating = airport.OperatingWindow
+		}
+	}
+
+	data, err := GetWeatherData(r.Context(), airport, profile)
+	if err != nil {
+		slog.Error("failed to fetch weather data", "airport", airport.Identifier, "profile", profile.ID, "error", err)
+		http.Error(w, "Failed to fetch weather data", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	data = applyNowcast(data, airport, profile, now)
+
+	response := WindowsResponse{
+		Airport:         airport.Identifier,
+		Profile:         profile.ID,
+		MinScore:        minScore,
+		DurationMinutes: duration.Minutes(),
+		OperatingHours:  opts.operating != nil,
+		RestrictedAreas: []string{},
+		Stale:           data.Stale,
+	}
+	areas, degraded := restrictions.activeOver(airport.Latitude, airport.Longitude)
+	response.RestrictionsDegraded = degraded
+	for _, area := range areas {
+		response.RestrictedAreas = append(response.RestrictedAreas, area.Name)
+		for _, window := range area.Windows {
+			opts.restricted = append(opts.restricted, Interval{From: window.From, To: window.To})
+		}
+	}
+	response.Windows = findWindows(data, opts, now)
+	if response.Windows == nil {
+		response.Windows = []FlightWindow{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if data.Stale {
+		w.Header().Set("Cache-Control", "no-store")
+	} else {
+		w.Header().Set("Cache-Control", "private, max-age=60")
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode windows", "error", err)
+	}
+}
+
+func getTrend(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	airport, err := lookupAirport(query.Get("airport"))
+	if err != nil {
+		slog.Warn("rejected unknown airport", "error", err)
+		http.Error(w, "Unknown airport", http.StatusBadRequest)
+		return
+	}
+	profile, err := lookupProfile(query.Get("profile"))
+	if err != nil {
+		slog.Warn("rejected unknown profile", "error", err)
+		http.Error(w, "Unknown profile", http.StatusBadRequest)
+		return
+	}
+	hour, err := parseTrendHour(query.Get("hour"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Without an archive there is no history to read, which is a configuration matter
+	// rather than an empty trend: an empty one would read as "no runs covered this hour".
+	if !archive.enabled() {
+		http.Error(w, "No forecast archive configured; set "+archiveFileEnv, http.StatusServiceUnavailable)
+		return
+	}
+	records, err := archive.historyCovering(airport, hour.UTC().Format("2006-01-02T15:04"))
+	if err != nil {
+		slog.Error("failed to read the forecast archive", "airport", airport.Identifier, "profile", profile.ID, "error", err)
+		http.Error(w, "Failed to read the forecast archive", http.StatusInternalServerError)
+		return
+	}
+
+	response := TrendResponse{
+		Airport: airport.Identifier,
+		Profile: profile.ID,
+		Hour:    hour,
+		Points:  trendFor(records, profile, hour),
+	}
+	response.ProbabilitySpread = probabilitySpread(response.Points)
+
+	w.Header().Set("Content-Type", "application/json")
+	// A new point arrives with a model run, hours apart.
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode trend", "error", err)
+	}
+}
+
+func getVerification(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	airport, err := lookupAirport(query.Get("airport"))
+	if err != nil {
+		slog.Warn("rejected unknown airport", "error", err)
+		http.Error(w, "Unknown airport", http.StatusBadRequest)
+		return
+	}
+	profile, err := lookupProfile(query.Get("profile"))
+	if err != nil {
+		slog.Warn("rejected unknown profile", "error", err)
+		http.Error(w, "Unknown profile", http.StatusBadRequest)
+		return
+	}
+	days, err := parseVerificationDays(query.Get("days"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !archive.enabled() {
+		http.Error(w, "No forecast archive configured; set "+archiveFileEnv, http.StatusServiceUnavailable)
+		return
+	}
+
+	report, err := cachedVerification(airport, profile, days, time.Now().UTC())
+	// An airfield with no station in range is a question that cannot be answered, not an
+	// empty answer, as for operating_hours on /api/windows.
+	if errors.Is(err, errNoStation) {
+		http.Error(w, "No reporting station near "+airport.Identifier+" to verify against", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to verify forecasts", "airport", airport.Identifier, "profile", profile.ID, "error", err)
+		http.Error(w, "Failed to verify forecasts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("failed to encode verification", "error", err)
+	}
+}
+
+func createSubscription(w http.ResponseWriter, r *http.Request) {
+	var request subscriptionRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubscriptionBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		http.Error(w, "Invalid subscription: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, err := request.validate(time.Now().UTC())
+	if err != nil {
+		slog.Warn("rejected subscription", "error", err)
+		http.Error(w, "Invalid subscription: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The baseline: the score the creator is about to see, and the one the first crossing
+	// is measured from.
+	if score, ok := scoreSubscription(r.Context(), sub); ok {
+		sub.MinProbability, sub.CheckedAt = &score.min, time.Now().UTC()
+	}
+
+	sub.Client = subscriptionClient(r.RemoteAddr)
+	sub, err = subscriptions.add(sub)
+	if errors.Is(err, errTooManySubscriptions) {
+		http.Error(w, "Too many subscriptions", http.StatusServiceUnavailable)
+		return
+	}
+	if errors.Is(err, errTooManyFromClient) {
+		http.Error(w, "Too many subscriptions from this address", http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to store subscription", "error", err)
+		http.Error(w, "Failed to store subscription", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("subscription created", "id", sub.ID, "airport", sub.Airport, "from", sub.From, "to", sub.To)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/subscriptions/"+sub.ID)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(sub); err != nil {
+		slog.Error("failed to encode subscription", "error", err)
+	}
+}
+
+func getSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, ok := subscriptions.get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "Unknown subscription", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(sub); err != nil {
+		slog.Error("failed to encode subscription", "error", err)
+	}
+}
+
+func deleteSubscription(w http.ResponseWriter, r *http.Request) {
+	found, err := subscriptions.remove(r.PathValue("id"))
+	if !found {
+		http.Error(w, "Unknown subscription", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to save subscriptions", "error", err)
+		http.Error(w, "Failed to store subscriptions", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Wind shear and mechanical turbulence.
+//
+// The wind chart has had the wind at 10m, 80m and the pressure levels for as long as it has
+// drawn barbs, and the score read the 10m wind alone. 10kt on the ground with 35kt at 1500ft
+// is a rough approach into a grass strip -- the speed changes by the length of the runway's
+// usable headwind on the way down -- and scored as a light-wind day.
+//
+// Every hour now gets the shear between each pair of adjacent levels, the low-level shear
+// the score charges, and a mechanical-turbulence estimate for the lowest 3000ft. Shear is
+// the vector difference, so a veer at constant speed counts as much as a speed change: an
+// aircraft on the approach feels both as a change in airspeed or drift.
+//
+// The model's levels are hundreds of feet apart, which makes the rate per hundred feet the
+// ICAO categories are written in meaningless here: the surface layer's ordinary increase
+// from 10m to 80m would read as severe. The score charges the total change between the 10m
+// wind and the worst level up to lowLevelShearTopFt instead, in knots.
+
+// lowLevelShearTopFt is the top of what the low-level shear and the turbulence estimate
+// look at: circuit height and the approach below it, with a margin.
+const lowLevelShearTopFt = 3000
+
+// WindShear is the vector wind difference between two adjacent levels of an hour.
+type WindShear struct {
+	BottomFeet int     `json:"bottom_feet"`
+	TopFeet    int     `json:"top_feet"`
+	Knots      float64 `json:"knots"`
+}
+
+// windLevelWind is one level's wind, at its height above the ground.
+type windLevelWind struct {
+	feet      int     // above ground
+	speed     float64 // kn
+	direction int     // degrees true, where the wind is from
+}
+
+// shearProfile is every wind the hour has a speed, direction and height for, from 10m up,
+// calm included -- unlike the chart's barbs, which leave out calm levels and draw only a
+// few pressure levels. Heights are above the ground at groundFt: 10m and 80m are already,
+// the pressure levels' geopotential heights are above sea level.
+//
+// A pressure level at or below 80m above the ground is left out. In a deep low 1000hPa and
+// even 975hPa lie underground, and Open-Meteo extrapolates them rather than leaving them
+// empty; kept, the extrapolated wind would sort below the 10m wind and become the surface
+// the low-level shear is measured from. Without a 10m wind there is no surface, and no
+// profile.
+func shearProfile(hour forecastHour, groundFt float64) []windLevelWind {
+	if hour.windSpeed10m == nil || hour.windDirection10m == nil {
+		return nil
+	}
+	feet := func(metres float64) float64 { return metres * 3.28084 }
+	profile := []windLevelWind{{int(feet(10)), *hour.windSpeed10m, *hour.windDirection10m}}
+	if hour.windSpeed80m != nil && hour.windDirection80m != nil {
+		profile = append(profile, windLevelWind{int(feet(80)), *hour.windSpeed80m, *hour.windDirection80m})
+	}
+	var levels []windLevelWind
+	for _, level := range hour.levels {
+		if level.windSpeed == nil || level.windDirection == nil || level.height == nil {
+			continue
+		}
+		if aboveGround := feet(*level.height) - groundFt; aboveGround > feet(80) {
+			levels = append(levels, windLevelWind{int(aboveGround), *level.windSpeed, *level.windDirection})
+		}
+	}
+	slices.SortStableFunc(levels, func(a, b windLevelWind) int { return a.feet - b.feet })
+	return append(profile, levels...)
+}
+
+// vectorDifference is the magnitude of the difference between two winds, in knots.
+func vectorDifference(a, b windLevelWind) float64 {
+	components := func(w windLevelWind) (float64, float64) {
+		rad := float64(w.direction) * math.Pi / 180
+		return w.speed * math.Sin(rad), w.speed * math.Cos(rad)
+	}
+	ax, ay := components(a)
+	bx, by := components(b)
+	return math.Hypot(ax-bx, ay-by)
+}
+
+// windShear is the shear between each pair of adjacent levels, lowest first. Non-nil, so an
+// hour without levels marshals as [].
+func windShear(profile []windLevelWind) []WindShear {
+	shear := make([]WindShear, 0, max(0, len(profile)-1))
+	for i := 1; i < len(profile); i++ {
+		shear = append(shear, WindShear{
+			BottomFeet: profile[i-1].feet,
+			TopFeet:    profile[i].feet,
+			Knots:      vectorDifference(profile[i-1], profile[i]),
+		})
+	}
+	return shear
+}
+
+// lowLevelShear is the worst change between the 10m wind and a level up to
+// lowLevelShearTopFt above the ground, and where it was.
+type lowLevelShear struct {
+	knots   float64
+	topFeet int
+}
+
+// lowLevelShearOf returns the hour's low-level shear, or false where there is no level above
+// the lowest one within reach.
+func lowLevelShearOf(profile []windLevelWind) (lowLevelShear, bool) {
+	if len(profile) < 2 {
+		return lowLevelShear{}, false
+	}
+	surface := profile[0]
+	var worst lowLevelShear
+	found := false
+	for _, level := range profile[1:] {
+		if level.feet > lowLevelShearTopFt {
+			break
+		}
+		if d := vectorDifference(surface, level); !found || d > worst.knots {
+			worst, found = lowLevelShear{knots: d, topFeet: level.feet}, true
+		}
+	}
+	return worst, found
+}
+
+// turbulence grades the mechanical turbulence expected in the lowest lowLevelShearTopFt. The
+// JSON carries it as a number: 0 none, 1 light, 2 moderate, 3 severe.
+type turbulence int
+
+const (
+	turbulenceNone turbulence = iota
+	turbulenceLight
+	turbulenceModerate
+	turbulenceSevere
+)
+
+func (t turbulence) String() string {
+	switch t {
+	case turbulenceLight:
+		return "light"
+	case turbulenceModerate:
+		return "moderate"
+	case turbulenceSevere:
+		return "severe"
+	}
+	return "none"
+}
+
+// mechanicalTurbulence estimates the turbulence of wind over the ground: the strongest wind
+// in the lowest 3000ft, the gusts at 10m, and the low-level shear, whichever is worst. The
+// thresholds are the rules of thumb briefed at every flying school -- 15kt over the trees is
+// bumpy, 25kt uncomfortable, 40kt more than a light aircraft should be in -- not a model.
+func mechanicalTurbulence(profile []windLevelWind, gusts10m float64) turbulence {
+	strongest := gusts10m
+	for _, level := range profile {
+		if level.feet <= lowLevelShearTopFt {
+			strongest = max(strongest, level.speed)
+		}
+	}
+	var shear float64
+	if low, ok := lowLevelShearOf(profile); ok {
+		shear = low.knots
+	}
+	switch {
+	case strongest >= 40 || shear >= 30:
+		return turbulenceSevere
+	case strongest >= 25 || shear >= 20:
+		return turbulenceModerate
+	case strongest >= 15 || shear >= 12:
+		return turbulenceLight
+	}
+	return turbulenceNone
+}
+
+// shearDetail says what the low-level shear factor charged for, in words.
+func shearDetail(c conditions) string {
+	return fmt.Sprintf("%.0f kn between the surface and %dft", *c.lowLevelShear, c.lowLevelShearTopFt)
+}
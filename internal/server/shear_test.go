@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// roughApproach is the request's hour: 10kt on the ground, 35kt at ~1500ft, and a strong
+// wind further up that is none of the approach's business.
+func roughApproach() forecastHour {
+	return forecastHour{
+		time:             "2026-08-03T12:00",
+		windSpeed10m:     ptrFloat(10),
+		windGusts10m:     ptrFloat(16),
+		windDirection10m: ptrTo(240),
+		windSpeed80m:     ptrFloat(0), // calm at 80m is still a wind to compare
+		windDirection80m: ptrTo(240),
+		levels: []pressureLevel{
+			{hPa: 950, height: ptrFloat(460), windSpeed: ptrFloat(35), windDirection: ptrTo(240)},
+			{hPa: 850, height: ptrFloat(1450), windSpeed: ptrFloat(60), windDirection: ptrTo(250)},
+		},
+	}
+}
+
+func TestVectorDifference(t *testing.T) {
+	tests := []struct {
+		a, b windLevelWind
+		want float64
+	}{
+		{windLevelWind{speed: 10, direction: 240}, windLevelWind{speed: 35, direction: 240}, 25},
+		// A reversal at constant speed is twice the speed.
+		{windLevelWind{speed: 10, direction: 90}, windLevelWind{speed: 10, direction: 270}, 20},
+		// A 90-degree veer at constant speed.
+		{windLevelWind{speed: 10, direction: 180}, windLevelWind{speed: 10, direction: 270}, 14.14},
+	}
+	for _, tc := range tests {
+		if got := vectorDifference(tc.a, tc.b); math.Abs(got-tc.want) > 0.01 {
+			t.Errorf("vectorDifference(%+v, %+v) = %.2f, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestShearProfileAndLowLevelShear(t *testing.T) {
+	winds := shearProfile(roughApproach(), 0)
+	if len(winds) != 4 || winds[0].feet != 32 || winds[1].feet != 262 || winds[1].speed != 0 {
+		t.Fatalf("shearProfile() = %+v, want 10m, a calm 80m, 950 and 850hPa in height order", winds)
+	}
+
+	shear := windShear(winds)
+	if len(shear) != 3 || shear[0].BottomFeet != 32 || shear[0].TopFeet != 262 || math.Abs(shear[0].Knots-10) > 0.01 {
+		t.Errorf("windShear() = %+v, want three pairs starting with 10kt between 10m and 80m", shear)
+	}
+
+	// 950hPa at ~1509ft is within 3000ft; 850hPa at ~4757ft is not.
+	low, ok := lowLevelShearOf(winds)
+	if !ok || math.Abs(low.knots-25) > 0.01 || low.topFeet != 1509 {
+		t.Errorf("lowLevelShearOf() = %+v, want 25kt at 1509ft", low)
+	}
+
+	if _, ok := lowLevelShearOf(winds[:1]); ok {
+		t.Error("a surface wind alone has a low-level shear")
+	}
+	if shear := windShear(nil); shear == nil || len(shear) != 0 {
+		t.Errorf("windShear(nil) = %#v, want an empty slice so it marshals as []", shear)
+	}
+}
+
+// In a deep low 1000hPa lies underground, where Open-Meteo extrapolates a wind for it. It
+// is no surface to measure from: the shear is the change from the 10m wind to 950hPa.
+func TestShearProfile_DeepLow(t *testing.T) {
+	hour := forecastHour{
+		time:             "2026-01-15T12:00",
+		windSpeed10m:     ptrFloat(12),
+		windGusts10m:     ptrFloat(20),
+		windDirection10m: ptrTo(220),
+		windSpeed80m:     ptrFloat(17),
+		windDirection80m: ptrTo(225),
+		levels: []pressureLevel{
+			{hPa: 1000, height: ptrFloat(-120), windSpeed: ptrFloat(30), windDirection: ptrTo(160)},
+			{hPa: 975, height: ptrFloat(95), windSpeed: ptrFloat(18), windDirection: ptrTo(225)},
+			{hPa: 950, height: ptrFloat(330), windSpeed: ptrFloat(20), windDirection: ptrTo(240)},
+		},
+	}
+
+	// At 100ft, 975hPa is ~212ft above the ground, below 80m, and left out with 1000hPa.
+	winds := shearProfile(hour, 100)
+	if len(winds) != 3 || winds[0].feet != 32 || winds[0].speed != 12 || winds[1].feet != 262 || winds[2].feet != 982 {
+		t.Fatalf("shearProfile() = %+v, want 10m, 80m and 950hPa at ~982ft above the ground", winds)
+	}
+
+	low, ok := lowLevelShearOf(winds)
+	want := vectorDifference(windLevelWind{speed: 12, direction: 220}, windLevelWind{speed: 20, direction: 240})
+	if !ok || math.Abs(low.knots-want) > 0.01 || low.topFeet != 982 {
+		t.Errorf("lowLevelShearOf() = %+v, want %.1fkt at 982ft", low, want)
+	}
+
+	if winds := shearProfile(forecastHour{levels: hour.levels}, 100); winds != nil {
+		t.Errorf("shearProfile() without a 10m wind = %+v, want none", winds)
+	}
+}
+
+func TestMechanicalTurbulence(t *testing.T) {
+	calm := []windLevelWind{{feet: 32, speed: 4, direction: 240}, {feet: 1500, speed: 8, direction: 250}}
+	tests := []struct {
+		name  string
+		winds []windLevelWind
+		gusts float64
+		want  turbulence
+	}{
+		{"a quiet morning", calm, 6, turbulenceNone},
+		{"gusty on the ground", calm, 18, turbulenceLight},
+		{"strong wind aloft", []windLevelWind{{feet: 32, speed: 12, direction: 240}, {feet: 1500, speed: 26, direction: 240}}, 15, turbulenceModerate},
+		{"sheared", shearProfile(roughApproach(), 0), 16, turbulenceModerate},
+		{"a gale", []windLevelWind{{feet: 32, speed: 30, direction: 240}, {feet: 1500, speed: 45, direction: 240}}, 40, turbulenceSevere},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := mechanicalTurbulence(tc.winds, tc.gusts); got != tc.want {
+				t.Errorf("mechanicalTurbulence() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScoreVFR_LowLevelShear(t *testing.T) {
+	c := scoringConditions(t)
+	c.windSpeed = 10
+	c.lowLevelShear, c.lowLevelShearTopFt = ptrFloat(25), 1509
+
+	penalties := mustScore(t, c)
+	if len(penalties) == 0 || penalties[0].Factor != "low-level shear" || penalties[0].Severity != critical.String() {
+		t.Fatalf("penalties = %+v, want low-level shear first and critical", penalties)
+	}
+	if got, want := penalties[0].Detail, "25 kn between the surface and 1509ft"; got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+
+	// Without levels to compare there is nothing to charge.
+	c.lowLevelShear = nil
+	for _, p := range mustScore(t, c) {
+		if p.Factor == "low-level shear" {
+			t.Errorf("charged %+v without levels", p)
+		}
+	}
+}
+
+func TestProcessWeatherData_Shear(t *testing.T) {
+	stubDayLight(t)
+
+	forecast := hourlyFixture([]string{"2026-08-03T12:00"})
+	hour := roughApproach()
+	hour.time, hour.temperature, hour.dewPoint = forecast.hours[0].time, ptrFloat(18), ptrFloat(10)
+	hour.precipitation, hour.precipitationProbability = ptrFloat(0), ptrTo(0)
+	hour.visibility = ptrFloat(30000)
+	forecast.hours[0] = hour
+
+	got := processWeatherData(context.Background(), forecast, testAirport, defaultProfile())
+	wind := got.WindData[0]
+	if len(wind.Shear) != 3 || wind.LowLevelShear == nil || math.Abs(*wind.LowLevelShear-25) > 0.01 || turbulence(wind.Turbulence) != turbulenceModerate {
+		t.Errorf("WindData[0] = shear %+v, low-level %v, turbulence %v; want 25kt and moderate", wind.Shear, deref(wind.LowLevelShear), turbulence(wind.Turbulence))
+	}
+	if got.VfrData[0].Probability > 75 {
+		t.Errorf("score = %d, want the shear to show in it", got.VfrData[0].Probability)
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Soft and waterlogged grass.
+//
+// A third of the fields in airports.json have a grass runway, and a grass runway after
+// 25mm of rain in two days is closed or marginal under a clear blue sky: the wheels sink,
+// the takeoff run doubles, and the aircraft that does get down ruts the strip for everyone
+// after it. Nothing in the table looked further back than the hour being scored.
+//
+// The query now asks for the two days before the forecast as well (see apiURLTemplate),
+// and every hour gets what the last 48 hours have left on the ground: the rain that fell,
+// less what the hours since have dried off -- a bucket, filled by each hour's rain and
+// emptied by its warmth, so a warm morning before the rain dries nothing of it. Lying snow
+// counts as so much rain, and frozen ground dries nothing: what falls on it sits on top,
+// and the first thaw turns it to grease.
+//
+// The soft field factor charges it on a grass end in use. A paved runway drains, and a
+// route's en-route samples have no runway to land on. Where the 48 hours are not all there
+// -- an hour in a forecast without its history -- there is nothing to charge.
+
+const (
+	// softFieldWindow is how far back the rain is counted.
+	softFieldWindow = 48 * time.Hour
+
+	// dryingPerDegreeHour is mm of water a grass strip loses per hour per degree above
+	// zero: 0.2mm a day per degree, 1mm on a cool spring day and 4mm on a warm summer one,
+	// which is about what short grass evaporates.
+	dryingPerDegreeHour = 0.2 / 24
+
+	// snowAsRainMM is what a centimetre of lying snow counts for, mm of rain: 5cm of it on
+	// a grass strip is as closed as the 25mm of rain is.
+	snowAsRainMM = 5.0
+)
+
+// groundState is what the last two days have left on the airfield at one hour.
+type groundState struct {
+	// rain24h and rain48h are mm fallen over the 24 and 48 hours up to and including the
+	// hour, as the payload reports them.
+	rain24h float64
+	rain48h float64
+	// standing is the part of rain48h not yet dried off.
+	standing float64
+	// snowCM is the snow lying at the hour, nil where the forecast does not say; frozen
+	// is ground at or below zero at the surface.
+	snowCM *float64
+	frozen bool
+}
+
+// wetness is the ground's state as mm of rain: what is still standing, and the snow.
+func (g groundState) wetness() float64 {
+	wet := g.standing
+	if g.snowCM != nil {
+		wet += *g.snowCM * snowAsRainMM
+	}
+	return wet
+}
+
+// frozenGround reports whether an hour's surface is frozen. A forecast without a soil
+// temperature is not.
+func frozenGround(h forecastHour) bool {
+	return h.soilTemperature != nil && *h.soilTemperature <= 0
+}
+
+// groundSeries is the ground's state at every hour of hours, index-aligned, looking back
+// into history and the forecast's own earlier hours. Hours are looked up by time, as the
+// pressure tendency's are, so a gap is a gap rather than the hour next to it; an hour
+// whose window has a gap, or an hour without a rain figure, is nil.
+func groundSeries(history, hours []forecastHour) []*groundState {
+	byTime := make(map[string]forecastHour, len(history)+len(hours))
+	for _, h := range history {
+		byTime[h.time] = h
+	}
+	for _, h := range hours {
+		byTime[h.time] = h
+	}
+
+	series := make([]*groundState, len(hours))
+	for i, h := range hours {
+		at, err := hourTime(h.time)
+		if err != nil {
+			continue
+		}
+		series[i] = groundAt(byTime, at, h)
+	}
+	return series
+}
+
+// groundAt fills the bucket over the window ending at the hour at, oldest hour first.
+func groundAt(byTime map[string]forecastHour, at time.Time, hour forecastHour) *groundState {
+	g := &groundState{frozen: frozenGround(hour)}
+	if hour.snowDepth != nil {
+		cm := max(0, *hour.snowDepth*100)
+		g.snowCM = &cm
+	}
+
+	hours := int(softFieldWindow / time.Hour)
+	for back := hours - 1; back >= 0; back-- {
+		h, ok := byTime[at.Add(-time.Duration(back)*time.Hour).Format("2006-01-02T15:04")]
+		if !ok || h.precipitation == nil {
+			return nil
+		}
+		rain := max(0, *h.precipitation)
+		g.rain48h += rain
+		if back < 24 {
+			g.rain24h += rain
+		}
+		g.standing += rain
+		if h.temperature != nil && !frozenGround(h) {
+			g.standing = max(0, g.standing-max(0, *h.temperature)*dryingPerDegreeHour)
+		}
+	}
+	return g
+}
+
+// runwaySurface is the surface of the runway an end belongs to, "" where the airfield
+// gives no details for it.
+func (a Airport) runwaySurface(end string) string {
+	if end == "" {
+		return ""
+	}
+	for _, e := range a.runwayEnds() {
+		if e.designator != end {
+			continue
+		}
+		if detail, ok := a.runwayDetail(e.runway); ok {
+			return detail.Surface
+		}
+	}
+	return ""
+}
+
+// softFieldOf is the ground's wetness, mm, under the grass end in use, false where the end
+// in use is not grass or the hour has no ground state.
+func softFieldOf(c conditions) (float64, bool) {
+	if c.runwayInUse == "" || c.runwaySurface != surfaceGrass || c.ground == nil {
+		return 0, false
+	}
+	return c.ground.wetness(), true
+}
+
+// softFieldDetail says what the wetness is made of: "grass 23: 27mm of rain in 48h, 21mm
+// of it still there, 2cm of snow, ground frozen".
+func softFieldDetail(c conditions) string {
+	g := c.ground
+	parts := []string{fmt.Sprintf("%.0fmm of rain in 48h", g.rain48h)}
+	if g.rain48h > 0 {
+		parts = append(parts, fmt.Sprintf("%.0fmm of it still there", g.standing))
+	}
+	if g.snowCM != nil && *g.snowCM >= 0.5 {
+		parts = append(parts, fmt.Sprintf("%.0fcm of snow", *g.snowCM))
+	}
+	if g.frozen {
+		parts = append(parts, "ground frozen")
+	}
+	return fmt.Sprintf("grass %s: %s", c.runwayInUse, strings.Join(parts, ", "))
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// wetWeekend is 72 dry hours at 10C from Friday midnight, with rain in the hours given.
+func wetWeekend(rain map[string]float64) []forecastHour {
+	start := time.Date(2026, 10, 9, 0, 0, 0, 0, time.UTC)
+	var times []string
+	for h := range 72 {
+		times = append(times, start.Add(time.Duration(h)*time.Hour).Format("2006-01-02T15:04"))
+	}
+	hours := hourlyFixture(times).hours
+	for i := range hours {
+		hours[i].temperature = ptrFloat(10)
+		if mm, ok := rain[hours[i].time]; ok {
+			hours[i].precipitation = ptrFloat(mm)
+		}
+	}
+	return hours
+}
+
+// grassAirport is testAirport with its runway turned to grass.
+func grassAirport() Airport {
+	airport := testAirport
+	airport.RunwayDetails = []RunwayDetail{{Designator: "05/23", LengthM: 800, Surface: surfaceGrass}}
+	return airport
+}
+
+func TestGroundSeries(t *testing.T) {
+	// 30mm through Friday morning, none since.
+	hours := wetWeekend(map[string]float64{"2026-10-09T06:00": 10, "2026-10-09T07:00": 12, "2026-10-09T08:00": 8})
+	history, forecast := hours[:48], hours[48:]
+	series := groundSeries(history, forecast)
+	if len(series) != len(forecast) {
+		t.Fatalf("len(series) = %d, want %d", len(series), len(forecast))
+	}
+
+	// Sunday 00:00: the rain is in the 48 hours and not the last 24, and the 43 hours at
+	// 10C from the first of it on have dried three and a half millimetres.
+	g := series[0]
+	if g == nil || g.rain48h != 30 || g.rain24h != 0 {
+		t.Fatalf("Sunday 00:00 = %+v, want 30mm in 48h and none in 24h", g)
+	}
+	if want := 30 - 43*10*dryingPerDegreeHour; math.Abs(g.standing-want) > 0.01 {
+		t.Errorf("standing = %.2f, want %.2f", g.standing, want)
+	}
+	// Friday's six hours before the rain dried nothing of it.
+	if early := groundSeries(nil, hours)[47]; early == nil || math.Abs(early.standing-(30-42*10*dryingPerDegreeHour)) > 0.01 {
+		t.Errorf("Saturday 23:00 = %+v, want only the hours from the rain on to have dried it", early)
+	}
+	// Sunday 09:00 has the rain out of its window.
+	if g := series[9]; g == nil || g.rain48h != 0 || g.standing != 0 {
+		t.Errorf("Sunday 09:00 = %+v, want the rain gone from the window", g)
+	}
+
+	// Without history, the first 47 hours of a forecast have nothing to look back over.
+	if bare := groundSeries(nil, hours); bare[0] != nil || bare[46] != nil || bare[47] == nil {
+		t.Errorf("without history = %v ... %v, %v; want nil up to the 48th hour", bare[0], bare[46], bare[47])
+	}
+	// A gap in the window is a gap, and so is an hour without a rain figure.
+	gappy := append(append([]forecastHour(nil), history[:10]...), history[11:]...)
+	if got := groundSeries(gappy, forecast)[0]; got != nil {
+		t.Errorf("with a missing hour = %+v, want nil", got)
+	}
+	history[20].precipitation = nil
+	if got := groundSeries(history, forecast)[0]; got != nil {
+		t.Errorf("with a null hour = %+v, want nil", got)
+	}
+}
+
+func TestGroundSeries_SnowAndFrozenGround(t *testing.T) {
+	hours := wetWeekend(map[string]float64{"2026-10-10T06:00": 10})
+	for i := range hours {
+		hours[i].soilTemperature = ptrFloat(-2)
+	}
+	hours[60].snowDepth = ptrFloat(0.03)
+
+	series := groundSeries(hours[:48], hours[48:])
+	// Frozen all weekend: nothing dried, whatever the air did.
+	if g := series[0]; g == nil || g.standing != 10 || !g.frozen {
+		t.Errorf("Sunday 00:00 = %+v, want all 10mm standing on frozen ground", g)
+	}
+	if g := series[12]; g.snowCM == nil || math.Abs(*g.snowCM-3) > 1e-9 || math.Abs(g.wetness()-(10+3*snowAsRainMM)) > 1e-9 {
+		t.Errorf("Sunday 12:00 = %+v, wetness %v; want 3cm of snow on top of the rain", g, g.wetness())
+	}
+}
+
+func TestScoreVFR_SoftField(t *testing.T) {
+	c := scoringConditions(t)
+	c.runwayInUse, c.runwaySurface = "23", surfaceGrass
+	c.ground = &groundState{rain24h: 4, rain48h: 27, standing: 20}
+
+	var soft *VfrPenalty
+	for _, p := range mustScore(t, c) {
+		if p.Factor == "soft field" {
+			soft = &p
+		}
+	}
+	if soft == nil || soft.Severity != critical.String() {
+		t.Fatalf("soft field penalty = %+v, want critical: 20mm is half way from 15 to the wall", soft)
+	}
+	if got, want := soft.Detail, "grass 23: 27mm of rain in 48h, 20mm of it still there"; got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+
+	// Past 25mm standing is the wall, and so is more than 5cm of snow on a dry strip.
+	for name, ground := range map[string]*groundState{
+		"waterlogged":  {rain48h: 31, standing: 26},
+		"snow-covered": {snowCM: ptrFloat(6), frozen: true},
+	} {
+		c := c
+		c.ground = ground
+		if got, _, _ := scoreVFR(c, vfrLimits); got != 0 {
+			t.Errorf("%s: probability = %d, want 0", name, got)
+		}
+	}
+
+	// Asphalt drains, an en-route sample has no runway, and an hour without its two days
+	// has nothing to say.
+	for name, with := range map[string]func(c *conditions){
+		"asphalt":    func(c *conditions) { c.runwaySurface = surfaceAsphalt },
+		"no runway":  func(c *conditions) { c.runwayInUse = "" },
+		"no history": func(c *conditions) { c.ground = nil },
+	} {
+		c := c
+		with(&c)
+		for _, p := range mustScore(t, c) {
+			if p.Factor == "soft field" {
+				t.Errorf("%s: charged %+v", name, p)
+			}
+		}
+	}
+}
+
+func TestRunwaySurface(t *testing.T) {
+	airport := grassAirport()
+	for end, want := range map[string]string{"05": surfaceGrass, "23": surfaceGrass, "": "", "14": ""} {
+		if got := airport.runwaySurface(end); got != want {
+			t.Errorf("runwaySurface(%q) = %q, want %q", end, got, want)
+		}
+	}
+	// An airfield without runway details has no surface to report.
+	if got := testAirport.runwaySurface("23"); got != "" {
+		t.Errorf("runwaySurface() = %q without details, want none", got)
+	}
+}
+
+func TestProcessWeatherData_SoftField(t *testing.T) {
+	stubDayLightByDate(t)
+
+	hours := wetWeekend(map[string]float64{"2026-10-10T06:00": 10, "2026-10-10T07:00": 12, "2026-10-10T08:00": 8})
+	// Sunday noon, a south-westerly: 23 in use, on a strip with 30mm on it.
+	for i := range hours {
+		hours[i].windSpeed10m, hours[i].windDirection10m = ptrFloat(8), ptrTo(230)
+	}
+	forecast := &hourlyForecast{history: hours[:48], hours: hours[48:]}
+
+	got := processWeatherData(context.Background(), forecast, grassAirport(), defaultProfile())
+	point := got.TemperatureData[12]
+	if point.Precipitation48h == nil || *point.Precipitation48h != 30 || point.Precipitation24h == nil || point.GroundWetness == nil {
+		t.Fatalf("Sunday 12:00 = %+v, want the sums and the wetness", point)
+	}
+	charged := false
+	for _, p := range got.VfrData[12].Penalties {
+		charged = charged || p.Factor == "soft field"
+	}
+	if !charged {
+		t.Errorf("penalties = %+v, want soft field on grass 23", got.VfrData[12].Penalties)
+	}
+
+	// The same weekend on asphalt: the sums are reported, and nothing is charged.
+	paved := grassAirport()
+	paved.RunwayDetails[0].Surface = surfaceAsphalt
+	got = processWeatherData(context.Background(), forecast, paved, defaultProfile())
+	for _, p := range got.VfrData[12].Penalties {
+		if p.Factor == "soft field" {
+			t.Errorf("charged %+v on asphalt", p)
+		}
+	}
+	if got.TemperatureData[12].Precipitation48h == nil {
+		t.Error("asphalt lost the rain sums")
+	}
+}
+
+func TestDecodeOpenMeteo_PastDaysAreHistory(t *testing.T) {
+	start := time.Date(2026, 10, 8, 0, 0, 0, 0, time.UTC)
+	body := func(days int, extra string) []byte {
+		times := make([]string, days*24)
+		for i := range times {
+			times[i] = start.Add(time.Duration(i) * time.Hour).Format("2006-01-02T15:04")
+		}
+		raw, err := json.Marshal(times)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []byte(fmt.Sprintf(`{%s"hourly": {"time": %s}}`, extra, raw))
+	}
+
+	forecast, err := decodeOpenMeteo(body(openMeteoPastDays+openMeteoForecastDays, ""), openMeteoPastDays)
+	if err != nil {
+		t.Fatalf("decodeOpenMeteo() = %v", err)
+	}
+	if len(forecast.history) != openMeteoPastDays*24 || len(forecast.hours) != openMeteoForecastDays*24 {
+		t.Fatalf("decoded %d history and %d forecast hours", len(forecast.history), len(forecast.hours))
+	}
+	if forecast.hours[0].time != "2026-10-10T00:00" {
+		t.Errorf("forecast starts at %s, want 2026-10-10T00:00", forecast.hours[0].time)
+	}
+
+	// Sixteen days without past_days are sixteen days of forecast, not nine and seven.
+	if forecast, err := decodeOpenMeteo(body(16, ""), 0); err != nil || len(forecast.history) != 0 || forecast.hours[0].time != "2026-10-08T00:00" {
+		t.Errorf("16 days without history = %v, %v", forecast, err)
+	}
+
+	// A capture's own past_days wins over the caller's.
+	if forecast, err := decodeOpenMeteo(body(3, `"past_days": 1, `), 0); err != nil || len(forecast.history) != 24 || forecast.hours[0].time != "2026-10-09T00:00" {
+		t.Errorf("capture with one past day = %v, %v", forecast, err)
+	}
+
+	if _, err := decodeOpenMeteo(body(2, ""), openMeteoPastDays); err == nil {
+		t.Error("a response with no hours past the history decoded")
+	}
+}
+
+func TestArchivedConditions_KeepTheGround(t *testing.T) {
+	c := conditions{ground: &groundState{rain24h: 4, rain48h: 27, standing: 20, snowCM: ptrFloat(2), frozen: true}}
+	body, err := json.Marshal(archiveConditions(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archived archivedConditions
+	if err := json.Unmarshal(body, &archived); err != nil {
+		t.Fatal(err)
+	}
+	got := archived.restore(nil).ground
+	if got == nil || got.rain24h != 4 || got.rain48h != 27 || got.standing != 20 || got.snowCM == nil || *got.snowCM != 2 || !got.frozen {
+		t.Errorf("restored ground = %+v, want %+v", got, c.ground)
+	}
+	if archiveConditions(conditions{}).restore(nil).ground != nil {
+		t.Error("an hour without its two days came back with a ground")
+	}
+}
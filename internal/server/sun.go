@@ -0,0 +1,191 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Sunrise, sunset and civil twilight, worked out locally.
+//
+// The daylight window used to come from sunrise-sunset.org, one request per airfield and
+// date. When it failed, resolveDaylight left the date out and every hour of it scored -1:
+// a forecast lost to an API whose answer has been known in closed form for two centuries.
+// It is now computed here, with NOAA's solar position algorithm -- the one behind NOAA's
+// solar calculator spreadsheet. The remote API is kept only as an optional cross-check; see
+// getDayLight.
+//
+// The algorithm is accurate to about a minute between 72 degrees north and south, which
+// is what the score's daylight boundaries need. Above that, refraction near the horizon
+// dominates, and polar day and night have no sunrise at all.
+
+// Solar zenith angles, degrees, of the two boundaries the score uses.
+const (
+	// zenithSunrise puts the sun's upper limb on the horizon: 90 degrees, plus 50 minutes of
+	// arc for the sun's radius and atmospheric refraction.
+	zenithSunrise = 90.833
+	// zenithCivilTwilight is the sun 6 degrees below the horizon.
+	zenithCivilTwilight = 96.0
+)
+
+// julianDay is t as a Julian day number.
+func julianDay(t time.Time) float64 {
+	return float64(t.UTC().Unix())/86400 + 2440587.5
+}
+
+// sunPosition returns the sun's declination, degrees, and the equation of time, minutes,
+// at t. NOAA's series in Julian centuries since J2000.0.
+func sunPosition(t time.Time) (declination, equationOfTime float64) {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	c := (julianDay(t) - 2451545) / 36525
+	meanLongitude := math.Mod(280.46646+c*(36000.76983+c*0.0003032), 360)
+	meanAnomaly := 357.52911 + c*(35999.05029-0.0001537*c)
+	eccentricity := 0.016708634 - c*(0.000042037+0.0000001267*c)
+	center := math.Sin(rad(meanAnomaly))*(1.914602-c*(0.004817+0.000014*c)) +
+		math.Sin(rad(2*meanAnomaly))*(0.019993-0.000101*c) +
+		math.Sin(rad(3*meanAnomaly))*0.000289
+	omega := 125.04 - 1934.136*c
+	apparentLongitude := meanLongitude + center - 0.00569 - 0.00478*math.Sin(rad(omega))
+	meanObliquity := 23 + (26+(21.448-c*(46.815+c*(0.00059-c*0.001813)))/60)/60
+	obliquity := meanObliquity + 0.00256*math.Cos(rad(omega))
+
+	declination = math.Asin(math.Sin(rad(obliquity))*math.Sin(rad(apparentLongitude))) * 180 / math.Pi
+
+	y := math.Pow(math.Tan(rad(obliquity/2)), 2)
+	l0, m := rad(meanLongitude), rad(meanAnomaly)
+	equationOfTime = 4 * (y*math.Sin(2*l0) -
+		2*eccentricity*math.Sin(m) +
+		4*eccentricity*y*math.Sin(m)*math.Cos(2*l0) -
+		0.5*y*y*math.Sin(4*l0) -
+		1.25*eccentricity*eccentricity*math.Sin(2*m)) * 180 / math.Pi
+	return declination, equationOfTime
+}
+
+// solarNoon is when the sun crosses the meridian at longitude on the UTC date of midnight.
+func solarNoon(midnight time.Time, longitude float64) time.Time {
+	noon := midnight.Add(12 * time.Hour)
+	// Twice: the equation of time is taken at the first estimate, then at the answer.
+	for range 2 {
+		_, eot := sunPosition(noon)
+		noon = midnight.Add(time.Duration((720 - 4*longitude - eot) * float64(time.Minute)))
+	}
+	return noon
+}
+
+// sunCrossing is when the sun passes zenith degrees on the morning (rising) or evening side
+// of solar noon, or false where it does not that day: polar day or night.
+func sunCrossing(midnight time.Time, latitude, longitude, zenith float64, rising bool) (time.Time, bool) {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	at := solarNoon(midnight, longitude)
+	// Iterated as solarNoon is: the sun's position is taken at the estimate, which three
+	// rounds bring to within seconds of the crossing.
+	for range 3 {
+		declination, eot := sunPosition(at)
+		cosHourAngle := math.Cos(rad(zenith))/(math.Cos(rad(latitude))*math.Cos(rad(declination))) -
+			math.Tan(rad(latitude))*math.Tan(rad(declination))
+		if cosHourAngle < -1 || cosHourAngle > 1 {
+			return time.Time{}, false
+		}
+		hourAngle := math.Acos(cosHourAngle) * 180 / math.Pi
+		if rising {
+			hourAngle = -hourAngle
+		}
+		at = midnight.Add(time.Duration((720 - 4*(longitude-hourAngle) - eot) * float64(time.Minute)))
+	}
+	return at, true
+}
+
+// sunSky is where the sun stands in the sky: its azimuth, degrees true, and its elevation
+// above the horizon, degrees, without refraction.
+type sunSky struct {
+	azimuth   float64
+	elevation float64
+}
+
+// sunSkyAt is where the sun stands at t, seen from a position. NOAA's algorithm again: the
+// hour angle from the true solar time, then the spherical triangle to the zenith.
+func sunSkyAt(t time.Time, latitude, longitude float64) sunSky {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	deg := func(rad float64) float64 { return rad * 180 / math.Pi }
+
+	t = t.UTC()
+	declination, eot := sunPosition(t)
+	minutes := float64(t.Hour()*60+t.Minute()) + float64(t.Second())/60
+	trueSolarTime := math.Mod(minutes+eot+4*longitude, 1440)
+	hourAngle := trueSolarTime/4 - 180
+
+	cosZenith := math.Sin(rad(latitude))*math.Sin(rad(declination)) +
+		math.Cos(rad(latitude))*math.Cos(rad(declination))*math.Cos(rad(hourAngle))
+	zenith := math.Acos(max(-1, min(1, cosZenith)))
+
+	// Measured from north, clockwise; the afternoon sun is west of south.
+	cosAzimuth := (math.Sin(rad(latitude))*math.Cos(zenith) - math.Sin(rad(declination))) /
+		(math.Cos(rad(latitude)) * math.Sin(zenith))
+	azimuth := deg(math.Acos(max(-1, min(1, cosAzimuth))))
+	if hourAngle > 0 {
+		azimuth = math.Mod(azimuth+180, 360)
+	} else {
+		azimuth = math.Mod(540-azimuth, 360)
+	}
+	return sunSky{azimuth: azimuth, elevation: 90 - deg(zenith)}
+}
+
+// computeDaylight works out the daylight window for the UTC date of t at a position, in
+// the shape sunrise-sunset.org answers in, so nothing downstream can tell the difference.
+// An error for a date without a sunrise, sunset or civil twilight: resolveDaylight then
+// leaves the date out, as it did for a failed lookup.
+func computeDaylight(latitude, longitude float64, t time.Time) (*SunriseSunsetResponse, error) {
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	date := midnight.Format("2006-01-02")
+
+	result := &SunriseSunsetResponse{Status: "OK"}
+	for _, f := range []struct {
+		name   string
+		zenith float64
+		rising bool
+		parsed *time.Time
+		raw    *string
+	}{
+		{"sunrise", zenithSunrise, true, &result.Parsed.Sunrise, &result.Results.Sunrise},
+		{"sunset", zenithSunrise, false, &result.Parsed.Sunset, &result.Results.Sunset},
+		{"civil twilight begin", zenithCivilTwilight, true, &result.Parsed.CivilTwilightBegin, &result.Results.CivilTwilight_Begin},
+		{"civil twilight end", zenithCivilTwilight, false, &result.Parsed.CivilTwilightEnd, &result.Results.CivilTwilight_End},
+	} {
+		at, ok := sunCrossing(midnight, latitude, longitude, f.zenith, f.rising)
+		if !ok {
+			return nil, fmt.Errorf("no %s at %.4f,%.4f on %s", f.name, latitude, longitude, date)
+		}
+		at = at.Round(time.Second)
+		*f.parsed = at
+		*f.raw = at.Format(time.RFC3339)
+	}
+	result.Results.SolarNoon = solarNoon(midnight, longitude).Round(time.Second).Format(time.RFC3339)
+	result.Results.DayLength = int64(result.Parsed.Sunset.Sub(result.Parsed.Sunrise).Seconds())
+	return result, nil
+}
+
+// sunriseCrossCheckEnv turns on the comparison with sunrise-sunset.org. Off by default, so
+// the scoring path makes no request for the daylight window at all.
+const sunriseCrossCheckEnv = "FLUGWETTER_SUNRISE_CROSSCHECK"
+
+// sunriseCrossCheck is whether the cross-check is on, set from the environment in Run.
+var sunriseCrossCheck bool
+
+// sunriseCrossCheckFromEnv reads FLUGWETTER_SUNRISE_CROSSCHECK: anything strconv.ParseBool
+// takes, unset being false. Anything else is an error, as a mistyped MOSMIX mode is.
+func sunriseCrossCheckFromEnv() (bool, error) {
+	raw := os.Getenv(sunriseCrossCheckEnv)
+	if raw == "" {
+		return false, nil
+	}
+	on, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s=%q, want true or false", sunriseCrossCheckEnv, raw)
+	}
+	return on, nil
+}
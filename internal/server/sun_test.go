@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The parseSunriseSunset fixture is a hand-written window of round numbers, not a captured
+// response -- EDWN's real sunrise on 2026-08-03 is twenty minutes after its 03:30 -- so the
+// computation is checked against the sunrise and sunset times the almanacs publish instead,
+// to the minute. Civil twilight is the same calculation at a different zenith.
+func TestComputeDaylight(t *testing.T) {
+	tests := []struct {
+		name            string
+		lat, lon        float64
+		date            string
+		sunrise, sunset string
+	}{
+		// 04:43 and 21:21 BST at Greenwich on the longest day.
+		{"Greenwich, midsummer", 51.4779, -0.0015, "2026-06-21", "03:43", "20:21"},
+		// 07:20 and 16:39 EST in Manhattan on New Year's Day.
+		{"New York, New Year", 40.7128, -74.0060, "2026-01-01", "12:20", "21:39"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			day, _ := time.Parse("2006-01-02", tc.date)
+			got, err := computeDaylight(tc.lat, tc.lon, day.Add(15*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, b := range []struct {
+				name string
+				got  time.Time
+				want string
+			}{
+				{"sunrise", got.Parsed.Sunrise, tc.sunrise},
+				{"sunset", got.Parsed.Sunset, tc.sunset},
+			} {
+				want, _ := time.Parse(time.RFC3339, tc.date+"T"+b.want+":00Z")
+				if diff := b.got.Sub(want).Abs(); diff > time.Minute {
+					t.Errorf("%s = %s, want %s within a minute", b.name, b.got.Format("15:04:05"), b.want)
+				}
+			}
+		})
+	}
+}
+
+// The computed window is in the shape the API answered in: it round-trips through the
+// parser that used to read the API, and the order of its boundaries is the day's.
+func TestComputeDaylight_ReadsLikeTheAPI(t *testing.T) {
+	day := time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC)
+	got, err := computeDaylight(testAirport.Latitude, testAirport.Longitude, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := got.Parsed
+	if !(p.CivilTwilightBegin.Before(p.Sunrise) && p.Sunrise.Before(p.Sunset) && p.Sunset.Before(p.CivilTwilightEnd)) {
+		t.Errorf("boundaries out of order: %+v", p)
+	}
+
+	body, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parseSunriseSunset(body, "2026-08-03")
+	if err != nil {
+		t.Fatalf("parseSunriseSunset rejects the computed window: %v", err)
+	}
+	if parsed.Parsed != got.Parsed {
+		t.Errorf("round trip = %+v, want %+v", parsed.Parsed, got.Parsed)
+	}
+}
+
+func TestComputeDaylight_PolarNight(t *testing.T) {
+	// Tromsø at midwinter has civil twilight but no sunrise: there is no window to score.
+	if got, err := computeDaylight(69.6492, 18.9553, time.Date(2026, 12, 21, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Errorf("computeDaylight() = %+v, want an error for a day without a sunrise", got.Parsed)
+	}
+}
+
+// roundTripFunc stands in for the network, so the cross-check can be watched.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestGetDayLight_CrossCheck(t *testing.T) {
+	requests := 0
+	original := httpClient
+	httpClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		// Half an hour out on every boundary: logged, and never used.
+		body := `{"results":{"sunrise":"2026-08-03T04:20:00+00:00","sunset":"2026-08-03T19:00:00+00:00",
+			"civil_twilight_begin":"2026-08-03T03:30:00+00:00","civil_twilight_end":"2026-08-03T19:40:00+00:00"},"status":"OK"}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})}
+	t.Cleanup(func() { httpClient = original; sunriseCrossCheck = false })
+
+	day := time.Date(2026, 8, 3, 12, 0, 0, 0, time.UTC)
+	want, err := computeDaylight(testAirport.Latitude, testAirport.Longitude, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, on := range []bool{false, true} {
+		sunriseCrossCheck = on
+		requests = 0
+		got, err := getDayLight(context.Background(), testAirport.LatString(), testAirport.LonString(), day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Parsed != want.Parsed {
+			t.Errorf("cross-check %v: window = %+v, want the computed one", on, got.Parsed)
+		}
+		if wantRequests := map[bool]int{false: 0, true: 1}[on]; requests != wantRequests {
+			t.Errorf("cross-check %v: %d requests to sunrise-sunset.org, want %d", on, requests, wantRequests)
+		}
+	}
+
+	if _, err := getDayLight(context.Background(), "north", testAirport.LonString(), day); err == nil {
+		t.Error("getDayLight() accepted an unparseable latitude")
+	}
+}
+
+func TestSunriseCrossCheckFromEnv(t *testing.T) {
+	for raw, want := range map[string]bool{"": false, "true": true, "1": true, "false": false} {
+		t.Setenv(sunriseCrossCheckEnv, raw)
+		if got, err := sunriseCrossCheckFromEnv(); err != nil || got != want {
+			t.Errorf("%q: got %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	t.Setenv(sunriseCrossCheckEnv, "yes please")
+	if _, err := sunriseCrossCheckFromEnv(); err == nil {
+		t.Error("accepted a mistyped switch")
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"fmt"
+	"time"
+)
+
+// The run-to-run trend: what every archived model run said about one hour.
+//
+// Planning Saturday on a Wednesday is a question about convergence rather than about any
+// one forecast. Five runs that agree on a low cloud base are a different answer from five
+// that swing between FL15 and FL60, even when the latest of each says the same thing. The
+// archive already holds every run, so the trend is read straight from it: one point per
+// record that covers the hour, oldest run first, each re-scored against the table loaded
+// now so that a retune does not show up as a swing in the weather.
+
+// TrendPoint is one model run's forecast for the hour.
+type TrendPoint struct {
+	// InitializedAt is the newest run in the set -- the one the point is plotted at.
+	InitializedAt time.Time  `json:"initialized_at"`
+	ModelRuns     []ModelRun `json:"model_runs"`
+	GeneratedAt   time.Time  `json:"generated_at"`
+
+	Probability     int          `json:"probability"`
+	VisibilityKnown bool         `json:"visibility_known"`
+	Penalties       []VfrPenalty `json:"penalties,omitempty"`
+
+	CloudBaseFL    *int     `json:"cloud_base_fl"`
+	VisibilityKM   *float64 `json:"visibility_km"`
+	WindSpeed      float64  `json:"wind_speed"`
+	WindGusts      float64  `json:"wind_gusts"`
+	Crosswind      float64  `json:"crosswind"`
+	CrosswindGusts float64  `json:"crosswind_gusts"`
+}
+
+// TrendResponse is what /api/trend serves.
+type TrendResponse struct {
+	Airport string       `json:"airport"`
+	Profile string       `json:"profile"`
+	Hour    time.Time    `json:"hour"`
+	Points  []TrendPoint `json:"points"`
+	// ProbabilitySpread is the highest score any run gave the hour less the lowest, over
+	// the scored points only. The single number for "the runs disagree".
+	ProbabilitySpread int `json:"probability_spread"`
+}
+
+// parseTrendHour reads the hour parameter, in the payload's own "2006-01-02T15:04" notation
+// and UTC. Only whole hours are accepted: the forecast has nothing in between.
+func parseTrendHour(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, fmt.Errorf("hour is required")
+	}
+	hour, err := hourTime(raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("hour must look like 2026-10-24T12:00")
+	}
+	if hour.Minute() != 0 {
+		return time.Time{}, fmt.Errorf("hour must be a whole hour")
+	}
+	return hour, nil
+}
+
+// trendFor picks hour out of every record, oldest run first. Records that do not reach the
+// hour -- built before it came into range, or after it had passed -- are left out. Only
+// the one hour of each record is restored and scored: the endpoint is anonymous, and
+// re-scoring every hour of every archived run to read one of them is a lot of work to
+// hand anyone who asks.
+func trendFor(records []archivedPayload, profile *scoringProfile, hour time.Time) []TrendPoint {
+	key := hour.UTC().Format("2006-01-02T15:04")
+
+	points := []TrendPoint{}
+	for _, record := range records {
+		vfr, c, ok := record.restoreHour(profile, key)
+		if !ok {
+			continue
+		}
+
+		point := TrendPoint{
+			ModelRuns:       record.ModelRuns,
+			GeneratedAt:     record.GeneratedAt,
+			Probability:     vfr.Probability,
+			VisibilityKnown: vfr.VisibilityKnown,
+			Penalties:       vfr.Penalties,
+		}
+		for _, run := range record.ModelRuns {
+			if run.InitializedAt.After(point.InitializedAt) {
+				point.InitializedAt = run.InitializedAt
+			}
+		}
+		if !c.time.IsZero() {
+			point.CloudBaseFL, point.VisibilityKM = c.cloudBaseFL, c.visibilityKM
+			point.WindSpeed, point.Crosswind, point.CrosswindGusts = c.windSpeed, c.crosswind, c.crosswindGusts
+		}
+		if i := vfrIndex(record.Payload, key); i < len(record.Payload.WindData) && record.Payload.WindData[i].Time == key {
+			point.WindGusts = record.Payload.WindData[i].WindGusts10m
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// vfrIndex is the index of the hour in data's VfrData, or -1.
+func vfrIndex(data *ProcessedWeatherData, key string) int {
+	for i, point := range data.VfrData {
+		if point.Time == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// probabilitySpread is the range of the scored points' probabilities; unscored ones (-1)
+// do not count.
+func probabilitySpread(points []TrendPoint) int {
+	lowest, highest := -1, -1
+	for _, point := range points {
+		if point.Probability < 0 {
+			continue
+		}
+		if lowest < 0 || point.Probability < lowest {
+			lowest = point.Probability
+		}
+		highest = max(highest, point.Probability)
+	}
+	if lowest < 0 {
+		return 0
+	}
+	return highest - lowest
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestGetTrend(t *testing.T) {
+	withTestAirports(t)
+	withArchive(t, 400*24*time.Hour)
+
+	// Three runs on the nowcastPayload hours, each with a different cloud base for them.
+	first := time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC)
+	for i, fl := range []int{30, 8, 25} {
+		runs := runsAt(first.Add(time.Duration(i) * 3 * time.Hour))
+		if err := archive.store(testAirport, defaultProfile(), archivePayload(t, runs, ptrTo(fl))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	getTrend(rec, httptest.NewRequest(http.MethodGet, "/api/trend?airport=EDWN&hour=2026-08-03T12:00", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var got TrendResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(got.Points) != 3 {
+		t.Fatalf("got %d points, want one per run", len(got.Points))
+	}
+	for i, want := range []int{30, 8, 25} {
+		point := got.Points[i]
+		if !point.InitializedAt.Equal(first.Add(time.Duration(i) * 3 * time.Hour)) {
+			t.Errorf("point %d initialized at %v, want oldest run first", i, point.InitializedAt)
+		}
+		if point.CloudBaseFL == nil || *point.CloudBaseFL != want {
+			t.Errorf("point %d cloud base = %v, want FL%d", i, point.CloudBaseFL, want)
+		}
+	}
+	if got.Points[1].Probability >= got.Points[0].Probability || len(got.Points[1].Penalties) == 0 {
+		t.Errorf("the FL8 run scored %d against %d, want it lower and explained", got.Points[1].Probability, got.Points[0].Probability)
+	}
+	if want := got.Points[0].Probability - got.Points[1].Probability; got.ProbabilitySpread != want {
+		t.Errorf("spread = %d, want %d", got.ProbabilitySpread, want)
+	}
+
+	// An hour no run covered is an empty trend, not an error.
+	rec = httptest.NewRecorder()
+	getTrend(rec, httptest.NewRequest(http.MethodGet, "/api/trend?airport=EDWN&hour=2026-08-05T12:00", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK || len(got.Points) != 0 {
+		t.Errorf("uncovered hour = %d, %+v", rec.Code, got)
+	}
+
+	for _, query := range []string{
+		"?airport=EDXX&hour=2026-08-03T12:00",
+		"?airport=EDWN",
+		"?airport=EDWN&hour=2026-08-03T12:30",
+		"?airport=EDWN&hour=saturday",
+		"?airport=EDWN&hour=2026-08-03T12:00&profile=glider",
+	} {
+		rec := httptest.NewRecorder()
+		getTrend(rec, httptest.NewRequest(http.MethodGet, "/api/trend"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestGetTrend_WithoutAnArchive(t *testing.T) {
+	withTestAirports(t)
+
+	rec := httptest.NewRecorder()
+	getTrend(rec, httptest.NewRequest(http.MethodGet, "/api/trend?airport=EDWN&hour=2026-08-03T12:00", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503 with no archive to read", rec.Code)
+	}
+}
+
+// The trend restores one hour of each record; it must be the hour restore would give, and
+// leave the rest of the record as it was read.
+func TestRestoreHour_MatchesRestore(t *testing.T) {
+	withTestAirports(t)
+	withArchive(t, 400*24*time.Hour)
+	runs := runsAt(time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC))
+	if err := archive.store(testAirport, defaultProfile(), archivePayload(t, runs, ptrTo(8))); err != nil {
+		t.Fatal(err)
+	}
+	records, err := archive.history(testAirport)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("history() = %d records, %v", len(records), err)
+	}
+	record := records[0]
+	stored := append([]VfrPoint(nil), record.Payload.VfrData...)
+
+	full := record.restore(defaultProfile())
+	for i, want := range full.VfrData {
+		got, c, ok := record.restoreHour(defaultProfile(), want.Time)
+		if !ok {
+			t.Fatalf("restoreHour(%s) found nothing", want.Time)
+		}
+		if got.Probability != want.Probability || got.VisibilityKnown != want.VisibilityKnown || len(got.Penalties) != len(want.Penalties) {
+			t.Errorf("%s: restoreHour = %+v, restore = %+v", want.Time, got, want)
+		}
+		if !c.time.Equal(full.modelConditions[i].time) || (c.takeoff == nil) != (full.modelConditions[i].takeoff == nil) || c.runwaySurface != full.modelConditions[i].runwaySurface {
+			t.Errorf("%s: conditions = %+v, want %+v", want.Time, c, full.modelConditions[i])
+		}
+	}
+	for i := range stored {
+		if record.Payload.VfrData[i].Probability != stored[i].Probability {
+			t.Fatalf("restoreHour changed the record's hour %s", stored[i].Time)
+		}
+	}
+
+	if _, _, ok := record.restoreHour(defaultProfile(), "2026-08-20T12:00"); ok {
+		t.Error("restoreHour found an hour the record does not cover")
+	}
+}
+
+// The trend reads only the records that cover its hour, through an index that follows the
+// archive's writes.
+func TestHistoryCovering(t *testing.T) {
+	withTestAirports(t)
+	withArchive(t, 400*24*time.Hour)
+	first := time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC)
+
+	// 10:00-15:00, and 13:00-15:00.
+	if err := archive.store(testAirport, defaultProfile(), archivePayload(t, runsAt(first), ptrTo(8))); err != nil {
+		t.Fatal(err)
+	}
+	later := archivePayload(t, runsAt(first.Add(3*time.Hour)), ptrTo(8))
+	later.VfrData, later.modelConditions = later.VfrData[3:], later.modelConditions[3:]
+	if err := archive.store(testAirport, defaultProfile(), later); err != nil {
+		t.Fatal(err)
+	}
+
+	covering := func(hour string) int {
+		t.Helper()
+		records, err := archive.historyCovering(testAirport, hour)
+		if err != nil {
+			t.Fatalf("historyCovering(%s) = %v", hour, err)
+		}
+		return len(records)
+	}
+	for hour, want := range map[string]int{"2026-08-03T11:00": 1, "2026-08-03T14:00": 2, "2026-08-03T16:00": 0} {
+		if got := covering(hour); got != want {
+			t.Errorf("historyCovering(%s) = %d records, want %d", hour, got, want)
+		}
+	}
+
+	// A record written after the index was built is in it.
+	if err := archive.store(testAirport, defaultProfile(), archivePayload(t, runsAt(first.Add(6*time.Hour)), ptrTo(8))); err != nil {
+		t.Fatal(err)
+	}
+	if got := covering("2026-08-03T11:00"); got != 2 {
+		t.Errorf("after a write: %d records cover 11:00, want 2", got)
+	}
+
+	// One pruned from under it is not.
+	err := archive.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(archiveBucket(testAirport)).Delete([]byte(runSetKey(runsAt(first), time.Time{})))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := covering("2026-08-03T11:00"); got != 1 {
+		t.Errorf("after a prune: %d records cover 11:00, want 1", got)
+	}
+}
@@ -0,0 +1,516 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Verification: how well the forecasts did, measured against what was then observed.
+//
+// The precipitation anchors were tuned from ERA5, the rest of the table from experience,
+// and nothing so far has checked either against the field. The archive has both halves of
+// the check: every run's forecast for every hour, and every METAR of the station the
+// airfield is matched to. Pairing them answers two questions.
+//
+// How far off the inputs were, per factor and by lead time -- forecast minus observed, so
+// a negative cloud base bias is a model that puts the ceiling too low. And whether the
+// score means what it says: of the hours forecast at 70-80, how many turned out flyable.
+// That is the reliability table; a well-calibrated score has its observed frequency inside
+// its bin.
+//
+// "Flyable" is the observation scored through the same profile: the METAR's ceiling,
+// visibility and wind in place of the model's, reaching verificationFlyableScore. The
+// model's precipitation is left out of that score -- a METAR reports rain, not how much of
+// it fell -- so rain counts through what it did to the ceiling and the visibility.
+//
+// Night hours are left out. They score 0 whatever the weather, and would fill the lowest
+// bin with agreement that says nothing.
+const (
+	// verificationFlyableScore is the observed score an hour needs to count as flyable:
+	// where the bars leave orange for yellow -- marginal, but flown.
+	verificationFlyableScore = 60
+
+	// verificationMatchWindow is how far from the hour a METAR may be observed and still
+	// stand for it. Routine reports are at :20 and :50, so every hour has two.
+	verificationMatchWindow = 30 * time.Minute
+
+	// verificationVisibilityCapKM is where visibility stops being compared: a METAR says
+	// 9999 for anything from ten kilometres up.
+	verificationVisibilityCapKM = 10.0
+
+	// verificationCacheTTL is how long a report is reused. The archive grows by a run
+	// every few hours and a METAR every half hour; an hour-old report is as good.
+	verificationCacheTTL = time.Hour
+)
+
+// verificationLeads are the lead-time bins, in hours from the run's initialization. The
+// last is open-ended.
+var verificationLeads = []int{0, 6, 12, 24, 48, 72}
+
+// VerificationReport is what /api/verification serves and the verify subcommand prints.
+type VerificationReport struct {
+	Airport string    `json:"airport"`
+	Profile string    `json:"profile"`
+	Station string    `json:"station"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	// FlyableScore is the observed score an hour needed to count as flyable.
+	FlyableScore int `json:"flyable_score"`
+	// Pairs is how many forecast hours had an observation to be checked against. One
+	// hour forecast by eight runs is eight pairs, each at its own lead time.
+	Pairs       int                `json:"pairs"`
+	Leads       []LeadVerification `json:"leads"`
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
+// LeadVerification is one lead-time bin.
+type LeadVerification struct {
+	Lead      string `json:"lead"` // "6-12h"
+	FromHours int    `json:"from_hours"`
+	// ToHours is absent on the last, open-ended bin.
+	ToHours     int              `json:"to_hours,omitempty"`
+	Pairs       int              `json:"pairs"`
+	Errors      []FactorError    `json:"errors"`
+	Reliability []ReliabilityBin `json:"reliability"`
+	// BrierScore is the mean squared difference between the score, read as a probability,
+	// and the outcome: 0 is perfect. Absent when no pair had an outcome.
+	BrierScore *float64 `json:"brier_score,omitempty"`
+}
+
+// FactorError is one input's error, forecast minus observed.
+type FactorError struct {
+	Factor string  `json:"factor"` // "cloud base"
+	Unit   string  `json:"unit"`   // "FL"
+	Count  int     `json:"count"`
+	Bias   float64 `json:"bias"`
+	MAE    float64 `json:"mae"`
+	RMSE   float64 `json:"rmse"`
+}
+
+// ReliabilityBin is one band of forecast scores and how often its hours were flyable.
+type ReliabilityBin struct {
+	From              int     `json:"from"` // 70
+	To                int     `json:"to"`   // 79, or 100 for the top bin
+	Count             int     `json:"count"`
+	MeanForecast      float64 `json:"mean_forecast"`
+	ObservedFrequency float64 `json:"observed_frequency"` // 0-100, like the score
+}
+
+// verifiedFactors are the inputs compared, in the order they are reported.
+var verifiedFactors = []struct{ name, unit string }{
+	{"cloud base", "FL"},
+	{"visibility", "km"},
+	{"wind speed", "kn"},
+	{"crosswind gusts", "kn"},
+	{"temperature", "°C"},
+}
+
+// errorStats accumulates one factor's errors.
+type errorStats struct {
+	n                int
+	sum, abs, square float64
+}
+
+func (s *errorStats) add(forecast, observed float64) {
+	e := forecast - observed
+	s.n++
+	s.sum += e
+	s.abs += math.Abs(e)
+	s.square += e * e
+}
+
+// leadStats accumulates one lead-time bin.
+type leadStats struct {
+	pairs    int
+	errors   map[string]*errorStats
+	bins     [10]struct{ n, flyable, forecast int }
+	outcomes int
+	brier    float64
+}
+
+// verify pairs every record's hours with the observations and builds the report. metars
+// are oldest first. Hours after now, or without a METAR near enough, are skipped.
+func verify(airport Airport, profile *scoringProfile, records []archivedPayload, metars []*Metar, now time.Time) VerificationReport {
+	leads := make([]*leadStats, len(verificationLeads))
+	for i := range leads {
+		leads[i] = &leadStats{errors: make(map[string]*errorStats)}
+	}
+
+	pairs := 0
+	for _, record := range records {
+		data := record.restore(profile)
+		if len(data.modelConditions) != len(data.VfrData) {
+			continue
+		}
+		initialized := record.GeneratedAt
+		for i, run := range record.ModelRuns {
+			if i == 0 || run.InitializedAt.After(initialized) {
+				initialized = run.InitializedAt
+			}
+		}
+
+		for i, c := range data.modelConditions {
+			if c.time.IsZero() || c.daylight == nil || c.time.After(now) || daylightOrdinal(c) == daylightNight {
+				continue
+			}
+			if data.VfrData[i].Probability < 0 {
+				continue
+			}
+			m := nearestMetar(metars, c.time)
+			if m == nil {
+				continue
+			}
+			lead := c.time.Sub(initialized)
+			if lead < 0 {
+				continue
+			}
+			pairs++
+			leads[leadBin(lead)].add(c, data.VfrData[i].Probability, m, airport, profile)
+		}
+	}
+
+	report := VerificationReport{
+		Airport:      airport.Identifier,
+		Profile:      profile.ID,
+		FlyableScore: verificationFlyableScore,
+		Pairs:        pairs,
+		Leads:        make([]LeadVerification, len(leads)),
+		GeneratedAt:  now,
+	}
+	for i, stats := range leads {
+		report.Leads[i] = stats.summary(i)
+	}
+	return report
+}
+
+// add records one pair.
+func (s *leadStats) add(c conditions, probability int, m *Metar, airport Airport, profile *scoringProfile) {
+	s.pairs++
+	observed := observedFrom(m, airport, profile.aircraft)
+	stat := func(name string) *errorStats {
+		if s.errors[name] == nil {
+			s.errors[name] = &errorStats{}
+		}
+		return s.errors[name]
+	}
+
+	// Ceilings are compared as the score sees them: above MSL, which observedFrom has
+	// already raised the METAR's to, and anything from FL50 up, or none at all, as
+	// clearSkyFL. Where both sides are clear there is nothing to compare.
+	if observed.ceilingKnown {
+		f, o := capFL(c.cloudBaseFL), capFL(observed.cloudBaseFL)
+		if f < clearSkyFL || o < clearSkyFL {
+			stat("cloud base").add(float64(f), float64(o))
+		}
+	}
+	if observed.visibilityKM != nil && c.visibilityKM != nil {
+		stat("visibility").add(min(*c.visibilityKM, verificationVisibilityCapKM), min(*observed.visibilityKM, verificationVisibilityCapKM))
+	}
+	if observed.windKnown {
+		stat("wind speed").add(c.windSpeed, observed.windSpeed)
+		stat("crosswind gusts").add(c.crosswindGusts, observed.crosswindGusts)
+	}
+	if m.TemperatureC != nil {
+		stat("temperature").add(c.temperature, *m.TemperatureC)
+	}
+
+	// The outcome needs the whole observation: a METAR without a cloud group would leave
+	// the model's own ceiling to decide it.
+	if !observed.ceilingKnown || observed.visibilityKM == nil || !observed.windKnown {
+		return
+	}
+	oc := blendConditions(c, observed, 1)
+	oc.precipitation, oc.precipitationProbability = 0, 0
+	if m.TemperatureC != nil {
+		oc.temperature = *m.TemperatureC
+	}
+	score, _, _ := scoreVFR(oc, profile.limits)
+
+	bin := &s.bins[min(probability/10, len(s.bins)-1)]
+	bin.n++
+	bin.forecast += probability
+	outcome := 0.0
+	if score >= verificationFlyableScore {
+		bin.flyable++
+		outcome = 1
+	}
+	s.outcomes++
+	s.brier += math.Pow(float64(probability)/100-outcome, 2)
+}
+
+// summary is the bin as reported.
+func (s *leadStats) summary(i int) LeadVerification {
+	lead := LeadVerification{
+		FromHours:   verificationLeads[i],
+		Pairs:       s.pairs,
+		Errors:      []FactorError{},
+		Reliability: []ReliabilityBin{},
+	}
+	if i+1 < len(verificationLeads) {
+		lead.ToHours = verificationLeads[i+1]
+		lead.Lead = fmt.Sprintf("%d-%dh", lead.FromHours, lead.ToHours)
+	} else {
+		lead.Lead = fmt.Sprintf("%dh+", lead.FromHours)
+	}
+
+	for _, f := range verifiedFactors {
+		stats := s.errors[f.name]
+		if stats == nil || stats.n == 0 {
+			continue
+		}
+		n := float64(stats.n)
+		lead.Errors = append(lead.Errors, FactorError{
+			Factor: f.name,
+			Unit:   f.unit,
+			Count:  stats.n,
+			Bias:   round2(stats.sum / n),
+			MAE:    round2(stats.abs / n),
+			RMSE:   round2(math.Sqrt(stats.square / n)),
+		})
+	}
+
+	for b, bin := range s.bins {
+		if bin.n == 0 {
+			continue
+		}
+		to := b*10 + 9
+		if b == len(s.bins)-1 {
+			to = 100
+		}
+		lead.Reliability = append(lead.Reliability, ReliabilityBin{
+			From:              b * 10,
+			To:                to,
+			Count:             bin.n,
+			MeanForecast:      round2(float64(bin.forecast) / float64(bin.n)),
+			ObservedFrequency: round2(100 * float64(bin.flyable) / float64(bin.n)),
+		})
+	}
+	if s.outcomes > 0 {
+		brier := math.Round(s.brier/float64(s.outcomes)*1000) / 1000
+		lead.BrierScore = &brier
+	}
+	return lead
+}
+
+// leadBin is the index of the bin lead falls in.
+func leadBin(lead time.Duration) int {
+	hours := lead.Hours()
+	i := 0
+	for i+1 < len(verificationLeads) && hours >= float64(verificationLeads[i+1]) {
+		i++
+	}
+	return i
+}
+
+// nearestMetar is the observation closest to at, within verificationMatchWindow, or nil.
+func nearestMetar(metars []*Metar, at time.Time) *Metar {
+	i := sort.Search(len(metars), func(i int) bool { return !metars[i].ObservedAt.Before(at) })
+	var best *Metar
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(metars) {
+			continue
+		}
+		gap := metars[j].ObservedAt.Sub(at).Abs()
+		if gap <= verificationMatchWindow && (best == nil || gap < best.ObservedAt.Sub(at).Abs()) {
+			best = metars[j]
+		}
+	}
+	return best
+}
+
+// capFL is a cloud base as the score sees it, with no ceiling and anything above it at
+// clearSkyFL.
+func capFL(fl *int) int {
+	if fl == nil {
+		return clearSkyFL
+	}
+	return min(*fl, clearSkyFL)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// errNoStation is buildVerification's error for an airfield with no reporting station in
+// range: a question that cannot be answered, rather than a failure to answer it.
+var errNoStation = errors.New("no reporting station")
+
+// buildVerification reads the last days of the archive for one airport and profile and
+// verifies them.
+func buildVerification(airport Airport, profile *scoringProfile, days int, now time.Time) (VerificationReport, error) {
+	match, ok := stationFor(airport, metarRadiusKM())
+	if !ok {
+		return VerificationReport{}, fmt.Errorf("%w near %s to verify against", errNoStation, airport.Identifier)
+	}
+
+	records, err := archive.history(airport)
+	if err != nil {
+		return VerificationReport{}, fmt.Errorf("failed to read the forecast archive: %w", err)
+	}
+	from := now.Add(-time.Duration(days) * 24 * time.Hour)
+	kept := records[:0]
+	for _, record := range records {
+		if !record.GeneratedAt.Before(from) {
+			kept = append(kept, record)
+		}
+	}
+
+	metars, err := archive.metars(match.ICAO, from, now)
+	if err != nil {
+		return VerificationReport{}, fmt.Errorf("failed to read the archived METARs: %w", err)
+	}
+
+	report := verify(airport, profile, kept, metars, now)
+	report.Station, report.From, report.To = match.ICAO, from, now
+	return report, nil
+}
+
+// verificationReports memoizes buildVerification, for verificationCacheTTL. A report is
+// built with no lock held, so one airport's does not wait on another's; generation counts
+// the reloads, so a report scored against a table that was replaced meanwhile is not kept.
+var verificationReports = struct {
+	mutex      sync.Mutex
+	entries    map[string]VerificationReport
+	generation uint64
+}{entries: make(map[string]VerificationReport)}
+
+func cachedVerification(airport Airport, profile *scoringProfile, days int, now time.Time) (VerificationReport, error) {
+	key := fmt.Sprintf("%s/%d", cacheKey(airport, profile), days)
+
+	verificationReports.mutex.Lock()
+	report, ok := verificationReports.entries[key]
+	generation := verificationReports.generation
+	verificationReports.mutex.Unlock()
+	if ok && now.Sub(report.GeneratedAt) < verificationCacheTTL {
+		return report, nil
+	}
+
+	report, err := buildVerification(airport, profile, days, now)
+	if err != nil {
+		return VerificationReport{}, err
+	}
+	verificationReports.mutex.Lock()
+	defer verificationReports.mutex.Unlock()
+	if verificationReports.generation == generation {
+		verificationReports.entries[key] = report
+	}
+	return report, nil
+}
+
+// invalidateVerification drops every memoized report, for a reload of the limits: a
+// report's scores are the table's it was built with.
+func invalidateVerification() {
+	verificationReports.mutex.Lock()
+	defer verificationReports.mutex.Unlock()
+
+	verificationReports.generation++
+	clear(verificationReports.entries)
+}
+
+// parseVerificationDays reads the days parameter; empty is defaultArchiveDays.
+func parseVerificationDays(raw string) (int, error) {
+	if raw == "" {
+		return defaultArchiveDays, nil
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 1 || days > archiveMaxDays {
+		return 0, fmt.Errorf("days must be a whole number from 1 to %d", archiveMaxDays)
+	}
+	return days, nil
+}
+
+// Verify is the verify subcommand: the same report as /api/verification, read from the
+// archive file directly and printed as tables, or as JSON with -json.
+//
+// bbolt gives the running server the file to itself, so this is for a copy of the archive
+// or a stopped server. Against a running one, ask /api/verification.
+func Verify(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	airportID := flags.String("airport", "", "airfield to verify (default: the default airport)")
+	profileID := flags.String("profile", "", "scoring profile (default: "+defaultProfileID+")")
+	daysRaw := flags.String("days", "", fmt.Sprintf("how many days back to verify (default %d)", defaultArchiveDays))
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	setupLogging()
+	if err := loadAirports(); err != nil {
+		return fmt.Errorf("failed to load airports: %w", err)
+	}
+	if path := os.Getenv(limitsFileEnv); path != "" {
+		if err := reloadLimitsFile(path); err != nil {
+			return fmt.Errorf("failed to load VFR limits: %w", err)
+		}
+	}
+	airport, err := lookupAirport(*airportID)
+	if err != nil {
+		return err
+	}
+	profile, err := lookupProfile(*profileID)
+	if err != nil {
+		return err
+	}
+	days, err := parseVerificationDays(*daysRaw)
+	if err != nil {
+		return err
+	}
+
+	if err := archive.openReadOnly(os.Getenv(archiveFileEnv)); err != nil {
+		return err
+	}
+	defer archive.close()
+
+	report, err := buildVerification(airport, profile, days, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return printVerification(out, report)
+}
+
+// printVerification writes the report as one block of tables per lead-time bin.
+func printVerification(out io.Writer, report VerificationReport) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s (%s) against %s, %s to %s: %d pairs\n",
+		report.Airport, report.Profile, report.Station,
+		report.From.Format("2006-01-02"), report.To.Format("2006-01-02"), report.Pairs)
+
+	for _, lead := range report.Leads {
+		if lead.Pairs == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\nlead %s: %d pairs", lead.Lead, lead.Pairs)
+		if lead.BrierScore != nil {
+			fmt.Fprintf(w, ", Brier %.3f", *lead.BrierScore)
+		}
+		fmt.Fprintln(w)
+
+		fmt.Fprintln(w, "  factor\tunit\tn\tbias\tMAE\tRMSE")
+		for _, e := range lead.Errors {
+			fmt.Fprintf(w, "  %s\t%s\t%d\t%+.2f\t%.2f\t%.2f\n", e.Factor, e.Unit, e.Count, e.Bias, e.MAE, e.RMSE)
+		}
+		fmt.Fprintln(w, "  score\tn\tmean forecast\tobserved flyable %")
+		for _, bin := range lead.Reliability {
+			fmt.Fprintf(w, "  %d-%d\t%d\t%.1f\t%.1f\n", bin.From, bin.To, bin.Count, bin.MeanForecast, bin.ObservedFrequency)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verificationMetars are EHTW reports at ten to each hour from 09:50 to 14:50 on the
+// nowcastPayload day, each with a 2000ft ceiling -- FL21 over Twente's 115ft -- missing the
+// 12:50 one.
+func verificationMetars(t *testing.T) []*Metar {
+	t.Helper()
+
+	var metars []*Metar
+	for hour := 9; hour <= 14; hour++ {
+		if hour == 12 {
+			continue
+		}
+		raw := "METAR EHTW 03" + time.Date(2026, 8, 3, hour, 50, 0, 0, time.UTC).Format("1504") + "Z 24006KT 9999 OVC020 16/12 Q1015"
+		m, err := parseMETAR(raw, nowcastNow)
+		if err != nil {
+			t.Fatalf("parseMETAR(%q) = %v", raw, err)
+		}
+		metars = append(metars, m)
+	}
+	return metars
+}
+
+func TestVerify(t *testing.T) {
+	withTestAirports(t)
+
+	// One run at 06:00 putting the ceiling at FL12 for 10:00-15:00, checked at 14:30.
+	data := archivePayload(t, runsAt(time.Date(2026, 8, 3, 6, 0, 0, 0, time.UTC)), ptrTo(12))
+	records := []archivedPayload{newArchivedPayload(testAirport, defaultProfile(), data)}
+	now := time.Date(2026, 8, 3, 14, 30, 0, 0, time.UTC)
+
+	report := verify(testAirport, defaultProfile(), records, verificationMetars(t), now)
+
+	// 13:00 has no report within half an hour, and 15:00 is still to come.
+	if report.Pairs != 4 {
+		t.Fatalf("pairs = %d, want 10, 11, 12 and 14", report.Pairs)
+	}
+	if short, long := report.Leads[0], report.Leads[1]; short.Lead != "0-6h" || short.Pairs != 2 || long.Lead != "6-12h" || long.Pairs != 2 {
+		t.Fatalf("leads = %+v, want two pairs at 4-5h and two at 6-8h", report.Leads[:2])
+	}
+	if last := report.Leads[len(report.Leads)-1]; last.Lead != "72h+" || last.ToHours != 0 {
+		t.Errorf("last lead = %+v, want it open-ended", last)
+	}
+
+	errs := make(map[string]FactorError)
+	for _, e := range report.Leads[0].Errors {
+		errs[e.Factor] = e
+	}
+	for factor, want := range map[string]float64{"cloud base": -9, "visibility": 0, "wind speed": -6, "temperature": 2} {
+		if e, ok := errs[factor]; !ok || e.Count != 2 || e.Bias != want || e.MAE != max(want, -want) {
+			t.Errorf("%s error = %+v, want a bias of %v over 2 pairs", factor, e, want)
+		}
+	}
+
+	// Forecast at FL12, observed at FL21: whatever the forecast bin, both pairs went into
+	// it, and the observation decides the frequency.
+	observed := scoringConditions(t)
+	observed.cloudBaseFL, observed.windSpeed, observed.temperature = ptrTo(21), 6, 16
+	observed.crosswind = testAirport.crosswindComponent(6, 240)
+	observed.crosswindGusts = observed.crosswind
+	observedScore, _, _ := scoreVFR(observed, defaultProfile().limits)
+	wantFrequency := 0.0
+	if observedScore >= verificationFlyableScore {
+		wantFrequency = 100
+	}
+	reliability := report.Leads[0].Reliability
+	if len(reliability) != 1 || reliability[0].Count != 2 || reliability[0].ObservedFrequency != wantFrequency {
+		t.Errorf("reliability = %+v, want both pairs in one bin at %v%% flyable", reliability, wantFrequency)
+	}
+	if forecast := data.VfrData[0].Probability; reliability[0].From > forecast || reliability[0].To < forecast {
+		t.Errorf("bin %d-%d does not hold the forecast score %d", reliability[0].From, reliability[0].To, forecast)
+	}
+	if report.Leads[0].BrierScore == nil {
+		t.Error("no Brier score with two outcomes")
+	}
+
+	var out bytes.Buffer
+	if err := printVerification(&out, report); err != nil || !strings.Contains(out.String(), "lead 6-12h: 2 pairs") {
+		t.Errorf("printed report = %q, %v", out.String(), err)
+	}
+}
+
+// An airfield at 1300ft reporting for itself: a model base at FL25 and an observed
+// ceiling of 1200ft are the same cloud, and neither the error nor the outcome may say
+// otherwise.
+func TestVerify_RaisedAirfield(t *testing.T) {
+	withTestAirports(t)
+	raised := Airport{Identifier: "EDXR", Latitude: testAirport.Latitude, Longitude: testAirport.Longitude, ElevationFt: ptrFloat(1300)}
+
+	data := archivePayload(t, runsAt(time.Date(2026, 8, 3, 6, 0, 0, 0, time.UTC)), ptrTo(25))
+	records := []archivedPayload{newArchivedPayload(raised, defaultProfile(), data)}
+	var metars []*Metar
+	for _, raw := range []string{
+		"METAR EDXR 030950Z 24006KT 9999 OVC012 18/12 Q1015",
+		"METAR EDXR 031050Z 24006KT 9999 OVC012 18/12 Q1015",
+	} {
+		m, err := parseMETAR(raw, nowcastNow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		metars = append(metars, m)
+	}
+
+	report := verify(raised, defaultProfile(), records, metars, time.Date(2026, 8, 3, 11, 30, 0, 0, time.UTC))
+	if report.Pairs != 2 {
+		t.Fatalf("pairs = %d, want 2", report.Pairs)
+	}
+	for _, e := range report.Leads[0].Errors {
+		if e.Factor == "cloud base" && (e.Count != 2 || e.Bias != 0) {
+			t.Errorf("cloud base error = %+v, want no bias at all", e)
+		}
+	}
+	observed := scoringConditions(t)
+	observed.cloudBaseFL, observed.windSpeed, observed.temperature = ptrTo(25), 6, 18
+	observed.crosswind, observed.crosswindGusts = 0, 0
+	observedScore, _, _ := scoreVFR(observed, defaultProfile().limits)
+	wantFrequency := 0.0
+	if observedScore >= verificationFlyableScore {
+		wantFrequency = 100
+	}
+	reliability := report.Leads[0].Reliability
+	if len(reliability) != 1 || reliability[0].ObservedFrequency != wantFrequency {
+		t.Errorf("reliability = %+v, want the outcome of FL25 (%d): %v%%", reliability, observedScore, wantFrequency)
+	}
+}
+
+func TestNearestMetar(t *testing.T) {
+	metars := verificationMetars(t)
+	for _, tc := range []struct {
+		at   time.Time
+		want string // HHMM of the report, or "" for none
+	}{
+		{time.Date(2026, 8, 3, 10, 0, 0, 0, time.UTC), "0950"},
+		{time.Date(2026, 8, 3, 10, 25, 0, 0, time.UTC), "1050"},
+		{time.Date(2026, 8, 3, 13, 0, 0, 0, time.UTC), ""},
+		{time.Date(2026, 8, 3, 9, 0, 0, 0, time.UTC), ""},
+		{time.Date(2026, 8, 3, 15, 20, 0, 0, time.UTC), "1450"},
+	} {
+		got := nearestMetar(metars, tc.at)
+		switch {
+		case tc.want == "" && got != nil:
+			t.Errorf("%v: got the %s report, want none", tc.at, got.ObservedAt.Format("1504"))
+		case tc.want != "" && (got == nil || got.ObservedAt.Format("1504") != tc.want):
+			t.Errorf("%v: got %v, want the %s report", tc.at, got, tc.want)
+		}
+	}
+}
+
+func TestArchive_KeepsTheMetars(t *testing.T) {
+	withArchive(t, 30*24*time.Hour)
+	now := time.Now().UTC().Truncate(time.Hour)
+
+	for _, at := range []time.Time{now.Add(-90 * time.Minute), now.Add(-60 * time.Minute), now.Add(-90 * time.Minute)} {
+		if err := archive.storeMetars(map[string]*Metar{"EHTW": {Station: "EHTW", ObservedAt: at}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	metars, err := archive.metars("EHTW", now.Add(-2*time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metars) != 2 || !metars[0].ObservedAt.Equal(now.Add(-90*time.Minute)) {
+		t.Errorf("got %d METARs, want the two observations once each, oldest first", len(metars))
+	}
+	if metars, _ := archive.metars("EHTW", now.Add(-75*time.Minute), now); len(metars) != 1 {
+		t.Errorf("got %d METARs since 75 minutes ago, want 1", len(metars))
+	}
+}
+
+func TestGetVerification(t *testing.T) {
+	withTestAirports(t)
+	t.Cleanup(func() {
+		verificationReports.mutex.Lock()
+		clear(verificationReports.entries)
+		verificationReports.mutex.Unlock()
+	})
+
+	rec := httptest.NewRecorder()
+	getVerification(rec, httptest.NewRequest(http.MethodGet, "/api/verification?airport=EDWN", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d without an archive, want 503", rec.Code)
+	}
+
+	withArchive(t, 30*24*time.Hour)
+	rec = httptest.NewRecorder()
+	getVerification(rec, httptest.NewRequest(http.MethodGet, "/api/verification?airport=EDWN", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"station":"EHTW"`) {
+		t.Errorf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	// The Azores: listed, and nowhere near a polled station.
+	airportsByID["LPAZ"] = Airport{Identifier: "LPAZ", Latitude: 36.97, Longitude: -25.17}
+	for _, query := range []string{"?airport=EDXX", "?airport=LPAZ", "?profile=glider", "?days=0", "?days=401", "?days=week"} {
+		rec := httptest.NewRecorder()
+		getVerification(rec, httptest.NewRequest(http.MethodGet, "/api/verification"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
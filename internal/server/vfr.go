@@ -18,7 +18,8 @@ import (
 // and immediately cost the full 5 points.
 //
 // vfrLimits below is the single source of truth for every limit and every penalty.
-// Retuning the score means editing that table and nothing else.
+// Retuning the score means editing that table and nothing else. The scoring profiles in
+// profiles.go retune some of its factors for other pilots, but never add to it.
 
 // severity names the bands of a factor's curve. The order matters: a curve's anchors must
 // be listed in increasing severity, and the evaluator reports the worst one reached.
@@ -105,6 +106,11 @@ type factor struct {
 	// an unlikely forecast is not a decision anyone should ship.
 	wall bool
 
+	// ladder is the severity ladder this factor is scored against. It is set when a table is
+	// resolved from a limits file (see limitsfile.go); nil means severityCost, which is what
+	// every factor written in this file is scored against.
+	ladder map[severity]float64
+
 	// scaledBy, when set, multiplies this factor's cost by how likely its value is to
 	// materialise. The curve stays in the factor's own unit, so its anchors keep reading
 	// as "this much, if it happens", and the scale answers "and how likely is that".
@@ -114,6 +120,14 @@ type factor struct {
 	// is not, and no penalty added to the amount can express that, because the amount
 	// plays no part in what the addition costs.
 	scaledBy *scale
+
+	// source, when set, names the forecast the value was taken from, for the breakdown.
+	// Only the factors a second source can feed have one.
+	source func(c conditions) string
+
+	// detail, when set, says in words what the value is made of, for the breakdown --
+	// "TODR 520m of 600m available" rather than "86.7%".
+	detail func(c conditions) string
 }
 
 // scale modulates a factor's cost by a second quantity.
@@ -170,20 +184,86 @@ func (f factor) weightFor(c conditions) (w float64, v float64, scaled bool) {
 	return f.scaledBy.weight(v), v, true
 }
 
+// sourceOf names the forecast this hour's value came from, or "" when there was only one.
+func (f factor) sourceOf(c conditions) string {
+	if f.source == nil {
+		return ""
+	}
+	return f.source(c)
+}
+
+// detailOf describes this hour's value in words, or "" for a factor whose number says it.
+func (f factor) detailOf(c conditions) string {
+	if f.detail == nil {
+		return ""
+	}
+	return f.detail(c)
+}
+
 // conditions is one hour's input to the scoring.
 type conditions struct {
 	time     time.Time
 	daylight *SunriseSunsetResponse
 
-	cloudBaseFL    *int // flight levels, i.e. feet/100
-	windSpeed      float64
+	cloudBaseFL *int // flight levels, i.e. feet/100
+	// freezingLevelFt is feet above MSL, nil where unknown; baseIcing is the icing risk of
+	// the model's cloud at its base. See icing.go.
+	freezingLevelFt *int
+	baseIcing       icingRisk
+	windSpeed       float64
+	// windGusts and windDirection are the 10m wind's, which the runway in use and the
+	// components on it are worked out from. See runways.go.
+	windGusts      float64
+	windDirection  int
 	crosswind      float64
 	crosswindGusts float64
-	visibilityKM   *float64
+	tailwind       float64 // kn along the runway in use, zero with any headwind
+	// runwayInUse and runwayHeading are the end in use, "" where there is none; sun is
+	// where the sun stands, and cloudCover the total cover that may hide it. See glare.go.
+	runwayInUse   string
+	runwayHeading float64
+	sun           sunSky
+	cloudCover    *int // %
+	// runwaySurface is the surface of the end in use, as airports.json gives it, and ground
+	// what the last two days have left on the airfield, nil without them. See softfield.go.
+	runwaySurface string
+	ground        *groundState
+	// lowLevelShear is kn between the surface and lowLevelShearTopFt, nil without levels to
+	// compare. See shear.go.
+	lowLevelShear      *float64
+	lowLevelShearTopFt int
+	visibilityKM       *float64
 
 	temperature              float64
+	pressureMSL              *float64 // hPa
 	precipitation            float64
 	precipitationProbability int
+
+	// The radiation fog inputs, any of which may be missing. See fog.go.
+	dewPoint         *float64 // C at 2m
+	relativeHumidity *int     // % at 2m
+	cloudCoverMid    *int     // %
+	cloudCoverHigh   *int     // %
+
+	// The convective inputs, any of which may be missing. See convective.go.
+	weatherCode      *int     // WMO 4677
+	cape             *float64 // J/kg
+	liftedIndex      *float64
+	convectiveBaseFt *int // above ground
+
+	// cloudBaseSource and visibilitySource say which forecast the two inputs came from,
+	// where MOSMIX was consulted for the hour: factorSourceModel or factorSourceMosmix.
+	// Empty otherwise. See mosmix.go.
+	cloudBaseSource  string
+	visibilitySource string
+
+	// takeoff is the airfield and aircraft the takeoff distance is worked out for, or nil
+	// where there is none. It is the same for every hour of a payload. See aircraft.go.
+	takeoff *takeoffCase
+
+	// carburetted is whether the profile's aircraft has a carburettor, which enables the
+	// carb icing factor. Like takeoff it is configuration, not forecast. See carbicing.go.
+	carburetted bool
 }
 
 // Daylight is an ordinal rather than a measurement: the twilight boundaries move with the
@@ -214,6 +294,7 @@ var vfrLimits = []factor{
 			}
 			return float64(*c.cloudBaseFL), true
 		},
+		source: func(c conditions) string { return c.cloudBaseSource },
 		curve: []anchor{
 			{perfect, 50},
 			{good, 25},
@@ -239,6 +320,7 @@ var vfrLimits = []factor{
 			}
 			return *c.visibilityKM, true
 		},
+		source: func(c conditions) string { return c.visibilitySource },
 		curve: []anchor{
 			{perfect, 50},
 			{good, 30},
@@ -265,8 +347,8 @@ var vfrLimits = []factor{
 		wall:   true,
 	},
 	{
-		// Crosswind is against the best runway end -- crosswindComponent takes the
-		// minimum over the airport's runway headings.
+		// Crosswind is on the runway end in use -- the usable end with the least of it,
+		// tailwind counted double. See runways.go.
 		name:  "crosswind",
 		unit:  "kn",
 		value: func(c conditions) (float64, bool) { return c.crosswind, true },
@@ -296,6 +378,196 @@ var vfrLimits = []factor{
 		weight: 1.0,
 		wall:   true,
 	},
+	{
+		// Radiation fog the model's visibility has not seen, graded from the dew point
+		// spread, the wind and the sky above -- see fog.go. An ordinal like daylight, and
+		// the breakdown gives the reasons rather than a number.
+		//
+		// No wall: it is an estimate, and the visibility factor ends the hour where the model
+		// has the fog itself. Likely fog is still critical, because when it comes the
+		// morning is gone.
+		name:  "fog",
+		unit:  "",
+		value: func(c conditions) (float64, bool) { return float64(fogRiskOf(c)), true },
+		detail: func(c conditions) string {
+			if fogRiskOf(c) == fogUnlikely {
+				return ""
+			}
+			return fogDetail(c)
+		},
+		curve: []anchor{
+			{perfect, float64(fogUnlikely)},
+			{difficult, float64(fogPossible)},
+			{critical, float64(fogLikely)},
+		},
+		weight: 1.0,
+	},
+	{
+		// Convection, graded from the model's instability and its weather code -- see
+		// convective.go. Like daylight an ordinal, so its anchors are bands rather than
+		// measurements, and the breakdown names the hazard instead of a number.
+		//
+		// Showers are nearly free, and a forecast thunderstorm is the wall: the cell, its
+		// gust front and its hail are no place for a VFR flight whatever the cloud base
+		// under the anvil says. CAPE alone, without the model resolving the storm, stops
+		// short of it at critical, because the trigger may not come.
+		name:  "convection",
+		unit:  "",
+		value: func(c conditions) (float64, bool) { return float64(convectiveRiskOf(c)), true },
+		detail: func(c conditions) string {
+			if convectiveRiskOf(c) == convectionNone {
+				return ""
+			}
+			return convectiveDetail(c)
+		},
+		curve: []anchor{
+			{perfect, float64(convectionNone)},
+			{good, float64(convectionShowers)},
+			{difficult, float64(convectionModerate)},
+			{critical, float64(convectionHigh)},
+		},
+		weight: 1.0,
+		wall:   true,
+	},
+	{
+		// Icing in the cloud a VFR flight cruises under: how far below the cloud base the
+		// freezing level sits, in feet. Above the base there is nothing to charge -- the
+		// cloud is liquid and the air under it is above zero. Below it, an inadvertent
+		// entry, or the shower falling out of it, ices the airframe, and the further down
+		// the freezing level the less room there is to descend out of it. A cloud too cold
+		// or too dry to hold supercooled water skips the factor. See icing.go.
+		//
+		// No wall: flying under icing cloud is legal, and the cloud base factor already ends
+		// the hour where there is no room under it at all.
+		name: "icing",
+		unit: "ft",
+		value: func(c conditions) (float64, bool) {
+			if c.cloudBaseFL == nil || c.freezingLevelFt == nil || c.baseIcing == icingNone {
+				return 0, false
+			}
+			return float64(*c.cloudBaseFL*100 - *c.freezingLevelFt), true
+		},
+		detail: func(c conditions) string { return icingDetail(c) },
+		curve: []anchor{
+			{perfect, 0},
+			{good, 500},
+			{difficult, 1500},
+			{critical, 3000},
+		},
+		weight: 1.0,
+	},
+	{
+		// Carburettor icing, on the CAA chart's bands -- see carbicing.go. Only for a
+		// profile whose aircraft has a carburettor; the rest skip it. An advisory: carb heat
+		// deals with it, so even serious icing at any power stops at difficult, and at half
+		// the weight of a factor that changes whether the flight can go.
+		name:  "carb icing",
+		unit:  "",
+		value: func(c conditions) (float64, bool) { return float64(carbIcingOf(c)), c.carburetted },
+		detail: func(c conditions) string {
+			if carbIcingOf(c) == carbIcingNone {
+				return ""
+			}
+			return carbIcingDetail(c)
+		},
+		curve: []anchor{
+			{perfect, float64(carbIcingNone)},
+			{good, float64(carbIcingSeriousDescent)},
+			{difficult, float64(carbIcingSeriousAnyPower)},
+		},
+		weight: 0.5,
+	},
+	{
+		// Tailwind on the runway end in use, which is only ever not zero at a one-way strip
+		// whose other end is closed, when the wind leaves no better runway to use.
+		// Ten knots is the limit most light-aircraft handbooks publish a figure for at
+		// all, and the takeoff distance has grown by half long before it.
+		name:  "tailwind",
+		unit:  "kn",
+		value: func(c conditions) (float64, bool) { return c.tailwind, true },
+		curve: []anchor{
+			{perfect, 0},
+			{good, 3},
+			{difficult, 6},
+			{critical, 10},
+		},
+		weight: 1.0,
+		wall:   true,
+	},
+	{
+		// A low sun ahead on the runway end in use: the angle between the two, in degrees,
+		// for hours the sun is up but below 15 degrees and not behind an overcast -- see
+		// glare.go. The perfect anchor is the edge of the cone. No wall: a pilot can wait
+		// for the sun to set or circle to the other end, and the daylight factor takes over
+		// from there.
+		name:  "sun glare",
+		unit:  "°",
+		value: func(c conditions) (float64, bool) { return sunGlareOf(c) },
+		detail: func(c conditions) string {
+			if _, ok := sunGlareOf(c); !ok {
+				return ""
+			}
+			return sunGlareDetail(c)
+		},
+		curve: []anchor{
+			{perfect, sunGlareCone},
+			{good, 15},
+			{difficult, 5},
+		},
+		weight: 1.0,
+	},
+	{
+		// Standing water and snow on a grass end in use, as mm of rain -- see softfield.go.
+		// 25mm in two days is a strip closed or marginal whatever the sky does, so it is the
+		// wall, and the anchors below it are a strip getting softer, as it does long before
+		// anyone closes it. The takeoff distance's grass factor is for dry grass.
+		name:  "soft field",
+		unit:  "mm",
+		value: func(c conditions) (float64, bool) { return softFieldOf(c) },
+		detail: func(c conditions) string {
+			if _, ok := softFieldOf(c); !ok {
+				return ""
+			}
+			return softFieldDetail(c)
+		},
+		curve: []anchor{
+			{perfect, 5},
+			{good, 10},
+			{difficult, 15},
+			{critical, 25},
+		},
+		weight: 1.0,
+		wall:   true,
+	},
+	{
+		// Low-level shear: the worst change in the wind between the surface and 3000ft, as a
+		// vector, so a sharp veer counts with a speed change. Ten knots of it is the ordinary
+		// increase with height on a breezy day; 10kt on the ground under 35kt at 1500ft is
+		// 25, and a rough approach with the airspeed jumping about on short final. See
+		// shear.go for why this is knots rather than a rate.
+		name: "low-level shear",
+		unit: "kn",
+		value: func(c conditions) (float64, bool) {
+			if c.lowLevelShear == nil {
+				return 0, false
+			}
+			return *c.lowLevelShear, true
+		},
+		detail: func(c conditions) string {
+			if c.lowLevelShear == nil {
+				return ""
+			}
+			return shearDetail(c)
+		},
+		curve: []anchor{
+			{perfect, 10},
+			{good, 15},
+			{difficult, 20},
+			{critical, 35},
+		},
+		weight: 1.0,
+		wall:   true,
+	},
 	{
 		// The anchors are what the rain is worth if it falls; the scale then asks how
 		// likely that is. No wall, because a no-go multiplied by an unlikely forecast is
@@ -339,12 +611,54 @@ var vfrLimits = []factor{
 		},
 	},
 	{
-		// Density altitude on a short grass strip. The wall is a formality at this
-		// latitude -- EDWN has not reached 38C in three years -- and is kept so the table
-		// has no factor that simply runs off the end.
-		name:  "temperature",
-		unit:  "C",
-		value: func(c conditions) (float64, bool) { return c.temperature, true },
+		// How much of the runway the takeoff needs: the aircraft's book distance corrected
+		// for the hour's density altitude, the runway's surface and the tailwind, against
+		// the runway's length -- on the runway of the end in use. See aircraft.go.
+		//
+		// Difficult at 90% is Safety Sense 7's 1.33 safety factor with a little given
+		// back, because the correction is a rule of thumb in the first place; needing the
+		// whole runway is the wall.
+		name: "takeoff distance",
+		unit: "%",
+		value: func(c conditions) (float64, bool) {
+			run, ok := c.takeoffRun()
+			if !ok {
+				return 0, false
+			}
+			return run.share(), true
+		},
+		detail: func(c conditions) string {
+			run, ok := c.takeoffRun()
+			if !ok {
+				return ""
+			}
+			return fmt.Sprintf("TODR %.0fm of %.0fm available", run.requiredM, run.availableM)
+		},
+		curve: []anchor{
+			{perfect, 60},
+			{good, 75},
+			{difficult, 90},
+			{critical, 100},
+		},
+		weight: 1.0,
+		wall:   true,
+	},
+	{
+		// Density altitude on a short grass strip, where the takeoff distance cannot be
+		// worked out: an airfield without runway lengths, a profile without an aircraft, a
+		// sample along a route. Where it can, the factor above asks the question this one
+		// only stands in for, and charging both would count one hot afternoon twice.
+		//
+		// The wall is a formality at this latitude -- EDWN has not reached 38C in three
+		// years -- and is kept so the table has no factor that simply runs off the end.
+		name: "temperature",
+		unit: "C",
+		value: func(c conditions) (float64, bool) {
+			if _, ok := c.takeoffRun(); ok {
+				return 0, false
+			}
+			return c.temperature, true
+		},
 		curve: []anchor{
 			{perfect, 25},
 			{good, 28},
@@ -384,6 +698,15 @@ func daylightOrdinal(c conditions) float64 {
 	return daylightDay
 }
 
+// costOf is what reaching a severity costs on this factor: the ladder times the weight.
+func (f factor) costOf(s severity) float64 {
+	ladder := f.ladder
+	if ladder == nil {
+		ladder = severityCost
+	}
+	return ladder[s] * f.weight
+}
+
 // worseHigher reports whether the factor gets worse as its value rises. It is inferred
 // from the thresholds rather than declared, so the table cannot disagree with itself.
 func (f factor) worseHigher() bool {
@@ -406,7 +729,7 @@ func (f factor) evaluate(v float64) (cost float64, sev severity, isNoGo bool) {
 		sign = -1.0
 	}
 	at := func(i int) float64 { return sign * f.curve[i].at }
-	costAt := func(i int) float64 { return severityCost[f.curve[i].severity] * f.weight }
+	costAt := func(i int) float64 { return f.costOf(f.curve[i].severity) }
 	n := sign * v
 	last := len(f.curve) - 1
 
@@ -432,7 +755,7 @@ func (f factor) evaluate(v float64) (cost float64, sev severity, isNoGo bool) {
 	return cost, f.curve[i+1].severity, false
 }
 
-// scoreVFR scores one hour against vfrLimits.
+// scoreVFR scores one hour against a table: vfrLimits, or a profile's retuning of it.
 //
 // probability is 0-100, or -1 when the hour could not be scored at all: a nil daylight
 // window means there is no way to tell a CAVOK afternoon from the middle of the night, so
@@ -444,7 +767,7 @@ func (f factor) evaluate(v float64) (cost float64, sev severity, isNoGo bool) {
 //
 // visibilityKnown reports whether the model supplied a visibility for this hour. Those
 // hours are still scored on the remaining factors; the caller presents them as estimates.
-func scoreVFR(c conditions) (probability int, penalties []VfrPenalty, visibilityKnown bool) {
+func scoreVFR(c conditions, limits []factor) (probability int, penalties []VfrPenalty, visibilityKnown bool) {
 	if c.daylight == nil {
 		return -1, nil, false
 	}
@@ -453,7 +776,7 @@ func scoreVFR(c conditions) (probability int, penalties []VfrPenalty, visibility
 	slog.Debug("scoring vfr probability", "hour", c.time.Format(time.RFC822))
 
 	probability = 100
-	for _, f := range vfrLimits {
+	for _, f := range limits {
 		v, ok := f.value(c)
 		if !ok {
 			continue
@@ -462,7 +785,7 @@ func scoreVFR(c conditions) (probability int, penalties []VfrPenalty, visibility
 		raw, sev, isNoGo := f.evaluate(v)
 		if isNoGo {
 			slog.Debug("vfr no-go", "factor", f.name, "value", v, "unit", f.unit)
-			return 0, []VfrPenalty{{Factor: f.name, Value: v, Unit: f.unit, Severity: sev.String(), Cost: noGoPenaltyCost}}, visibilityKnown
+			return 0, []VfrPenalty{{Factor: f.name, Value: v, Unit: f.unit, Severity: sev.String(), Cost: noGoPenaltyCost, Source: f.sourceOf(c), Detail: f.detailOf(c)}}, visibilityKnown
 		}
 
 		// A scale never applies to a no-go -- validate() rejects a factor carrying both --
@@ -474,7 +797,8 @@ func scoreVFR(c conditions) (probability int, penalties []VfrPenalty, visibility
 		}
 
 		penalty := VfrPenalty{
-			Factor: f.name, Value: v, Unit: f.unit, Severity: sev.String(), Cost: cost,
+			Factor: f.name, Value: v, Unit: f.unit, Severity: sev.String(), Cost: cost, Source: f.sourceOf(c),
+			Detail: f.detailOf(c),
 		}
 		if scaled {
 			penalty.Scale = &VfrScale{Name: f.scaledBy.name, Value: scaleValue, Unit: f.scaledBy.unit}
@@ -500,13 +824,20 @@ func scoreVFR(c conditions) (probability int, penalties []VfrPenalty, visibility
 
 // init rejects a malformed vfrLimits at startup, the same way a malformed airports.json
 // is fatal: a curve that runs backwards or repeats a threshold would silently score every
-// hour wrong, and the table is the one place where that mistake is easy to make.
+// hour wrong, and the table is the one place where that mistake is easy to make. Every
+// profile is resolved and held to the same rules here too.
 func init() {
 	for _, f := range vfrLimits {
 		if err := f.validate(); err != nil {
 			panic(fmt.Sprintf("vfrLimits: factor %q: %v", f.name, err))
 		}
 	}
+
+	table, err := buildScoringTable(vfrLimits, nil, profileDefinitions)
+	if err != nil {
+		panic(fmt.Sprintf("scoring profiles: %v", err))
+	}
+	scoring.Store(table)
 }
 
 func (f factor) validate() error {
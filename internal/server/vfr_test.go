@@ -306,7 +306,7 @@ func TestScoreVFR_TheLastLimitScoresRatherThanEndsTheHour(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got, penalties, _ := scoreVFR(tc.with(scoringConditions(t)))
+			got, penalties, _ := scoreVFR(tc.with(scoringConditions(t)), vfrLimits)
 
 			if got != tc.want {
 				t.Errorf("probability = %d, want %d", got, tc.want)
@@ -444,7 +444,7 @@ func TestScoreVFR_Calibration(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got, _, _ := scoreVFR(tc.with(scoringConditions(t)))
+			got, _, _ := scoreVFR(tc.with(scoringConditions(t)), vfrLimits)
 
 			if got != tc.want {
 				t.Errorf("probability = %d, want %d", got, tc.want)
@@ -509,7 +509,7 @@ func TestScoreVFR_NoGos(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			prob, penalties, _ := scoreVFR(tc.with(scoringConditions(t)))
+			prob, penalties, _ := scoreVFR(tc.with(scoringConditions(t)), vfrLimits)
 
 			if prob != 0 {
 				t.Errorf("probability = %d, want 0", prob)
@@ -537,7 +537,7 @@ func TestScoreVFR_UnknownVisibilityStillScores(t *testing.T) {
 	c.visibilityKM = nil
 	c.cloudBaseFL = ptrInt(25)
 
-	prob, _, known := scoreVFR(c)
+	prob, _, known := scoreVFR(c, vfrLimits)
 
 	if known {
 		t.Error("visibilityKnown = true, want false when visibility is nil")
@@ -548,7 +548,7 @@ func TestScoreVFR_UnknownVisibilityStillScores(t *testing.T) {
 }
 
 func TestScoreVFR_KnownVisibility(t *testing.T) {
-	prob, penalties, known := scoreVFR(scoringConditions(t))
+	prob, penalties, known := scoreVFR(scoringConditions(t), vfrLimits)
 
 	if !known {
 		t.Error("visibilityKnown = false, want true when visibility is present")
@@ -568,7 +568,7 @@ func TestScoreVFR_NoDaylightWindow(t *testing.T) {
 	c := scoringConditions(t)
 	c.daylight = nil
 
-	prob, penalties, known := scoreVFR(c)
+	prob, penalties, known := scoreVFR(c, vfrLimits)
 
 	if prob != -1 {
 		t.Errorf("probability = %d, want -1 without a daylight window", prob)
@@ -589,7 +589,7 @@ func TestScoreVFR_BreakdownExplainsTheScore(t *testing.T) {
 	c.windSpeed = 12
 	c.temperature = 32
 
-	prob, penalties, _ := scoreVFR(c)
+	prob, penalties, _ := scoreVFR(c, vfrLimits)
 
 	if len(penalties) != 3 {
 		t.Fatalf("penalties = %+v, want one per factor that cost something", penalties)
@@ -627,7 +627,7 @@ func TestScoreVFR_BreakdownCarriesTheScale(t *testing.T) {
 	c := scoringConditions(t)
 	c.precipitation, c.precipitationProbability = 3.2, 88
 
-	prob, penalties, _ := scoreVFR(c)
+	prob, penalties, _ := scoreVFR(c, vfrLimits)
 
 	if prob != 59 {
 		t.Errorf("probability = %d, want 59", prob)
@@ -655,7 +655,7 @@ func TestScoreVFR_UnscaledPenaltiesCarryNoScale(t *testing.T) {
 	c := scoringConditions(t)
 	c.windSpeed = 12
 
-	_, penalties, _ := scoreVFR(c)
+	_, penalties, _ := scoreVFR(c, vfrLimits)
 
 	if len(penalties) != 1 {
 		t.Fatalf("penalties = %+v, want just the wind one", penalties)
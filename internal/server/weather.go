@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -44,141 +46,81 @@ func getJSON(ctx context.Context, url string) ([]byte, error) {
 	return body, nil
 }
 
-// WeatherAPIResponse represents the complete API response from open-meteo
-type WeatherAPIResponse struct {
-	Hourly struct {
-		Time                     []string   `json:"time"`
-		Temperature2m            []float64  `json:"temperature_2m"`
-		DewPoint2m               []float64  `json:"dew_point_2m"`
-		PrecipitationProbability []int      `json:"precipitation_probability"`
-		CloudCoverLow            []int      `json:"cloud_cover_low"`
-		CloudCover               []int      `json:"cloud_cover"`
-		CloudCoverMid            []int      `json:"cloud_cover_mid"`
-		CloudCoverHigh           []int      `json:"cloud_cover_high"`
-		Precipitation            []float64  `json:"precipitation"`
-		WindSpeed10m             []float64  `json:"wind_speed_10m"`
-		WindDirection10m         []int      `json:"wind_direction_10m"`
-		WindGusts10m             []float64  `json:"wind_gusts_10m"`
-		WindSpeed80m             []float64  `json:"wind_speed_80m"`
-		WindDirection80m         []int      `json:"wind_direction_80m"`
-		Pressure                 []float64  `json:"pressure_msl"`
-		RelativeHumidity2m       []int      `json:"relative_humidity_2m"`
-		Visibility               []*float64 `json:"visibility,omitempty"`
-		WeatherCode              []int      `json:"weather_code,omitempty"`
-
-		// hPa-based wind data
-		WindSpeed1000hPa []float64 `json:"wind_speed_1000hPa"`
-		WindSpeed975hPa  []float64 `json:"wind_speed_975hPa"`
-		WindSpeed950hPa  []float64 `json:"wind_speed_950hPa"`
-		WindSpeed925hPa  []float64 `json:"wind_speed_925hPa"`
-		WindSpeed900hPa  []float64 `json:"wind_speed_900hPa"`
-		WindSpeed850hPa  []float64 `json:"wind_speed_850hPa"`
-		WindSpeed800hPa  []float64 `json:"wind_speed_800hPa"`
-		WindSpeed700hPa  []float64 `json:"wind_speed_700hPa"`
-		WindSpeed600hPa  []float64 `json:"wind_speed_600hPa"`
-
-		WindDirection1000hPa []int `json:"wind_direction_1000hPa"`
-		WindDirection975hPa  []int `json:"wind_direction_975hPa"`
-		WindDirection950hPa  []int `json:"wind_direction_950hPa"`
-		WindDirection925hPa  []int `json:"wind_direction_925hPa"`
-		WindDirection900hPa  []int `json:"wind_direction_900hPa"`
-		WindDirection850hPa  []int `json:"wind_direction_850hPa"`
-		WindDirection800hPa  []int `json:"wind_direction_800hPa"`
-		WindDirection700hPa  []int `json:"wind_direction_700hPa"`
-		WindDirection600hPa  []int `json:"wind_direction_600hPa"`
-
-		// hPa-based cloud cover data
-		CloudCover1000hPa []int `json:"cloud_cover_1000hPa"`
-		CloudCover975hPa  []int `json:"cloud_cover_975hPa"`
-		CloudCover950hPa  []int `json:"cloud_cover_950hPa"`
-		CloudCover925hPa  []int `json:"cloud_cover_925hPa"`
-		CloudCover900hPa  []int `json:"cloud_cover_900hPa"`
-		CloudCover850hPa  []int `json:"cloud_cover_850hPa"`
-		CloudCover800hPa  []int `json:"cloud_cover_800hPa"`
-		CloudCover700hPa  []int `json:"cloud_cover_700hPa"`
-		CloudCover600hPa  []int `json:"cloud_cover_600hPa"`
-		CloudCover500hPa  []int `json:"cloud_cover_500hPa"`
-		CloudCover400hPa  []int `json:"cloud_cover_400hPa"`
-		CloudCover300hPa  []int `json:"cloud_cover_300hPa"`
-		CloudCover250hPa  []int `json:"cloud_cover_250hPa"`
-		CloudCover200hPa  []int `json:"cloud_cover_200hPa"`
-		CloudCover150hPa  []int `json:"cloud_cover_150hPa"`
-		CloudCover100hPa  []int `json:"cloud_cover_100hPa"`
-		CloudCover70hPa   []int `json:"cloud_cover_70hPa"`
-		CloudCover50hPa   []int `json:"cloud_cover_50hPa"`
-		CloudCover30hPa   []int `json:"cloud_cover_30hPa"`
-
-		// Geopotential heights
-		GeopotentialHeight1000hPa []float64 `json:"geopotential_height_1000hPa"`
-		GeopotentialHeight975hPa  []float64 `json:"geopotential_height_975hPa"`
-		GeopotentialHeight950hPa  []float64 `json:"geopotential_height_950hPa"`
-		GeopotentialHeight925hPa  []float64 `json:"geopotential_height_925hPa"`
-		GeopotentialHeight900hPa  []float64 `json:"geopotential_height_900hPa"`
-		GeopotentialHeight850hPa  []float64 `json:"geopotential_height_850hPa"`
-		GeopotentialHeight800hPa  []float64 `json:"geopotential_height_800hPa"`
-		GeopotentialHeight700hPa  []float64 `json:"geopotential_height_700hPa"`
-		GeopotentialHeight600hPa  []float64 `json:"geopotential_height_600hPa"`
-		GeopotentialHeight500hPa  []float64 `json:"geopotential_height_500hPa"`
-		GeopotentialHeight400hPa  []float64 `json:"geopotential_height_400hPa"`
-		GeopotentialHeight300hPa  []float64 `json:"geopotential_height_300hPa"`
-		GeopotentialHeight250hPa  []float64 `json:"geopotential_height_250hPa"`
-		GeopotentialHeight200hPa  []float64 `json:"geopotential_height_200hPa"`
-		GeopotentialHeight150hPa  []float64 `json:"geopotential_height_150hPa"`
-		GeopotentialHeight100hPa  []float64 `json:"geopotential_height_100hPa"`
-		GeopotentialHeight70hPa   []float64 `json:"geopotential_height_70hPa"`
-		GeopotentialHeight50hPa   []float64 `json:"geopotential_height_50hPa"`
-		GeopotentialHeight30hPa   []float64 `json:"geopotential_height_30hPa"`
-	} `json:"hourly"`
-}
-
-// cacheEntry is one airport's cached payload.
+// cacheEntry is one airport's cached payload, as scored against one profile.
 type cacheEntry struct {
 	data      *ProcessedWeatherData
 	timestamp time.Time
 }
 
-// WeatherCache manages cached weather data, one entry per airport identifier.
+// airportForecast is what upstream said about one airport, before any profile scored it:
+// the model's forecast, and for the primary model the station's and the ensemble's where
+// the airfield has them. Every profile's payload is scored from the same one, so asking
+// about an airfield as four pilots costs upstream what asking as one does.
+type airportForecast struct {
+	forecast *hourlyForecast
+	mosmix   *mosmixForecast
+	ensemble *ensembleForecast
+	// runs and fetchedAt are the provenance of every payload scored from it.
+	runs      []ModelRun
+	fetchedAt time.Time
+}
+
+// WeatherCache manages cached weather data: the forecasts fetched, one per airport and
+// model, and the payloads scored from them, one per airport and scoring profile. See
+// forecastKey and cacheKey.
 type WeatherCache struct {
-	entries map[string]*cacheEntry
-	mutex   sync.RWMutex
+	entries   map[string]*cacheEntry
+	forecasts map[string]*airportForecast
+	mutex     sync.RWMutex
 }
 
 var (
 	cache = &WeatherCache{
-		entries: make(map[string]*cacheEntry),
+		entries:   make(map[string]*cacheEntry),
+		forecasts: make(map[string]*airportForecast),
 	}
 	// The backstop, not the schedule. What normally refreshes the forecast is a model
 	// announcing a new run (see modelruns.go); this only decides how long an entry may
 	// live when that mechanism is unavailable, so it is set by how stale a forecast may
 	// quietly get, not by how often the data changes.
 	cacheDuration = time.Hour
-	// apiURLTemplate takes latitude and longitude; everything else about the query is
-	// identical for every airport.
-	//
-	// Every variable here is decoded by WeatherAPIResponse. The query previously also asked
-	// for these, which nothing ever read -- they inflated the upstream response and the
-	// parse for nothing. Kept as a list rather than deleted outright, because re-enabling
-	// one is then a matter of adding it back here and declaring the field:
-	//
-	//   apparent_temperature, convective_cloud_base, rain, showers, snowfall, snow_depth,
-	//   surface_pressure, temperature_80m, temperature_120m, temperature_180m,
-	//   wind_speed_120m, wind_speed_180m, wind_direction_120m, wind_direction_180m
-	//
-	// Note the reverse also holds: a variable removed from this query while its field stays
-	// declared makes TestGoldenFixture_EveryHourlyFieldBinds fail, which is the point.
-	apiURLTemplate = "https://api.open-meteo.com/v1/forecast?latitude=%s&longitude=%s&hourly=precipitation_probability,pressure_msl,cloud_cover_low,cloud_cover,cloud_cover_mid,cloud_cover_high,temperature_2m,relative_humidity_2m,dew_point_2m,precipitation,weather_code,visibility,wind_speed_10m,wind_speed_80m,wind_direction_10m,wind_direction_80m,wind_gusts_10m,cloud_cover_1000hPa,cloud_cover_975hPa,cloud_cover_950hPa,cloud_cover_925hPa,cloud_cover_900hPa,cloud_cover_850hPa,cloud_cover_800hPa,cloud_cover_700hPa,cloud_cover_600hPa,cloud_cover_500hPa,cloud_cover_400hPa,cloud_cover_300hPa,cloud_cover_200hPa,cloud_cover_250hPa,cloud_cover_150hPa,cloud_cover_100hPa,cloud_cover_70hPa,cloud_cover_50hPa,cloud_cover_30hPa,wind_speed_1000hPa,wind_speed_975hPa,wind_speed_950hPa,wind_speed_925hPa,wind_speed_900hPa,wind_speed_850hPa,wind_speed_800hPa,wind_speed_700hPa,wind_speed_600hPa,wind_direction_600hPa,wind_direction_700hPa,wind_direction_800hPa,wind_direction_850hPa,wind_direction_900hPa,wind_direction_925hPa,wind_direction_950hPa,wind_direction_975hPa,wind_direction_1000hPa,geopotential_height_1000hPa,geopotential_height_975hPa,geopotential_height_950hPa,geopotential_height_925hPa,geopotential_height_900hPa,geopotential_height_850hPa,geopotential_height_800hPa,geopotential_height_700hPa,geopotential_height_600hPa,geopotential_height_500hPa,geopotential_height_400hPa,geopotential_height_300hPa,geopotential_height_250hPa,geopotential_height_200hPa,geopotential_height_150hPa,geopotential_height_100hPa,geopotential_height_70hPa,geopotential_height_50hPa,geopotential_height_30hPa&models=icon_seamless&timezone=GMT&wind_speed_unit=kn"
 )
 
-// buildAPIURL returns the Open-Meteo query for one airport.
-func buildAPIURL(airport Airport) string {
-	return fmt.Sprintf(apiURLTemplate, airport.LatString(), airport.LonString())
+// cacheKey identifies one cached payload. The profile is part of it because the score is:
+// two pilots asking about the same airfield get the same weather and different VfrData.
+func cacheKey(airport Airport, profile *scoringProfile) string {
+	return airport.Identifier + "/" + profile.ID
 }
 
-// GetWeatherData returns cached data for the given airport if available and fresh,
-// otherwise fetches new data.
-func GetWeatherData(ctx context.Context, airport Airport) (*ProcessedWeatherData, error) {
+// modelCacheKey is cacheKey for a payload from the given model. The primary model's key is
+// cacheKey's; any other carries the model after an "@", which neither identifiers nor
+// profile IDs contain: see profileIDRe.
+func modelCacheKey(airport Airport, profile *scoringProfile, model forecastModel) string {
+	if model.isPrimary() {
+		return cacheKey(airport, profile)
+	}
+	return cacheKey(airport, profile) + "@" + model.ID
+}
+
+// forecastKey identifies one cached forecast: the airport, and the model after an "@" for
+// any but the primary, as modelCacheKey has it.
+func forecastKey(airport Airport, model forecastModel) string {
+	if model.isPrimary() {
+		return airport.Identifier
+	}
+	return airport.Identifier + "@" + model.ID
+}
+
+// GetWeatherData returns cached data for the given airport and profile if available and
+// fresh, otherwise fetches new data.
+func GetWeatherData(ctx context.Context, airport Airport, profile *scoringProfile) (*ProcessedWeatherData, error) {
+	return getModelWeatherData(ctx, airport, profile, primaryModel())
+}
+
+// getModelWeatherData is GetWeatherData for a payload from the given model.
+func getModelWeatherData(ctx context.Context, airport Airport, profile *scoringProfile, model forecastModel) (*ProcessedWeatherData, error) {
 	cache.mutex.RLock()
-	entry, ok := cache.entries[airport.Identifier]
+	entry, ok := cache.entries[modelCacheKey(airport, profile, model)]
 	cache.mutex.RUnlock()
 
 	if ok && time.Since(entry.timestamp) < cacheDuration {
@@ -186,11 +128,11 @@ func GetWeatherData(ctx context.Context, airport Airport) (*ProcessedWeatherData
 	}
 
 	// Fetch new data
-	return fetchAndCacheWeatherData(ctx, airport)
+	return fetchAndCacheModelWeatherData(ctx, airport, profile, model)
 }
 
-// invalidateAll drops every airport's entry, which is what a new model run calls for: runs
-// are global, so one arriving makes all thirteen stale at the same instant.
+// invalidateAll drops every airport's scored payloads. The forecasts they were scored from
+// stay: a retune changes the score, not the weather, and is no reason to ask upstream again.
 func (c *WeatherCache) invalidateAll() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -198,11 +140,29 @@ func (c *WeatherCache) invalidateAll() {
 	clear(c.entries)
 }
 
-// cachedEntry returns the stored entry for an airport regardless of its age.
-func cachedEntry(identifier string) (*cacheEntry, bool) {
+// invalidateModels drops every airport's forecasts and payloads from the given models, which
+// is what a new model run calls for: runs are global, so one arriving makes all thirteen
+// stale at the same instant -- but only for the model that ran.
+func (c *WeatherCache) invalidateModels(ids []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	fromModels := func(key string) bool {
+		model := primaryModel().ID
+		if _, suffix, ok := strings.Cut(key, "@"); ok {
+			model = suffix
+		}
+		return slices.Contains(ids, model)
+	}
+	maps.DeleteFunc(c.entries, func(key string, _ *cacheEntry) bool { return fromModels(key) })
+	maps.DeleteFunc(c.forecasts, func(key string, _ *airportForecast) bool { return fromModels(key) })
+}
+
+// cachedEntry returns the stored entry for a cache key regardless of its age.
+func cachedEntry(key string) (*cacheEntry, bool) {
 	cache.mutex.RLock()
 	defer cache.mutex.RUnlock()
-	entry, ok := cache.entries[identifier]
+	entry, ok := cache.entries[key]
 	return entry, ok
 }
 
@@ -213,17 +173,34 @@ func cachedEntry(identifier string) (*cacheEntry, bool) {
 // behind one slow Open-Meteo request. The tradeoff is that two simultaneous requests for the
 // same cold airport may both fetch; the double-check below makes the loser discard its
 // result rather than overwrite a fresher entry.
-func fetchAndCacheWeatherData(ctx context.Context, airport Airport) (*ProcessedWeatherData, error) {
-	slog.Info("fetching fresh weather data", "airport", airport.Identifier)
+func fetchAndCacheWeatherData(ctx context.Context, airport Airport, profile *scoringProfile) (*ProcessedWeatherData, error) {
+	return fetchAndCacheModelWeatherData(ctx, airport, profile, primaryModel())
+}
 
-	processedData, err := fetchWeatherFn(ctx, airport)
+// fetchAndCacheModelWeatherData is fetchAndCacheWeatherData for the given model. Only the
+// primary model's payloads are archived: the archive is the history of the forecast the
+// server serves, and what it is replayed for -- the trend and the verification -- is that
+// forecast. And only a configured airfield's: a route's samples are fetched under synthetic
+// "lat,lon" airfields that nothing reads back, each of which would grow a bucket of its own.
+func fetchAndCacheModelWeatherData(ctx context.Context, airport Airport, profile *scoringProfile, model forecastModel) (*ProcessedWeatherData, error) {
+	slog.Info("fetching fresh weather data", "airport", airport.Identifier, "profile", profile.ID, "model", model.ID)
+
+	key := modelCacheKey(airport, profile, model)
+	fetch := fetchWeatherFn
+	if !model.isPrimary() {
+		fetch = func(ctx context.Context, airport Airport, profile *scoringProfile) (*ProcessedWeatherData, error) {
+			return fetchModelWeatherFn(ctx, airport, profile, model)
+		}
+	}
+	processedData, err := fetch(ctx, airport, profile)
 	if err != nil {
 		// Forecast data ages gracefully, so an expired entry beats no data at all when
 		// upstream is unreachable. It is flagged rather than passed off as current: for a
 		// flight-planning tool, silently showing stale weather is the worse failure.
-		if entry, ok := cachedEntry(airport.Identifier); ok {
+		if entry, ok := cachedEntry(key); ok {
 			slog.Warn("serving stale weather data",
 				"airport", airport.Identifier,
+				"profile", profile.ID,
 				"age", time.Since(entry.timestamp).Round(time.Minute),
 				"error", err)
 
@@ -236,20 +213,29 @@ func fetchAndCacheWeatherData(ctx context.Context, airport Airport) (*ProcessedW
 		return nil, err
 	}
 
+	// Archived before the check below and outside the lock. A payload that loses the race
+	// was built from the same runs as the winner, and lands on the same record.
+	if _, configured := airportsByID[airport.Identifier]; configured && model.isPrimary() {
+		if err := archive.store(airport, profile, processedData); err != nil {
+			slog.Warn("failed to archive weather data", "airport", airport.Identifier, "profile", profile.ID, "error", err)
+		}
+	}
+
 	cache.mutex.Lock()
 	defer cache.mutex.Unlock()
 
 	// Another goroutine may have stored a fresher entry while this fetch was in flight.
-	if entry, ok := cache.entries[airport.Identifier]; ok && entry.timestamp.After(processedData.GeneratedAt) {
+	if entry, ok := cache.entries[key]; ok && entry.timestamp.After(processedData.GeneratedAt) {
 		return entry.data, nil
 	}
 
-	cache.entries[airport.Identifier] = &cacheEntry{
+	cache.entries[key] = &cacheEntry{
 		data:      processedData,
 		timestamp: processedData.GeneratedAt,
 	}
 
-	slog.Info("cached weather data", "airport", airport.Identifier, "points", len(processedData.TemperatureData))
+	slog.Info("cached weather data", "airport", airport.Identifier, "profile", profile.ID,
+		"model", model.ID, "points", len(processedData.TemperatureData))
 
 	return processedData, nil
 }
@@ -258,19 +244,82 @@ func fetchAndCacheWeatherData(ctx context.Context, airport Airport) (*ProcessedW
 // getDayLightFn does for the sunrise API.
 var fetchWeatherFn = fetchWeather
 
-// fetchWeather retrieves and processes one airport's forecast without touching the cache.
-func fetchWeather(ctx context.Context, airport Airport) (*ProcessedWeatherData, error) {
-	body, err := getJSON(ctx, buildAPIURL(airport))
+// fetchWeather scores one airport's forecast from the primary model against profile,
+// without touching the payload cache. The forecast itself is the cached one where there
+// is a fresh one: only the score is the profile's.
+func fetchWeather(ctx context.Context, airport Airport, profile *scoringProfile) (*ProcessedWeatherData, error) {
+	return fetchModelWeather(ctx, airport, profile, primaryModel())
+}
+
+// fetchModelWeatherFn indirects fetchModelWeather for the comparison models, as
+// fetchWeatherFn does for the primary one.
+var fetchModelWeatherFn = fetchModelWeather
+
+// fetchModelWeather is fetchWeather for the given model.
+func fetchModelWeather(ctx context.Context, airport Airport, profile *scoringProfile, model forecastModel) (*ProcessedWeatherData, error) {
+	forecast, err := forecastFor(ctx, airport, model)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch weather data: %w", err)
+		return nil, err
 	}
+	return forecast.score(ctx, airport, profile, model), nil
+}
 
-	var apiResponse WeatherAPIResponse
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse API response: %w", err)
+// forecastFor returns the cached forecast for the airport and model if it is fresh, or
+// fetches one. As with the payloads, the fetch runs with no lock held, and of two that
+// race the fresher is kept.
+func forecastFor(ctx context.Context, airport Airport, model forecastModel) (*airportForecast, error) {
+	key := forecastKey(airport, model)
+	cache.mutex.RLock()
+	cached, ok := cache.forecasts[key]
+	cache.mutex.RUnlock()
+	if ok && time.Since(cached.fetchedAt) < cacheDuration {
+		return cached, nil
+	}
+
+	forecast, err := fetchForecast(ctx, airport, model)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cached, ok := cache.forecasts[key]; ok && cached.fetchedAt.After(forecast.fetchedAt) {
+		return cached, nil
+	}
+	cache.forecasts[key] = forecast
+	return forecast, nil
+}
+
+// fetchForecast asks upstream for one airport's forecast from the given model. The station
+// forecast and the ensemble refine the primary model's alone, and are fetched with it.
+func fetchForecast(ctx context.Context, airport Airport, model forecastModel) (*airportForecast, error) {
+	runs := modelRuns.runsFor(model)
+	hourly, err := weatherSource.forecast(ctx, airport, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch weather data: %w", err)
+	}
+	forecast := &airportForecast{forecast: hourly, runs: runs, fetchedAt: time.Now()}
+	if model.isPrimary() {
+		forecast.mosmix = mosmixFor(ctx, airport)
+		forecast.ensemble = ensembleFor(ctx, airport)
 	}
+	return forecast, nil
+}
 
-	return processWeatherData(ctx, &apiResponse, airport), nil
+// score processes the forecast into a payload scored against profile. The runs and the
+// time are the fetch's rather than the scoring's: every profile's payload from one fetch
+// has the same provenance, ages out of the cache with it, and lands on the same archive
+// record.
+func (f *airportForecast) score(ctx context.Context, airport Airport, profile *scoringProfile, model forecastModel) *ProcessedWeatherData {
+	data := processModelWeatherData(ctx, f.forecast, airport, profile, model)
+	data.ModelRuns, data.GeneratedAt = f.runs, f.fetchedAt
+	if f.mosmix != nil {
+		applyMosmix(data, f.mosmix, mosmixSetting, profile)
+	}
+	if f.ensemble != nil {
+		applyEnsemble(data, f.ensemble, airport, profile)
+	}
+	return data
 }
 
 // hourTime parses one of Open-Meteo's naive-UTC hourly timestamps ("2026-08-03T12:00").
@@ -289,7 +338,8 @@ func hourTime(timeStr string) (time.Time, error) {
 //
 // A date that could not be resolved is absent from the map. Callers treat that the same way
 // they treated a failed lookup before: the icon keeps its daytime variant and the hour scores
-// -1, rather than taking the process down with a nil dereference.
+// -1, rather than taking the process down with a nil dereference. Since the window is
+// computed locally (see sun.go), that is left to a date on which the sun does not rise or set.
 func resolveDaylight(ctx context.Context, airport Airport, times []string) map[string]*SunriseSunsetResponse {
 	daylight := make(map[string]*SunriseSunsetResponse)
 
@@ -455,30 +505,48 @@ func isNextDay(a, b string) bool {
 	return parsed.AddDate(0, 0, 1).Format("2006-01-02") == b
 }
 
-// processWeatherData converts API response to frontend-friendly format
-func processWeatherData(ctx context.Context, apiResponse *WeatherAPIResponse, airport Airport) *ProcessedWeatherData {
+// processWeatherData converts a provider's forecast to frontend-friendly format, scoring
+// every hour against the profile's table.
+func processWeatherData(ctx context.Context, forecast *hourlyForecast, airport Airport, profile *scoringProfile) *ProcessedWeatherData {
+	return processModelWeatherData(ctx, forecast, airport, profile, primaryModel())
+}
+
+// processModelWeatherData is processWeatherData for a forecast from the given model, which
+// the payload's runs are that of.
+func processModelWeatherData(ctx context.Context, forecast *hourlyForecast, airport Airport, profile *scoringProfile, model forecastModel) *ProcessedWeatherData {
 	// The runs are stamped here rather than at serve time because they describe *this*
 	// data: they are the provenance of the payload, and a cached entry must keep the runs
 	// it was built from even after newer ones appear. It also keeps the cached payload
 	// immutable, which is what lets it be shared between goroutines without copying.
-	runs, _ := modelRuns.snapshot()
+	runs := modelRuns.runsFor(model)
 
 	processed := &ProcessedWeatherData{
 		TemperatureData: make([]TemperaturePoint, 0),
 		CloudData:       make([]CloudPoint, 0),
 		WindData:        make([]WindPoint, 0),
+		PressureData:    pressureSeries(forecast.hours),
 		GeneratedAt:     time.Now(),
 		ModelRuns:       runs,
 	}
 
 	// One lookup per date, before the loop, rather than two per hour inside it.
-	daylight := resolveDaylight(ctx, airport, apiResponse.Hourly.Time)
-	from, to := forecastWindow(apiResponse.Hourly.Time)
+	times := forecast.times()
+	daylight := resolveDaylight(ctx, airport, times)
+	from, to := forecastWindow(times)
 	processed.NightPeriods = nightIntervals(daylight, from, to)
 	processed.TwilightPeriods = twilightIntervals(daylight, from, to)
+	takeoff := takeoffCaseFor(airport, profile)
+	carburetted := carburettedFor(profile)
+	ground := groundSeries(forecast.history, forecast.hours)
+	var groundFt float64
+	if airport.ElevationFt != nil {
+		groundFt = *airport.ElevationFt
+	}
 
 	// Process temperature and cloud data
-	for i, timeStr := range apiResponse.Hourly.Time {
+	for i, hour := range forecast.hours {
+		timeStr := hour.time
+
 		// The daylight window covering this hour, or nil if the date could not be resolved.
 		var hourDaylight *SunriseSunsetResponse
 		if t, err := hourTime(timeStr); err == nil {
@@ -487,54 +555,85 @@ func processWeatherData(ctx context.Context, apiResponse *WeatherAPIResponse, ai
 
 		// Add temperature data
 		tempPoint := TemperaturePoint{}
-		if i < len(apiResponse.Hourly.Temperature2m) && i < len(apiResponse.Hourly.DewPoint2m) && i < len(apiResponse.Hourly.Precipitation) && i < len(apiResponse.Hourly.PrecipitationProbability) {
+		if hour.temperature != nil && hour.dewPoint != nil && hour.precipitation != nil && hour.precipitationProbability != nil {
 			tempPoint = TemperaturePoint{
 				Time:                     timeStr,
-				Temperature:              apiResponse.Hourly.Temperature2m[i],
-				DewPoint:                 apiResponse.Hourly.DewPoint2m[i],
-				Precipitation:            apiResponse.Hourly.Precipitation[i],
-				PrecipitationProbability: apiResponse.Hourly.PrecipitationProbability[i],
+				Temperature:              *hour.temperature,
+				DewPoint:                 *hour.dewPoint,
+				Precipitation:            *hour.precipitation,
+				PrecipitationProbability: *hour.precipitationProbability,
+			}
+			tempPoint.CarbIcing = int(carbIcingRiskAt(tempPoint.Temperature, relativeHumidityFrom(tempPoint.Temperature, tempPoint.DewPoint)))
+			if g := ground[i]; g != nil {
+				wetness := g.wetness()
+				tempPoint.Precipitation24h, tempPoint.Precipitation48h, tempPoint.GroundWetness = &g.rain24h, &g.rain48h, &wetness
 			}
 			processed.TemperatureData = append(processed.TemperatureData, tempPoint)
 		}
 
 		// Add cloud data - process all hPa levels
-		cloudLayers := processCloudLayers(apiResponse, i)
+		cloudLayers := processCloudLayers(hour)
 
 		// Get visibility data if available
 		var visibility *float64 = nil
-		if i < len(apiResponse.Hourly.Visibility) && apiResponse.Hourly.Visibility[i] != nil {
+		if hour.visibility != nil {
 			// Convert visibility from meters to kilometers
-			v := *apiResponse.Hourly.Visibility[i]
-			v = v / 1000
+			v := *hour.visibility / 1000
 			visibility = &v
 		}
 
 		cloudBase := getCloudBase(cloudLayers)
+		freezingLevel := freezingLevelFeet(hour, groundFt)
+		var convectiveBase *int
+		if hour.convectiveCloudBase != nil {
+			feet := int(*hour.convectiveCloudBase * 3.28084)
+			convectiveBase = &feet
+		}
+		convective := conditions{weatherCode: hour.weatherCode, cape: hour.cape, liftedIndex: hour.liftedIndex}
 		// Always include a CloudPoint with visibility data, even if there are no cloud layers
 		processed.CloudData = append(processed.CloudData, CloudPoint{
-			Time:        timeStr,
-			CloudLayers: cloudLayers,
-			Visibility:  visibility,
-			Base:        cloudBase,
+			Time:               timeStr,
+			CloudLayers:        cloudLayers,
+			Visibility:         visibility,
+			Base:               cloudBase,
+			FreezingLevelFeet:  freezingLevel,
+			ConvectiveRisk:     int(convectiveRiskOf(convective)),
+			ConvectiveBaseFeet: convectiveBase,
 		})
 
 		// Get 10m wind speed, gusts and direction for line chart
 		var windSpeed10m, windGusts10m float64
 		var windDirection10m int
-		if i < len(apiResponse.Hourly.WindSpeed10m) {
-			windSpeed10m = apiResponse.Hourly.WindSpeed10m[i]
+		if hour.windSpeed10m != nil {
+			windSpeed10m = *hour.windSpeed10m
 		}
-		if i < len(apiResponse.Hourly.WindGusts10m) {
-			windGusts10m = apiResponse.Hourly.WindGusts10m[i]
+		if hour.windGusts10m != nil {
+			windGusts10m = *hour.windGusts10m
 		}
-		if i < len(apiResponse.Hourly.WindDirection10m) {
-			windDirection10m = apiResponse.Hourly.WindDirection10m[i]
+		if hour.windDirection10m != nil {
+			windDirection10m = *hour.windDirection10m
 		}
 
-		// Crosswind components for the runway in use
-		crosswind10m := airport.crosswindComponent(windSpeed10m, windDirection10m)
-		crosswindGusts10m := airport.crosswindComponent(windGusts10m, windDirection10m)
+		// Shear and turbulence over every level with a wind, not only the barbs'.
+		winds := shearProfile(hour, groundFt)
+		var lowShear *float64
+		var lowShearTopFt int
+		if low, ok := lowLevelShearOf(winds); ok {
+			lowShear, lowShearTopFt = &low.knots, low.topFeet
+		}
+
+		// The wind on every runway end, and the components on the one in use.
+		onRunways := airport.windOnRunways(windSpeed10m, windGusts10m, windDirection10m, profile.aircraft)
+		crosswind10m, crosswindGusts10m := onRunways.crosswind, onRunways.crosswindGusts
+		inUse, _ := onRunways.endInUse()
+
+		// Where the sun stands, and the ends it is low ahead of. An unparseable timestamp
+		// leaves the sun at the horizon, which glares on nothing.
+		var sun sunSky
+		if t, err := hourTime(timeStr); err == nil {
+			sun = sunSkyAt(t, airport.Latitude, airport.Longitude)
+		}
+		markSunGlare(onRunways.ends, sun, hour.cloudCover)
 
 		// Add wind data - process all levels.
 		// Always emit a WindPoint, even when no level qualified: the 10m speed, gusts
@@ -547,7 +646,12 @@ func processWeatherData(ctx context.Context, apiResponse *WeatherAPIResponse, ai
 			WindGusts10m:      windGusts10m,
 			Crosswind10m:      crosswind10m,
 			CrosswindGusts10m: crosswindGusts10m,
-			WindLayers:        processWindLayers(apiResponse, i),
+			Tailwind10m:       onRunways.tailwind,
+			Runways:           onRunways.ends,
+			WindLayers:        processWindLayers(hour),
+			Shear:             windShear(winds),
+			LowLevelShear:     lowShear,
+			Turbulence:        int(mechanicalTurbulence(winds, windGusts10m)),
 		})
 
 		// Calculate VFR probability
@@ -556,27 +660,61 @@ func processWeatherData(ctx context.Context, apiResponse *WeatherAPIResponse, ai
 		hourStart, timeErr := hourTime(timeStr)
 		vfrProbability, visibilityKnown := -1, false
 		var vfrPenalties []VfrPenalty
+		var hourConditions conditions
 		if timeErr != nil {
 			slog.Error("failed to parse time", "time", timeStr, "error", timeErr)
 		} else {
-			vfrProbability, vfrPenalties, visibilityKnown = scoreVFR(conditions{
+			// The spread is only a spread against the temperature the hour is scored at,
+			// which is 0 where the temperature series has no point.
+			var dewPoint *float64
+			if tempPoint.Time != "" {
+				dewPoint = &tempPoint.DewPoint
+			}
+			hourConditions = conditions{
 				time:                     hourStart,
 				daylight:                 hourDaylight,
 				cloudBaseFL:              cloudBase,
+				freezingLevelFt:          freezingLevel,
+				baseIcing:                baseIcingRisk(cloudLayers),
 				windSpeed:                windSpeed10m,
+				windGusts:                windGusts10m,
+				windDirection:            windDirection10m,
 				crosswind:                crosswind10m,
 				crosswindGusts:           crosswindGusts10m,
+				tailwind:                 onRunways.tailwind,
+				runwayInUse:              inUse.Runway,
+				runwayHeading:            inUse.Heading,
+				sun:                      sun,
+				cloudCover:               hour.cloudCover,
+				runwaySurface:            airport.runwaySurface(inUse.Runway),
+				ground:                   ground[i],
+				lowLevelShear:            lowShear,
+				lowLevelShearTopFt:       lowShearTopFt,
 				visibilityKM:             visibility,
 				temperature:              tempPoint.Temperature,
+				pressureMSL:              hour.pressureMSL,
 				precipitation:            tempPoint.Precipitation,
 				precipitationProbability: tempPoint.PrecipitationProbability,
-			})
+				dewPoint:                 dewPoint,
+				relativeHumidity:         hour.relativeHumidity,
+				cloudCoverMid:            hour.cloudCoverMid,
+				cloudCoverHigh:           hour.cloudCoverHigh,
+				weatherCode:              hour.weatherCode,
+				cape:                     hour.cape,
+				liftedIndex:              hour.liftedIndex,
+				convectiveBaseFt:         convectiveBase,
+				takeoff:                  takeoff,
+				carburetted:              carburetted,
+			}
+			vfrProbability, vfrPenalties, visibilityKnown = scoreVFR(hourConditions, profile.limits)
+			processed.CloudData[len(processed.CloudData)-1].FogRisk = int(fogRiskOf(hourConditions))
 		}
+		processed.modelConditions = append(processed.modelConditions, hourConditions)
 
 		// Get weather code if available
 		processWeatherCode := ""
-		if i < len(apiResponse.Hourly.WeatherCode) {
-			processWeatherCode = strconv.Itoa(apiResponse.Hourly.WeatherCode[i])
+		if hour.weatherCode != nil {
+			processWeatherCode = strconv.Itoa(*hour.weatherCode)
 
 			// is daylight? An unresolved date leaves hourDaylight nil, and the icon simply
 			// keeps its daytime variant rather than taking the process down.
@@ -593,6 +731,7 @@ func processWeatherData(ctx context.Context, apiResponse *WeatherAPIResponse, ai
 			WeatherCode:     processWeatherCode,
 			VisibilityKnown: visibilityKnown,
 			Penalties:       vfrPenalties,
+			Source:          sourceForecast,
 		})
 
 	}
@@ -600,108 +739,74 @@ func processWeatherData(ctx context.Context, apiResponse *WeatherAPIResponse, ai
 	return processed
 }
 
-// processCloudLayers extracts cloud cover data for all hPa levels and converts to layers with heights
-func processCloudLayers(apiResponse *WeatherAPIResponse, timeIndex int) []CloudLayer {
-	// Define pressure levels and their corresponding cloud cover and geopotential height data
-	pressureLevels := []struct {
-		CloudCover []int
-		GeoHeight  []float64
-	}{
-		{apiResponse.Hourly.CloudCover1000hPa, apiResponse.Hourly.GeopotentialHeight1000hPa},
-		{apiResponse.Hourly.CloudCover975hPa, apiResponse.Hourly.GeopotentialHeight975hPa},
-		{apiResponse.Hourly.CloudCover950hPa, apiResponse.Hourly.GeopotentialHeight950hPa},
-		{apiResponse.Hourly.CloudCover925hPa, apiResponse.Hourly.GeopotentialHeight925hPa},
-		{apiResponse.Hourly.CloudCover900hPa, apiResponse.Hourly.GeopotentialHeight900hPa},
-		{apiResponse.Hourly.CloudCover850hPa, apiResponse.Hourly.GeopotentialHeight850hPa},
-		{apiResponse.Hourly.CloudCover800hPa, apiResponse.Hourly.GeopotentialHeight800hPa},
-		{apiResponse.Hourly.CloudCover700hPa, apiResponse.Hourly.GeopotentialHeight700hPa},
-		{apiResponse.Hourly.CloudCover600hPa, apiResponse.Hourly.GeopotentialHeight600hPa},
-		{apiResponse.Hourly.CloudCover500hPa, apiResponse.Hourly.GeopotentialHeight500hPa},
-		{apiResponse.Hourly.CloudCover400hPa, apiResponse.Hourly.GeopotentialHeight400hPa},
-		{apiResponse.Hourly.CloudCover300hPa, apiResponse.Hourly.GeopotentialHeight300hPa},
-		{apiResponse.Hourly.CloudCover250hPa, apiResponse.Hourly.GeopotentialHeight250hPa},
-		{apiResponse.Hourly.CloudCover200hPa, apiResponse.Hourly.GeopotentialHeight200hPa},
-		{apiResponse.Hourly.CloudCover150hPa, apiResponse.Hourly.GeopotentialHeight150hPa},
-		{apiResponse.Hourly.CloudCover100hPa, apiResponse.Hourly.GeopotentialHeight100hPa},
-		{apiResponse.Hourly.CloudCover70hPa, apiResponse.Hourly.GeopotentialHeight70hPa},
-		{apiResponse.Hourly.CloudCover50hPa, apiResponse.Hourly.GeopotentialHeight50hPa},
-		{apiResponse.Hourly.CloudCover30hPa, apiResponse.Hourly.GeopotentialHeight30hPa},
-	}
-
+// processCloudLayers turns the hour's pressure levels into cloud layers, lowest first, each
+// graded for icing where the level has a temperature and humidity.
+func processCloudLayers(hour forecastHour) []CloudLayer {
 	// Non-nil so an overcast-free hour marshals as [] rather than null.
 	layers := make([]CloudLayer, 0)
 
-	for _, level := range pressureLevels {
-		// Check if data is available for this time index
-		if timeIndex < len(level.CloudCover) && timeIndex < len(level.GeoHeight) {
-			coverage := level.CloudCover[timeIndex]
-			geoHeight := level.GeoHeight[timeIndex]
-			// Convert geopotential height from meters to feet (1 meter = 3.28084 feet)
-			heightFeet := int(geoHeight * 3.28084)
-
-			// Only include layers with some cloud coverage (avoid completely transparent symbols)
-			if coverage > 0 {
-				layers = append(layers, CloudLayer{
-					HeightFeet: heightFeet,
-					Coverage:   coverage,
-				})
+	for _, level := range hour.levels {
+		if level.cloudCover == nil || level.height == nil {
+			continue
+		}
+		// Only include layers with some cloud coverage (avoid completely transparent symbols)
+		if *level.cloudCover > 0 {
+			layer := CloudLayer{
+				// Convert geopotential height from meters to feet (1 meter = 3.28084 feet)
+				HeightFeet: int(*level.height * 3.28084),
+				Coverage:   *level.cloudCover,
 			}
+			if level.temperature != nil && level.relativeHumidity != nil {
+				layer.IcingRisk = int(icingRiskAt(*level.temperature, *level.relativeHumidity))
+			}
+			layers = append(layers, layer)
 		}
 	}
 
 	return layers
 }
 
-// processWindLayers extracts wind data for hPa levels and converts to layers with heights
-func processWindLayers(apiResponse *WeatherAPIResponse, timeIndex int) []WindLayer {
-	// Only use hPa-based wind data with geopotential heights
-	windLevels := []struct {
-		Speed     []float64
-		Direction []int
-		GeoHeight []float64
-	}{
-		{apiResponse.Hourly.WindSpeed10m, apiResponse.Hourly.WindDirection10m, []float64{}},
-		{apiResponse.Hourly.WindSpeed80m, apiResponse.Hourly.WindDirection80m, []float64{}},
-		//{apiResponse.Hourly.WindSpeed1000hPa, apiResponse.Hourly.WindDirection1000hPa, apiResponse.Hourly.GeopotentialHeight1000hPa},
-		{apiResponse.Hourly.WindSpeed975hPa, apiResponse.Hourly.WindDirection975hPa, apiResponse.Hourly.GeopotentialHeight975hPa},
-		{apiResponse.Hourly.WindSpeed950hPa, apiResponse.Hourly.WindDirection950hPa, apiResponse.Hourly.GeopotentialHeight950hPa},
-		{apiResponse.Hourly.WindSpeed925hPa, apiResponse.Hourly.WindDirection925hPa, apiResponse.Hourly.GeopotentialHeight925hPa},
-		//{apiResponse.Hourly.WindSpeed900hPa, apiResponse.Hourly.WindDirection900hPa, apiResponse.Hourly.GeopotentialHeight900hPa},
-		//{apiResponse.Hourly.WindSpeed850hPa, apiResponse.Hourly.WindDirection850hPa, apiResponse.Hourly.GeopotentialHeight850hPa},
-		{apiResponse.Hourly.WindSpeed800hPa, apiResponse.Hourly.WindDirection800hPa, apiResponse.Hourly.GeopotentialHeight800hPa},
-		//{apiResponse.Hourly.WindSpeed700hPa, apiResponse.Hourly.WindDirection700hPa, apiResponse.Hourly.GeopotentialHeight700hPa},
-		{apiResponse.Hourly.WindSpeed600hPa, apiResponse.Hourly.WindDirection600hPa, apiResponse.Hourly.GeopotentialHeight600hPa},
+// windLayerLevels are the pressure levels the wind chart draws barbs for, above the 10m and
+// 80m winds. 1000hPa is usually underground or indistinguishable from 80m, and the levels
+// between those left out crowded the barbs together without saying anything new.
+var windLayerLevels = []int{975, 950, 925, 800, 600}
+
+// processWindLayers turns the hour's 10m and 80m winds and windLayerLevels into wind
+// layers, lowest first.
+func processWindLayers(hour forecastHour) []WindLayer {
+	type windLevel struct {
+		speed     *float64
+		direction *int
+		height    *float64 // m
+	}
+	// 10m and 80m carry no geopotential height and stand at their own.
+	levels := []windLevel{
+		{hour.windSpeed10m, hour.windDirection10m, ptrTo(10.0)},
+		{hour.windSpeed80m, hour.windDirection80m, ptrTo(80.0)},
+	}
+	for _, hPa := range windLayerLevels {
+		if level, ok := hour.level(hPa); ok {
+			levels = append(levels, windLevel{level.windSpeed, level.windDirection, level.height})
+		}
 	}
 
 	// Non-nil so a calm hour marshals as [] rather than null.
 	layers := make([]WindLayer, 0)
 
-	// Process hPa-based levels only
-	for i, level := range windLevels {
-		if timeIndex < len(level.Speed) && timeIndex < len(level.Direction) {
-			speed := level.Speed[timeIndex]
-			direction := level.Direction[timeIndex]
-
-			geoHeight := 0.0
-			if timeIndex < len(level.GeoHeight) {
-				geoHeight = level.GeoHeight[timeIndex]
-			} else if i == 0 {
-				geoHeight = 10 // wind 10m
-			} else if i == 1 {
-				geoHeight = 80 // wind 80m
-			}
-
-			// Convert geopotential height from meters to feet (1 meter = 3.28084 feet)
-			heightFeet := int(geoHeight * 3.28084)
-
-			// Only include if we have valid data and height is within range (600-12000 feet)
-			if speed > 0 && heightFeet <= 12000 {
-				layers = append(layers, WindLayer{
-					HeightFeet: heightFeet,
-					Speed:      speed,
-					Direction:  direction,
-				})
-			}
+	for _, level := range levels {
+		if level.speed == nil || level.direction == nil || level.height == nil {
+			continue
+		}
+		// Convert geopotential height from meters to feet (1 meter = 3.28084 feet)
+		heightFeet := int(*level.height * 3.28084)
+
+		// Only include if we have valid data and height is within range (600-12000 feet)
+		if *level.speed > 0 && heightFeet <= 12000 {
+			layers = append(layers, WindLayer{
+				HeightFeet: heightFeet,
+				Speed:      *level.speed,
+				Direction:  *level.direction,
+			})
 		}
 	}
 
@@ -745,6 +850,8 @@ type SunriseSunsetResponse struct {
 	Status string `json:"status"`
 }
 
+// SunriseCache holds sunrise-sunset.org's answers for the cross-check, keyed by position and
+// date. The window the score uses is computed, and not cached: it costs microseconds.
 type SunriseCache struct {
 	data  map[string]*SunriseSunsetResponse
 	mutex sync.RWMutex
@@ -775,11 +882,62 @@ func (c *SunriseCache) pruneLocked(now time.Time) {
 	}
 }
 
-// getDayLightFn indirects getDayLight so tests can stub the network call.
+// getDayLightFn indirects getDayLight so tests can pin the daylight window.
 var getDayLightFn = getDayLight
 
+// getDayLight returns the daylight window for the UTC date of t, worked out locally -- see
+// sun.go. With the cross-check on, sunrise-sunset.org is asked too and a disagreement is
+// logged; its answer, or its failure, never changes the window returned.
 func getDayLight(ctx context.Context, latitude, longitude string, t time.Time) (*SunriseSunsetResponse, error) {
+	lat, err := strconv.ParseFloat(latitude, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latitude %q: %w", latitude, err)
+	}
+	lon, err := strconv.ParseFloat(longitude, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid longitude %q: %w", longitude, err)
+	}
+
+	result, err := computeDaylight(lat, lon, t)
+	if err != nil {
+		return nil, err
+	}
+	if sunriseCrossCheck {
+		crossCheckDaylight(ctx, latitude, longitude, t, result)
+	}
+	return result, nil
+}
+
+// sunriseCrossCheckTolerance is how far the remote answer may be from the local one before
+// the cross-check says so: the accuracy the algorithm is good for.
+const sunriseCrossCheckTolerance = time.Minute
+
+// crossCheckDaylight compares a locally computed window with sunrise-sunset.org's, and logs
+// every boundary that differs by more than sunriseCrossCheckTolerance.
+func crossCheckDaylight(ctx context.Context, latitude, longitude string, t time.Time, local *SunriseSunsetResponse) {
+	remote, err := fetchSunriseSunset(ctx, latitude, longitude, t)
+	if err != nil {
+		slog.Warn("sunrise cross-check unavailable", "lat", latitude, "lon", longitude, "error", err)
+		return
+	}
+	for _, b := range []struct {
+		name          string
+		local, remote time.Time
+	}{
+		{"sunrise", local.Parsed.Sunrise, remote.Parsed.Sunrise},
+		{"sunset", local.Parsed.Sunset, remote.Parsed.Sunset},
+		{"civil twilight begin", local.Parsed.CivilTwilightBegin, remote.Parsed.CivilTwilightBegin},
+		{"civil twilight end", local.Parsed.CivilTwilightEnd, remote.Parsed.CivilTwilightEnd},
+	} {
+		if diff := b.local.Sub(b.remote).Abs(); diff > sunriseCrossCheckTolerance {
+			slog.Warn("computed daylight disagrees with sunrise-sunset.org", "boundary", b.name,
+				"lat", latitude, "lon", longitude, "local", b.local, "remote", b.remote, "difference", diff)
+		}
+	}
+}
 
+// fetchSunriseSunset asks sunrise-sunset.org for the window, cached by position and date.
+func fetchSunriseSunset(ctx context.Context, latitude, longitude string, t time.Time) (*SunriseSunsetResponse, error) {
 	// Format date as YYYY-MM-DD
 	dateStr := t.Format("2006-01-02")
 
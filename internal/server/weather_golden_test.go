@@ -3,26 +3,45 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"math"
 	"os"
 	"reflect"
 	"testing"
 	"time"
 )
 
-// This file tests the decode path against a real Open-Meteo response, captured with the
-// exact query the app sends (see apiURLTemplate) and trimmed to 24 hours.
+// This file tests the decode path against an Open-Meteo response to the exact query the app
+// sends (see apiURLTemplate), trimmed to its past_days of history and the first
+// goldenFixtureHours of forecast, with the past_days it was fetched with recorded beside
+// "hourly". `make golden` re-captures it.
 //
-// The ~80 `json:` tags on WeatherAPIResponse are the most brittle thing in the backend:
-// nothing in the type system connects them to the variables apiURLTemplate asks for. A
-// renamed or dropped upstream field leaves its slice empty, and every value derived from it
-// silently becomes zero -- a cloud layer at 0ft, a wind of 0kn -- with no error anywhere.
+// The forecast day in the tree was captured before the query asked for history, CAPE, the
+// lifted index, the soil temperature and the level temperatures and humidities. The two
+// days of history and those columns were filled in by hand, in the shape upstream sends.
+// A hand-filled column can only agree with the name it was filled in under, so the name
+// guard leaves them out (see goldenHandFilled): until the fixture is re-captured, they pin
+// the decode path and what is derived from them, and nothing catches upstream renaming
+// them.
+//
+// The variable names in openmeteo.go are the most brittle thing in the backend: nothing in
+// the type system connects them to what upstream sends back. A renamed or dropped upstream
+// field leaves its values nil, and every layer and wind derived from it silently vanishes,
+// with no error anywhere.
+//
+// Everything past decodeOpenMeteo runs against the neutral hourlyForecast, as it would for
+// any other provider.
 
 const goldenFixture = "testdata/openmeteo_edwn.json"
 
-// goldenFixtureHours is the number of hours in the captured response.
+// goldenFixtureHours is the number of forecast hours in the captured response, after the
+// openMeteoPastDays of history.
 const goldenFixtureHours = 24
 
-func loadGoldenFixture(t *testing.T) *WeatherAPIResponse {
+// captureGoldenEnv, when set, has TestGoldenFixture_Capture fetch a fresh response and write
+// it over goldenFixture. See `make golden`.
+const captureGoldenEnv = "FLUGWETTER_CAPTURE_GOLDEN"
+
+func loadGoldenFixture(t *testing.T) *hourlyForecast {
 	t.Helper()
 
 	body, err := os.ReadFile(goldenFixture)
@@ -30,11 +49,12 @@ func loadGoldenFixture(t *testing.T) *WeatherAPIResponse {
 		t.Fatalf("failed to read %s: %v", goldenFixture, err)
 	}
 
-	var response WeatherAPIResponse
-	if err := json.Unmarshal(body, &response); err != nil {
+	// As a replay decodes it: the fixture's own past_days say where the history ends.
+	forecast, err := decodeOpenMeteo(body, 0)
+	if err != nil {
 		t.Fatalf("failed to decode %s: %v", goldenFixture, err)
 	}
-	return &response
+	return forecast
 }
 
 // stubDayLightByDate answers with a plausible early-August window for whatever date is
@@ -65,26 +85,105 @@ func stubDayLightByDate(t *testing.T) {
 	t.Cleanup(func() { getDayLightFn = original })
 }
 
-// TestGoldenFixture_EveryHourlyFieldBinds is the tag guard. Every hourly slice the struct
-// declares is a variable apiURLTemplate requests, so all of them must come back populated.
-// An empty one means the tag and the query have drifted apart.
-func TestGoldenFixture_EveryHourlyFieldBinds(t *testing.T) {
-	response := loadGoldenFixture(t)
+// TestGoldenFixture_Capture writes a fresh fixture when captureGoldenEnv is set, and is
+// skipped otherwise: it needs the network. Every column the query asks for must come back,
+// which is the name guard at the source.
+func TestGoldenFixture_Capture(t *testing.T) {
+	if os.Getenv(captureGoldenEnv) == "" {
+		t.Skip(captureGoldenEnv + " is not set")
+	}
+
+	body, err := getJSON(context.Background(), buildAPIURL(testAirport))
+	if err != nil {
+		t.Fatalf("failed to fetch: %v", err)
+	}
+	var response struct {
+		Hourly map[string][]json.RawMessage `json:"hourly"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("failed to parse the response: %v", err)
+	}
 
-	hourly := reflect.ValueOf(response.Hourly)
-	hourlyType := hourly.Type()
+	keep := openMeteoPastDays*24 + goldenFixtureHours
+	hourly := make(map[string][]json.RawMessage)
+	for _, name := range append([]string{"time"}, openMeteoHourly()...) {
+		column, ok := response.Hourly[name]
+		if !ok {
+			t.Fatalf("the response has no %s", name)
+		}
+		hourly[name] = column[:min(keep, len(column))]
+	}
 
-	for i := 0; i < hourly.NumField(); i++ {
-		field := hourly.Field(i)
-		name := hourlyType.Field(i).Name
-		tag := hourlyType.Field(i).Tag.Get("json")
+	fixture, err := json.MarshalIndent(struct {
+		PastDays int                          `json:"past_days"`
+		Hourly   map[string][]json.RawMessage `json:"hourly"`
+	}{openMeteoPastDays, hourly}, "", " ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(goldenFixture, fixture, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("wrote %s; re-pin the values TestGoldenFixture_ProcessesToKnownValues checks, and empty goldenHandFilled", goldenFixture)
+}
 
-		if field.Kind() != reflect.Slice {
-			continue
+// goldenLevelTops is the highest level, the lowest pressure, each level variable is asked
+// for up to; one that is not listed is asked for at every level.
+var goldenLevelTops = map[string]int{
+	"windSpeed":     openMeteoWindTopHPa,
+	"windDirection": openMeteoWindTopHPa,
+}
+
+// goldenHandFilled are the forecastHour and pressureLevel fields decoded from columns that
+// were filled in by hand rather than captured. The name guard skips them, and the history
+// with them; drop an entry once `make golden` has captured its column.
+var goldenHandFilled = struct {
+	surface, level map[string]bool
+}{
+	surface: map[string]bool{"cape": true, "liftedIndex": true, "soilTemperature": true},
+	level:   map[string]bool{"temperature": true, "relativeHumidity": true},
+}
+
+// TestGoldenFixture_EveryRequestedVariableDecodes is the name guard. Every variable the
+// query asks for comes back in the fixture, so every captured hour of the decoded forecast
+// must have every captured value the decoder reads. A nil means a name and upstream have
+// drifted apart.
+func TestGoldenFixture_EveryRequestedVariableDecodes(t *testing.T) {
+	forecast := loadGoldenFixture(t)
+	if len(forecast.hours) != goldenFixtureHours || len(forecast.history) != openMeteoPastDays*24 {
+		t.Fatalf("decoded %d hours and %d of history, want %d and %d", len(forecast.hours), len(forecast.history), goldenFixtureHours, openMeteoPastDays*24)
+	}
+
+	for _, hour := range forecast.hours {
+		surface := reflect.ValueOf(hour)
+		for i := 0; i < surface.NumField(); i++ {
+			name := surface.Type().Field(i).Name
+			if goldenHandFilled.surface[name] {
+				continue
+			}
+			if field := surface.Field(i); field.Kind() == reflect.Pointer && field.IsNil() {
+				t.Errorf("%s: %s is nil — its name and the response have drifted apart", hour.time, name)
+			}
 		}
-		if field.Len() != goldenFixtureHours {
-			t.Errorf("%s (json:%q) decoded %d values, want %d — the tag and the query have drifted apart",
-				name, tag, field.Len(), goldenFixtureHours)
+
+		if len(hour.levels) != len(openMeteoLevels) {
+			t.Fatalf("%s: %d levels, want %d", hour.time, len(hour.levels), len(openMeteoLevels))
+		}
+		for _, level := range hour.levels {
+			values := reflect.ValueOf(level)
+			for i := 0; i < values.NumField(); i++ {
+				field := values.Field(i)
+				if field.Kind() != reflect.Pointer {
+					continue
+				}
+				name := values.Type().Field(i).Name
+				if goldenHandFilled.level[name] {
+					continue
+				}
+				if want := level.hPa >= goldenLevelTops[name]; field.IsNil() == want {
+					t.Errorf("%s: %dhPa %s is nil = %v, want a value up to %dhPa and none above", hour.time, level.hPa, name, field.IsNil(), goldenLevelTops[name])
+				}
+			}
 		}
 	}
 }
@@ -95,7 +194,7 @@ func TestGoldenFixture_EveryHourlyFieldBinds(t *testing.T) {
 func TestGoldenFixture_ProcessesToKnownValues(t *testing.T) {
 	stubDayLightByDate(t)
 
-	got := processWeatherData(context.Background(), loadGoldenFixture(t), testAirport)
+	got := processWeatherData(context.Background(), loadGoldenFixture(t), testAirport, defaultProfile())
 
 	for _, series := range []struct {
 		name string
@@ -124,6 +223,25 @@ func TestGoldenFixture_ProcessesToKnownValues(t *testing.T) {
 		}
 	})
 
+	t.Run("the history fills the two days of rain", func(t *testing.T) {
+		// The fixture's history has 4mm of showers on the afternoon before, of which a
+		// warm evening and night leave 1.67mm on the ground by midnight.
+		first, last := got.TemperatureData[0], got.TemperatureData[goldenFixtureHours-1]
+		if first.Precipitation24h == nil || *first.Precipitation24h != 4 || *first.Precipitation48h != 4 {
+			t.Errorf("at %s: 24h %v, 48h %v, want 4mm in both", first.Time, first.Precipitation24h, first.Precipitation48h)
+		}
+		if last.Precipitation24h == nil || *last.Precipitation24h != 0 || *last.Precipitation48h != 4 {
+			t.Errorf("at %s: 24h %v, 48h %v, want the showers out of the day and in the two", last.Time, last.Precipitation24h, last.Precipitation48h)
+		}
+		if w := first.GroundWetness; w == nil || math.Abs(*w-1.668) > 0.001 {
+			t.Errorf("ground wetness = %v, want ~1.668mm", w)
+		}
+		// And the soft field factor is handed the same ground to score.
+		if c := got.modelConditions[0]; c.ground == nil || math.Abs(c.ground.wetness()-1.668) > 0.001 {
+			t.Errorf("scored ground = %+v, want the payload's", c.ground)
+		}
+	})
+
 	t.Run("visibility is converted to km", func(t *testing.T) {
 		// Upstream reports 28400 m for this hour.
 		vis := got.CloudData[0].Visibility
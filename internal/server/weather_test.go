@@ -111,36 +111,32 @@ func TestGetCloudBase(t *testing.T) {
 	}
 }
 
-// hourlyFixture builds a response whose hourly arrays are all the same length, with
-// every wind level dead calm so processWindLayers yields nothing.
-func hourlyFixture(times []string) *WeatherAPIResponse {
-	n := len(times)
-	zerosF := make([]float64, n)
-	zerosI := make([]int, n)
-	visibility := make([]*float64, n)
-	for i := range visibility {
-		visibility[i] = ptrFloat(30000)
-	}
-
-	r := &WeatherAPIResponse{}
-	r.Hourly.Time = times
-	r.Hourly.Temperature2m = zerosF
-	r.Hourly.DewPoint2m = zerosF
-	r.Hourly.Precipitation = zerosF
-	r.Hourly.PrecipitationProbability = zerosI
-	r.Hourly.WeatherCode = zerosI
-	r.Hourly.Visibility = visibility
-	r.Hourly.WindSpeed10m = zerosF
-	r.Hourly.WindGusts10m = zerosF
-	r.Hourly.WindDirection10m = zerosI
-	return r
+// hourlyFixture builds a forecast with every surface value at zero, a 30km visibility, and
+// no pressure levels, so processWindLayers yields nothing.
+func hourlyFixture(times []string) *hourlyForecast {
+	f := &hourlyForecast{}
+	for _, t := range times {
+		f.hours = append(f.hours, forecastHour{
+			time:                     t,
+			temperature:              ptrFloat(0),
+			dewPoint:                 ptrFloat(0),
+			precipitation:            ptrFloat(0),
+			precipitationProbability: ptrTo(0),
+			weatherCode:              ptrTo(0),
+			visibility:               ptrFloat(30000),
+			windSpeed10m:             ptrFloat(0),
+			windGusts10m:             ptrFloat(0),
+			windDirection10m:         ptrTo(0),
+		})
+	}
+	return f
 }
 
 func TestProcessWeatherData_CalmHourKeepsWindRow(t *testing.T) {
 	stubDayLight(t)
 	times := []string{"2026-08-03T10:00", "2026-08-03T11:00", "2026-08-03T12:00"}
 
-	got := processWeatherData(context.Background(), hourlyFixture(times), testAirport)
+	got := processWeatherData(context.Background(), hourlyFixture(times), testAirport, defaultProfile())
 
 	// The regression: `if len(windLayers) > 0` dropped the entire WindPoint when no
 	// level qualified, taking the 10m speed, gusts and both crosswind series with it
@@ -174,7 +170,7 @@ func TestProcessWeatherData_SurvivesDaylightLookupFailure(t *testing.T) {
 			t.Fatalf("processWeatherData panicked on a daylight lookup failure: %v", r)
 		}
 	}()
-	got := processWeatherData(context.Background(), hourlyFixture(times), testAirport)
+	got := processWeatherData(context.Background(), hourlyFixture(times), testAirport, defaultProfile())
 
 	if len(got.VfrData) != len(times) {
 		t.Fatalf("len(VfrData) = %d, want %d", len(got.VfrData), len(times))
@@ -193,7 +189,7 @@ func TestProcessWeatherData_SeriesStayAligned(t *testing.T) {
 	stubDayLight(t)
 	times := []string{"2026-08-03T10:00", "2026-08-03T11:00", "2026-08-03T12:00"}
 
-	got := processWeatherData(context.Background(), hourlyFixture(times), testAirport)
+	got := processWeatherData(context.Background(), hourlyFixture(times), testAirport, defaultProfile())
 
 	for _, tc := range []struct {
 		name string
@@ -233,7 +229,7 @@ func TestProcessWeatherData_ResolvesDaylightOncePerDate(t *testing.T) {
 	}
 	times = append(times, "2026-08-04T00:00", "2026-08-04T01:00")
 
-	got := processWeatherData(context.Background(), hourlyFixture(times), testAirport)
+	got := processWeatherData(context.Background(), hourlyFixture(times), testAirport, defaultProfile())
 
 	if len(got.VfrData) != len(times) {
 		t.Fatalf("len(VfrData) = %d, want %d", len(got.VfrData), len(times))
@@ -249,7 +245,7 @@ func TestProcessWeatherData_SetsGeneratedAt(t *testing.T) {
 	stubDayLight(t)
 
 	before := time.Now()
-	got := processWeatherData(context.Background(), hourlyFixture([]string{"2026-08-03T10:00"}), testAirport)
+	got := processWeatherData(context.Background(), hourlyFixture([]string{"2026-08-03T10:00"}), testAirport, defaultProfile())
 
 	if got.GeneratedAt.Before(before) || got.GeneratedAt.After(time.Now()) {
 		t.Errorf("GeneratedAt = %v, want a timestamp from during this call", got.GeneratedAt)
@@ -266,10 +262,10 @@ func TestProcessWeatherData_ScaledPenaltyReachesTheWireFormat(t *testing.T) {
 	stubDayLight(t)
 
 	fixture := hourlyFixture([]string{"2026-08-03T12:00"})
-	fixture.Hourly.Precipitation = []float64{3.2}
-	fixture.Hourly.PrecipitationProbability = []int{88}
+	fixture.hours[0].precipitation = ptrFloat(3.2)
+	fixture.hours[0].precipitationProbability = ptrTo(88)
 
-	got := processWeatherData(context.Background(), fixture, testAirport)
+	got := processWeatherData(context.Background(), fixture, testAirport, defaultProfile())
 
 	if len(got.VfrData) != 1 {
 		t.Fatalf("len(VfrData) = %d, want 1", len(got.VfrData))
@@ -309,7 +305,7 @@ func TestProcessWeatherData_ScaledPenaltyReachesTheWireFormat(t *testing.T) {
 func TestProcessWeatherData_UnparseableHourIsNotScored(t *testing.T) {
 	stubDayLight(t)
 
-	got := processWeatherData(context.Background(), hourlyFixture([]string{"not-a-time"}), testAirport)
+	got := processWeatherData(context.Background(), hourlyFixture([]string{"not-a-time"}), testAirport, defaultProfile())
 
 	if len(got.VfrData) != 1 {
 		t.Fatalf("len(VfrData) = %d, want 1", len(got.VfrData))
@@ -324,7 +320,7 @@ func TestProcessWeatherData_UnparseableHourIsNotScored(t *testing.T) {
 
 // stubFetchWeather replaces the upstream weather call for the duration of the test and
 // resets the cache, which is package-level state shared between tests.
-func stubFetchWeather(t *testing.T, fn func(context.Context, Airport) (*ProcessedWeatherData, error)) {
+func stubFetchWeather(t *testing.T, fn func(context.Context, Airport, *scoringProfile) (*ProcessedWeatherData, error)) {
 	t.Helper()
 
 	original := fetchWeatherFn
@@ -333,18 +329,58 @@ func stubFetchWeather(t *testing.T, fn func(context.Context, Airport) (*Processe
 		fetchWeatherFn = original
 		cache.mutex.Lock()
 		cache.entries = make(map[string]*cacheEntry)
+		cache.forecasts = make(map[string]*airportForecast)
 		cache.mutex.Unlock()
 	})
 
 	cache.mutex.Lock()
 	cache.entries = make(map[string]*cacheEntry)
+	cache.forecasts = make(map[string]*airportForecast)
 	cache.mutex.Unlock()
 }
 
+// countingProvider serves hourlyFixture's hours and counts the requests.
+type countingProvider struct{ calls *int }
+
+func (p countingProvider) forecast(context.Context, Airport, forecastModel) (*hourlyForecast, error) {
+	*p.calls++
+	return hourlyFixture([]string{"2026-08-03T10:00", "2026-08-03T11:00"}), nil
+}
+
+// Four profiles asking about one airfield are one request upstream: the forecast is the
+// airfield's, and only the score is the profile's. A retune re-scores what was fetched; a
+// new run fetches again.
+func TestGetWeatherData_FetchesOnceForEveryProfile(t *testing.T) {
+	withTestAirports(t)
+	t.Setenv(ensembleURLEnv, "off")
+	stubFetchWeather(t, fetchWeather)
+	calls := 0
+	withWeatherSource(t, countingProvider{calls: &calls})
+
+	for _, profile := range scoring.Load().profiles {
+		data, err := GetWeatherData(context.Background(), testAirport, profile)
+		if err != nil || len(data.VfrData) != 2 {
+			t.Fatalf("%s: %v, %+v", profile.ID, err, data)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("%d requests upstream for %d profiles, want 1", calls, len(profileList()))
+	}
+
+	cache.invalidateAll()
+	if _, err := GetWeatherData(context.Background(), testAirport, defaultProfile()); err != nil || calls != 1 {
+		t.Errorf("after a retune: %v, %d requests, want the fetched forecast re-scored", err, calls)
+	}
+	cache.invalidateModels([]string{primaryModel().ID})
+	if _, err := GetWeatherData(context.Background(), testAirport, defaultProfile()); err != nil || calls != 2 {
+		t.Errorf("after a new run: %v, %d requests, want a fetch", err, calls)
+	}
+}
+
 // With upstream down and the TTL expired, a 16-minute-old forecast is far more useful than
 // an error page -- but it must arrive flagged, or the dashboard presents it as current.
 func TestFetchAndCacheWeatherData_ServesStaleOnFailure(t *testing.T) {
-	stubFetchWeather(t, func(context.Context, Airport) (*ProcessedWeatherData, error) {
+	stubFetchWeather(t, func(context.Context, Airport, *scoringProfile) (*ProcessedWeatherData, error) {
 		return nil, errors.New("open-meteo unreachable")
 	})
 
@@ -353,10 +389,10 @@ func TestFetchAndCacheWeatherData_ServesStaleOnFailure(t *testing.T) {
 		GeneratedAt:     time.Now().Add(-30 * time.Minute),
 	}
 	cache.mutex.Lock()
-	cache.entries[testAirport.Identifier] = &cacheEntry{data: expired, timestamp: expired.GeneratedAt}
+	cache.entries[cacheKey(testAirport, defaultProfile())] = &cacheEntry{data: expired, timestamp: expired.GeneratedAt}
 	cache.mutex.Unlock()
 
-	got, err := fetchAndCacheWeatherData(context.Background(), testAirport)
+	got, err := fetchAndCacheWeatherData(context.Background(), testAirport, defaultProfile())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -374,11 +410,11 @@ func TestFetchAndCacheWeatherData_ServesStaleOnFailure(t *testing.T) {
 
 // With nothing cached there is nothing to fall back to, and the error has to surface.
 func TestFetchAndCacheWeatherData_ErrorsWithoutCache(t *testing.T) {
-	stubFetchWeather(t, func(context.Context, Airport) (*ProcessedWeatherData, error) {
+	stubFetchWeather(t, func(context.Context, Airport, *scoringProfile) (*ProcessedWeatherData, error) {
 		return nil, errors.New("open-meteo unreachable")
 	})
 
-	if _, err := fetchAndCacheWeatherData(context.Background(), testAirport); err == nil {
+	if _, err := fetchAndCacheWeatherData(context.Background(), testAirport, defaultProfile()); err == nil {
 		t.Error("got no error, want one when the fetch fails and the cache is empty")
 	}
 }
@@ -388,17 +424,17 @@ func TestFetchAndCacheWeatherData_ErrorsWithoutCache(t *testing.T) {
 func TestFetchAndCacheWeatherData_KeepsFresherEntry(t *testing.T) {
 	fresh := &ProcessedWeatherData{GeneratedAt: time.Now()}
 
-	stubFetchWeather(t, func(context.Context, Airport) (*ProcessedWeatherData, error) {
+	stubFetchWeather(t, func(context.Context, Airport, *scoringProfile) (*ProcessedWeatherData, error) {
 		// Simulates a fetch that started earlier and finished later: while it was in
 		// flight, another goroutine stored a newer entry.
 		cache.mutex.Lock()
-		cache.entries[testAirport.Identifier] = &cacheEntry{data: fresh, timestamp: fresh.GeneratedAt}
+		cache.entries[cacheKey(testAirport, defaultProfile())] = &cacheEntry{data: fresh, timestamp: fresh.GeneratedAt}
 		cache.mutex.Unlock()
 
 		return &ProcessedWeatherData{GeneratedAt: time.Now().Add(-time.Minute)}, nil
 	})
 
-	got, err := fetchAndCacheWeatherData(context.Background(), testAirport)
+	got, err := fetchAndCacheWeatherData(context.Background(), testAirport, defaultProfile())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -464,65 +500,56 @@ func TestGetJSON(t *testing.T) {
 	})
 }
 
-// The layer builders index into ~19 parallel slices per hour. The bounds guards are what
-// stop a short or absent slice from panicking, and they are easy to break.
-func TestProcessLayers_ToleratesShortSlices(t *testing.T) {
-	// One time step, but every level's data is missing entirely.
-	response := &WeatherAPIResponse{}
-	response.Hourly.Time = []string{"2026-08-03T10:00"}
-
-	t.Run("cloud layers", func(t *testing.T) {
-		layers := processCloudLayers(response, 0)
-		if layers == nil {
-			t.Error("got nil, want an empty slice so it marshals as [] rather than null")
-		}
-		if len(layers) != 0 {
-			t.Errorf("len = %d, want 0 when no level has data", len(layers))
-		}
-	})
-
-	t.Run("wind layers", func(t *testing.T) {
-		layers := processWindLayers(response, 0)
-		if layers == nil {
-			t.Error("got nil, want an empty slice so it marshals as [] rather than null")
+// A provider may have none of the levels, or some of a level's values and not others. The
+// layer builders must skip what is missing rather than draw it at zero.
+func TestProcessLayers_ToleratesMissingValues(t *testing.T) {
+	t.Run("no levels at all", func(t *testing.T) {
+		hour := forecastHour{time: "2026-08-03T10:00"}
+		if layers := processCloudLayers(hour); layers == nil || len(layers) != 0 {
+			t.Errorf("cloud layers = %#v, want an empty slice so it marshals as [] rather than null", layers)
 		}
-		if len(layers) != 0 {
-			t.Errorf("len = %d, want 0 when no level has data", len(layers))
+		if layers := processWindLayers(hour); layers == nil || len(layers) != 0 {
+			t.Errorf("wind layers = %#v, want an empty slice so it marshals as [] rather than null", layers)
 		}
 	})
 
-	// An index past the end of every slice must also be safe: the hourly arrays are not
-	// guaranteed to be the same length as Time.
-	t.Run("index past the end", func(t *testing.T) {
-		if got := processCloudLayers(response, 99); len(got) != 0 {
-			t.Errorf("cloud layers = %d, want 0", len(got))
+	// A level with cover but no height would otherwise be a layer at 0ft, and a wind with no
+	// height a barb on the ground.
+	t.Run("levels without a height", func(t *testing.T) {
+		hour := forecastHour{time: "2026-08-03T10:00", levels: []pressureLevel{
+			{hPa: 975, cloudCover: ptrTo(80), windSpeed: ptrFloat(20), windDirection: ptrTo(270)},
+			{hPa: 950, height: ptrFloat(500), cloudCover: ptrTo(60)},
+		}}
+		if layers := processCloudLayers(hour); len(layers) != 1 || layers[0].HeightFeet != 1640 {
+			t.Errorf("cloud layers = %+v, want the 950hPa one alone", layers)
 		}
-		if got := processWindLayers(response, 99); len(got) != 0 {
-			t.Errorf("wind layers = %d, want 0", len(got))
+		if layers := processWindLayers(hour); len(layers) != 0 {
+			t.Errorf("wind layers = %+v, want none", layers)
 		}
 	})
 }
 
 func TestProcessWindLayers_FiltersAndHeights(t *testing.T) {
-	response := &WeatherAPIResponse{}
-	response.Hourly.Time = []string{"2026-08-03T10:00"}
-	// 10m and 80m carry no geopotential height and fall back to fixed altitudes.
-	response.Hourly.WindSpeed10m = []float64{12}
-	response.Hourly.WindDirection10m = []int{270}
-	response.Hourly.WindSpeed80m = []float64{0} // calm: dropped by the speed > 0 filter
-	response.Hourly.WindDirection80m = []int{270}
-	// 975hPa well inside range, 600hPa deliberately above the 12000ft ceiling.
-	response.Hourly.WindSpeed975hPa = []float64{20}
-	response.Hourly.WindDirection975hPa = []int{300}
-	response.Hourly.GeopotentialHeight975hPa = []float64{300}
-	response.Hourly.WindSpeed600hPa = []float64{40}
-	response.Hourly.WindDirection600hPa = []int{310}
-	response.Hourly.GeopotentialHeight600hPa = []float64{4400} // ~14435 ft
-
-	layers := processWindLayers(response, 0)
+	hour := forecastHour{
+		time: "2026-08-03T10:00",
+		// 10m and 80m carry no geopotential height and fall back to fixed altitudes.
+		windSpeed10m:     ptrFloat(12),
+		windDirection10m: ptrTo(270),
+		windSpeed80m:     ptrFloat(0), // calm: dropped by the speed > 0 filter
+		windDirection80m: ptrTo(270),
+		levels: []pressureLevel{
+			// 975hPa well inside range, 900hPa not a barb level, 600hPa deliberately above
+			// the 12000ft ceiling.
+			{hPa: 975, height: ptrFloat(300), windSpeed: ptrFloat(20), windDirection: ptrTo(300)},
+			{hPa: 900, height: ptrFloat(1000), windSpeed: ptrFloat(25), windDirection: ptrTo(300)},
+			{hPa: 600, height: ptrFloat(4400), windSpeed: ptrFloat(40), windDirection: ptrTo(310)}, // ~14435 ft
+		},
+	}
+
+	layers := processWindLayers(hour)
 
 	if len(layers) != 2 {
-		t.Fatalf("len(layers) = %d, want 2 (calm 80m dropped, 600hPa above the ceiling)", len(layers))
+		t.Fatalf("len(layers) = %d, want 2 (calm 80m dropped, 900hPa not drawn, 600hPa above the ceiling)", len(layers))
 	}
 	// 10m falls back to 10 metres -> 32 ft.
 	if layers[0].HeightFeet != 32 {
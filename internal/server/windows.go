@@ -0,0 +1,182 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// The flight-window finder: "when this week can I fly two hours from EDWN", answered from
+// the same VfrData the chart draws, so the list and the bars cannot disagree.
+//
+// A window is a stretch in which every hour scores at least min_score. It is then cut down
+// to what the hours alone do not say:
+//
+//   - Daylight, sunrise to sunset. Civil twilight is legal, and the score charges it rather
+//     than zeroing it, but a planned slot that ends in twilight has no margin left -- and
+//     sunset is where EDWN's winter hours end anyway.
+//   - The airfield's operating window, when asked for and when it has one (see
+//     Airport.OperatingWindow).
+//   - Every published activation of a restricted area over the airfield. The plan thins out
+//     after the first few days (see restrictions.go), so a window on day six being clear of
+//     ED-R activity means only that nobody has filed yet.
+//
+// The edges are minutes, not hours: sunset at 18:47 ends a window at 18:47. What is left is
+// kept if it is at least duration long, and ranked best first by its worst hour, then its
+// mean -- a slot scoring 80 throughout beats one that dips to 60 between two 100s.
+const (
+	defaultWindowMinScore = 70
+	defaultWindowDuration = time.Hour
+	// maxWindowDuration is a day of flying; more is a typo rather than a plan.
+	maxWindowDuration = 12 * time.Hour
+)
+
+// FlightWindow is one slot /api/windows offers.
+type FlightWindow struct {
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	DurationMinutes float64   `json:"duration_minutes"`
+	// MinProbability and MeanProbability are over the hours the window touches.
+	MinProbability  int     `json:"min_probability"`
+	MeanProbability float64 `json:"mean_probability"`
+}
+
+// windowOptions is a parsed /api/windows query.
+type windowOptions struct {
+	minScore int
+	duration time.Duration
+	// operating is the airfield's daily window, nil when it is not applied.
+	operating *DailyWindow
+	// restricted are the activations to stay out of.
+	restricted []Interval
+}
+
+// findWindows returns the windows in data at or after now, best first.
+func findWindows(data *ProcessedWeatherData, opts windowOptions, now time.Time) []FlightWindow {
+	type hour struct {
+		from        time.Time
+		probability int
+	}
+	hours := make([]hour, 0, len(data.VfrData))
+	var good []Interval
+	for _, point := range data.VfrData {
+		t, err := hourTime(point.Time)
+		if err != nil {
+			continue
+		}
+		hours = append(hours, hour{t, point.Probability})
+		if point.Probability < opts.minScore {
+			continue
+		}
+		// Consecutive hours join into one stretch.
+		if n := len(good); n > 0 && good[n-1].To.Equal(t) {
+			good[n-1].To = t.Add(time.Hour)
+		} else {
+			good = append(good, Interval{From: t, To: t.Add(time.Hour)})
+		}
+	}
+	if len(good) == 0 {
+		return nil
+	}
+
+	good = subtractIntervals(good, []Interval{{From: good[0].From, To: now}})
+	good = subtractIntervals(good, data.NightPeriods)
+	good = subtractIntervals(good, data.TwilightPeriods)
+	good = subtractIntervals(good, opts.restricted)
+	if opts.operating != nil {
+		good = subtractIntervals(good, closedHours(*opts.operating, good))
+	}
+
+	var windows []FlightWindow
+	for _, span := range good {
+		if span.To.Sub(span.From) < opts.duration {
+			continue
+		}
+		w := FlightWindow{From: span.From, To: span.To, DurationMinutes: span.To.Sub(span.From).Minutes(), MinProbability: 100}
+		sum, n := 0, 0
+		for _, h := range hours {
+			if h.from.Before(span.To) && h.from.Add(time.Hour).After(span.From) {
+				w.MinProbability = min(w.MinProbability, h.probability)
+				sum += h.probability
+				n++
+			}
+		}
+		w.MeanProbability = float64(sum) / float64(n)
+		windows = append(windows, w)
+	}
+
+	slices.SortStableFunc(windows, func(a, b FlightWindow) int {
+		return cmp.Or(
+			cmp.Compare(b.MinProbability, a.MinProbability),
+			cmp.Compare(b.MeanProbability, a.MeanProbability),
+			a.From.Compare(b.From),
+		)
+	})
+	return windows
+}
+
+// closedHours is the complement of an operating window over the days spans touches. The
+// window was validated when the airports were loaded.
+func closedHours(window DailyWindow, spans []Interval) []Interval {
+	if len(spans) == 0 {
+		return nil
+	}
+	var closed []Interval
+	first := spans[0].From.UTC().Truncate(24 * time.Hour)
+	for day := first; day.Before(spans[len(spans)-1].To); day = day.Add(24 * time.Hour) {
+		open, err := window.on(day)
+		if err != nil {
+			return nil
+		}
+		closed = append(closed, Interval{From: day, To: open.From}, Interval{From: open.To, To: day.Add(24 * time.Hour)})
+	}
+	return closed
+}
+
+// subtractIntervals returns what is left of a after removing every interval of b. a must be
+// sorted and disjoint, which is what it is everywhere here; b need not be.
+func subtractIntervals(a, b []Interval) []Interval {
+	out := a
+	for _, cut := range b {
+		if !cut.To.After(cut.From) {
+			continue
+		}
+		next := make([]Interval, 0, len(out)+1)
+		for _, span := range out {
+			if !cut.From.Before(span.To) || !cut.To.After(span.From) {
+				next = append(next, span)
+				continue
+			}
+			if cut.From.After(span.From) {
+				next = append(next, Interval{From: span.From, To: cut.From})
+			}
+			if cut.To.Before(span.To) {
+				next = append(next, Interval{From: cut.To, To: span.To})
+			}
+		}
+		out = next
+	}
+	return out
+}
+
+// parseWindowQuery reads min_score= and duration=, defaulting both.
+func parseWindowQuery(minScore, duration string) (int, time.Duration, error) {
+	score, length := defaultWindowMinScore, defaultWindowDuration
+	if minScore != "" {
+		parsed, err := strconv.Atoi(minScore)
+		if err != nil || parsed < 0 || parsed > 100 {
+			return 0, 0, fmt.Errorf("min_score must be 0-100, is %q", minScore)
+		}
+		score = parsed
+	}
+	if duration != "" {
+		parsed, err := time.ParseDuration(duration)
+		if err != nil || parsed <= 0 || parsed > maxWindowDuration {
+			return 0, 0, fmt.Errorf("duration must be a positive duration up to %v, such as 2h or 90m, is %q", maxWindowDuration, duration)
+		}
+		length = parsed
+	}
+	return score, length, nil
+}
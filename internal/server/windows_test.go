@@ -0,0 +1,224 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// windowDay is the fixture day as findWindows sees it: every hour scored by score, with
+// testDayLight's night and twilight.
+func windowDay(t *testing.T, score func(hour int) int) *ProcessedWeatherData {
+	t.Helper()
+
+	data := &ProcessedWeatherData{}
+	for hour := 0; hour < 24; hour++ {
+		data.VfrData = append(data.VfrData, VfrPoint{
+			Time:        time.Date(2026, 8, 3, hour, 0, 0, 0, time.UTC).Format("2006-01-02T15:04"),
+			Probability: score(hour),
+		})
+	}
+	daylight := map[string]*SunriseSunsetResponse{"2026-08-03": testDayLight(t)}
+	from, to := time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC), time.Date(2026, 8, 4, 0, 0, 0, 0, time.UTC)
+	data.NightPeriods = nightIntervals(daylight, from, to)
+	data.TwilightPeriods = twilightIntervals(daylight, from, to)
+	return data
+}
+
+// morningAndAfternoon scores 80 until noon, 50 for the noon hour and 90 after it.
+func morningAndAfternoon(hour int) int {
+	switch {
+	case hour < 12:
+		return 80
+	case hour == 12:
+		return 50
+	default:
+		return 90
+	}
+}
+
+func at(hour, minute int) time.Time {
+	return time.Date(2026, 8, 3, hour, minute, 0, 0, time.UTC)
+}
+
+// dawn is well before anything in the fixture, so nothing is clipped to now.
+var dawn = at(0, 0)
+
+func TestFindWindows_RanksAndClipsToDaylight(t *testing.T) {
+	got := findWindows(windowDay(t, morningAndAfternoon), windowOptions{minScore: 70, duration: time.Hour}, dawn)
+
+	// Sunrise 03:30 and sunset 19:30, not the hours either side of them; the afternoon's
+	// better score puts it first.
+	want := []FlightWindow{
+		{From: at(13, 0), To: at(19, 30), DurationMinutes: 390, MinProbability: 90, MeanProbability: 90},
+		{From: at(3, 30), To: at(12, 0), DurationMinutes: 510, MinProbability: 80, MeanProbability: 80},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if !got[i].From.Equal(want[i].From) || !got[i].To.Equal(want[i].To) || got[i].DurationMinutes != want[i].DurationMinutes ||
+			got[i].MinProbability != want[i].MinProbability || got[i].MeanProbability != want[i].MeanProbability {
+			t.Errorf("window %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// Worst hour first, then the mean: a dip costs a window its rank even when the rest of it
+// is better.
+func TestFindWindows_RanksByWorstHourThenMean(t *testing.T) {
+	score := func(hour int) int {
+		switch {
+		case hour >= 6 && hour < 9:
+			return []int{100, 75, 100}[hour-6]
+		case hour >= 14 && hour < 17:
+			return 80
+		}
+		return 0
+	}
+	got := findWindows(windowDay(t, score), windowOptions{minScore: 70, duration: time.Hour}, dawn)
+
+	if len(got) != 2 || !got[0].From.Equal(at(14, 0)) || got[1].MinProbability != 75 || got[1].MeanProbability != 275.0/3 {
+		t.Errorf("got %+v, want 14:00 ahead of the morning that dips to 75", got)
+	}
+}
+
+func TestFindWindows_Constraints(t *testing.T) {
+	tests := []struct {
+		name string
+		opts windowOptions
+		now  time.Time
+		want []Interval
+	}{
+		{
+			name: "too short for the duration",
+			opts: windowOptions{minScore: 70, duration: 7 * time.Hour},
+			now:  dawn,
+			want: []Interval{{at(3, 30), at(12, 0)}},
+		},
+		{
+			name: "a threshold nothing reaches",
+			opts: windowOptions{minScore: 95, duration: time.Hour},
+			now:  dawn,
+		},
+		{
+			name: "operating hours",
+			opts: windowOptions{minScore: 70, duration: time.Hour, operating: &DailyWindow{From: "0800", To: "1800"}},
+			now:  dawn,
+			want: []Interval{{at(13, 0), at(18, 0)}, {at(8, 0), at(12, 0)}},
+		},
+		{
+			name: "a restricted area's activation",
+			opts: windowOptions{minScore: 70, duration: time.Hour, restricted: []Interval{{at(14, 0), at(15, 30)}}},
+			now:  dawn,
+			// Equal scores rank earliest first.
+			want: []Interval{{at(13, 0), at(14, 0)}, {at(15, 30), at(19, 30)}, {at(3, 30), at(12, 0)}},
+		},
+		{
+			name: "what has already gone",
+			opts: windowOptions{minScore: 70, duration: time.Hour},
+			now:  at(11, 20),
+			want: []Interval{{at(13, 0), at(19, 30)}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := findWindows(windowDay(t, morningAndAfternoon), tc.opts, tc.now)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %+v, want %v", got, tc.want)
+			}
+			for i := range tc.want {
+				if !got[i].From.Equal(tc.want[i].From) || !got[i].To.Equal(tc.want[i].To) {
+					t.Errorf("window %d = %v-%v, want %v-%v", i, got[i].From, got[i].To, tc.want[i].From, tc.want[i].To)
+				}
+			}
+		})
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	square := [][2]float64{{52, 7}, {52, 8}, {53, 8}, {53, 7}}
+	if !polygonContains(square, 52.5, 7.5) {
+		t.Error("the centre is outside")
+	}
+	for _, p := range [][2]float64{{51.9, 7.5}, {52.5, 8.1}, {54, 7.5}} {
+		if polygonContains(square, p[0], p[1]) {
+			t.Errorf("%v is inside", p)
+		}
+	}
+	if polygonContains(nil, 52.5, 7.5) {
+		t.Error("an area with no boundary contains a point")
+	}
+}
+
+func TestParseWindowQuery(t *testing.T) {
+	if score, duration, err := parseWindowQuery("", ""); err != nil || score != defaultWindowMinScore || duration != defaultWindowDuration {
+		t.Errorf("defaults = %d, %v, %v", score, duration, err)
+	}
+	if score, duration, err := parseWindowQuery("55", "90m"); err != nil || score != 55 || duration != 90*time.Minute {
+		t.Errorf("55, 90m = %d, %v, %v", score, duration, err)
+	}
+	for _, q := range [][2]string{{"101", ""}, {"x", ""}, {"", "2"}, {"", "-1h"}, {"", "13h"}} {
+		if _, _, err := parseWindowQuery(q[0], q[1]); err == nil {
+			t.Errorf("parseWindowQuery(%q, %q) accepted", q[0], q[1])
+		}
+	}
+}
+
+func TestGetWindows(t *testing.T) {
+	withTestAirports(t)
+	stubAUP(t, nil)
+
+	// Six good hours from the current one, and an ED-R over EDWN active for the third.
+	hour := time.Now().UTC().Truncate(time.Hour)
+	payload := &ProcessedWeatherData{}
+	for i := range 6 {
+		payload.VfrData = append(payload.VfrData, VfrPoint{Time: hour.Add(time.Duration(i) * time.Hour).Format("2006-01-02T15:04"), Probability: 90})
+	}
+	stubFetchWeather(t, func(context.Context, Airport, *scoringProfile) (*ProcessedWeatherData, error) {
+		return payload, nil
+	})
+	restrictions.mutex.Lock()
+	restrictions.areas = []RestrictedArea{
+		{Name: "ED-R37A", Polygon: [][2]float64{{52, 7}, {52, 8}, {53, 8}, {53, 7}}, Windows: []RestrictionWindow{{From: hour.Add(2 * time.Hour), To: hour.Add(3 * time.Hour)}}},
+		{Name: "ED-R112A", Polygon: [][2]float64{{50, 7}, {50, 8}, {51, 8}, {51, 7}}, Windows: []RestrictionWindow{{From: hour, To: hour.Add(6 * time.Hour)}}},
+	}
+	restrictions.mutex.Unlock()
+
+	rec := httptest.NewRecorder()
+	getWindows(rec, httptest.NewRequest(http.MethodGet, "/api/windows?airport=EDWN&min_score=70&duration=2h", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var got WindowsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(got.RestrictedAreas) != 1 || got.RestrictedAreas[0] != "ED-R37A" {
+		t.Errorf("restricted areas = %v, want only the one over EDWN", got.RestrictedAreas)
+	}
+	// The hours before the activation are under two hours once now has taken its bite.
+	if len(got.Windows) != 1 || !got.Windows[0].From.Equal(hour.Add(3*time.Hour)) || !got.Windows[0].To.Equal(hour.Add(6*time.Hour)) {
+		t.Errorf("windows = %+v, want the three hours after the activation", got.Windows)
+	}
+
+	for _, query := range []string{
+		"?airport=EDXX",
+		"?min_score=200",
+		"?duration=forever",
+		"?operating_hours=maybe",
+		// The test EDWG has no operating window to apply.
+		"?airport=EDWG&operating_hours=true",
+	} {
+		rec := httptest.NewRecorder()
+		getWindows(rec, httptest.NewRequest(http.MethodGet, "/api/windows"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
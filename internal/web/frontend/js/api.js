@@ -4,7 +4,7 @@ import { charts } from './charts.js';
 import { applyInitialZoomOnce } from './panzoom.js';
 import { getAppConfig, getCurrentAirportId, isHomeAirport } from './airports.js';
 import { toEpochMs } from './time.js';
-import { shouldReload, shouldReloadPage, latestModelRun, formatModelRun } from './status.js';
+import { shouldReload, shouldReloadPage, observationChanged, latestModelRun, formatModelRun } from './status.js';
 import { setBands } from './bands.js';
 import { loadRestrictions, windowsFor, HOME_AREA } from './restrictions.js';
 
@@ -15,6 +15,9 @@ let lastLoadedAt = Date.now();
 // this, so it is what makes a reload conditional rather than periodic.
 let renderedRun = null;
 
+// The METAR the first hours on screen were scored from, or null when they are all forecast.
+let renderedObservation = null;
+
 // The two conditions the error area can be in. They are tracked separately because they are
 // different events: one means there is no forecast, the other means there is one but the
 // mechanism that keeps it current has stopped working.
@@ -168,6 +171,7 @@ export function updateCharts(data) {
         restricted: home ? windowsFor(HOME_AREA) : [],
     });
     lastLoadedAt = Date.now();
+    renderedObservation = data.nowcast ? data.nowcast.observed_at : null;
 
     // Guarded like wind_data and vfr_data below. Unguarded, a payload missing this one
     // series threw here and aborted updateCharts before any of the other three charts were
@@ -285,7 +289,8 @@ export function updateCharts(data) {
             // Add line data for the crosswind components at 10m
             crosswind10mData.push({
                 x: timeValue,
-                y: timePoint.crosswind_10m
+                y: timePoint.crosswind_10m,
+                runways: timePoint.runways
             });
 
             crosswindGusts10mData.push({
@@ -314,7 +319,10 @@ export function updateCharts(data) {
                 probability: timePoint.probability, // Keep original percentage for display
                 weatherCode: timePoint.weather_code, // Include weather code for icon display
                 visibilityKnown: timePoint.visibility_known, // false => score is an estimate
-                penalties: timePoint.penalties // what the score lost, worst first; absent when nothing did
+                penalties: timePoint.penalties, // what the score lost, worst first; absent when nothing did
+                source: timePoint.source, // forecast, observed or blended -- see formatSource
+                observed_weight: timePoint.observed_weight,
+                ensemble: timePoint.ensemble // the members' spread; absent past the ensemble's range
             })
         })
     }
@@ -346,7 +354,8 @@ let pageReloadRequested = false;
 export async function checkForNewData() {
     let status = null;
     try {
-        const response = await fetch('/api/status');
+        const airport = encodeURIComponent(getCurrentAirportId());
+        const response = await fetch(`/api/status?airport=${airport}`);
         if (response.ok) {
             status = await response.json();
         }
@@ -367,7 +376,8 @@ export async function checkForNewData() {
     }
 
     const latest = status ? status.latest_initialized_at : null;
-    if (shouldReload(renderedRun, latest, Date.now() - lastLoadedAt, MAX_AGE_MS)) {
+    const newObservation = status !== null && observationChanged(renderedObservation, status.latest_observed_at);
+    if (newObservation || shouldReload(renderedRun, latest, Date.now() - lastLoadedAt, MAX_AGE_MS)) {
         await loadWeatherData();
     }
 }
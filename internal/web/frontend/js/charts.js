@@ -10,7 +10,8 @@
 import './plugins.js';
 import { getWindDirectionName } from './plugins.js';
 import { pinAxisWidth, AXIS_WIDTHS_WIDE, isNarrowViewport } from './viewport.js';
-import { formatPenalties } from './vfr-penalties.js';
+import { formatPenalties, formatSource, formatEnsemble } from './vfr-penalties.js';
+import { formatRunwayWinds } from './runways.js';
 
 export const charts = {
     vfr: null,
@@ -19,6 +20,13 @@ export const charts = {
     wind: null,
 };
 
+// vfrPointAt is the VFR point for the hour at x, for the tooltips of the other three charts:
+// their own points carry a temperature or a wind, not what the hour was scored from.
+function vfrPointAt(x) {
+    const points = (charts.vfr && charts.vfr.data.datasets[0].data) || [];
+    return points.find(point => point.x === x);
+}
+
 export function initializeCharts() {
     const vfrCtx = document.getElementById('vfrChart').getContext('2d');
 
@@ -132,10 +140,20 @@ export function initializeCharts() {
                             // Display time in local timezone with date and time
                             return new Date(context[0].parsed.x).toLocaleString();
                         },
+                        // Observed or forecast, under the time. Empty for a forecast hour,
+                        // which Chart.js then leaves out.
+                        afterTitle: function(context) {
+                            return formatSource(vfrPointAt(context[0].parsed.x));
+                        },
                         // What the score lost and to what. Chart.js renders an array as
                         // one line each.
                         label: function(context) {
                             return formatPenalties(context.raw, { compact: isNarrowViewport() });
+                        },
+                        // How sure the score is, under the breakdown. Empty where the
+                        // ensemble does not reach, which Chart.js then leaves out.
+                        footer: function(context) {
+                            return formatEnsemble(context[0].raw);
                         }
                     }
                 }
@@ -270,6 +288,11 @@ export function initializeCharts() {
                             // Display time in local timezone with date and time
                             return new Date(context[0].parsed.x).toLocaleString();
                         },
+                        // Observed or forecast, under the time. Empty for a forecast hour,
+                        // which Chart.js then leaves out.
+                        afterTitle: function(context) {
+                            return formatSource(vfrPointAt(context[0].parsed.x));
+                        },
                         label: function(context) {
                             const point = context.raw;
                             if (context.dataset.label === '2m Temperature (°C)') {
@@ -407,6 +430,11 @@ export function initializeCharts() {
                             // Display time in local timezone with date and time
                             return new Date(context[0].parsed.x).toLocaleString();
                         },
+                        // Observed or forecast, under the time. Empty for a forecast hour,
+                        // which Chart.js then leaves out.
+                        afterTitle: function(context) {
+                            return formatSource(vfrPointAt(context[0].parsed.x));
+                        },
                         // One row per dataset, each reporting its own series.
                         //
                         // Visibility used to be appended to the cloud row, which read fine
@@ -587,6 +615,11 @@ export function initializeCharts() {
                             // Display time in local timezone with date and time
                             return new Date(context[0].parsed.x).toLocaleString();
                         },
+                        // Observed or forecast, under the time. Empty for a forecast hour,
+                        // which Chart.js then leaves out.
+                        afterTitle: function(context) {
+                            return formatSource(vfrPointAt(context[0].parsed.x));
+                        },
                         label: function(context) {
                             const point = context.raw;
                             if (context.dataset.label === 'Wind Layers') {
@@ -596,7 +629,8 @@ export function initializeCharts() {
                             } else if (context.dataset.label === 'Wind Gusts 10m (kn)') {
                                 return `Wind Gusts: ${point.y.toFixed(1)} kn`;
                             } else if (context.dataset.label === 'Crosswind 10m (kn)') {
-                                return `Crosswind: ${point.y.toFixed(1)} kn`;
+                                // The crosswind is on the end in use; the ends say which.
+                                return [`Crosswind: ${point.y.toFixed(1)} kn`, ...formatRunwayWinds(point.runways)];
                             } else if (context.dataset.label === 'Crosswind Gusts 10m (kn)') {
                                 return `Crosswind Gusts: ${point.y.toFixed(1)} kn`;
                             }
@@ -0,0 +1,38 @@
+// Formats the wind on each runway end for the wind chart's tooltip.
+//
+// The crosswind line is the crosswind on the runway end in use, and at a field with more
+// than one runway the reader wants to know which one that is, and what the others would
+// have been. The backend sends every end with its head- or tailwind, crosswind and gust
+// crosswind (internal/server/runways.go), and a low sun ahead of it; this turns them into
+// lines.
+//
+// Deliberately free of Chart.js and of the DOM, so internal/web/jstest/ can run it under
+// node --test.
+
+// formatRunwayWinds turns an hour's runway ends into tooltip lines, the end in use first.
+// An hour without ends -- an airfield whose runways are unknown -- has no lines.
+export function formatRunwayWinds(runways) {
+    if (!runways || runways.length === 0) {
+        return [];
+    }
+    const ordered = [...runways].sort((a, b) => Number(Boolean(b.in_use)) - Number(Boolean(a.in_use)));
+    return ordered.map(formatRunwayWind);
+}
+
+function formatRunwayWind(end) {
+    const name = `RWY ${end.runway}${end.in_use ? ' in use' : ''}`;
+    if (end.unusable) {
+        return `${name}: ${end.unusable}`;
+    }
+
+    const along = Math.round(Math.abs(end.headwind));
+    const parts = [along === 0 ? 'no headwind' : `${along} kn ${end.headwind < 0 ? 'tailwind' : 'headwind'}`];
+    const crosswind = Math.round(end.crosswind);
+    const gusts = Math.round(end.crosswind_gusts);
+    parts.push(gusts > crosswind ? `${crosswind} kn crosswind (gusts ${gusts})` : `${crosswind} kn crosswind`);
+    // Set only for an end with a low sun ahead of it: see internal/server/glare.go.
+    if (end.sun_glare != null) {
+        parts.push(`low sun ${Math.round(end.sun_glare)}° ahead`);
+    }
+    return `${name}: ${parts.join(', ')}`;
+}
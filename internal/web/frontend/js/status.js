@@ -31,6 +31,18 @@ export function shouldReload(renderedRun, latestRun, ageMs, maxAgeMs) {
     return latestRun !== renderedRun;
 }
 
+// observationChanged decides whether the nowcast on screen is out of date: the first hours
+// are re-scored from each new METAR, which arrives without a new model run.
+//
+// Either side may be absent -- no station in range, or an observation too old to use -- and
+// a change in that is a change too: a nowcast that has expired must give way to the plain
+// forecast, and one that has appeared must be shown.
+export function observationChanged(renderedObservedAt, latestObservedAt) {
+    const rendered = isKnownRun(renderedObservedAt) ? renderedObservedAt : null;
+    const latest = isKnownRun(latestObservedAt) ? latestObservedAt : null;
+    return rendered !== latest;
+}
+
 // shouldReloadPage decides whether the page itself is out of date -- a deployment happened
 // and this tab is running frontend code the server no longer serves.
 //
@@ -28,13 +28,53 @@ export function formatPenalties(point, { compact = false } = {}) {
     return penalties.map(penalty => formatPenalty(penalty, compact));
 }
 
+// formatSource says what an hour was scored from, or nothing for a plain forecast hour --
+// which is almost all of them, and needs no label. The first hours after a METAR are
+// scored from it, fading back to the model; the reader should know the 100% at 11:00 was
+// measured, and that the 80% at 13:00 is only partly.
+export function formatSource(point) {
+    if (!point || point.probability < 0) {
+        return '';
+    }
+    switch (point.source) {
+    case 'observed':
+        return 'observed';
+    case 'blended': {
+        const observed = Math.round((point.observed_weight || 0) * 100);
+        return `${observed}% observed, ${100 - observed}% forecast`;
+    }
+    default:
+        return '';
+    }
+}
+
+// formatEnsemble says how far the ensemble members agree with the score, or nothing where
+// the ensemble does not reach. P10-P90 is the range most members fall in; the no-go share is
+// named separately, because "P10 0" alone cannot say whether that is one member or half.
+export function formatEnsemble(point) {
+    const ensemble = point && point.ensemble;
+    if (!ensemble || point.probability < 0) {
+        return '';
+    }
+    const range = ensemble.p10 === ensemble.p90
+        ? `members agree: ${ensemble.p50}`
+        : `members: ${ensemble.p10}–${ensemble.p90}, median ${ensemble.p50}`;
+    const noGo = Math.round((ensemble.no_go_fraction || 0) * 100);
+    return noGo > 0 ? `${range}; ${noGo}% no-go` : range;
+}
+
+// FACTOR_SOURCES names the forecasts a penalty's value can come from.
+const FACTOR_SOURCES = { model: 'model', mosmix: 'MOSMIX' };
+
 // formatPenalty renders one factor: what it was, and what it cost.
 //
 // A no-go is not written as a cost. It did not subtract 100 points from something -- it
 // ended the hour on its own, and reporting it as arithmetic would invite the reader to add
 // it to the others.
 function formatPenalty(penalty, compact) {
-    const parts = [penalty.factor, formatValue(penalty.value, penalty.unit)];
+    // A detail says what the number is made of -- "TODR 520m of 600m available" is what a
+    // pilot checks against the runway, where "86.7%" is only arithmetic -- and replaces it.
+    const parts = [penalty.factor, penalty.detail || formatValue(penalty.value, penalty.unit)];
 
     // A scaled factor was charged for what would happen, times how likely it is to happen.
     // Showing only the cost would make an hour of near-certain drizzle and one of unlikely
@@ -42,6 +82,11 @@ function formatPenalty(penalty, compact) {
     if (penalty.scale) {
         parts.push(`at ${formatValue(penalty.scale.value, penalty.scale.unit)}`);
     }
+    // Where DWD's station forecast was weighed against the model, the value is whichever of
+    // the two won, and the reader should know which one the hour was charged for.
+    if (penalty.source) {
+        parts.push(`(${FACTOR_SOURCES[penalty.source] || penalty.source})`);
+    }
     const head = parts.filter(Boolean).join(' ');
 
     // A no-go keeps its word either way: it is the reason the hour scored 0, and it is
@@ -0,0 +1,54 @@
+import assert from 'node:assert/strict';
+import test from 'node:test';
+
+import { formatRunwayWinds } from '../frontend/js/runways.js';
+
+test('an hour without runway ends has no lines', () => {
+    assert.deepEqual(formatRunwayWinds(undefined), []);
+    assert.deepEqual(formatRunwayWinds([]), []);
+});
+
+test('the end in use comes first, with its components rounded', () => {
+    const lines = formatRunwayWinds([
+        { runway: '05', heading: 55, headwind: -7.6, crosswind: 4.8, crosswind_gusts: 8.7 },
+        { runway: '23', heading: 235, headwind: 7.6, crosswind: 4.8, crosswind_gusts: 8.7, in_use: true },
+    ]);
+
+    assert.deepEqual(lines, [
+        'RWY 23 in use: 8 kn headwind, 5 kn crosswind (gusts 9)',
+        'RWY 05: 8 kn tailwind, 5 kn crosswind (gusts 9)',
+    ]);
+});
+
+// Gusts that add nothing across the runway are not worth a parenthesis.
+test('gusts are left out where they do not add to the crosswind', () => {
+    const lines = formatRunwayWinds([
+        { runway: '27', heading: 274.1, headwind: 0.2, crosswind: 12, crosswind_gusts: 12.1, in_use: true },
+    ]);
+
+    assert.deepEqual(lines, ['RWY 27 in use: no headwind, 12 kn crosswind']);
+});
+
+test('an unusable end says why instead of its components', () => {
+    const lines = formatRunwayWinds([
+        { runway: '19', heading: 195.5, headwind: 14.9, crosswind: 1.4, crosswind_gusts: 2.4, unusable: 'too short for the Cessna 172S' },
+        { runway: '27', heading: 274.1, headwind: 1.5, crosswind: 14.9, crosswind_gusts: 24.9, in_use: true },
+    ]);
+
+    assert.deepEqual(lines, [
+        'RWY 27 in use: 2 kn headwind, 15 kn crosswind (gusts 25)',
+        'RWY 19: too short for the Cessna 172S',
+    ]);
+});
+
+test('an end facing a low sun says so', () => {
+    const lines = formatRunwayWinds([
+        { runway: '05', heading: 55, headwind: -7.9, crosswind: 1.4, crosswind_gusts: 1.4 },
+        { runway: '23', heading: 235, headwind: 7.9, crosswind: 1.4, crosswind_gusts: 1.4, in_use: true, sun_glare: 7.5 },
+    ]);
+
+    assert.deepEqual(lines, [
+        'RWY 23 in use: 8 kn headwind, 1 kn crosswind, low sun 8° ahead',
+        'RWY 05: 8 kn tailwind, 1 kn crosswind',
+    ]);
+});
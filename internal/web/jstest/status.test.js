@@ -1,7 +1,7 @@
 import assert from 'node:assert/strict';
 import test from 'node:test';
 
-import { shouldReload, shouldReloadPage, isKnownRun, latestModelRun, formatModelRun } from '../frontend/js/status.js';
+import { shouldReload, shouldReloadPage, observationChanged, isKnownRun, latestModelRun, formatModelRun } from '../frontend/js/status.js';
 
 const MAX_AGE = 15 * 60 * 1000;
 const RUN_06Z = '2026-08-07T06:00:00Z';
@@ -100,3 +100,17 @@ test('an unknown commit on either side reloads nothing', () => {
     assert.equal(shouldReloadPage(undefined, undefined), false);
     assert.equal(shouldReloadPage('601c20f', null), false);
 });
+
+// A new METAR re-scores the first hours without a new model run, so it has to be able to
+// trigger a reload on its own.
+test('a new observation reloads, the same one does not', () => {
+    assert.equal(observationChanged('2026-08-07T11:20:00Z', '2026-08-07T11:50:00Z'), true);
+    assert.equal(observationChanged('2026-08-07T11:20:00Z', '2026-08-07T11:20:00Z'), false);
+});
+
+// An observation appearing or expiring changes what the first hours are scored from too.
+test('an observation appearing or expiring reloads', () => {
+    assert.equal(observationChanged(null, '2026-08-07T11:20:00Z'), true);
+    assert.equal(observationChanged('2026-08-07T11:20:00Z', undefined), true);
+    assert.equal(observationChanged(null, undefined), false);
+});
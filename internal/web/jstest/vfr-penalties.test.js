@@ -1,7 +1,7 @@
 import assert from 'node:assert/strict';
 import test from 'node:test';
 
-import { formatPenalties } from '../frontend/js/vfr-penalties.js';
+import { formatPenalties, formatSource, formatEnsemble } from '../frontend/js/vfr-penalties.js';
 
 test('an hour with nothing against it says so', () => {
     assert.deepEqual(formatPenalties({ probability: 100 }), ['nothing against it']);
@@ -81,6 +81,34 @@ test('an unscaled penalty is unchanged by the scale support', () => {
 // overflow at the box edge -- taking the cost with it, which is the part worth reading.
 // Observed on a 360px screen as "crosswind gust spread 5.7 kn — good, −" with the digit
 // gone. Less text is the reliable lever; the severity word is what a reader can spare.
+// Cloud base and visibility can come from DWD's station forecast instead of the model, at
+// the airfields that have a station; the line says which one the hour was charged for.
+test('a penalty names the forecast its value came from', () => {
+    const lines = formatPenalties({
+        probability: 0,
+        penalties: [{ factor: 'cloud base', value: 4, unit: 'FL', severity: 'no-go', cost: 100, source: 'mosmix' }],
+    });
+    assert.deepEqual(lines, ['cloud base 4 FL (MOSMIX) — no-go']);
+
+    const model = formatPenalties({
+        probability: 81,
+        penalties: [{ factor: 'visibility', value: 12, unit: 'km', severity: 'difficult', cost: 19, source: 'model' }],
+    }, { compact: true });
+    assert.deepEqual(model, ['visibility 12 km (model) — −19']);
+});
+
+test('a penalty with a detail shows it in place of the number', () => {
+    const lines = formatPenalties({
+        probability: 70,
+        penalties: [{
+            factor: 'takeoff distance', value: 86.67, unit: '%', severity: 'difficult', cost: 30,
+            detail: 'TODR 520m of 600m available',
+        }],
+    });
+
+    assert.deepEqual(lines, ['takeoff distance TODR 520m of 600m available — difficult, −30']);
+});
+
 test('the compact form drops the severity word but keeps the cost', () => {
     const point = {
         probability: 99,
@@ -111,3 +139,24 @@ test('a factor without a unit shows no value', () => {
 
     assert.deepEqual(lines, ['daylight — no-go']);
 });
+
+test('a forecast hour carries no source line', () => {
+    assert.equal(formatSource({ probability: 80, source: 'forecast' }), '');
+    assert.equal(formatSource({ probability: 80 }), '');
+});
+
+test('an observed or blended hour says how much of it was measured', () => {
+    assert.equal(formatSource({ probability: 40, source: 'observed', observed_weight: 1 }), 'observed');
+    assert.equal(formatSource({ probability: 60, source: 'blended', observed_weight: 2 / 3 }), '67% observed, 33% forecast');
+});
+
+test('an hour beyond the ensemble carries no ensemble line', () => {
+    assert.equal(formatEnsemble({ probability: 80 }), '');
+    assert.equal(formatEnsemble({ probability: -1, ensemble: { p10: 0, p50: 0, p90: 0 } }), '');
+});
+
+test('the ensemble line gives the members\' range and how many of them are no-go', () => {
+    const ensemble = { members: 40, p10: 0, p50: 55, p90: 85, no_go_fraction: 0.475 };
+    assert.equal(formatEnsemble({ probability: 80, ensemble }), 'members: 0–85, median 55; 48% no-go');
+    assert.equal(formatEnsemble({ probability: 90, ensemble: { p10: 90, p50: 90, p90: 90, no_go_fraction: 0 } }), 'members agree: 90');
+});
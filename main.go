@@ -19,6 +19,16 @@ import (
 )
 
 func main() {
+	// verify is the one subcommand: it reads the forecast archive and exits, and has flags
+	// of its own.
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		if err := server.Verify(os.Args[2:], os.Stdout); err != nil {
+			slog.Error("verification failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// The runtime image is scratch, so there is no shell or curl for a container
 	// HEALTHCHECK to call. The binary probes itself instead.
 	healthcheck := flag.Bool("healthcheck", false,